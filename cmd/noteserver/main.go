@@ -2,28 +2,60 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/KyleBrandon/sibyl/pkg/notes"
 	"github.com/KyleBrandon/sibyl/pkg/utils"
 	"github.com/joho/godotenv"
-	"github.com/mark3labs/mcp-go/server"
 )
 
 var (
 	logLevel        string
 	logFileName     string
 	notesFileFolder string
+	trashRetention  int
+	templatesFolder string
+
+	dailyNotePattern     string
+	weeklyNotePattern    string
+	monthlyNotePattern   string
+	quarterlyNotePattern string
+
+	watchInterval time.Duration
+
+	lintConfig string
+	check      bool
+
+	formatOnWrite bool
+	listMarker    string
+	maxBlankLines int
+
+	exportDir string
 )
 
 func init() {
 	flag.StringVar(&logLevel, "log-level", "INFO", "Default logging level to use")
 	flag.StringVar(&logFileName, "log-file", "notes-server.log", "Default log file to log to")
 	flag.StringVar(&notesFileFolder, "notes-folder", "", "Folder containing the notes")
+	flag.IntVar(&trashRetention, "trash-retention-days", 30, "Days deleted notes are kept in the vault trash and note history snapshots are kept")
+	flag.StringVar(&templatesFolder, "templates-folder", "Templates", "Vault folder containing user note templates")
+	flag.StringVar(&dailyNotePattern, "daily-note-pattern", "Journal/{{yyyy}}/{{yyyy-MM-dd}}.md", "Vault path pattern of daily notes")
+	flag.StringVar(&weeklyNotePattern, "weekly-note-pattern", "Journal/{{gggg}}/{{gggg}}-W{{ww}}.md", "Vault path pattern of weekly notes")
+	flag.StringVar(&monthlyNotePattern, "monthly-note-pattern", "Journal/{{yyyy}}/{{yyyy-MM}}.md", "Vault path pattern of monthly notes")
+	flag.StringVar(&quarterlyNotePattern, "quarterly-note-pattern", "Journal/{{yyyy}}/{{yyyy}}-Q{{Q}}.md", "Vault path pattern of quarterly notes")
+	flag.DurationVar(&watchInterval, "watch-interval", 2*time.Second, "How often to check the vault for outside changes, 0 disables watching")
+	flag.StringVar(&lintConfig, "lint-config", "", "Lint rules file, defaults to .sibyl/lint.yaml in the vault")
+	flag.BoolVar(&formatOnWrite, "format-on-write", false, "Format the markdown written by write_note and merge_note")
+	flag.StringVar(&listMarker, "list-marker", "-", "Bullet format_note writes unordered list items with: -, * or +")
+	flag.IntVar(&maxBlankLines, "max-blank-lines", 1, "Most blank lines format_note keeps between blocks")
+	flag.StringVar(&exportDir, "export-dir", "", "Directory export_site writes sites under, defaults to .sibyl/site in the vault")
+	flag.BoolVar(&check, "check", false, "Lint the vault, print the problems as JSON and exit, with status 1 if any are errors")
 }
 
 func main() {
@@ -44,13 +76,96 @@ func main() {
 	notesRootFolder := parseRootFolder()
 	slog.Info("notesFolder", "folder", notesRootFolder)
 
-	notesServer := notes.NewNotesServer(ctx, notesRootFolder)
+	if check {
+		os.Exit(runCheck(ctx, notesRootFolder))
+	}
+
+	if flag.Arg(0) == "export" {
+		os.Exit(runExport(ctx, notesRootFolder, flag.Args()[1:]))
+	}
+
+	formatStyle := notes.FormatStyle{ListMarker: listMarker, MaxBlankLines: maxBlankLines}
+	if err := formatStyle.Validate(); err != nil {
+		log.Fatalf("Invalid format style: %v", err)
+	}
+
+	notesServer := notes.NewNotesServer(ctx, notesRootFolder,
+		notes.WithTrashRetention(trashRetention),
+		notes.WithTemplatesFolder(templatesFolder),
+		notes.WithPeriodicNotePattern(notes.PeriodDaily, dailyNotePattern),
+		notes.WithPeriodicNotePattern(notes.PeriodWeekly, weeklyNotePattern),
+		notes.WithPeriodicNotePattern(notes.PeriodMonthly, monthlyNotePattern),
+		notes.WithPeriodicNotePattern(notes.PeriodQuarterly, quarterlyNotePattern),
+		notes.WithWatchInterval(watchInterval),
+		notes.WithLintConfig(lintConfig),
+		notes.WithFormatStyle(formatStyle),
+		notes.WithFormatOnWrite(formatOnWrite),
+		notes.WithExportDir(exportDir))
 
-	if err := server.ServeStdio(notesServer.McpServer); err != nil {
+	if err := notesServer.ServeStdio(); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
 
+// runCheck lints the vault and writes the report to stdout. It returns the
+// exit status: 0 when no errors were found, 1 when some were and 2 when the
+// vault could not be linted.
+func runCheck(ctx context.Context, notesRootFolder string) int {
+	notesServer := notes.NewNotesServer(ctx, notesRootFolder,
+		notes.WithTemplatesFolder(templatesFolder),
+		notes.WithLintConfig(lintConfig))
+
+	report, err := notesServer.Lint("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Lint failed: %v\n", err)
+		return 2
+	}
+
+	reportJSON, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(reportJSON))
+
+	if report.Errors > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runExport renders notes to a static site for the export subcommand and
+// writes a summary to stdout. It returns the exit status: 0 when the site was
+// written, 1 when the export failed and 2 for invalid arguments.
+func runExport(ctx context.Context, notesRootFolder string, args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := flags.String("out", "", "Directory to write the site to")
+	folder := flags.String("folder", "", "Vault folder to export, . for the whole vault")
+	published := flags.Bool("published", false, "Export only the notes with publish: true in their frontmatter")
+	title := flags.String("title", "", "Title of the site, defaults to the vault folder name")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *out == "" {
+		fmt.Fprintln(os.Stderr, "export needs --out, the directory to write the site to")
+		return 2
+	}
+
+	notesServer := notes.NewNotesServer(ctx, notesRootFolder,
+		notes.WithTemplatesFolder(templatesFolder))
+
+	result, err := notesServer.Export(notes.ExportOptions{
+		Folder:    *folder,
+		Published: *published,
+		Target:    *out,
+		Title:     *title,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(resultJSON))
+	return 0
+}
+
 func parseRootFolder() string {
 	// if there is no commandline argument for the notes folder, see if there is an environment
 	if notesFileFolder == "" {
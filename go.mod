@@ -7,6 +7,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/mark3labs/mcp-go v0.34.0
 	google.golang.org/api v0.241.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -9,12 +9,17 @@ type NoteMetadata struct {
 	Size     int64     `json:"size"`
 	Modified time.Time `json:"modified"`
 	IsDir    bool      `json:"is_dir"`
+
+	// Frontmatter holds the parsed YAML frontmatter of a note
+	Frontmatter map[string]interface{} `json:"frontmatter,omitempty"`
 }
 
 // SearchResult represents a search result
 type SearchResult struct {
-	Path    string `json:"path"`
-	Line    int    `json:"line"`
-	Content string `json:"content"`
-	Context string `json:"context"`
+	Path      string  `json:"path"`
+	Line      int     `json:"line"`
+	Content   string  `json:"content"`
+	Context   string  `json:"context"`
+	Score     float64 `json:"score"`
+	Highlight string  `json:"highlight,omitempty"`
 }
@@ -0,0 +1,77 @@
+package notes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ConflictError reports that a note changed since the caller last read it
+type ConflictError struct {
+	Path         string `json:"path"`
+	ExpectedHash string `json:"expected_hash"`
+	CurrentHash  string `json:"current_hash"`
+}
+
+func (e *ConflictError) Error() string {
+	if e.CurrentHash == "" {
+		return fmt.Sprintf("conflict: %s does not exist", e.Path)
+	}
+	return fmt.Sprintf("conflict: %s has changed since it was read (expected hash %s, current hash %s)", e.Path, e.ExpectedHash, e.CurrentHash)
+}
+
+// checkExpectedHash compares the note on disk with the hash the caller last
+// saw. An empty expected hash skips the check.
+func checkExpectedHash(fullPath, relativePath, expectedHash string) *ConflictError {
+	if expectedHash == "" {
+		return nil
+	}
+
+	currentHash := ""
+	if content, err := utils.ReadFile(fullPath); err == nil {
+		currentHash = contentHash(content)
+	}
+
+	if currentHash != expectedHash {
+		return &ConflictError{Path: relativePath, ExpectedHash: expectedHash, CurrentHash: currentHash}
+	}
+
+	return nil
+}
+
+// conflictResult converts a ConflictError into a structured MCP tool error
+func conflictResult(err *ConflictError) *mcp.CallToolResult {
+	payload := map[string]interface{}{
+		"error":         "conflict",
+		"message":       err.Error() + "; read the note again and reapply the change",
+		"path":          err.Path,
+		"expected_hash": err.ExpectedHash,
+		"current_hash":  err.CurrentHash,
+	}
+	payloadJSON, _ := json.MarshalIndent(payload, "", "  ")
+
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(payloadJSON)),
+		},
+	}
+}
+
+// hashMeta attaches the content hash of a note to a tool result
+func hashMeta(content []byte) mcp.Result {
+	return mcp.Result{Meta: map[string]any{"hash": contentHash(content)}}
+}
+
+// editErrorResult converts an error from modifyNote into an MCP tool error,
+// keeping conflicts structured
+func editErrorResult(err error) *mcp.CallToolResult {
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		return conflictResult(conflict)
+	}
+	return toolErrorResult("%v", err)
+}
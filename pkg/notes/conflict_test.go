@@ -0,0 +1,122 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func readHashForTest(t *testing.T, ns *NotesServer, path string) string {
+	t.Helper()
+
+	result, err := ns.ReadNote(context.Background(), mcp.CallToolRequest{}, ReadNoteRequest{Path: path})
+	if err != nil {
+		t.Fatalf("ReadNote failed: %v", err)
+	}
+
+	hash, _ := result.Meta["hash"].(string)
+	if hash == "" {
+		t.Fatal("ReadNote should return the content hash in _meta")
+	}
+
+	if len(result.Content) != 2 || result.Content[1].(mcp.TextContent).Text != "hash: "+hash {
+		t.Errorf("ReadNote should return the content hash after the note content, got %+v", result.Content)
+	}
+
+	return hash
+}
+
+func assertConflict(t *testing.T, result *mcp.CallToolResult, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("Expected MCP error, not Go error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("Expected a conflict error")
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &payload); err != nil {
+		t.Fatalf("Expected JSON error payload: %v", err)
+	}
+	if _, ok := payload["current_hash"]; payload["error"] != "conflict" || !ok {
+		t.Errorf("Unexpected conflict payload: %v", payload)
+	}
+}
+
+func TestExpectedHash_DetectsConcurrentEdits(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+	notePath := filepath.Join(tempDir, "note.md")
+
+	if err := os.WriteFile(notePath, []byte("# Note\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test note: %v", err)
+	}
+
+	hash := readHashForTest(t, ns, "note.md")
+
+	// Another editor changes the note after it was read
+	if err := os.WriteFile(notePath, []byte("# Note\n\nEdited in Obsidian\n"), 0644); err != nil {
+		t.Fatalf("Failed to edit test note: %v", err)
+	}
+
+	result, err := ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "note.md", Content: "overwrite", ExpectedHash: hash})
+	assertConflict(t, result, err)
+
+	result, err = ns.AppendNote(ctx, mcp.CallToolRequest{}, AppendNoteRequest{Path: "note.md", Content: "more", ExpectedHash: hash})
+	assertConflict(t, result, err)
+
+	result, err = ns.MergeNote(ctx, mcp.CallToolRequest{}, MergeNoteRequest{Path: "note.md", Content: "more", ExpectedHash: hash})
+	assertConflict(t, result, err)
+
+	if got := readForTest(t, tempDir, "note.md"); got != "# Note\n\nEdited in Obsidian\n" {
+		t.Errorf("Conflicting writes must leave the note untouched, got %q", got)
+	}
+}
+
+func TestExpectedHash_AllowsUpToDateWrites(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	writeNoteForTest(t, tempDir, "note.md", "# Note\n")
+
+	hash := readHashForTest(t, ns, "note.md")
+
+	result, err := ns.AppendNote(ctx, mcp.CallToolRequest{}, AppendNoteRequest{Path: "note.md", Content: "appended\n", ExpectedHash: hash})
+	if err != nil || result.IsError {
+		t.Fatalf("AppendNote failed: %v %v", err, result)
+	}
+
+	// Each write returns the new hash so changes can be chained
+	hash, _ = result.Meta["hash"].(string)
+	if hash != readHashForTest(t, ns, "note.md") {
+		t.Fatal("AppendNote should return the hash of the updated note")
+	}
+
+	var merge MergeResult
+	result, err = ns.MergeNote(ctx, mcp.CallToolRequest{}, MergeNoteRequest{Path: "note.md", Content: "merged", ExpectedHash: hash})
+	decodeToolResult(t, result, err, &merge)
+
+	result, err = ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "note.md", Content: "final", ExpectedHash: merge.Hash})
+	if err != nil || result.IsError {
+		t.Fatalf("WriteNote failed: %v %v", err, result)
+	}
+	if !strings.Contains(result.Content[0].(mcp.TextContent).Text, contentHash([]byte("final"))) {
+		t.Errorf("WriteNote should report the new hash, got %q", result.Content[0].(mcp.TextContent).Text)
+	}
+}
+
+func TestExpectedHash_MissingNote(t *testing.T) {
+	ns := &NotesServer{vaultDir: t.TempDir()}
+
+	result, err := ns.WriteNote(context.Background(), mcp.CallToolRequest{}, WriteNoteRequest{Path: "new.md", Content: "x", ExpectedHash: "abc"})
+	assertConflict(t, result, err)
+}
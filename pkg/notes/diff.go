@@ -0,0 +1,232 @@
+package notes
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines shown around each change
+	diffContextLines = 3
+
+	// diffMaxCells bounds the LCS table; larger changes are shown as a
+	// whole-block replacement instead of a minimal diff
+	diffMaxCells = 4_000_000
+
+	// noNewlineMarker follows the last line of a text without a final newline
+	noNewlineMarker = "\\ No newline at end of file"
+)
+
+// diffOp is a single line in a line diff. Kind is ' ' for unchanged lines,
+// '-' for removed lines and '+' for added lines. Line numbers are 1-based
+// and zero when the line does not exist on that side.
+type diffOp struct {
+	Kind    byte
+	Text    string
+	OldLine int
+	NewLine int
+}
+
+// splitLines splits content into lines without their terminators
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffTextLines splits content into the lines shown in a diff. When the
+// content does not end with a newline its last line carries noNewlineMarker,
+// so a change to only the final newline shows up as it does in diff -u.
+func diffTextLines(content string) []string {
+	lines := splitLines(content)
+	if len(lines) > 0 && !strings.HasSuffix(content, "\n") {
+		lines[len(lines)-1] += "\n" + noNewlineMarker
+	}
+	return lines
+}
+
+// diffLines computes a line diff between two slices using the longest common
+// subsequence of the lines that differ after trimming the common prefix and suffix
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{Kind: ' ', Text: a[i], OldLine: i + 1, NewLine: i + 1})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	oldLine, newLine := prefix+1, prefix+1
+	emit := func(kind byte, text string) {
+		op := diffOp{Kind: kind, Text: text}
+		if kind != '+' {
+			op.OldLine = oldLine
+			oldLine++
+		}
+		if kind != '-' {
+			op.NewLine = newLine
+			newLine++
+		}
+		ops = append(ops, op)
+	}
+
+	if len(midA)*len(midB) > diffMaxCells {
+		for _, line := range midA {
+			emit('-', line)
+		}
+		for _, line := range midB {
+			emit('+', line)
+		}
+	} else {
+		// lcs[i][j] is the LCS length of midA[i:] and midB[j:]
+		lcs := make([][]int, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(midA) && j < len(midB) {
+			switch {
+			case midA[i] == midB[j]:
+				emit(' ', midA[i])
+				i++
+				j++
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				emit('-', midA[i])
+				i++
+			default:
+				emit('+', midB[j])
+				j++
+			}
+		}
+		for ; i < len(midA); i++ {
+			emit('-', midA[i])
+		}
+		for ; j < len(midB); j++ {
+			emit('+', midB[j])
+		}
+	}
+
+	for i := len(a) - suffix; i < len(a); i++ {
+		emit(' ', a[i])
+	}
+
+	return ops
+}
+
+// diffHunk is a group of changes with their surrounding context
+type diffHunk struct {
+	OldStart, OldCount int
+	NewStart, NewCount int
+	Ops                []diffOp
+}
+
+// diffHunks groups a line diff into hunks with the given number of context lines
+func diffHunks(ops []diffOp, context int) []diffHunk {
+	var hunks []diffHunk
+
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		start := max(0, i-context)
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].Kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*context {
+				end = min(len(ops), end+context)
+				break
+			}
+			end = next
+		}
+
+		hunk := diffHunk{Ops: ops[start:end]}
+		for _, op := range hunk.Ops {
+			if op.Kind != '+' {
+				if hunk.OldStart == 0 {
+					hunk.OldStart = op.OldLine
+				}
+				hunk.OldCount++
+			}
+			if op.Kind != '-' {
+				if hunk.NewStart == 0 {
+					hunk.NewStart = op.NewLine
+				}
+				hunk.NewCount++
+			}
+		}
+
+		// Empty sides start at the line before the change, as in diff -u
+		if hunk.OldCount == 0 {
+			hunk.OldStart = precedingLine(ops[:start], func(op diffOp) int { return op.OldLine })
+		}
+		if hunk.NewCount == 0 {
+			hunk.NewStart = precedingLine(ops[:start], func(op diffOp) int { return op.NewLine })
+		}
+
+		hunks = append(hunks, hunk)
+		i = end
+	}
+
+	return hunks
+}
+
+// precedingLine returns the last non-zero line number before a hunk
+func precedingLine(ops []diffOp, line func(op diffOp) int) int {
+	for i := len(ops) - 1; i >= 0; i-- {
+		if n := line(ops[i]); n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// unifiedDiff renders the difference between two texts in unified diff
+// format. It returns an empty string when the texts are equal.
+func unifiedDiff(fromName, toName, from, to string) string {
+	hunks := diffHunks(diffLines(diffTextLines(from), diffTextLines(to)), diffContextLines)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", hunk.OldStart, hunk.OldCount, hunk.NewStart, hunk.NewCount)
+		for _, op := range hunk.Ops {
+			b.WriteByte(op.Kind)
+			b.WriteString(op.Text)
+			b.WriteByte('\n')
+		}
+	}
+
+	return b.String()
+}
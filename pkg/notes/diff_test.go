@@ -0,0 +1,81 @@
+package notes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		expected string
+	}{
+		{
+			name:     "Equal",
+			from:     "a\nb\n",
+			to:       "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "Changed line",
+			from:     "a\nb\nc\n",
+			to:       "a\nB\nc\n",
+			expected: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name:     "Added to empty",
+			from:     "",
+			to:       "a\n",
+			expected: "--- old\n+++ new\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+		{
+			name:     "Removed at end",
+			from:     "a\nb\n",
+			to:       "a\n",
+			expected: "--- old\n+++ new\n@@ -1,2 +1,1 @@\n a\n-b\n",
+		},
+		{
+			name:     "Final newline added",
+			from:     "a\nb",
+			to:       "a\nb\n",
+			expected: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name:     "Final newline removed",
+			from:     "a\n",
+			to:       "a",
+			expected: "--- old\n+++ new\n@@ -1,1 +1,1 @@\n-a\n+a\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("old", "new", tt.from, tt.to); got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff_SeparateHunks(t *testing.T) {
+	var from, to []string
+	for i := 1; i <= 20; i++ {
+		line := strings.Repeat("x", i)
+		from = append(from, line)
+		if i == 2 || i == 18 {
+			line += " changed"
+		}
+		to = append(to, line)
+	}
+
+	diff := unifiedDiff("old", "new", strings.Join(from, "\n"), strings.Join(to, "\n"))
+
+	if strings.Count(diff, "@@ -") != 2 {
+		t.Fatalf("Expected 2 hunks, got:\n%s", diff)
+	}
+	if !strings.Contains(diff, "@@ -1,5 +1,5 @@\n") || !strings.Contains(diff, "@@ -15,6 +15,6 @@\n") {
+		t.Errorf("Unexpected hunk headers:\n%s", diff)
+	}
+}
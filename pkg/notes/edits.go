@@ -0,0 +1,30 @@
+package notes
+
+import (
+	"sort"
+	"strings"
+)
+
+// textEdit replaces the bytes between start and end with text. An insertion
+// has start equal to end.
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// applyTextEdits applies non-overlapping edits to content. Insertions at the
+// same offset keep their order.
+func applyTextEdits(content string, edits []textEdit) string {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, edit := range edits {
+		b.WriteString(content[last:edit.start])
+		b.WriteString(edit.text)
+		last = edit.end
+	}
+	b.WriteString(content[last:])
+
+	return b.String()
+}
@@ -0,0 +1,25 @@
+package notes
+
+import "testing"
+
+func TestApplyTextEdits(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		edits    []textEdit
+		expected string
+	}{
+		{"No edits", "text", nil, "text"},
+		{"Replacements out of order", "see [[a]] and [[b]]", []textEdit{{16, 17, "d"}, {6, 7, "c"}}, "see [[c]] and [[d]]"},
+		{"Insertions at the same offset keep their order", "ab", []textEdit{{1, 1, "1"}, {1, 1, "2"}}, "a12b"},
+		{"Deletion", "one two", []textEdit{{3, 7, ""}}, "one"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := applyTextEdits(tt.content, tt.edits); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,722 @@
+package notes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// defaultExportFolder is the folder inside sibylDirName export_site
+	// writes sites to, unless WithExportDir sets another
+	defaultExportFolder = "site"
+
+	// Folders of the site holding the note pages, the copied attachments
+	// and the tag pages. The index, tags, backlinks and search pages are
+	// at the root of the site, so no note can replace them.
+	siteNotesFolder       = "notes"
+	siteAttachmentsFolder = "files"
+	siteTagsFolder        = "tags"
+
+	// siteSearchIndex is the search index written next to the search page
+	siteSearchIndex = "search-index.json"
+)
+
+// siteImageExtensions are the attachments embeds show as images
+var siteImageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true,
+	".svg": true, ".webp": true, ".bmp": true, ".avif": true,
+}
+
+// ExportSiteRequest represents a request to export notes as a static site
+type ExportSiteRequest struct {
+	Folder    string `json:"folder,omitempty" mcp:"Vault folder to export"`
+	Published bool   `json:"published,omitempty" mcp:"Export only the notes with publish: true in their frontmatter"`
+	Target    string `json:"target,omitempty" mcp:"Directory to write the site to, relative to the export folder"`
+	Title     string `json:"title,omitempty" mcp:"Title of the site"`
+}
+
+// ExportOptions selects the notes Export renders and where the site goes
+type ExportOptions struct {
+	// Folder limits the export to the notes under a vault folder, "." for
+	// the whole vault
+	Folder string
+
+	// Published limits the export to notes with publish: true
+	Published bool
+
+	// Target is the directory the site is written to
+	Target string
+
+	// Title is the site title, defaults to the name of the vault folder
+	Title string
+}
+
+// ExportResult describes an exported site
+type ExportResult struct {
+	Target      string   `json:"target"`
+	Notes       []string `json:"notes"`
+	Attachments []string `json:"attachments"`
+	Tags        int      `json:"tags"`
+	Files       int      `json:"files"`
+}
+
+// sitePage is an exported note
+type sitePage struct {
+	source  string // vault path
+	url     string // slash separated path in the site
+	title   string
+	tags    []string
+	body    string
+	content template.HTML
+
+	// vault paths of the exported notes linking to this one
+	backlinks map[string]bool
+}
+
+// siteTag is a tag page, listing the notes with the tag or a tag nested
+// below it
+type siteTag struct {
+	name  string
+	url   string
+	pages map[string]*sitePage
+}
+
+// siteExport holds the state of an export while its pages are rendered
+type siteExport struct {
+	ns       *NotesServer
+	resolver *linkResolver
+	pages    map[string]*sitePage
+	tags     map[string]*siteTag // by lower-cased tag
+
+	// site paths of the attachments linked from exported notes by vault
+	// path, "" for files that cannot be exported
+	attachments map[string]string
+}
+
+// siteLink is a link in the lists of the generated pages
+type siteLink struct {
+	Title string
+	URL   string
+	Count int
+}
+
+// siteSection is a titled list of links
+type siteSection struct {
+	Title string
+	URL   string
+	Links []siteLink
+}
+
+// siteSearchEntry is a note in the search index
+type siteSearchEntry struct {
+	Title string   `json:"title"`
+	URL   string   `json:"url"`
+	Tags  []string `json:"tags"`
+	Text  string   `json:"text"`
+}
+
+// sitePageData is what the page template renders
+type sitePageData struct {
+	Site       string
+	Title      string
+	Root       string // relative URL of the site root
+	Heading    string
+	Tags       []siteLink
+	Content    template.HTML
+	Sections   []siteSection
+	Backlinks  []siteLink
+	SearchPage bool
+	Search     []siteSearchEntry
+}
+
+var sitePageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{if .Title}}{{.Title}} - {{end}}{{.Site}}</title>
+<link rel="stylesheet" href="{{.Root}}style.css">
+</head>
+<body>
+<header>
+<a class="site" href="{{.Root}}index.html">{{.Site}}</a>
+<nav><a href="{{.Root}}tags.html">Tags</a> <a href="{{.Root}}backlinks.html">Backlinks</a> <a href="{{.Root}}search.html">Search</a></nav>
+</header>
+<main>
+{{- if .Heading}}
+<h1>{{.Heading}}</h1>
+{{- end}}
+{{- if .Tags}}
+<p class="tags">{{range .Tags}}<a class="tag" href="{{.URL}}">#{{.Title}}</a> {{end}}</p>
+{{- end}}
+{{- if .Content}}
+<article>
+{{.Content}}</article>
+{{- end}}
+{{- range .Sections}}
+<section>
+<h2>{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</h2>
+<ul>
+{{- range .Links}}
+<li><a href="{{.URL}}">{{.Title}}</a>{{if .Count}} ({{.Count}}){{end}}</li>
+{{- end}}
+</ul>
+</section>
+{{- end}}
+{{- if .Backlinks}}
+<section class="backlinks">
+<h2>Backlinks</h2>
+<ul>
+{{- range .Backlinks}}
+<li><a href="{{.URL}}">{{.Title}}</a></li>
+{{- end}}
+</ul>
+</section>
+{{- end}}
+{{- if .SearchPage}}
+<input id="query" type="search" placeholder="Search notes" autofocus>
+<ul id="results"></ul>
+<script>
+const index = {{.Search}};
+const query = document.getElementById("query");
+const results = document.getElementById("results");
+query.addEventListener("input", () => {
+  const terms = query.value.toLowerCase().split(/\s+/).filter(Boolean);
+  results.replaceChildren();
+  if (terms.length === 0) {
+    return;
+  }
+  for (const entry of index) {
+    const text = [entry.title, entry.tags.join(" "), entry.text].join(" ").toLowerCase();
+    if (terms.every((term) => text.includes(term))) {
+      const link = document.createElement("a");
+      link.href = entry.url;
+      link.textContent = entry.title;
+      const item = document.createElement("li");
+      item.appendChild(link);
+      results.appendChild(item);
+    }
+  }
+});
+</script>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+const siteStylesheet = `body { margin: 0 auto; max-width: 48rem; padding: 1rem; font-family: system-ui, sans-serif; line-height: 1.6; color: #222; }
+header { display: flex; justify-content: space-between; border-bottom: 1px solid #ddd; margin-bottom: 1.5rem; padding-bottom: 0.5rem; }
+header .site { font-weight: bold; }
+nav a { margin-left: 1rem; }
+a { color: #2a5db0; }
+pre { overflow-x: auto; padding: 0.75rem; background: #f5f5f5; }
+code { font-size: 0.9em; }
+blockquote { margin: 0; padding-left: 1rem; border-left: 3px solid #ccc; color: #555; }
+.callout-title { font-weight: bold; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 0.25rem 0.5rem; }
+img { max-width: 100%; }
+li.task { list-style: none; }
+.tag { margin-right: 0.5rem; }
+.unresolved { color: #888; }
+.backlinks { border-top: 1px solid #ddd; margin-top: 2rem; }
+#query { width: 100%; padding: 0.5rem; font-size: 1rem; }
+`
+
+func (ns *NotesServer) NewExportSiteTool() {
+	tool := mcp.NewTool(
+		"export_site",
+		mcp.WithDescription("Render a vault folder, or the notes with publish: true in their frontmatter, to a static HTML site. Wikilinks become links between the pages, linked attachments are copied, and tag, backlink and search pages are generated. Notes with publish: false are never exported."),
+		mcp.WithString("folder", mcp.Description("Vault folder to export, . for the whole vault")),
+		mcp.WithBoolean("published", mcp.Description("Export only the notes with publish: true in their frontmatter, within folder when it is given")),
+		mcp.WithString("target", mcp.Description("Directory to write the site to, relative to the server's export folder (defaults to the export folder)")),
+		mcp.WithString("title", mcp.Description("Title of the site (defaults to the vault folder name)")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.ExportSite))
+}
+
+// ExportSite renders notes to a static site in the export folder
+func (ns *NotesServer) ExportSite(ctx context.Context, req mcp.CallToolRequest, params ExportSiteRequest) (*mcp.CallToolResult, error) {
+	if filepath.IsAbs(params.Target) {
+		return toolErrorResult("Invalid target: %s must be relative to the export folder", params.Target), nil
+	}
+	target, err := utils.ValidatePath(ns.exportRoot(), params.Target)
+	if err != nil {
+		return toolErrorResult("Invalid target: %v", err), nil
+	}
+
+	result, err := ns.Export(ExportOptions{
+		Folder:    params.Folder,
+		Published: params.Published,
+		Target:    target,
+		Title:     params.Title,
+	})
+	if err != nil {
+		return toolErrorResult("%v", err), nil
+	}
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// exportRoot is the directory export_site writes sites under
+func (ns *NotesServer) exportRoot() string {
+	if ns.exportDir != "" {
+		return ns.exportDir
+	}
+	return filepath.Join(ns.vaultDir, sibylDirName, defaultExportFolder)
+}
+
+// Export renders notes to a static HTML site. It exports the notes under
+// options.Folder, or with options.Published those with publish: true in
+// their frontmatter; given both, the published notes under the folder. Notes
+// with publish: false and the templates folder are never exported.
+//
+// Links to notes that are not exported are rendered as text, and only the
+// attachments exported notes link to are copied. Every file is read through
+// utils.ValidatePath with symlinks resolved, so nothing outside the vault
+// ends up in the site. Existing files in the target are overwritten and
+// other files are left in place.
+func (ns *NotesServer) Export(options ExportOptions) (*ExportResult, error) {
+	if options.Folder == "" && !options.Published {
+		return nil, fmt.Errorf("choose a folder to export or export the published notes")
+	}
+
+	target, err := ns.siteTarget(options.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := ""
+	if options.Folder != "" {
+		fullPath, err := utils.ValidatePath(ns.vaultDir, options.Folder)
+		if err != nil {
+			return nil, err
+		}
+		if info, err := utils.Stat(fullPath); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("folder not found: %s", options.Folder)
+		}
+		scope, _ = filepath.Rel(ns.vaultDir, fullPath)
+	}
+
+	files, err := ns.vaultFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan vault: %w", err)
+	}
+
+	templates := ""
+	if dir, err := ns.templatesDir(); err == nil {
+		templates, _ = filepath.Rel(ns.vaultDir, dir)
+	}
+
+	export := &siteExport{
+		ns:          ns,
+		resolver:    newLinkResolver(files),
+		pages:       make(map[string]*sitePage),
+		tags:        make(map[string]*siteTag),
+		attachments: make(map[string]string),
+	}
+	for _, file := range files {
+		if !isMarkdownFile(file) || isInFolder(file, templates) {
+			continue
+		}
+		if scope != "" && scope != "." && !isInFolder(file, scope) {
+			continue
+		}
+
+		source, err := ns.siteSource(file)
+		if err != nil {
+			continue
+		}
+		content, err := utils.ReadFile(source)
+		if err != nil {
+			continue // Skip files we can't read
+		}
+
+		fm, _ := parseFrontmatter(string(content))
+		publish := strings.ToLower(frontmatterScalar(fm.Fields["publish"]))
+		if publish == "false" || publish == "no" || options.Published && publish != "true" && publish != "yes" {
+			continue
+		}
+
+		export.pages[file] = &sitePage{
+			source:    file,
+			url:       siteNotesFolder + "/" + filepath.ToSlash(strings.TrimSuffix(file, filepath.Ext(file))) + ".html",
+			title:     noteTitle(string(content), filepath.Base(file)),
+			tags:      extractTags(string(content)),
+			body:      fm.Body,
+			backlinks: make(map[string]bool),
+		}
+	}
+	if len(export.pages) == 0 {
+		return nil, fmt.Errorf("no notes to export")
+	}
+
+	title := options.Title
+	if title == "" {
+		title = filepath.Base(ns.vaultDir)
+	}
+
+	return export.write(target, title)
+}
+
+// siteTarget returns the absolute directory to write a site to. The site
+// and the vault may not contain each other, except for a site in a hidden
+// vault folder such as .sibyl, or its pages would become vault files.
+func (ns *NotesServer) siteTarget(target string) (string, error) {
+	if target == "" {
+		return "", fmt.Errorf("no target directory for the site")
+	}
+
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	vaultDir, err := filepath.Abs(ns.vaultDir)
+	if err != nil {
+		return "", err
+	}
+
+	if rel, err := filepath.Rel(target, vaultDir); err == nil && !isOutside(rel) {
+		return "", fmt.Errorf("the site cannot be written to a folder containing the vault")
+	}
+	if rel, err := filepath.Rel(vaultDir, target); err == nil && !isOutside(rel) {
+		if !isHiddenDir(strings.Split(rel, string(filepath.Separator))[0]) {
+			return "", fmt.Errorf("the site cannot be written into the vault, except under a hidden folder")
+		}
+	}
+
+	return target, nil
+}
+
+// isOutside reports whether a relative path leads out of its base folder
+func isOutside(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// siteSource returns the file a vault path refers to, resolving symlinks.
+// Paths and symlinks leading outside the vault are refused.
+func (ns *NotesServer) siteSource(file string) (string, error) {
+	fullPath, err := utils.ValidatePath(ns.vaultDir, file)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(fullPath)
+	if err != nil {
+		return "", err
+	}
+	vaultDir, err := filepath.EvalSymlinks(ns.vaultDir)
+	if err != nil {
+		return "", err
+	}
+	return utils.ValidatePath(vaultDir, resolved)
+}
+
+// write renders the pages of the site and writes them to target with the
+// attachments, tag, backlink and search pages
+func (e *siteExport) write(target, title string) (*ExportResult, error) {
+	var pages []*sitePage
+	for _, page := range e.pages {
+		pages = append(pages, page)
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].source < pages[j].source })
+
+	for _, page := range pages {
+		e.addTags(page)
+	}
+	for _, page := range pages {
+		page.content = template.HTML(e.render(page))
+	}
+
+	result := &ExportResult{Target: target, Notes: []string{}, Attachments: []string{}, Tags: len(e.tags)}
+	writePage := func(sitePath string, data sitePageData) error {
+		data.Site = title
+		data.Root = strings.Repeat("../", strings.Count(sitePath, "/"))
+
+		var buf bytes.Buffer
+		if err := sitePageTemplate.Execute(&buf, data); err != nil {
+			return err
+		}
+		result.Files++
+		return writeSiteFile(target, sitePath, buf.Bytes())
+	}
+
+	// Note pages
+	var search []siteSearchEntry
+	for _, page := range pages {
+		data := sitePageData{Title: page.title, Content: page.content}
+		for _, tag := range page.tags {
+			if t, ok := e.tags[strings.ToLower(tag)]; ok {
+				data.Tags = append(data.Tags, siteLink{Title: tag, URL: relativeURL(page.url, t.url)})
+			}
+		}
+		data.Backlinks = e.links(page.url, page.backlinks)
+		if err := writePage(page.url, data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", page.url, err)
+		}
+
+		result.Notes = append(result.Notes, filepath.ToSlash(page.source))
+		tags := page.tags
+		if tags == nil {
+			tags = []string{}
+		}
+		search = append(search, siteSearchEntry{Title: page.title, URL: page.url, Tags: tags, Text: htmlText(string(page.content))})
+	}
+
+	// The index lists the notes by folder
+	var index []siteSection
+	for _, page := range pages {
+		folder := path.Dir(filepath.ToSlash(page.source))
+		if folder == "." {
+			folder = "Notes"
+		}
+		if len(index) == 0 || index[len(index)-1].Title != folder {
+			index = append(index, siteSection{Title: folder})
+		}
+		section := &index[len(index)-1]
+		section.Links = append(section.Links, siteLink{Title: page.title, URL: page.url})
+	}
+	if err := writePage("index.html", sitePageData{Heading: title, Sections: index}); err != nil {
+		return nil, fmt.Errorf("failed to write the index: %w", err)
+	}
+
+	// Tag pages
+	var tags []*siteTag
+	for _, tag := range e.tags {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i].name) < strings.ToLower(tags[j].name) })
+
+	allTags := siteSection{Title: "All tags"}
+	for _, tag := range tags {
+		allTags.Links = append(allTags.Links, siteLink{Title: "#" + tag.name, URL: tag.url, Count: len(tag.pages)})
+
+		notes := siteSection{Title: "Notes"}
+		for _, page := range sortedPages(tag.pages) {
+			notes.Links = append(notes.Links, siteLink{Title: page.title, URL: relativeURL(tag.url, page.url)})
+		}
+		if err := writePage(tag.url, sitePageData{Title: "#" + tag.name, Heading: "#" + tag.name, Sections: []siteSection{notes}}); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", tag.url, err)
+		}
+	}
+	if err := writePage("tags.html", sitePageData{Title: "Tags", Heading: "Tags", Sections: []siteSection{allTags}}); err != nil {
+		return nil, fmt.Errorf("failed to write the tag index: %w", err)
+	}
+
+	// The backlink index lists the notes linking to each note
+	var backlinks []siteSection
+	for _, page := range pages {
+		if len(page.backlinks) > 0 {
+			backlinks = append(backlinks, siteSection{Title: page.title, URL: page.url, Links: e.links("backlinks.html", page.backlinks)})
+		}
+	}
+	if err := writePage("backlinks.html", sitePageData{Title: "Backlinks", Heading: "Backlinks", Sections: backlinks}); err != nil {
+		return nil, fmt.Errorf("failed to write the backlink index: %w", err)
+	}
+
+	// Search page and index
+	if err := writePage("search.html", sitePageData{Title: "Search", Heading: "Search", SearchPage: true, Search: search}); err != nil {
+		return nil, fmt.Errorf("failed to write the search page: %w", err)
+	}
+	searchJSON, _ := json.MarshalIndent(search, "", "  ")
+	if err := writeSiteFile(target, siteSearchIndex, searchJSON); err != nil {
+		return nil, fmt.Errorf("failed to write the search index: %w", err)
+	}
+	if err := writeSiteFile(target, "style.css", []byte(siteStylesheet)); err != nil {
+		return nil, fmt.Errorf("failed to write the stylesheet: %w", err)
+	}
+	result.Files += 2
+
+	// Attachments
+	var attachments []string
+	for file, sitePath := range e.attachments {
+		if sitePath != "" {
+			attachments = append(attachments, file)
+		}
+	}
+	sort.Strings(attachments)
+	for _, file := range attachments {
+		source, err := e.ns.siteSource(file)
+		if err != nil {
+			continue
+		}
+		data, err := utils.ReadFile(source)
+		if err != nil {
+			continue
+		}
+		if err := writeSiteFile(target, e.attachments[file], data); err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", file, err)
+		}
+		result.Attachments = append(result.Attachments, filepath.ToSlash(file))
+		result.Files++
+	}
+
+	return result, nil
+}
+
+// addTags adds a page to the pages of its tags and of the tags they are
+// nested in. Tags name the files of their pages, so frontmatter values that
+// are not valid tags are dropped.
+func (e *siteExport) addTags(page *sitePage) {
+	valid := page.tags[:0]
+	for _, tag := range page.tags {
+		if isValidTag(tag) {
+			valid = append(valid, tag)
+		}
+	}
+	page.tags = valid
+
+	for _, tag := range page.tags {
+		parts := strings.Split(tag, "/")
+		for i := range parts {
+			name := strings.Join(parts[:i+1], "/")
+			key := strings.ToLower(name)
+			t, ok := e.tags[key]
+			if !ok {
+				t = &siteTag{name: name, url: siteTagsFolder + "/" + key + ".html", pages: make(map[string]*sitePage)}
+				e.tags[key] = t
+			}
+			t.pages[page.source] = page
+		}
+	}
+}
+
+// render converts the body of a note to HTML, resolving its links against
+// the exported notes and recording the backlinks of the notes it links to
+func (e *siteExport) render(page *sitePage) string {
+	renderer := &htmlRenderer{
+		link: func(link rawLink) (string, bool, bool) {
+			return e.linkURL(page, link)
+		},
+		tag: func(tag string) (string, bool) {
+			t, ok := e.tags[strings.ToLower(tag)]
+			if !ok {
+				return "", false
+			}
+			return relativeURL(page.url, t.url), true
+		},
+	}
+	return renderer.render(page.body)
+}
+
+// linkURL returns the URL of a link from page, and whether it points at an
+// image. Links to notes that are not exported and to files that cannot be
+// copied are not resolved.
+func (e *siteExport) linkURL(page *sitePage, link rawLink) (string, bool, bool) {
+	target, ok := e.resolver.resolve(page.source, link)
+	if !ok {
+		return "", false, false
+	}
+
+	if !isMarkdownFile(target) {
+		sitePath, ok := e.attachment(target)
+		if !ok {
+			return "", false, false
+		}
+		return relativeURL(page.url, sitePath), siteImageExtensions[strings.ToLower(filepath.Ext(target))], true
+	}
+
+	linked, ok := e.pages[target]
+	if !ok {
+		return "", false, false
+	}
+
+	anchor := ""
+	if link.heading != "" {
+		// Obsidian links nested headings as [[note#Heading#Subheading]]
+		headings := strings.Split(link.heading, "#")
+		anchor = "#" + headingID(headings[len(headings)-1])
+	}
+	if linked == page && anchor != "" {
+		return anchor, false, true
+	}
+	if linked != page {
+		linked.backlinks[page.source] = true
+	}
+	return relativeURL(page.url, linked.url) + anchor, false, true
+}
+
+// attachment returns the site path of a linked vault file, refusing files
+// that resolve outside the vault
+func (e *siteExport) attachment(file string) (string, bool) {
+	if sitePath, seen := e.attachments[file]; seen {
+		return sitePath, sitePath != ""
+	}
+
+	sitePath := ""
+	if _, err := e.ns.siteSource(file); err == nil {
+		sitePath = siteAttachmentsFolder + "/" + filepath.ToSlash(file)
+	}
+	e.attachments[file] = sitePath
+	return sitePath, sitePath != ""
+}
+
+// links returns links from the page at from to the given exported notes,
+// sorted by title
+func (e *siteExport) links(from string, sources map[string]bool) []siteLink {
+	pages := make(map[string]*sitePage)
+	for source := range sources {
+		pages[source] = e.pages[source]
+	}
+
+	var links []siteLink
+	for _, page := range sortedPages(pages) {
+		links = append(links, siteLink{Title: page.title, URL: relativeURL(from, page.url)})
+	}
+	return links
+}
+
+// sortedPages returns pages sorted by title, then path
+func sortedPages(pages map[string]*sitePage) []*sitePage {
+	var sorted []*sitePage
+	for _, page := range pages {
+		sorted = append(sorted, page)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].title != sorted[j].title {
+			return sorted[i].title < sorted[j].title
+		}
+		return sorted[i].source < sorted[j].source
+	})
+	return sorted
+}
+
+// relativeURL returns the URL of the site path to from the page at from
+func relativeURL(from, to string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir(from)), filepath.FromSlash(to))
+	if err != nil {
+		rel = to
+	}
+	return (&url.URL{Path: filepath.ToSlash(rel)}).String()
+}
+
+// writeSiteFile writes a file of the site, creating its folder. The path
+// must stay inside the target folder.
+func writeSiteFile(target, sitePath string, data []byte) error {
+	fullPath, err := utils.ValidatePath(target, filepath.FromSlash(sitePath))
+	if err != nil || fullPath == filepath.Clean(target) {
+		return fmt.Errorf("invalid site path %s", sitePath)
+	}
+	if err := utils.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	return utils.WriteFile(fullPath, data, 0644)
+}
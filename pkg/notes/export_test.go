@@ -0,0 +1,257 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var exportVaultNotes = map[string]string{
+	"Blog/first.md": "---\npublish: true\ntags: [writing]\n---\n# First Post\n\n" +
+		"See [[second]], [[Blog/second#Details|the details]], [[private]] and [[Drafts/draft]].\n\n" +
+		"![[diagram.png]] ![[leak.png]] [Paper](../files/paper.pdf) #project/alpha\n",
+	"Blog/second.md":    "# Second\n\n## Details\n\nBack to [[first]] and [[#Details]].\n",
+	"Blog/hidden.md":    "---\npublish: false\n---\n# Hidden\n",
+	"Blog/diagram.png":  "png",
+	"files/paper.pdf":   "pdf",
+	"private.md":        "# Private\n\nSecret, see [[first]].\n",
+	"Drafts/draft.md":   "---\npublish: yes\n---\n# Draft\n\nAbout [[first]].\n",
+	"Templates/post.md": "---\npublish: true\n---\n# {{TITLE}}\n",
+}
+
+func TestExport_Folder(t *testing.T) {
+	tempDir := createVaultForTest(t, exportVaultNotes)
+
+	// An attachment that links to a file outside the vault
+	outside := filepath.Join(t.TempDir(), "secret.png")
+	if err := os.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(tempDir, "Blog", "leak.png")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	target := filepath.Join(t.TempDir(), "site")
+	ns := &NotesServer{vaultDir: tempDir}
+
+	result, err := ns.Export(ExportOptions{Folder: "Blog", Target: target, Title: "My Blog"})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if strings.Join(result.Notes, ",") != "Blog/first.md,Blog/second.md" {
+		t.Errorf("Unexpected notes: %v", result.Notes)
+	}
+	if strings.Join(result.Attachments, ",") != "Blog/diagram.png,files/paper.pdf" {
+		t.Errorf("Unexpected attachments: %v", result.Attachments)
+	}
+	if result.Tags != 3 {
+		t.Errorf("Expected the tags writing, project and project/alpha, got %d", result.Tags)
+	}
+
+	first := readForTest(t, target, "notes/Blog/first.html")
+	for _, expected := range []string{
+		"<title>First Post - My Blog</title>",
+		`<link rel="stylesheet" href="../../style.css">`,
+		`<a class="tag" href="../../tags/writing.html">#writing</a>`,
+		`<h1 id="first-post">First Post</h1>`,
+		`<a href="second.html">second</a>`,
+		`<a href="second.html#details">the details</a>`,
+		`<span class="unresolved">private</span>`,
+		`<span class="unresolved">Drafts/draft</span>`,
+		`<img src="../../files/Blog/diagram.png" alt="diagram.png">`,
+		`<span class="unresolved">leak.png</span>`,
+		`<a href="../../files/files/paper.pdf">Paper</a>`,
+		`<a class="tag" href="../../tags/project/alpha.html">#project/alpha</a>`,
+	} {
+		if !strings.Contains(first, expected) {
+			t.Errorf("Expected %s in the page:\n%s", expected, first)
+		}
+	}
+
+	second := readForTest(t, target, "notes/Blog/second.html")
+	if !strings.Contains(second, `<a href="#details">Details</a>`) || !strings.Contains(second, "<h2>Backlinks</h2>\n<ul>\n<li><a href=\"first.html\">First Post</a></li>") {
+		t.Errorf("Unexpected page:\n%s", second)
+	}
+
+	if readForTest(t, target, "files/Blog/diagram.png") != "png" {
+		t.Error("The attachment was not copied")
+	}
+	for _, leaked := range []string{"files/Blog/leak.png", "notes/Blog/hidden.html", "notes/private.html"} {
+		if _, err := os.Stat(filepath.Join(target, leaked)); err == nil {
+			t.Errorf("%s must not be exported", leaked)
+		}
+	}
+
+	index := readForTest(t, target, "index.html")
+	if !strings.Contains(index, `<a href="notes/Blog/first.html">First Post</a>`) {
+		t.Errorf("Unexpected index:\n%s", index)
+	}
+	tags := readForTest(t, target, "tags.html")
+	if !strings.Contains(tags, `<a href="tags/project.html">#project</a> (1)`) {
+		t.Errorf("Unexpected tag index:\n%s", tags)
+	}
+	tag := readForTest(t, target, "tags/project/alpha.html")
+	if !strings.Contains(tag, `<a href="../../notes/Blog/first.html">First Post</a>`) {
+		t.Errorf("Unexpected tag page:\n%s", tag)
+	}
+	backlinks := readForTest(t, target, "backlinks.html")
+	if !strings.Contains(backlinks, `<h2><a href="notes/Blog/second.html">Second</a></h2>`) {
+		t.Errorf("Unexpected backlink index:\n%s", backlinks)
+	}
+
+	var search []siteSearchEntry
+	if err := json.Unmarshal([]byte(readForTest(t, target, "search-index.json")), &search); err != nil {
+		t.Fatalf("Invalid search index: %v", err)
+	}
+	if len(search) != 2 || search[0].URL != "notes/Blog/first.html" || !strings.Contains(search[0].Text, "See second, the details, private") {
+		t.Errorf("Unexpected search index: %+v", search)
+	}
+	if !strings.Contains(readForTest(t, target, "search.html"), `"title":"Second"`) {
+		t.Error("Expected the search page to embed the index")
+	}
+}
+
+func TestExport_Published(t *testing.T) {
+	tempDir := createVaultForTest(t, exportVaultNotes)
+	target := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+
+	result, err := ns.Export(ExportOptions{Published: true, Target: target})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if strings.Join(result.Notes, ",") != "Blog/first.md,Drafts/draft.md" {
+		t.Errorf("Unexpected notes: %v", result.Notes)
+	}
+	first := readForTest(t, target, "notes/Blog/first.html")
+	if !strings.Contains(first, `<a href="../Drafts/draft.html">Drafts/draft</a>`) || !strings.Contains(first, `<span class="unresolved">second</span>`) {
+		t.Errorf("Unexpected page:\n%s", first)
+	}
+	if !strings.Contains(first, `<a href="../Drafts/draft.html">Draft</a>`) {
+		t.Errorf("Expected a backlink from the draft:\n%s", first)
+	}
+}
+
+func TestExport_Errors(t *testing.T) {
+	tempDir := createVaultForTest(t, exportVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	for _, options := range []ExportOptions{
+		{Target: t.TempDir()},
+		{Folder: "../outside", Target: t.TempDir()},
+		{Folder: "Missing", Target: t.TempDir()},
+		{Folder: "Templates", Target: t.TempDir()},
+		{Folder: "Blog", Target: filepath.Join(tempDir, "Site")},
+		{Folder: "Blog", Target: filepath.Dir(tempDir)},
+		{Folder: "Blog"},
+	} {
+		if _, err := ns.Export(options); err == nil {
+			t.Errorf("Expected an error for %+v", options)
+		}
+	}
+}
+
+func TestExportSite(t *testing.T) {
+	tempDir := createVaultForTest(t, exportVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	var result ExportResult
+	response, err := ns.ExportSite(context.Background(), mcp.CallToolRequest{}, ExportSiteRequest{Folder: ".", Target: "all"})
+	decodeToolResult(t, response, err, &result)
+	if result.Target != filepath.Join(tempDir, sibylDirName, defaultExportFolder, "all") || len(result.Notes) != 4 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if !strings.Contains(readForTest(t, result.Target, "index.html"), "<h1>"+filepath.Base(tempDir)+"</h1>") {
+		t.Error("Expected the vault folder name as the site title")
+	}
+
+	for _, target := range []string{"../../Blog", "../site-x", filepath.Join(t.TempDir(), "site")} {
+		response, err = ns.ExportSite(context.Background(), mcp.CallToolRequest{}, ExportSiteRequest{Folder: "Blog", Target: target})
+		if err != nil || !response.IsError {
+			t.Errorf("Expected an error for target %s, got %v %v", target, response, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, sibylDirName, "site-x")); err == nil {
+		t.Error("The site must not be written outside the export folder")
+	}
+
+	// An export folder sharing a prefix with a sibling directory
+	exportDir := filepath.Join(t.TempDir(), "www")
+	ns.exportDir = exportDir
+	response, err = ns.ExportSite(context.Background(), mcp.CallToolRequest{}, ExportSiteRequest{Folder: "Blog", Target: "../www-other"})
+	if err != nil || !response.IsError {
+		t.Errorf("Expected an error for a sibling of the export folder, got %v %v", response, err)
+	}
+}
+
+func TestExport_SymlinkToSiblingFolder(t *testing.T) {
+	root := t.TempDir()
+	vaultDir := filepath.Join(root, "notes")
+	writeNoteForTest(t, vaultDir, "Blog/post.md", "# Post\n\n[[leak]] ![[leak.png]]\n")
+	writeNoteForTest(t, root, "notes-private/secret.md", "# Secret\n\nprivate body\n")
+	writeNoteForTest(t, root, "notes-private/secret.png", "private image")
+	for name, target := range map[string]string{"leak.md": "secret.md", "leak.png": "secret.png"} {
+		if err := os.Symlink(filepath.Join("..", "..", "notes-private", target), filepath.Join(vaultDir, "Blog", name)); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+	ns := &NotesServer{vaultDir: vaultDir}
+
+	target := filepath.Join(t.TempDir(), "site")
+	result, err := ns.Export(ExportOptions{Folder: "Blog", Target: target})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if strings.Join(result.Notes, ",") != "Blog/post.md" || len(result.Attachments) != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	for _, leaked := range []string{"notes/Blog/leak.html", "files/Blog/leak.png"} {
+		if _, err := os.Stat(filepath.Join(target, leaked)); err == nil {
+			t.Errorf("%s must not be exported", leaked)
+		}
+	}
+	if strings.Contains(readForTest(t, target, "search-index.json"), "private body") {
+		t.Error("The private note leaked into the search index")
+	}
+}
+
+func TestExport_HostileFrontmatterTag(t *testing.T) {
+	tempDir := t.TempDir()
+	writeNoteForTest(t, tempDir, "Blog/post.md", "---\ntags: [\"x/../../../../pwned\", \"../escape\", safe]\n---\n# Post\n")
+	ns := &NotesServer{vaultDir: tempDir}
+
+	root := t.TempDir()
+	target := filepath.Join(root, "a", "b", "site")
+	result, err := ns.Export(ExportOptions{Folder: "Blog", Target: target})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if result.Tags != 1 {
+		t.Errorf("Expected only the safe tag, got %d", result.Tags)
+	}
+	if !strings.Contains(readForTest(t, target, "tags/safe.html"), "Post") {
+		t.Error("Expected a page for the safe tag")
+	}
+	for _, leaked := range []string{filepath.Join(root, "pwned.html"), filepath.Join(root, "a", "pwned.html"), filepath.Join(target, "escape.html")} {
+		if _, err := os.Stat(leaked); err == nil {
+			t.Errorf("%s must not be written", leaked)
+		}
+	}
+
+	for _, sitePath := range []string{"../outside.html", "tags/../../outside.html", "."} {
+		if err := writeSiteFile(target, sitePath, []byte("x")); err == nil {
+			t.Errorf("Expected an error writing %s", sitePath)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "a", "b", "outside.html")); err == nil {
+		t.Error("The site file must not be written outside the target")
+	}
+}
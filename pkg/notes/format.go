@@ -0,0 +1,576 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// defaultListMarker is the bullet unordered list items are written with
+	defaultListMarker = "-"
+
+	// defaultMaxBlankLines is how many blank lines may separate two blocks
+	defaultMaxBlankLines = 1
+)
+
+var (
+	// thematicBreakRegex matches a --- , *** or ___ separator line
+	thematicBreakRegex = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+
+	// bulletRegex matches an unordered list item and captures its
+	// indentation, marker and the rest of the line
+	bulletRegex = regexp.MustCompile(`^([ \t]*)([-*+])([ \t]+.*)$`)
+
+	// tableDelimiterRegex matches the delimiter row below a table header
+	tableDelimiterRegex = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+)
+
+// FormatStyle is the markdown style format_note normalizes notes to
+type FormatStyle struct {
+	// ListMarker is the bullet of unordered list items: -, * or +
+	ListMarker string
+
+	// MaxBlankLines is the most blank lines kept between blocks, at least 1
+	MaxBlankLines int
+}
+
+// FormatNoteRequest represents a request to normalize the markdown of a note
+type FormatNoteRequest struct {
+	Path          string `json:"path" mcp:"Path to the note"`
+	ListMarker    string `json:"list_marker,omitempty" mcp:"Bullet for unordered lists: -, * or + (defaults to the server style)"`
+	MaxBlankLines int    `json:"max_blank_lines,omitempty" mcp:"Most blank lines kept between blocks (defaults to the server style)"`
+	ExpectedHash  string `json:"expected_hash,omitempty" mcp:"Hash from read_note; the note is not formatted if it changed since"`
+	DryRun        bool   `json:"dry_run,omitempty" mcp:"Return the diff of the change without writing it"`
+}
+
+// formatLineKind is how the formatter treats a line of the body
+type formatLineKind int
+
+const (
+	formatText formatLineKind = iota
+	formatBlank
+	formatVerbatim
+	formatHeading
+	formatBreak
+	formatTable
+)
+
+// formatLine is a line of the body and how it is formatted
+type formatLine struct {
+	kind formatLineKind
+	text string
+}
+
+func (ns *NotesServer) NewFormatNoteTool() {
+	tool := mcp.NewTool(
+		"format_note",
+		mcp.WithDescription("Normalize the markdown of a note: list markers, trailing spaces, blank lines, repeated --- separators and table alignment. Frontmatter, code blocks and math are left untouched"),
+		mcp.WithString("path", mcp.Description("Path to the note"), mcp.Required()),
+		mcp.WithString("list_marker", mcp.Description("Bullet for unordered lists: -, * or + (defaults to the server style)")),
+		mcp.WithNumber("max_blank_lines", mcp.Description("Most blank lines kept between blocks (defaults to the server style)")),
+		mcp.WithString("expected_hash", mcp.Description("Hash from read_note; the note is not formatted if it changed since")),
+		mcp.WithBoolean("dry_run", mcp.Description("Return the diff of the change without writing it")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.FormatNote))
+}
+
+// FormatNote normalizes the markdown of a note in place
+func (ns *NotesServer) FormatNote(ctx context.Context, req mcp.CallToolRequest, params FormatNoteRequest) (*mcp.CallToolResult, error) {
+	fullPath, err := utils.ValidatePath(ns.vaultDir, params.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !isMarkdownFile(fullPath) {
+		return toolErrorResult("Only markdown notes can be formatted: %s", params.Path), nil
+	}
+
+	style := ns.formatStyle
+	if params.ListMarker != "" {
+		style.ListMarker = params.ListMarker
+	}
+	if params.MaxBlankLines > 0 {
+		style.MaxBlankLines = params.MaxBlankLines
+	}
+	if err := style.Validate(); err != nil {
+		return toolErrorResult("%v", err), nil
+	}
+
+	if params.DryRun {
+		return ns.previewWrite(fullPath, params.ExpectedHash, func(existing string) string {
+			return formatMarkdown(existing, style)
+		}), nil
+	}
+
+	var original string
+	updated, err := ns.modifyNote(fullPath, params.ExpectedHash, "format_note", func(content string) (string, error) {
+		original = content
+		return formatMarkdown(content, style), nil
+	})
+	if err != nil {
+		return editErrorResult(err), nil
+	}
+
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	message := fmt.Sprintf("Successfully formatted note: %s", relativePath)
+	if updated == original {
+		message = fmt.Sprintf("Note is already formatted: %s", relativePath)
+	}
+
+	result := map[string]interface{}{
+		"success": true,
+		"path":    relativePath,
+		"changed": updated != original,
+		"hash":    contentHash([]byte(updated)),
+		"message": message,
+	}
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Result: hashMeta([]byte(updated)),
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// formatOnWriteContent formats content about to be written to a note when
+// format-on-write is enabled
+func (ns *NotesServer) formatOnWriteContent(fullPath, content string) string {
+	if !ns.formatOnWrite || !isMarkdownFile(fullPath) {
+		return content
+	}
+	return formatMarkdown(content, ns.formatStyle)
+}
+
+// Validate reports a style that cannot be applied
+func (s FormatStyle) Validate() error {
+	if s.ListMarker != "" && s.ListMarker != "-" && s.ListMarker != "*" && s.ListMarker != "+" {
+		return fmt.Errorf("list marker must be -, * or +, got %q", s.ListMarker)
+	}
+	if s.MaxBlankLines < 0 {
+		return fmt.Errorf("max blank lines must not be negative, got %d", s.MaxBlankLines)
+	}
+	return nil
+}
+
+// formatMarkdown normalizes a note to style: unordered list items use one
+// marker, trailing whitespace is removed except for hard line breaks, runs
+// of blank lines are shortened, headings are set off by blank lines,
+// repeated separators collapse to one and tables are aligned. Frontmatter,
+// fenced and indented code and $$ math blocks are copied unchanged.
+func formatMarkdown(content string, style FormatStyle) string {
+	if style.ListMarker == "" {
+		style.ListMarker = defaultListMarker
+	}
+	if style.MaxBlankLines < 1 {
+		style.MaxBlankLines = defaultMaxBlankLines
+	}
+
+	newline := "\n"
+	if strings.Contains(content, "\r\n") {
+		newline = "\r\n"
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+	}
+
+	if content == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+
+	// Frontmatter is copied as written
+	bodyStart := 0
+	if fm, _ := parseFrontmatter(content); fm.EndLine > 0 {
+		bodyStart = fm.EndLine
+	}
+
+	body := classifyLines(lines[bodyStart:], style)
+	body = spaceBlocks(body, style.MaxBlankLines, bodyStart > 0)
+
+	var b strings.Builder
+	for _, line := range lines[:bodyStart] {
+		b.WriteString(line + newline)
+	}
+	for _, line := range body {
+		b.WriteString(line.text + newline)
+	}
+	return b.String()
+}
+
+// classifyLines sorts the body lines into blocks, formatting each line
+func classifyLines(lines []string, style FormatStyle) []formatLine {
+	var out []formatLine
+	inList := false
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		// Indented lines are code when they start a block outside a list,
+		// where they would continue an item instead
+		if trimmed != "" && !inList && isIndentedCode(line) && startsBlock(out) {
+			end := indentedCodeEnd(lines, i)
+			for ; i < end; i++ {
+				out = append(out, formatLine{formatVerbatim, lines[i]})
+			}
+			i--
+			continue
+		}
+		if listItemRegex.MatchString(line) {
+			inList = true
+		} else if trimmed != "" && !unicode.IsSpace(rune(line[0])) {
+			inList = false
+		}
+
+		// Fenced code runs to a closing fence of the same kind, or to the
+		// end of the note when it is never closed
+		if fence := fenceStart(trimmed); fence != "" {
+			out = append(out, formatLine{formatVerbatim, line})
+			for i+1 < len(lines) {
+				i++
+				out = append(out, formatLine{formatVerbatim, lines[i]})
+				if closesFence(strings.TrimSpace(lines[i]), fence) {
+					break
+				}
+			}
+			continue
+		}
+
+		// A $$ math block, which may open and close on the same line
+		if strings.HasPrefix(trimmed, "$$") {
+			out = append(out, formatLine{formatVerbatim, line})
+			if len(trimmed) > 2 && strings.HasSuffix(trimmed, "$$") {
+				continue
+			}
+			for i+1 < len(lines) {
+				i++
+				out = append(out, formatLine{formatVerbatim, lines[i]})
+				if strings.Contains(lines[i], "$$") {
+					break
+				}
+			}
+			continue
+		}
+
+		if trimmed == "" {
+			out = append(out, formatLine{formatBlank, ""})
+			continue
+		}
+
+		// --- right below a paragraph underlines a heading rather than
+		// separating blocks
+		if thematicBreakRegex.MatchString(line) {
+			underline := strings.Trim(trimmed, "-") == "" && len(out) > 0 && out[len(out)-1].kind == formatText
+			if !underline {
+				out = append(out, formatLine{formatBreak, trimmed})
+				continue
+			}
+		}
+
+		if atxHeadingRegex.MatchString(strings.TrimRight(line, " \t")) {
+			out = append(out, formatLine{formatHeading, strings.TrimRight(line, " \t")})
+			continue
+		}
+
+		if end := tableEnd(lines, i); end > i {
+			for _, row := range alignTable(lines[i:end]) {
+				out = append(out, formatLine{formatTable, row})
+			}
+			i = end - 1
+			continue
+		}
+
+		out = append(out, formatLine{formatText, formatTextLine(line, lines, i, style)})
+	}
+
+	return out
+}
+
+// startsBlock reports whether the next line starts a block rather than
+// continuing a paragraph
+func startsBlock(out []formatLine) bool {
+	if len(out) == 0 {
+		return true
+	}
+	kind := out[len(out)-1].kind
+	return kind == formatBlank || kind == formatHeading || kind == formatBreak
+}
+
+// isIndentedCode reports whether a line is indented by four or more columns,
+// counting a tab as four
+func isIndentedCode(line string) bool {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width >= 4
+		}
+		if width >= 4 {
+			return true
+		}
+	}
+	return false
+}
+
+// indentedCodeEnd returns the index of the line after the indented code
+// block starting at line i. Blank lines inside the block belong to it, those
+// after its last indented line do not.
+func indentedCodeEnd(lines []string, i int) int {
+	end := i + 1
+	for j := i + 1; j < len(lines); j++ {
+		if isIndentedCode(lines[j]) {
+			end = j + 1
+		} else if strings.TrimSpace(lines[j]) != "" {
+			break
+		}
+	}
+	return end
+}
+
+// formatTextLine normalizes the list marker and trailing whitespace of a
+// line of text. Two or more trailing spaces before another line of the same
+// paragraph are a hard line break and are kept as two.
+func formatTextLine(line string, lines []string, i int, style FormatStyle) string {
+	if m := bulletRegex.FindStringSubmatch(line); m != nil {
+		line = m[1] + style.ListMarker + m[3]
+	}
+
+	trimmed := strings.TrimRight(line, " \t")
+	if strings.HasSuffix(line, "  ") && i+1 < len(lines) && continuesParagraph(lines[i+1]) {
+		return trimmed + "  "
+	}
+	return trimmed
+}
+
+// continuesParagraph reports whether a line continues the paragraph above
+// it rather than starting a new block
+func continuesParagraph(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" && fenceStart(trimmed) == "" && !strings.HasPrefix(trimmed, "$$") &&
+		!listItemRegex.MatchString(line) && !atxHeadingRegex.MatchString(line) && !thematicBreakRegex.MatchString(line)
+}
+
+// spaceBlocks shortens runs of blank lines, sets headings off with blank
+// lines and drops separators that repeat or that start or end the body
+func spaceBlocks(lines []formatLine, maxBlank int, afterFrontmatter bool) []formatLine {
+	// Collapse separators that follow one another with only blank lines
+	// between them, and those at the start or end of the body. A --- on the
+	// first line of the note is left alone, as it may open frontmatter.
+	var kept []formatLine
+	for i, line := range lines {
+		if line.kind == formatBreak {
+			previous := lastContent(kept)
+			if previous == nil && (afterFrontmatter || i > 0) || previous != nil && previous.kind == formatBreak {
+				continue
+			}
+			if !hasContentAfter(lines, i+1) {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+
+	var out []formatLine
+	blank := 0
+	for i, line := range kept {
+		if line.kind == formatBlank {
+			blank++
+			continue
+		}
+
+		needsBlank := line.kind == formatHeading || line.kind == formatBreak
+		if previous := lastContent(out); previous != nil && (previous.kind == formatHeading || previous.kind == formatBreak) {
+			needsBlank = true
+		}
+		if len(out) > 0 {
+			if needsBlank && blank == 0 {
+				blank = 1
+			}
+			if blank > maxBlank {
+				blank = maxBlank
+			}
+			for ; blank > 0; blank-- {
+				out = append(out, formatLine{formatBlank, ""})
+			}
+		} else if afterFrontmatter && blank > 0 {
+			// Keep a blank line below the frontmatter when there was one
+			out = append(out, formatLine{formatBlank, ""})
+		}
+		blank = 0
+		out = append(out, kept[i])
+	}
+
+	return out
+}
+
+// lastContent returns the last line that is not blank
+func lastContent(lines []formatLine) *formatLine {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if lines[i].kind != formatBlank {
+			return &lines[i]
+		}
+	}
+	return nil
+}
+
+// hasContentAfter reports whether a line other than a blank line or a
+// separator follows start
+func hasContentAfter(lines []formatLine, start int) bool {
+	for i := start; i < len(lines); i++ {
+		if lines[i].kind != formatBlank && lines[i].kind != formatBreak {
+			return true
+		}
+	}
+	return false
+}
+
+// alignTable pads the cells of a table so its columns line up. The second
+// row is the delimiter row, whose colons set each column's alignment. The
+// header sets the number of columns; the extra cells of a longer row are not
+// rendered and are kept after the aligned ones as written.
+func alignTable(rows []string) []string {
+	indent := rows[0][:len(rows[0])-len(strings.TrimLeft(rows[0], " \t"))]
+
+	var cells [][]string
+	for _, row := range rows {
+		cells = append(cells, splitTableRow(row))
+	}
+
+	columns := len(cells[0])
+
+	align := make([]string, columns)
+	for col, cell := range cells[1] {
+		align[col] = columnAlignment(cell)
+	}
+
+	widths := make([]int, columns)
+	for r, row := range cells {
+		if r == 1 {
+			continue
+		}
+		for col, cell := range row[:min(len(row), columns)] {
+			widths[col] = max(widths[col], utf8.RuneCountInString(cell))
+		}
+	}
+	for col := range widths {
+		widths[col] = max(widths[col], 3)
+	}
+
+	var out []string
+	for r, row := range cells {
+		parts := make([]string, columns)
+		for col := range parts {
+			cell := ""
+			if col < len(row) {
+				cell = row[col]
+			}
+			if r == 1 {
+				parts[col] = delimiterCell(align[col], widths[col])
+			} else {
+				parts[col] = padCell(cell, align[col], widths[col])
+			}
+		}
+		if len(row) > columns {
+			parts = append(parts, row[columns:]...)
+		}
+		out = append(out, indent+"| "+strings.Join(parts, " | ")+" |")
+	}
+	return out
+}
+
+// tableEnd returns the index of the line after the table starting at line
+// i, or i when no table starts there. A table is a header row, a delimiter
+// row and the rows up to the next blank line or line without a pipe. As in
+// GFM the delimiter row has a cell for each header cell, and it must have a
+// pipe so that text with a pipe above a --- stays a setext heading.
+func tableEnd(lines []string, i int) int {
+	if !strings.Contains(lines[i], "|") || i+1 >= len(lines) || !strings.Contains(lines[i+1], "|") || !tableDelimiterRegex.MatchString(lines[i+1]) {
+		return i
+	}
+	if len(splitTableRow(lines[i])) != len(splitTableRow(lines[i+1])) {
+		return i
+	}
+
+	end := i + 2
+	for end < len(lines) && strings.Contains(lines[end], "|") && strings.TrimSpace(lines[end]) != "" {
+		end++
+	}
+	return end
+}
+
+// columnAlignment returns the alignment a delimiter row cell sets for its
+// column: left, right, center or "" when none is given
+func columnAlignment(cell string) string {
+	left, right := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+	switch {
+	case left && right:
+		return "center"
+	case right:
+		return "right"
+	case left:
+		return "left"
+	}
+	return ""
+}
+
+// splitTableRow splits a table row into its trimmed cells. Escaped pipes and
+// pipes inside inline code do not separate cells.
+func splitTableRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, "\\|") {
+		row = row[:len(row)-1]
+	}
+
+	masked := maskInlineCode(row)
+	var cells []string
+	start := 0
+	for i := 0; i < len(masked); i++ {
+		switch masked[i] {
+		case '\\':
+			i++
+		case '|':
+			cells = append(cells, strings.TrimSpace(row[start:i]))
+			start = i + 1
+		}
+	}
+	return append(cells, strings.TrimSpace(row[start:]))
+}
+
+// padCell pads a cell to width, keeping its alignment
+func padCell(cell, align string, width int) string {
+	pad := width - utf8.RuneCountInString(cell)
+	switch align {
+	case "right":
+		return strings.Repeat(" ", pad) + cell
+	case "center":
+		return strings.Repeat(" ", pad/2) + cell + strings.Repeat(" ", pad-pad/2)
+	}
+	return cell + strings.Repeat(" ", pad)
+}
+
+// delimiterCell returns the delimiter row cell of a column
+func delimiterCell(align string, width int) string {
+	switch align {
+	case "left":
+		return ":" + strings.Repeat("-", width-1)
+	case "right":
+		return strings.Repeat("-", width-1) + ":"
+	case "center":
+		return ":" + strings.Repeat("-", width-2) + ":"
+	}
+	return strings.Repeat("-", width)
+}
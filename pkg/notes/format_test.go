@@ -0,0 +1,221 @@
+package notes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestFormatMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			"List markers",
+			"* one\n+ two\n  * nested\n- three\n**bold** line\n",
+			"- one\n- two\n  - nested\n- three\n**bold** line\n",
+		},
+		{
+			"Trailing spaces and hard breaks",
+			"first line  \nsecond line \t\nlast   \n",
+			"first line  \nsecond line\nlast\n",
+		},
+		{
+			"Blank lines",
+			"\n\nIntro\n\n\n\nMore\n\n\n",
+			"Intro\n\nMore\n",
+		},
+		{
+			"Headings set off by blank lines",
+			"# Title\nIntro\n## Tasks\n- [ ] one\n",
+			"# Title\n\nIntro\n\n## Tasks\n\n- [ ] one\n",
+		},
+		{
+			"Repeated separators",
+			"Existing\n\n---\n\n\n---\n\n## 2025-01-02\n\nEntry\n\n---\n\n",
+			"Existing\n\n---\n\n## 2025-01-02\n\nEntry\n",
+		},
+		{
+			"Leading separator from a merge into an empty note",
+			"\n\n---\n\n## 2025-01-02\n\nEntry\n",
+			"## 2025-01-02\n\nEntry\n",
+		},
+		{
+			"Setext heading underline is kept",
+			"Title\n---\n\nText\n",
+			"Title\n---\n\nText\n",
+		},
+		{
+			"Other separators",
+			"One\n\n* * *\n\nTwo\n",
+			"One\n\n* * *\n\nTwo\n",
+		},
+		{
+			"Tables",
+			"| Name | Qty | Note |\n|:--|--:|:-:|\n| apple | 3 | red |\n| kiwi `a|b` | 12 | x \\| y |\n",
+			"| Name       | Qty |  Note  |\n| :--------- | --: | :----: |\n| apple      |   3 |  red   |\n| kiwi `a|b` |  12 | x \\| y |\n",
+		},
+		{
+			"Table without outer pipes and short rows",
+			"a | b\n--- | ---\n1 |\n",
+			"| a   | b   |\n| --- | --- |\n| 1   |     |\n",
+		},
+		{
+			"Extra cells of a longer row add no column",
+			"a | b\n--- | ---\n1 | 2 | 3\n",
+			"| a   | b   |\n| --- | --- |\n| 1   | 2   | 3 |\n",
+		},
+		{
+			"Setext heading with a pipe is not a table",
+			"A | B\n---\n\nText\n",
+			"A | B\n---\n\nText\n",
+		},
+		{
+			"Delimiter row with fewer cells than the header is not a table",
+			"| a | b |\n| --- |\n| 1 | 2 |\n",
+			"| a | b |\n| --- |\n| 1 | 2 |\n",
+		},
+		{
+			"Code blocks are untouched",
+			"Text\n\n```go\n* not a list  \n\n\n\n| a | b |\n|---|---|\n```\n~~~\n---\n---\n~~~\n",
+			"Text\n\n```go\n* not a list  \n\n\n\n| a | b |\n|---|---|\n```\n~~~\n---\n---\n~~~\n",
+		},
+		{
+			"Indented code is untouched",
+			"intro\n\n    * code item  \n\n\n\n    + other\n\n\ntext\n",
+			"intro\n\n    * code item  \n\n\n\n    + other\n\ntext\n",
+		},
+		{
+			"Indented list continuation is not code",
+			"* item\n\n    * nested  \n",
+			"- item\n\n    - nested\n",
+		},
+		{
+			"Math is untouched",
+			"$$\n* a  \n\n\n+ b\n$$\n$$ x * y  $$\n",
+			"$$\n* a  \n\n\n+ b\n$$\n$$ x * y  $$\n",
+		},
+		{
+			"Frontmatter is untouched",
+			"---\ntitle: Note   \ntags:\n  * odd\n---\n\n\n* item\n",
+			"---\ntitle: Note   \ntags:\n  * odd\n---\n\n- item\n",
+		},
+		{
+			"Windows line endings",
+			"* one  \r\n\r\n\r\n* two\r\n",
+			"- one\r\n\r\n- two\r\n",
+		},
+		{
+			"Empty note",
+			"",
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted := formatMarkdown(tt.content, FormatStyle{})
+			if formatted != tt.expected {
+				t.Errorf("Unexpected result:\n%q\nexpected:\n%q", formatted, tt.expected)
+			}
+			if again := formatMarkdown(formatted, FormatStyle{}); again != formatted {
+				t.Errorf("Formatting is not stable:\n%q", again)
+			}
+		})
+	}
+}
+
+func TestFormatMarkdown_Style(t *testing.T) {
+	content := "- one\n+ two\n\n\n\nText\n"
+
+	formatted := formatMarkdown(content, FormatStyle{ListMarker: "*", MaxBlankLines: 2})
+	if formatted != "* one\n* two\n\n\nText\n" {
+		t.Errorf("Unexpected result: %q", formatted)
+	}
+}
+
+func TestFormatNote(t *testing.T) {
+	tempDir := t.TempDir()
+	writeNoteForTest(t, tempDir, "messy.md", "# Messy\n* one  \n+ two\n\n\n\n---\n\n---\n")
+	ns := &NotesServer{vaultDir: tempDir}
+
+	preview, err := ns.FormatNote(context.Background(), mcp.CallToolRequest{}, FormatNoteRequest{Path: "messy.md", DryRun: true})
+	if err != nil || preview.IsError {
+		t.Fatalf("Dry run failed: %v %v", preview, err)
+	}
+	if readForTest(t, tempDir, "messy.md") != "# Messy\n* one  \n+ two\n\n\n\n---\n\n---\n" {
+		t.Error("A dry run must not write the note")
+	}
+
+	var result map[string]interface{}
+	response, err := ns.FormatNote(context.Background(), mcp.CallToolRequest{}, FormatNoteRequest{Path: "messy.md"})
+	decodeToolResult(t, response, err, &result)
+	if result["changed"] != true {
+		t.Errorf("Expected the note to change: %v", result)
+	}
+	if formatted := readForTest(t, tempDir, "messy.md"); formatted != "# Messy\n\n- one\n- two\n" {
+		t.Errorf("Unexpected note: %q", formatted)
+	}
+
+	response, err = ns.FormatNote(context.Background(), mcp.CallToolRequest{}, FormatNoteRequest{Path: "messy.md"})
+	decodeToolResult(t, response, err, &result)
+	if result["changed"] != false || !strings.Contains(result["message"].(string), "already formatted") {
+		t.Errorf("Expected no change: %v", result)
+	}
+}
+
+func TestFormatNote_Errors(t *testing.T) {
+	tempDir := t.TempDir()
+	writeNoteForTest(t, tempDir, "note.md", "* one\n")
+	writeNoteForTest(t, tempDir, "data.txt", "* one\n")
+	ns := &NotesServer{vaultDir: tempDir}
+
+	for _, params := range []FormatNoteRequest{
+		{Path: "data.txt"},
+		{Path: "missing.md"},
+		{Path: "note.md", ListMarker: "1."},
+		{Path: "note.md", ExpectedHash: "stale"},
+	} {
+		result, err := ns.FormatNote(context.Background(), mcp.CallToolRequest{}, params)
+		if err != nil || !result.IsError {
+			t.Errorf("Expected an error for %+v, got %v %v", params, result, err)
+		}
+	}
+	if readForTest(t, tempDir, "note.md") != "* one\n" {
+		t.Error("The note must not be changed")
+	}
+}
+
+func TestFormatOnWrite(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir, formatOnWrite: true, formatStyle: FormatStyle{ListMarker: "*"}}
+
+	result, err := ns.WriteNote(context.Background(), mcp.CallToolRequest{}, WriteNoteRequest{Path: "a.md", Content: "# A\n- one   \n- two\n\n\n"})
+	if err != nil || result.IsError {
+		t.Fatalf("WriteNote failed: %v %v", result, err)
+	}
+	if content := readForTest(t, tempDir, "a.md"); content != "# A\n\n* one\n* two\n" {
+		t.Errorf("Unexpected note: %q", content)
+	}
+
+	result, err = ns.MergeNote(context.Background(), mcp.CallToolRequest{}, MergeNoteRequest{Path: "a.md", Content: "- three", Strategy: MergeDateSection})
+	if err != nil || result.IsError {
+		t.Fatalf("MergeNote failed: %v %v", result, err)
+	}
+	if content := readForTest(t, tempDir, "a.md"); strings.Contains(content, "- three") || !strings.Contains(content, "* three") {
+		t.Errorf("Merged content was not formatted: %q", content)
+	}
+
+	// Other files are written as given
+	result, err = ns.WriteNote(context.Background(), mcp.CallToolRequest{}, WriteNoteRequest{Path: "b.txt", Content: "- one   \n"})
+	if err != nil || result.IsError {
+		t.Fatalf("WriteNote failed: %v %v", result, err)
+	}
+	if content := readForTest(t, tempDir, "b.txt"); content != "- one   \n" {
+		t.Errorf("Unexpected file: %q", content)
+	}
+}
@@ -0,0 +1,400 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// frontmatterDelimiter opens and closes the YAML frontmatter block of a note
+const frontmatterDelimiter = "---"
+
+// yamlLineRegex matches the line number that starts a YAML error message
+var yamlLineRegex = regexp.MustCompile(`^line (\d+):\s*`)
+
+// Frontmatter is the parsed YAML header of a note
+type Frontmatter struct {
+	// Fields holds the decoded YAML values. Dates are normalized to
+	// YYYY-MM-DD strings (or RFC 3339 when they carry a time).
+	Fields map[string]interface{}
+
+	// Raw is the YAML text between the delimiters
+	Raw string
+
+	// Body is the note content following the frontmatter
+	Body string
+
+	// EndLine is the 1-based line number of the closing delimiter, 0 when
+	// the note has no frontmatter
+	EndLine int
+}
+
+// FrontmatterError describes frontmatter that could not be parsed
+type FrontmatterError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e *FrontmatterError) Error() string {
+	return fmt.Sprintf("invalid frontmatter at line %d: %s", e.Line, e.Message)
+}
+
+// QueryFrontmatterRequest represents a request to filter and sort notes by frontmatter
+type QueryFrontmatterRequest struct {
+	Path       string                 `json:"path,omitempty" mcp:"Directory path (optional, defaults to vault root)"`
+	Where      map[string]interface{} `json:"where,omitempty" mcp:"Field filters, e.g. {\"status\": \"done\", \"priority\": \">2\"}"`
+	Fields     []string               `json:"fields,omitempty" mcp:"Frontmatter fields to return (defaults to all)"`
+	SortBy     string                 `json:"sort_by,omitempty" mcp:"Frontmatter field to sort by"`
+	Descending bool                   `json:"descending,omitempty" mcp:"Sort in descending order"`
+	Limit      int                    `json:"limit,omitempty" mcp:"Maximum number of notes to return"`
+}
+
+// FrontmatterMatch is a note returned by query_frontmatter
+type FrontmatterMatch struct {
+	Path        string                 `json:"path"`
+	Frontmatter map[string]interface{} `json:"frontmatter"`
+}
+
+// FrontmatterQueryResult is the response of query_frontmatter
+type FrontmatterQueryResult struct {
+	Count   int                `json:"count"`
+	Notes   []FrontmatterMatch `json:"notes"`
+	Invalid []InvalidNote      `json:"invalid,omitempty"`
+}
+
+// InvalidNote is a note whose frontmatter could not be parsed
+type InvalidNote struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// parseFrontmatter splits a note into its YAML frontmatter and body. Notes
+// without frontmatter return empty fields and the whole content as the body.
+// Unclosed delimiters and invalid YAML are reported as a FrontmatterError,
+// with the body still set to the content after the header when possible.
+func parseFrontmatter(content string) (*Frontmatter, error) {
+	fm := &Frontmatter{Fields: map[string]interface{}{}, Body: content}
+
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != frontmatterDelimiter {
+		return fm, nil
+	}
+
+	offset := len(lines[0])
+	closing := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == frontmatterDelimiter {
+			closing = i
+			break
+		}
+		offset += len(lines[i])
+	}
+
+	if closing < 0 {
+		return fm, &FrontmatterError{Line: 1, Message: "frontmatter is not closed with ---"}
+	}
+
+	fm.Raw = content[len(lines[0]):offset]
+	fm.Body = content[offset+len(lines[closing]):]
+	fm.EndLine = closing + 1
+
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal([]byte(fm.Raw), &fields); err != nil {
+		return fm, yamlFrontmatterError(err)
+	}
+
+	if fields != nil {
+		fm.Fields = normalizeYAML(fields).(map[string]interface{})
+	}
+
+	return fm, nil
+}
+
+// yamlFrontmatterError converts a YAML error into a FrontmatterError on the
+// note line it refers to. YAML counts lines from the first line after the
+// opening ---, and errors without a line are reported on that first line.
+func yamlFrontmatterError(err error) *FrontmatterError {
+	message := strings.TrimPrefix(err.Error(), "yaml: ")
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		message = typeErr.Errors[0]
+	}
+
+	line := 1
+	if m := yamlLineRegex.FindStringSubmatch(message); m != nil {
+		line, _ = strconv.Atoi(m[1])
+		message = message[len(m[0]):]
+	}
+
+	return &FrontmatterError{Line: line + 1, Message: message}
+}
+
+// normalizeYAML converts decoded YAML into JSON friendly values
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeYAML(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339)
+	}
+
+	return value
+}
+
+// lookupFrontmatter returns the value at a dotted key path such as "project.status"
+func lookupFrontmatter(fields map[string]interface{}, key string) (interface{}, bool) {
+	if value, exists := fields[key]; exists {
+		return value, true
+	}
+
+	var current interface{} = fields
+	for _, part := range strings.Split(key, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// frontmatterStrings flattens a frontmatter value into strings. Lists produce
+// one string per item; comma separated strings are split for list fields
+// such as tags and aliases.
+func frontmatterStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			values = append(values, frontmatterStrings(item)...)
+		}
+		return values
+	case string:
+		var values []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		return values
+	case map[string]interface{}:
+		return nil
+	}
+
+	return []string{fmt.Sprint(value)}
+}
+
+// frontmatterScalar returns a frontmatter value as a single string
+func frontmatterScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		return strings.Join(frontmatterStrings(v), ", ")
+	}
+
+	return fmt.Sprint(value)
+}
+
+// matchFrontmatterValue reports whether a frontmatter value satisfies a
+// comparison. List values match when any item does, except for != which
+// requires that no item equals the expected value.
+func matchFrontmatterValue(value interface{}, expected, op string) bool {
+	if list, ok := value.([]interface{}); ok {
+		if op == "!=" {
+			return !matchFrontmatterValue(list, expected, "=")
+		}
+		for _, item := range list {
+			if matchFrontmatterValue(item, expected, op) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return compareFieldValue(frontmatterScalar(value), expected, op)
+}
+
+// parseFilterExpression splits a filter such as ">=2" into its operator and value
+func parseFilterExpression(expression string) (string, string) {
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(expression, op); ok {
+			return op, strings.TrimSpace(rest)
+		}
+	}
+	return "=", strings.TrimSpace(expression)
+}
+
+func (ns *NotesServer) NewQueryFrontmatterTool() {
+	tool := mcp.NewTool(
+		"query_frontmatter",
+		mcp.WithDescription("Find notes by their YAML frontmatter, filtering and sorting on field values"),
+		mcp.WithString("path", mcp.Description("Directory path (optional, defaults to vault root)")),
+		mcp.WithObject("where",
+			mcp.Description(`Field filters keyed by field name (dots for nested fields). Values may start with =, !=, >, >=, < or <=; use "*" to require that a field exists, e.g. {"status": "done", "priority": ">2"}`),
+		),
+		mcp.WithArray("fields", mcp.Description("Frontmatter fields to return (defaults to all)"), mcp.WithStringItems()),
+		mcp.WithString("sort_by", mcp.Description("Frontmatter field to sort by")),
+		mcp.WithBoolean("descending", mcp.Description("Sort in descending order")),
+		mcp.WithNumber("limit", mcp.Description("Maximum number of notes to return")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.QueryFrontmatter))
+}
+
+// QueryFrontmatter filters and sorts notes by their frontmatter values
+func (ns *NotesServer) QueryFrontmatter(ctx context.Context, req mcp.CallToolRequest, params QueryFrontmatterRequest) (*mcp.CallToolResult, error) {
+	fullPath, err := utils.ValidatePath(ns.vaultDir, params.Path)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(fmt.Sprintf("Invalid path: %v", err)),
+			},
+		}, nil
+	}
+
+	result := FrontmatterQueryResult{Notes: []FrontmatterMatch{}}
+
+	err = utils.WalkDir(fullPath, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != fullPath && isHiddenDir(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !isMarkdownFile(info.Name()) {
+			return nil
+		}
+
+		content, err := utils.ReadFile(path)
+		if err != nil {
+			return nil // Skip files we can't read
+		}
+
+		relativePath, _ := filepath.Rel(ns.vaultDir, path)
+		fm, err := parseFrontmatter(string(content))
+		if err != nil {
+			result.Invalid = append(result.Invalid, InvalidNote{Path: relativePath, Error: err.Error()})
+			return nil
+		}
+
+		for field, filter := range params.Where {
+			expression := frontmatterScalar(filter)
+			value, exists := lookupFrontmatter(fm.Fields, field)
+			if expression == "*" {
+				if !exists {
+					return nil
+				}
+				continue
+			}
+
+			op, expected := parseFilterExpression(expression)
+			if !exists {
+				if op == "!=" {
+					continue
+				}
+				return nil
+			}
+			if !matchFrontmatterValue(value, expected, op) {
+				return nil
+			}
+		}
+
+		result.Notes = append(result.Notes, FrontmatterMatch{Path: relativePath, Frontmatter: fm.Fields})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query frontmatter: %w", err)
+	}
+
+	if params.SortBy != "" {
+		sortByFrontmatter(result.Notes, params.SortBy, params.Descending)
+	}
+
+	if params.Limit > 0 && len(result.Notes) > params.Limit {
+		result.Notes = result.Notes[:params.Limit]
+	}
+	result.Count = len(result.Notes)
+
+	if len(params.Fields) > 0 {
+		for i, note := range result.Notes {
+			fields := make(map[string]interface{})
+			for _, field := range params.Fields {
+				if value, exists := lookupFrontmatter(note.Frontmatter, field); exists {
+					fields[field] = value
+				}
+			}
+			result.Notes[i].Frontmatter = fields
+		}
+	}
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// sortByFrontmatter orders notes by a field, numerically or by date when the
+// values allow it. Notes missing the field always sort last.
+func sortByFrontmatter(notes []FrontmatterMatch, field string, descending bool) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		a, aExists := lookupFrontmatter(notes[i].Frontmatter, field)
+		b, bExists := lookupFrontmatter(notes[j].Frontmatter, field)
+		if !aExists || !bExists {
+			return aExists && !bExists
+		}
+
+		as, bs := frontmatterScalar(a), frontmatterScalar(b)
+		if descending {
+			return compareFieldValue(as, bs, ">")
+		}
+		return compareFieldValue(as, bs, "<")
+	})
+}
@@ -0,0 +1,231 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/KyleBrandon/sibyl/pkg/dto"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseFrontmatter_FullYAML(t *testing.T) {
+	content := `---
+title: Project Alpha
+created: 2025-01-15
+tags:
+  - project
+  - alpha
+aliases: [PA, "Alpha Project"]
+owner: &owner
+  name: Dana
+  team: platform
+reviewer: *owner
+---
+
+# Body heading
+
+Body text.`
+
+	fm, err := parseFrontmatter(content)
+	if err != nil {
+		t.Fatalf("parseFrontmatter failed: %v", err)
+	}
+
+	if fm.Fields["title"] != "Project Alpha" {
+		t.Errorf("Unexpected title: %v", fm.Fields["title"])
+	}
+
+	if fm.Fields["created"] != "2025-01-15" {
+		t.Errorf("Dates should be normalized to YYYY-MM-DD, got %v", fm.Fields["created"])
+	}
+
+	tags := frontmatterStrings(fm.Fields["tags"])
+	if len(tags) != 2 || tags[0] != "project" || tags[1] != "alpha" {
+		t.Errorf("Unexpected multi-line tags: %v", tags)
+	}
+
+	aliases := frontmatterStrings(fm.Fields["aliases"])
+	if len(aliases) != 2 || aliases[1] != "Alpha Project" {
+		t.Errorf("Unexpected aliases: %v", aliases)
+	}
+
+	if value, _ := lookupFrontmatter(fm.Fields, "reviewer.team"); value != "platform" {
+		t.Errorf("YAML anchors should be resolved, got %v", value)
+	}
+
+	if fm.Body != "\n# Body heading\n\nBody text." {
+		t.Errorf("Unexpected body: %q", fm.Body)
+	}
+
+	if fm.EndLine != 12 {
+		t.Errorf("Expected closing delimiter on line 12, got %d", fm.EndLine)
+	}
+
+	// Fields must be serializable for the MCP responses
+	if _, err := json.Marshal(fm.Fields); err != nil {
+		t.Errorf("Fields should marshal to JSON: %v", err)
+	}
+}
+
+func TestParseFrontmatter_NoFrontmatter(t *testing.T) {
+	content := "# Title\n\n---\n\nAfter a rule."
+
+	fm, err := parseFrontmatter(content)
+	if err != nil {
+		t.Fatalf("parseFrontmatter failed: %v", err)
+	}
+
+	if len(fm.Fields) != 0 || fm.Body != content || fm.EndLine != 0 {
+		t.Errorf("Expected no frontmatter, got %+v", fm)
+	}
+}
+
+func TestParseFrontmatter_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		message string
+	}{
+		{"Unclosed", "---\ntitle: Test\n\n# Body", 1, "frontmatter is not closed with ---"},
+		{"Invalid YAML", "---\ntitle: [unclosed\n---\n# Body", 2, "did not find expected ',' or ']'"},
+		{"Error on a later line", "---\ntitle: Bad\nstatus: done\n  nested: value\n---\n# Body", 4, "mapping values are not allowed in this context"},
+		{"Not a map", "---\n- a\n- b\n---\n# Body", 2, "cannot unmarshal !!seq into map[string]interface {}"},
+		{"Duplicate key", "---\ntitle: A\ntitle: B\n---\n# Body", 3, `mapping key "title" already defined at line 1`},
+		{"No line in the error", "---\na: b: c\n---\n# Body", 2, "mapping values are not allowed in this context"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFrontmatter(tt.content)
+
+			var fmErr *FrontmatterError
+			if !errors.As(err, &fmErr) {
+				t.Fatalf("Expected FrontmatterError, got %v", err)
+			}
+			if fmErr.Line != tt.line || fmErr.Message != tt.message {
+				t.Errorf("Expected %q at line %d, got %q at line %d", tt.message, tt.line, fmErr.Message, fmErr.Line)
+			}
+		})
+	}
+}
+
+func TestExtractTags_MultilineFrontmatter(t *testing.T) {
+	content := "---\ntags:\n  - alpha\n  - beta\n---\n\n# Note"
+
+	tags := extractTags(content)
+	if len(tags) != 2 || tags[0] != "alpha" || tags[1] != "beta" {
+		t.Errorf("Expected [alpha beta], got %v", tags)
+	}
+}
+
+var frontmatterVaultNotes = map[string]string{
+	"a.md":         "---\nstatus: done\npriority: 3\ndue: 2025-03-01\ntags: [work]\n---\n# A",
+	"b.md":         "---\nstatus: active\npriority: 10\ndue: 2025-01-15\ntags: [work, urgent]\n---\n# B",
+	"sub/c.md":     "---\nstatus: active\npriority: 1\n---\n# C",
+	"plain.md":     "# No frontmatter",
+	"invalid.md":   "---\nstatus: [broken\n---\n# Invalid",
+	"sub/notes.md": "---\nproject:\n  name: Alpha\n  phase: 2\n---\n# Nested",
+}
+
+func queryFrontmatterForTest(t *testing.T, ns *NotesServer, params QueryFrontmatterRequest) FrontmatterQueryResult {
+	t.Helper()
+
+	var queryResult FrontmatterQueryResult
+	result, err := ns.QueryFrontmatter(context.Background(), mcp.CallToolRequest{}, params)
+	decodeToolResult(t, result, err, &queryResult)
+	return queryResult
+}
+
+func TestQueryFrontmatter_FilterAndSort(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, frontmatterVaultNotes)}
+
+	result := queryFrontmatterForTest(t, ns, QueryFrontmatterRequest{
+		Where:  map[string]interface{}{"status": "active"},
+		SortBy: "priority",
+	})
+
+	if result.Count != 2 {
+		t.Fatalf("Expected 2 active notes, got %d", result.Count)
+	}
+
+	// Numeric sort: 1 before 10
+	if result.Notes[0].Path != filepath.Join("sub", "c.md") || result.Notes[1].Path != "b.md" {
+		t.Errorf("Unexpected order: %+v", result.Notes)
+	}
+
+	if len(result.Invalid) != 1 || result.Invalid[0].Path != "invalid.md" {
+		t.Errorf("Expected invalid.md to be reported, got %+v", result.Invalid)
+	}
+}
+
+func TestQueryFrontmatter_Operators(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, frontmatterVaultNotes)}
+
+	tests := []struct {
+		name     string
+		where    map[string]interface{}
+		expected int
+	}{
+		{"Greater than", map[string]interface{}{"priority": ">2"}, 2},
+		{"Numeric value", map[string]interface{}{"priority": 3}, 1},
+		{"Date comparison", map[string]interface{}{"due": "<2025-02-01"}, 1},
+		{"List contains", map[string]interface{}{"tags": "urgent"}, 1},
+		{"List not contains", map[string]interface{}{"tags": "!=urgent", "status": "*"}, 2},
+		{"Exists", map[string]interface{}{"due": "*"}, 2},
+		{"Nested field", map[string]interface{}{"project.name": "Alpha"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := queryFrontmatterForTest(t, ns, QueryFrontmatterRequest{Where: tt.where})
+			if result.Count != tt.expected {
+				t.Errorf("Expected %d notes, got %d: %+v", tt.expected, result.Count, result.Notes)
+			}
+		})
+	}
+}
+
+func TestQueryFrontmatter_FieldsAndLimit(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, frontmatterVaultNotes)}
+
+	result := queryFrontmatterForTest(t, ns, QueryFrontmatterRequest{
+		Where:      map[string]interface{}{"priority": "*"},
+		Fields:     []string{"status"},
+		SortBy:     "due",
+		Descending: true,
+		Limit:      1,
+	})
+
+	if result.Count != 1 || result.Notes[0].Path != "a.md" {
+		t.Fatalf("Expected a.md with latest due date, got %+v", result.Notes)
+	}
+
+	if len(result.Notes[0].Frontmatter) != 1 || result.Notes[0].Frontmatter["status"] != "done" {
+		t.Errorf("Expected only the status field, got %v", result.Notes[0].Frontmatter)
+	}
+}
+
+func TestListNotes_IncludesFrontmatter(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, frontmatterVaultNotes)}
+
+	var notes []dto.NoteMetadata
+	result, err := ns.ListNotes(context.Background(), mcp.CallToolRequest{}, ListNotesRequest{Path: ""})
+	decodeToolResult(t, result, err, &notes)
+
+	for _, note := range notes {
+		switch note.Name {
+		case "a.md":
+			if note.Frontmatter["status"] != "done" {
+				t.Errorf("Expected frontmatter on a.md, got %v", note.Frontmatter)
+			}
+		case "plain.md", "invalid.md":
+			if note.Frontmatter != nil {
+				t.Errorf("Expected no frontmatter on %s, got %v", note.Name, note.Frontmatter)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package notes
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// createVaultForTest creates a vault holding notes, keyed by their vault
+// relative path. A path ending in a slash creates an empty folder.
+func createVaultForTest(t *testing.T, notes map[string]string) string {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	for name, content := range notes {
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(filepath.Join(tempDir, name), 0755); err != nil {
+				t.Fatalf("Failed to create folder: %v", err)
+			}
+			continue
+		}
+		writeNoteForTest(t, tempDir, name, content)
+	}
+	return tempDir
+}
+
+func writeNoteForTest(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test note %s: %v", name, err)
+	}
+}
+
+func readForTest(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return string(content)
+}
+
+// decodeToolResult fails the test when a tool failed and decodes its JSON
+// result into v
+func decodeToolResult(t *testing.T, result *mcp.CallToolResult, err error, v interface{}) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("Tool failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Tool returned error: %v", result.Content[0])
+	}
+
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), v); err != nil {
+		t.Fatalf("Invalid JSON returned: %v", err)
+	}
+}
@@ -0,0 +1,559 @@
+package notes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// historyDirName holds note snapshots inside sibylDirName. Contents are
+	// stored once per hash under objects/ and each note keeps a version log
+	// under logs/.
+	historyDirName = "history"
+
+	// currentVersion refers to the note as it is on disk
+	currentVersion = "current"
+)
+
+// NoteVersion is a snapshot of a note taken before a tool changed it
+type NoteVersion struct {
+	Version   int       `json:"version"`
+	Hash      string    `json:"hash"`
+	Size      int       `json:"size"`
+	Timestamp time.Time `json:"timestamp"`
+	Tool      string    `json:"tool"`
+}
+
+// noteHistory is the version log of a single note
+type noteHistory struct {
+	Path     string        `json:"path"`
+	Versions []NoteVersion `json:"versions"`
+}
+
+// ListNoteVersionsRequest represents a request for the version history of a note
+type ListNoteVersionsRequest struct {
+	Path string `json:"path" mcp:"Path to the note"`
+}
+
+// DiffNoteVersionsRequest represents a request to compare two versions of a note
+type DiffNoteVersionsRequest struct {
+	Path string `json:"path" mcp:"Path to the note"`
+	From string `json:"from,omitempty" mcp:"Version number to diff from (defaults to the latest snapshot)"`
+	To   string `json:"to,omitempty" mcp:"Version number or \"current\" to diff to (defaults to current)"`
+}
+
+// RestoreNoteVersionRequest represents a request to roll a note back to a snapshot
+type RestoreNoteVersionRequest struct {
+	Path    string `json:"path" mcp:"Path to the note"`
+	Version int    `json:"version" mcp:"Version number to restore"`
+}
+
+// NoteVersionsResult is the response of list_note_versions
+type NoteVersionsResult struct {
+	Path        string        `json:"path"`
+	CurrentHash string        `json:"current_hash,omitempty"`
+	Versions    []NoteVersion `json:"versions"`
+}
+
+// NoteDiffResult is the response of diff_note_versions
+type NoteDiffResult struct {
+	Path    string `json:"path"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Changed bool   `json:"changed"`
+	Diff    string `json:"diff"`
+}
+
+func (ns *NotesServer) NewListNoteVersionsTool() {
+	tool := mcp.NewTool(
+		"list_note_versions",
+		mcp.WithDescription("List the snapshots saved before each change to a note, newest first"),
+		mcp.WithString("path", mcp.Description("Path to the note"), mcp.Required()),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.ListNoteVersions))
+}
+
+func (ns *NotesServer) NewDiffNoteVersionsTool() {
+	tool := mcp.NewTool(
+		"diff_note_versions",
+		mcp.WithDescription("Show a unified diff between two versions of a note"),
+		mcp.WithString("path", mcp.Description("Path to the note"), mcp.Required()),
+		mcp.WithString("from", mcp.Description("Version number to diff from (defaults to the latest snapshot)")),
+		mcp.WithString("to", mcp.Description("Version number or \"current\" to diff to (defaults to current)")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.DiffNoteVersions))
+}
+
+func (ns *NotesServer) NewRestoreNoteVersionTool() {
+	tool := mcp.NewTool(
+		"restore_note_version",
+		mcp.WithDescription("Restore a note to a saved version. The current content is snapshotted first so the restore can be undone"),
+		mcp.WithString("path", mcp.Description("Path to the note"), mcp.Required()),
+		mcp.WithNumber("version", mcp.Description("Version number to restore"), mcp.Required()),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.RestoreNoteVersion))
+}
+
+// ListNoteVersions lists the saved snapshots of a note
+func (ns *NotesServer) ListNoteVersions(ctx context.Context, req mcp.CallToolRequest, params ListNoteVersionsRequest) (*mcp.CallToolResult, error) {
+	fullPath, err := utils.ValidatePath(ns.vaultDir, params.Path)
+	if err != nil {
+		return toolErrorResult("Invalid path: %v", err), nil
+	}
+
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	history, err := ns.loadHistory(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NoteVersionsResult{Path: relativePath, Versions: []NoteVersion{}}
+	if content, err := utils.ReadFile(fullPath); err == nil {
+		result.CurrentHash = contentHash(content)
+	}
+
+	for i := len(history.Versions) - 1; i >= 0; i-- {
+		result.Versions = append(result.Versions, history.Versions[i])
+	}
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// DiffNoteVersions compares two versions of a note
+func (ns *NotesServer) DiffNoteVersions(ctx context.Context, req mcp.CallToolRequest, params DiffNoteVersionsRequest) (*mcp.CallToolResult, error) {
+	fullPath, err := utils.ValidatePath(ns.vaultDir, params.Path)
+	if err != nil {
+		return toolErrorResult("Invalid path: %v", err), nil
+	}
+
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	history, err := ns.loadHistory(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(history.Versions) == 0 {
+		return toolErrorResult("No saved versions for %s", relativePath), nil
+	}
+
+	from, to := params.From, params.To
+	if from == "" {
+		from = strconv.Itoa(history.Versions[len(history.Versions)-1].Version)
+	}
+	if to == "" {
+		to = currentVersion
+	}
+
+	fromContent, err := ns.versionContent(fullPath, history, from)
+	if err != nil {
+		return toolErrorResult("%v", err), nil
+	}
+
+	toContent, err := ns.versionContent(fullPath, history, to)
+	if err != nil {
+		return toolErrorResult("%v", err), nil
+	}
+
+	diff := unifiedDiff(
+		fmt.Sprintf("%s@%s", filepath.ToSlash(relativePath), from),
+		fmt.Sprintf("%s@%s", filepath.ToSlash(relativePath), to),
+		fromContent, toContent)
+
+	result := NoteDiffResult{Path: relativePath, From: from, To: to, Changed: diff != "", Diff: diff}
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// RestoreNoteVersion writes a saved version back to the note
+func (ns *NotesServer) RestoreNoteVersion(ctx context.Context, req mcp.CallToolRequest, params RestoreNoteVersionRequest) (*mcp.CallToolResult, error) {
+	fullPath, err := utils.ValidatePath(ns.vaultDir, params.Path)
+	if err != nil {
+		return toolErrorResult("Invalid path: %v", err), nil
+	}
+
+	unlock := ns.lockPaths(fullPath)
+	defer unlock()
+
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	history, err := ns.loadHistory(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ns.versionContent(fullPath, history, strconv.Itoa(params.Version))
+	if err != nil {
+		return toolErrorResult("%v", err), nil
+	}
+
+	if err := ns.snapshotNote(fullPath, "restore_note_version"); err != nil {
+		return nil, err
+	}
+
+	if err := utils.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := utils.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(fmt.Sprintf("Restored %s to version %d", relativePath, params.Version)),
+		},
+	}, nil
+}
+
+// contentHash returns the hex encoded SHA-256 of note content
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func (ns *NotesServer) historyDir() string {
+	return filepath.Join(ns.vaultDir, sibylDirName, historyDirName)
+}
+
+// objectPath returns where the content with the given hash is stored
+func (ns *NotesServer) objectPath(hash string) string {
+	return filepath.Join(ns.historyDir(), "objects", hash[:2], hash)
+}
+
+// historyLogPath returns the version log file of a note. Logs are keyed by the
+// hash of the slash separated path so nested folders need no mirroring.
+func (ns *NotesServer) historyLogPath(relativePath string) string {
+	sum := sha256.Sum256([]byte(filepath.ToSlash(relativePath)))
+	return filepath.Join(ns.historyDir(), "logs", hex.EncodeToString(sum[:])+".json")
+}
+
+// loadHistory reads the version log of a note, returning an empty log when
+// the note has no history yet
+func (ns *NotesServer) loadHistory(relativePath string) (*noteHistory, error) {
+	history := &noteHistory{Path: filepath.ToSlash(relativePath)}
+
+	data, err := utils.ReadFile(ns.historyLogPath(relativePath))
+	if err != nil {
+		return history, nil
+	}
+
+	if err := json.Unmarshal(data, history); err != nil {
+		return nil, fmt.Errorf("failed to read history for %s: %w", relativePath, err)
+	}
+
+	return history, nil
+}
+
+// versionContent returns the content of a numbered version or the current note
+func (ns *NotesServer) versionContent(fullPath string, history *noteHistory, version string) (string, error) {
+	if version == currentVersion {
+		content, err := utils.ReadFile(fullPath)
+		if err != nil {
+			return "", nil // A deleted note compares as empty
+		}
+		return string(content), nil
+	}
+
+	number, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return "", fmt.Errorf("invalid version %q: use a version number or %q", version, currentVersion)
+	}
+
+	for _, v := range history.Versions {
+		if v.Version == number {
+			content, err := utils.ReadFile(ns.objectPath(v.Hash))
+			if err != nil {
+				return "", fmt.Errorf("snapshot for version %d is missing: %w", number, err)
+			}
+			return string(content), nil
+		}
+	}
+
+	return "", fmt.Errorf("version %d not found for %s", number, history.Path)
+}
+
+// snapshotNote saves the current content of a note before a tool changes it.
+// Notes that do not exist yet have nothing to save, and unchanged content is
+// only recorded once.
+func (ns *NotesServer) snapshotNote(fullPath, tool string) error {
+	content, err := utils.ReadFile(fullPath)
+	if err != nil {
+		return nil
+	}
+
+	ns.historyMu.Lock()
+	defer ns.historyMu.Unlock()
+
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	history, err := ns.loadHistory(relativePath)
+	if err != nil {
+		return err
+	}
+
+	hash := contentHash(content)
+	if n := len(history.Versions); n > 0 && history.Versions[n-1].Hash == hash {
+		return nil
+	}
+
+	objectPath := ns.objectPath(hash)
+	if _, err := utils.Stat(objectPath); err != nil {
+		if err := utils.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+			return fmt.Errorf("failed to create history folder: %w", err)
+		}
+		if err := utils.WriteFile(objectPath, content, 0644); err != nil {
+			return fmt.Errorf("failed to save snapshot: %w", err)
+		}
+	}
+
+	version := 1
+	if n := len(history.Versions); n > 0 {
+		version = history.Versions[n-1].Version + 1
+	}
+
+	history.Versions = append(history.Versions, NoteVersion{
+		Version:   version,
+		Hash:      hash,
+		Size:      len(content),
+		Timestamp: time.Now().UTC(),
+		Tool:      tool,
+	})
+	expired := pruneVersions(history, time.Now().Add(-ns.trashRetention()))
+
+	logPath := ns.historyLogPath(relativePath)
+	if err := utils.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return fmt.Errorf("failed to create history folder: %w", err)
+	}
+
+	data, _ := json.MarshalIndent(history, "", "  ")
+	if err := utils.WriteFile(logPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+
+	ns.removeUnreferencedObjects(expired)
+
+	return nil
+}
+
+// pruneVersions drops the snapshots taken before the cutoff, always keeping
+// the newest one, and returns the hashes no remaining version of the note
+// refers to
+func pruneVersions(history *noteHistory, cutoff time.Time) []string {
+	kept := []NoteVersion{}
+	for i, v := range history.Versions {
+		if i == len(history.Versions)-1 || !v.Timestamp.Before(cutoff) {
+			kept = append(kept, v)
+		}
+	}
+
+	referenced := map[string]bool{}
+	for _, v := range kept {
+		referenced[v.Hash] = true
+	}
+
+	var expired []string
+	for _, v := range history.Versions {
+		if !referenced[v.Hash] {
+			referenced[v.Hash] = true
+			expired = append(expired, v.Hash)
+		}
+	}
+
+	history.Versions = kept
+	return expired
+}
+
+// removeUnreferencedObjects deletes the snapshot contents no version log
+// refers to any more. Objects are shared between notes with the same content,
+// so every log, including those kept with trashed notes, is checked first.
+// Failures are logged and the objects kept, so pruning never fails the tool
+// that triggered it. The caller holds historyMu.
+func (ns *NotesServer) removeUnreferencedObjects(hashes []string) {
+	if len(hashes) == 0 {
+		return
+	}
+
+	histories, err := ns.historyLogs()
+	if err != nil {
+		slog.Warn("Failed to read history logs", "error", err)
+		return
+	}
+	trashed, err := ns.trashedHistories()
+	if err != nil {
+		slog.Warn("Failed to read trashed history logs", "error", err)
+		return
+	}
+
+	candidates := map[string]bool{}
+	for _, hash := range hashes {
+		candidates[hash] = true
+	}
+	for _, history := range append(histories, trashed...) {
+		for _, v := range history.Versions {
+			delete(candidates, v.Hash)
+		}
+	}
+
+	for hash := range candidates {
+		if err := utils.RemoveAll(ns.objectPath(hash)); err != nil {
+			slog.Warn("Failed to remove expired snapshot", "hash", hash, "error", err)
+		}
+	}
+}
+
+// historyLogs reads every version log of the vault
+func (ns *NotesServer) historyLogs() ([]noteHistory, error) {
+	logsDir := filepath.Join(ns.historyDir(), "logs")
+	entries, err := utils.ReadDir(logsDir)
+	if err != nil {
+		if _, statErr := utils.Stat(logsDir); statErr != nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var histories []noteHistory
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		data, err := utils.ReadFile(filepath.Join(logsDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var history noteHistory
+		if err := json.Unmarshal(data, &history); err != nil {
+			return nil, fmt.Errorf("failed to read history log %s: %w", entry.Name(), err)
+		}
+		histories = append(histories, history)
+	}
+
+	return histories, nil
+}
+
+// notesHistory returns the version logs of a note, or of every note in a
+// folder, so they can go to the trash with it
+func (ns *NotesServer) notesHistory(relativePath string, folder bool) ([]noteHistory, error) {
+	ns.historyMu.Lock()
+	defer ns.historyMu.Unlock()
+
+	if !folder {
+		history, err := ns.loadHistory(relativePath)
+		if err != nil || len(history.Versions) == 0 {
+			return nil, err
+		}
+		return []noteHistory{*history}, nil
+	}
+
+	histories, err := ns.historyLogs()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.ToSlash(relativePath) + "/"
+	var inFolder []noteHistory
+	for _, history := range histories {
+		if strings.HasPrefix(history.Path, prefix) {
+			inFolder = append(inFolder, history)
+		}
+	}
+	return inFolder, nil
+}
+
+// removeHistoryLogs deletes the version logs of notes that went to the trash
+func (ns *NotesServer) removeHistoryLogs(histories []noteHistory) error {
+	ns.historyMu.Lock()
+	defer ns.historyMu.Unlock()
+
+	for _, history := range histories {
+		if err := utils.RemoveAll(ns.historyLogPath(history.Path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreHistoryLogs writes the version logs kept with a trash item back,
+// moving them from the path the item was deleted at to the one it is restored
+// to. As with moveHistory, a log already present at a path is kept. It
+// returns the logs written.
+func (ns *NotesServer) restoreHistoryLogs(histories []noteHistory, from, to string) ([]string, error) {
+	ns.historyMu.Lock()
+	defer ns.historyMu.Unlock()
+
+	from, to = filepath.ToSlash(from), filepath.ToSlash(to)
+
+	var written []string
+	for _, history := range histories {
+		history.Path = to + strings.TrimPrefix(history.Path, from)
+
+		logPath := ns.historyLogPath(history.Path)
+		if _, err := utils.Stat(logPath); err == nil {
+			continue
+		}
+		if err := utils.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+			return written, fmt.Errorf("failed to create history folder: %w", err)
+		}
+
+		data, _ := json.MarshalIndent(history, "", "  ")
+		if err := utils.WriteFile(logPath, data, 0644); err != nil {
+			return written, fmt.Errorf("failed to save history: %w", err)
+		}
+		written = append(written, logPath)
+	}
+
+	return written, nil
+}
+
+// moveHistory carries the version log of a note over to its new path. A log
+// already present at the destination is kept and the old one left in place.
+func (ns *NotesServer) moveHistory(from, to string) error {
+	ns.historyMu.Lock()
+	defer ns.historyMu.Unlock()
+
+	oldLog, newLog := ns.historyLogPath(from), ns.historyLogPath(to)
+	if _, err := utils.Stat(oldLog); err != nil {
+		return nil
+	}
+	if _, err := utils.Stat(newLog); err == nil {
+		return nil
+	}
+
+	history, err := ns.loadHistory(from)
+	if err != nil {
+		return err
+	}
+	history.Path = filepath.ToSlash(to)
+
+	data, _ := json.MarshalIndent(history, "", "  ")
+	if err := utils.WriteFile(newLog, data, 0644); err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+
+	return utils.RemoveAll(oldLog)
+}
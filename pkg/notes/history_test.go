@@ -0,0 +1,270 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestVersionHistory_MutatingTools(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	// Creating a note has nothing to snapshot
+	if _, err := ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "note.md", Content: "# Note\n\nfirst\n"}); err != nil {
+		t.Fatalf("WriteNote failed: %v", err)
+	}
+	if _, err := ns.AppendNote(ctx, mcp.CallToolRequest{}, AppendNoteRequest{Path: "note.md", Content: "second\n"}); err != nil {
+		t.Fatalf("AppendNote failed: %v", err)
+	}
+	if _, err := ns.MergeNote(ctx, mcp.CallToolRequest{}, MergeNoteRequest{Path: "note.md", Content: "# Replaced\n", Strategy: MergeReplace}); err != nil {
+		t.Fatalf("MergeNote failed: %v", err)
+	}
+	if _, err := ns.CreateNoteFromTemplate(ctx, mcp.CallToolRequest{}, CreateFromTemplateRequest{Path: "note.md", TemplateType: "daily"}); err != nil {
+		t.Fatalf("CreateNoteFromTemplate failed: %v", err)
+	}
+
+	var versions NoteVersionsResult
+	result, err := ns.ListNoteVersions(ctx, mcp.CallToolRequest{}, ListNoteVersionsRequest{Path: "note.md"})
+	decodeToolResult(t, result, err, &versions)
+
+	if len(versions.Versions) != 3 {
+		t.Fatalf("Expected 3 versions, got %+v", versions.Versions)
+	}
+
+	expectedTools := []string{"create_note_from_template", "merge_note", "append_note"}
+	for i, tool := range expectedTools {
+		if versions.Versions[i].Tool != tool || versions.Versions[i].Version != 3-i {
+			t.Errorf("Version %d: expected tool %s, got %+v", i, tool, versions.Versions[i])
+		}
+	}
+	if versions.CurrentHash == "" || versions.CurrentHash == versions.Versions[0].Hash {
+		t.Errorf("Expected a current hash different from the last snapshot, got %q", versions.CurrentHash)
+	}
+
+	// Snapshots live in the hidden .sibyl folder and stay out of listings
+	if _, err := os.Stat(filepath.Join(tempDir, sibylDirName, historyDirName, "objects")); err != nil {
+		t.Errorf("Expected snapshot objects on disk: %v", err)
+	}
+}
+
+func TestVersionHistory_UnchangedContentRecordedOnce(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "same.md", Content: "unchanged"}); err != nil {
+			t.Fatalf("WriteNote failed: %v", err)
+		}
+	}
+
+	var versions NoteVersionsResult
+	result, err := ns.ListNoteVersions(ctx, mcp.CallToolRequest{}, ListNoteVersionsRequest{Path: "same.md"})
+	decodeToolResult(t, result, err, &versions)
+
+	if len(versions.Versions) != 1 {
+		t.Errorf("Expected a single snapshot of unchanged content, got %d", len(versions.Versions))
+	}
+}
+
+func TestDiffAndRestoreNoteVersion(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	original := "# Plan\n\n- one\n- two\n- three\n"
+	writeNoteForTest(t, tempDir, "plan.md", original)
+
+	if _, err := ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "plan.md", Content: "# Plan\n\n- one\n- 2\n- three\n"}); err != nil {
+		t.Fatalf("WriteNote failed: %v", err)
+	}
+
+	var diff NoteDiffResult
+	result, err := ns.DiffNoteVersions(ctx, mcp.CallToolRequest{}, DiffNoteVersionsRequest{Path: "plan.md"})
+	decodeToolResult(t, result, err, &diff)
+
+	if !diff.Changed || diff.From != "1" || diff.To != currentVersion {
+		t.Fatalf("Unexpected diff result: %+v", diff)
+	}
+	for _, line := range []string{"--- plan.md@1", "+++ plan.md@current", "@@ -1,5 +1,5 @@", "-- two", "+- 2"} {
+		if !strings.Contains(diff.Diff, line+"\n") {
+			t.Errorf("Expected diff to contain %q:\n%s", line, diff.Diff)
+		}
+	}
+
+	result, err = ns.RestoreNoteVersion(ctx, mcp.CallToolRequest{}, RestoreNoteVersionRequest{Path: "plan.md", Version: 1})
+	if err != nil || result.IsError {
+		t.Fatalf("RestoreNoteVersion failed: %v %v", err, result)
+	}
+
+	if got := readForTest(t, tempDir, "plan.md"); got != original {
+		t.Errorf("Expected original content after restore, got %q", got)
+	}
+
+	// The restore itself can be undone
+	var versions NoteVersionsResult
+	result, err = ns.ListNoteVersions(ctx, mcp.CallToolRequest{}, ListNoteVersionsRequest{Path: "plan.md"})
+	decodeToolResult(t, result, err, &versions)
+	if len(versions.Versions) != 2 || versions.Versions[0].Tool != "restore_note_version" {
+		t.Errorf("Expected the restore to snapshot the edited content, got %+v", versions.Versions)
+	}
+
+	result, err = ns.RestoreNoteVersion(ctx, mcp.CallToolRequest{}, RestoreNoteVersionRequest{Path: "plan.md", Version: 9})
+	if err != nil || !result.IsError {
+		t.Error("Expected error for an unknown version")
+	}
+}
+
+func TestMoveNote_KeepsHistory(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "a.md", Content: "v1"})
+	ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "a.md", Content: "v2"})
+
+	if _, err := ns.RenameNote(ctx, mcp.CallToolRequest{}, RenameNoteRequest{Path: "a.md", NewName: "b"}); err != nil {
+		t.Fatalf("RenameNote failed: %v", err)
+	}
+
+	var versions NoteVersionsResult
+	result, err := ns.ListNoteVersions(ctx, mcp.CallToolRequest{}, ListNoteVersionsRequest{Path: "b.md"})
+	decodeToolResult(t, result, err, &versions)
+
+	if len(versions.Versions) != 1 {
+		t.Errorf("Expected history to follow the renamed note, got %+v", versions.Versions)
+	}
+}
+
+func TestVersionHistory_PrunesExpiredSnapshots(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir, trashRetentionDays: 7}
+	ctx := context.Background()
+
+	old, recent := time.Now().AddDate(0, 0, -10).UTC(), time.Now().AddDate(0, 0, -1).UTC()
+	unique, shared, kept := contentHash([]byte("unique")), contentHash([]byte("shared")), contentHash([]byte("kept"))
+	for _, content := range []string{"unique", "shared", "kept"} {
+		path := ns.objectPath(contentHash([]byte(content)))
+		writeNoteForTest(t, filepath.Dir(path), filepath.Base(path), content)
+	}
+
+	writeLog := func(history noteHistory) {
+		data, _ := json.Marshal(history)
+		path := ns.historyLogPath(history.Path)
+		writeNoteForTest(t, filepath.Dir(path), filepath.Base(path), string(data))
+	}
+	writeLog(noteHistory{Path: "a.md", Versions: []NoteVersion{
+		{Version: 1, Hash: unique, Timestamp: old, Tool: "write_note"},
+		{Version: 2, Hash: shared, Timestamp: old, Tool: "write_note"},
+		{Version: 3, Hash: kept, Timestamp: recent, Tool: "write_note"},
+	}})
+	writeLog(noteHistory{Path: "b.md", Versions: []NoteVersion{
+		{Version: 1, Hash: shared, Timestamp: recent, Tool: "write_note"},
+	}})
+	writeNoteForTest(t, tempDir, "a.md", "current")
+
+	if _, err := ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "a.md", Content: "next"}); err != nil {
+		t.Fatalf("WriteNote failed: %v", err)
+	}
+
+	var versions NoteVersionsResult
+	result, err := ns.ListNoteVersions(ctx, mcp.CallToolRequest{}, ListNoteVersionsRequest{Path: "a.md"})
+	decodeToolResult(t, result, err, &versions)
+
+	if len(versions.Versions) != 2 || versions.Versions[0].Version != 4 || versions.Versions[1].Version != 3 {
+		t.Errorf("Expected only the snapshots within the retention period, got %+v", versions.Versions)
+	}
+
+	if _, err := os.Stat(ns.objectPath(unique)); err == nil {
+		t.Error("Expected the expired snapshot to be removed")
+	}
+	for _, hash := range []string{shared, kept} {
+		if _, err := os.Stat(ns.objectPath(hash)); err != nil {
+			t.Errorf("Expected the snapshot %s still referenced to be kept: %v", hash, err)
+		}
+	}
+
+	// The newest snapshot is kept even when it is older than the retention period
+	history := &noteHistory{Versions: []NoteVersion{{Version: 1, Hash: unique, Timestamp: old}}}
+	if expired := pruneVersions(history, time.Now()); len(expired) != 0 || len(history.Versions) != 1 {
+		t.Errorf("Expected the newest snapshot to be kept, got %+v", history.Versions)
+	}
+}
+
+func TestDeleteAndRestoreNote_KeepsHistory(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	for _, content := range []string{"v1", "v2"} {
+		ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "a.md", Content: content})
+		ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "projects/b.md", Content: content})
+	}
+	snapshot := contentHash([]byte("v1"))
+
+	versionsOf := func(path string) []NoteVersion {
+		t.Helper()
+		var versions NoteVersionsResult
+		result, err := ns.ListNoteVersions(ctx, mcp.CallToolRequest{}, ListNoteVersionsRequest{Path: path})
+		decodeToolResult(t, result, err, &versions)
+		return versions.Versions
+	}
+
+	var note, folder TrashItem
+	result, err := ns.DeleteNote(ctx, mcp.CallToolRequest{}, DeleteNoteRequest{Path: "a.md"})
+	decodeToolResult(t, result, err, &note)
+	result, err = ns.DeleteFolder(ctx, mcp.CallToolRequest{}, DeleteNoteRequest{Path: "projects"})
+	decodeToolResult(t, result, err, &folder)
+
+	// The logs go to the trash with the notes, and the snapshots they refer
+	// to survive pruning of other notes
+	if len(versionsOf("a.md")) != 0 || len(versionsOf("projects/b.md")) != 0 {
+		t.Error("Expected the history to move to the trash")
+	}
+	ns.removeUnreferencedObjects([]string{snapshot})
+	if _, err := os.Stat(ns.objectPath(snapshot)); err != nil {
+		t.Errorf("Expected the trashed snapshot to be kept: %v", err)
+	}
+
+	var restored RestoreResult
+	result, err = ns.RestoreNote(ctx, mcp.CallToolRequest{}, RestoreNoteRequest{ID: note.ID, Destination: "restored/a.md"})
+	decodeToolResult(t, result, err, &restored)
+	if versions := versionsOf("restored/a.md"); len(versions) != 1 || versions[0].Hash != snapshot {
+		t.Errorf("Expected the history to be restored with the note, got %+v", versions)
+	}
+
+	result, err = ns.RestoreNote(ctx, mcp.CallToolRequest{}, RestoreNoteRequest{ID: folder.ID, Destination: "archive"})
+	decodeToolResult(t, result, err, &restored)
+	if versions := versionsOf("archive/b.md"); len(versions) != 1 {
+		t.Errorf("Expected the folder history to be restored, got %+v", versions)
+	}
+}
+
+func TestEmptyTrash_RemovesTrashedSnapshots(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "a.md", Content: "only here"})
+	ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "a.md", Content: "v2"})
+	snapshot := contentHash([]byte("only here"))
+
+	if _, err := ns.DeleteNote(ctx, mcp.CallToolRequest{}, DeleteNoteRequest{Path: "a.md"}); err != nil {
+		t.Fatalf("DeleteNote failed: %v", err)
+	}
+	if _, err := ns.EmptyTrash(ctx, mcp.CallToolRequest{}, EmptyTrashRequest{All: true}); err != nil {
+		t.Fatalf("EmptyTrash failed: %v", err)
+	}
+
+	if _, err := os.Stat(ns.objectPath(snapshot)); err == nil {
+		t.Error("Expected the snapshot of the purged note to be removed")
+	}
+}
@@ -0,0 +1,753 @@
+package notes
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// htmlListItemRegex matches a list item and captures its indentation,
+	// marker, the whitespace after the marker and the item text
+	htmlListItemRegex = regexp.MustCompile(`^([ \t]*)([-*+]|\d{1,9}[.)])(?:([ \t]+)(.*))?$`)
+
+	// setextUnderlineRegex matches the === or --- line below a setext heading
+	setextUnderlineRegex = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+
+	// taskMarkerRegex matches the checkbox at the start of a task list item
+	taskMarkerRegex = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+
+	// calloutRegex matches the first line of an Obsidian callout, > [!note] Title
+	calloutRegex = regexp.MustCompile(`^\[!([A-Za-z-]+)\][+-]?[ \t]*(.*)$`)
+
+	// Anchored forms of the inline patterns, matched at the current position
+	// while rendering inline markdown
+	wikiLinkPrefix     = regexp.MustCompile(`^(?:` + wikiLinkPattern.String() + `)`)
+	markdownLinkPrefix = regexp.MustCompile(`^(?:` + markdownLinkPattern.String() + `)`)
+	autolinkPrefix     = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]*:[^<>\s]+)>`)
+	bareURLPrefix      = regexp.MustCompile(`^https?://[^\s<>]+`)
+	inlineTagPrefix    = regexp.MustCompile(`^#([\p{L}\p{N}_/-]*[\p{L}_/-][\p{L}\p{N}_/-]*)`)
+
+	// htmlTagRegex matches an HTML tag, used to reduce rendered HTML to text
+	htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+)
+
+// htmlRenderer converts the markdown of a note to HTML. It covers what notes
+// are written with: headings, paragraphs, lists and task lists, quotes and
+// callouts, fenced code, tables, math, emphasis, links, wikilinks and tags.
+// Raw HTML in a note is escaped rather than passed through, so a note cannot
+// inject markup or scripts into the page.
+type htmlRenderer struct {
+	// link returns the URL of a wikilink or relative markdown link and
+	// whether it points at an image. When ok is false the target is not
+	// available and the link is rendered as plain text.
+	link func(link rawLink) (href string, image bool, ok bool)
+
+	// tag returns the URL of the page of a tag
+	tag func(tag string) (href string, ok bool)
+
+	// headingIDs counts the heading anchors used, to keep them unique
+	headingIDs map[string]int
+}
+
+// render converts a markdown document without frontmatter to HTML
+func (r *htmlRenderer) render(markdown string) string {
+	r.headingIDs = make(map[string]int)
+
+	var b strings.Builder
+	r.renderBlocks(&b, strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n"), false)
+	return b.String()
+}
+
+// renderBlocks renders block level markdown. The paragraphs of tight list
+// items are written without <p> tags.
+func (r *htmlRenderer) renderBlocks(b *strings.Builder, lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			i++
+		case fenceStart(trimmed) != "":
+			i = r.renderCode(b, lines, i)
+		case strings.HasPrefix(trimmed, "$$"):
+			i = r.renderMath(b, lines, i)
+		case atxHeadingRegex.MatchString(line):
+			m := atxHeadingRegex.FindStringSubmatch(line)
+			r.renderHeading(b, len(m[1]), closingHashesRegex.ReplaceAllString(m[2], ""))
+			i++
+		case thematicBreakRegex.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+		case strings.HasPrefix(trimmed, ">"):
+			i = r.renderQuote(b, lines, i)
+		case htmlListItemRegex.MatchString(line):
+			i = r.renderList(b, lines, i)
+		case tableEnd(lines, i) > i:
+			i = r.renderTable(b, lines, i)
+		default:
+			i = r.renderParagraph(b, lines, i, tight)
+		}
+	}
+}
+
+// interruptsParagraph reports whether a line starts a block that ends the
+// paragraph above it
+func interruptsParagraph(lines []string, i int) bool {
+	trimmed := strings.TrimSpace(lines[i])
+	return fenceStart(trimmed) != "" || strings.HasPrefix(trimmed, "$$") || strings.HasPrefix(trimmed, ">") ||
+		atxHeadingRegex.MatchString(lines[i]) || thematicBreakRegex.MatchString(lines[i]) ||
+		htmlListItemRegex.MatchString(lines[i]) || tableEnd(lines, i) > i
+}
+
+func (r *htmlRenderer) renderParagraph(b *strings.Builder, lines []string, start int, tight bool) int {
+	var text []string
+	end := start
+	for ; end < len(lines) && strings.TrimSpace(lines[end]) != ""; end++ {
+		if end > start {
+			if m := setextUnderlineRegex.FindStringSubmatch(lines[end]); m != nil {
+				level := 1
+				if m[1][0] == '-' {
+					level = 2
+				}
+				r.renderHeading(b, level, strings.TrimSpace(strings.Join(text, " ")))
+				return end + 1
+			}
+			if interruptsParagraph(lines, end) {
+				break
+			}
+		}
+		text = append(text, strings.TrimLeft(lines[end], " \t"))
+	}
+
+	content := r.inline(strings.TrimRight(strings.Join(text, "\n"), " \t"))
+	if tight {
+		b.WriteString(content + "\n")
+	} else {
+		b.WriteString("<p>" + content + "</p>\n")
+	}
+	return end
+}
+
+func (r *htmlRenderer) renderHeading(b *strings.Builder, level int, text string) {
+	id := headingID(text)
+	if id != "" {
+		if n := r.headingIDs[id]; n > 0 {
+			r.headingIDs[id]++
+			id += "-" + strconv.Itoa(n)
+		}
+		r.headingIDs[id]++
+		fmt.Fprintf(b, "<h%d id=\"%s\">%s</h%d>\n", level, html.EscapeString(id), r.inline(text), level)
+		return
+	}
+	fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, r.inline(text), level)
+}
+
+// headingID returns the anchor of a heading: its text lower-cased, with
+// spaces turned into hyphens and punctuation dropped
+func headingID(text string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '-':
+			b.WriteRune(c)
+		case unicode.IsSpace(c):
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+func (r *htmlRenderer) renderCode(b *strings.Builder, lines []string, start int) int {
+	opening := strings.TrimSpace(lines[start])
+	fence := fenceStart(opening)
+	indent := indentWidth(lines[start])
+
+	end := start + 1
+	for ; end < len(lines); end++ {
+		if closesFence(strings.TrimSpace(lines[end]), fence) {
+			break
+		}
+	}
+
+	b.WriteString("<pre><code")
+	if info := strings.Fields(opening[len(fence):]); len(info) > 0 {
+		b.WriteString(` class="language-` + html.EscapeString(info[0]) + `"`)
+	}
+	b.WriteString(">")
+	for _, line := range lines[start+1 : end] {
+		b.WriteString(html.EscapeString(dedent(line, indent)) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+
+	return min(end+1, len(lines))
+}
+
+// renderMath writes a $$ block as TeX between \[ and \], the delimiters
+// MathJax and KaTeX look for
+func (r *htmlRenderer) renderMath(b *strings.Builder, lines []string, start int) int {
+	first := strings.TrimSpace(lines[start])[2:]
+
+	var math []string
+	end := start
+	if closing := strings.Index(first, "$$"); closing >= 0 {
+		math = append(math, first[:closing])
+	} else {
+		math = append(math, first)
+		for end = start + 1; end < len(lines); end++ {
+			if before, found := strings.CutSuffix(strings.TrimSpace(lines[end]), "$$"); found {
+				math = append(math, before)
+				break
+			}
+			math = append(math, lines[end])
+		}
+	}
+
+	b.WriteString(`<div class="math">\[` + html.EscapeString(strings.TrimSpace(strings.Join(math, "\n"))) + "\\]</div>\n")
+	return min(end+1, len(lines))
+}
+
+func (r *htmlRenderer) renderQuote(b *strings.Builder, lines []string, start int) int {
+	var quoted []string
+	end := start
+	for ; end < len(lines); end++ {
+		rest, ok := strings.CutPrefix(strings.TrimLeft(lines[end], " \t"), ">")
+		if !ok {
+			break
+		}
+		quoted = append(quoted, strings.TrimPrefix(rest, " "))
+	}
+
+	if m := calloutRegex.FindStringSubmatch(strings.TrimSpace(quoted[0])); m != nil {
+		kind := strings.ToLower(m[1])
+		title := m[2]
+		if title == "" {
+			title = strings.ToUpper(kind[:1]) + kind[1:]
+		}
+		b.WriteString(`<blockquote class="callout callout-` + html.EscapeString(kind) + "\">\n")
+		b.WriteString(`<p class="callout-title">` + r.inline(title) + "</p>\n")
+		quoted = quoted[1:]
+	} else {
+		b.WriteString("<blockquote>\n")
+	}
+	r.renderBlocks(b, quoted, false)
+	b.WriteString("</blockquote>\n")
+
+	return end
+}
+
+// renderList renders a list and the lists nested in its items. Items are
+// siblings when indented at most one column more than the first; lines
+// indented further belong to the item above.
+func (r *htmlRenderer) renderList(b *strings.Builder, lines []string, start int) int {
+	first := htmlListItemRegex.FindStringSubmatch(lines[start])
+	base := indentWidth(first[1])
+	ordered := isOrderedMarker(first[2])
+
+	// A different bullet, or a ) after numbers instead of a ., starts a new list
+	isSibling := func(line string) (bool, []string) {
+		m := htmlListItemRegex.FindStringSubmatch(line)
+		return m != nil && indentWidth(m[1]) <= base+1 && m[2][len(m[2])-1] == first[2][len(first[2])-1], m
+	}
+
+	var items [][]string
+	contentIndent := 0
+	loose := false
+	end := start
+	for ; end < len(lines); end++ {
+		line := lines[end]
+		if strings.TrimSpace(line) == "" {
+			next := end + 1
+			for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+				next++
+			}
+			if next == len(lines) {
+				break
+			}
+			sibling, _ := isSibling(lines[next])
+			if !sibling && indentWidth(lines[next]) <= base {
+				break
+			}
+			if sibling || !htmlListItemRegex.MatchString(lines[next]) {
+				loose = true
+			}
+			items[len(items)-1] = append(items[len(items)-1], "")
+			continue
+		}
+
+		if sibling, m := isSibling(line); sibling {
+			contentIndent = indentWidth(m[1]) + len(m[2]) + 1
+			if spaces := indentWidth(m[1]+m[2]+m[3]) - indentWidth(m[1]+m[2]); spaces > 1 && spaces <= 4 {
+				contentIndent += spaces - 1
+			}
+			items = append(items, []string{m[4]})
+			continue
+		}
+
+		item := items[len(items)-1]
+		switch width := indentWidth(line); {
+		case width > base:
+			items[len(items)-1] = append(item, dedent(line, min(width, contentIndent)))
+		case item[len(item)-1] != "" && !interruptsParagraph(lines, end):
+			// A lazy continuation of the item's paragraph
+			items[len(items)-1] = append(item, strings.TrimSpace(line))
+		default:
+			return r.writeList(b, items, first[2], ordered, loose, end)
+		}
+	}
+
+	return r.writeList(b, items, first[2], ordered, loose, end)
+}
+
+func (r *htmlRenderer) writeList(b *strings.Builder, items [][]string, marker string, ordered, loose bool, end int) int {
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+
+	b.WriteString("<" + tag)
+	if number, _ := strconv.Atoi(strings.TrimRight(marker, ".)")); ordered && number != 1 {
+		fmt.Fprintf(b, ` start="%d"`, number)
+	}
+	b.WriteString(">\n")
+
+	for _, item := range items {
+		if m := taskMarkerRegex.FindStringSubmatch(item[0]); m != nil {
+			b.WriteString(`<li class="task"><input type="checkbox" disabled`)
+			if m[1] != " " {
+				b.WriteString(" checked")
+			}
+			b.WriteString("> ")
+			item[0] = item[0][len(m[0]):]
+		} else {
+			b.WriteString("<li>")
+		}
+		r.renderBlocks(b, item, !loose)
+		b.WriteString("</li>\n")
+	}
+
+	b.WriteString("</" + tag + ">\n")
+	return end
+}
+
+func isOrderedMarker(marker string) bool {
+	return marker[0] >= '0' && marker[0] <= '9'
+}
+
+// indentWidth returns the width of the leading whitespace of a line, with
+// tabs counted as four columns
+func indentWidth(line string) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// dedent removes up to width columns of leading whitespace from a line
+func dedent(line string, width int) string {
+	removed := 0
+	for i, c := range line {
+		if removed >= width || (c != ' ' && c != '\t') {
+			return line[i:]
+		}
+		if c == '\t' {
+			removed += 4 - removed%4
+		} else {
+			removed++
+		}
+	}
+	return ""
+}
+
+func (r *htmlRenderer) renderTable(b *strings.Builder, lines []string, start int) int {
+	var align []string
+	for _, cell := range splitTableRow(lines[start+1]) {
+		align = append(align, columnAlignment(cell))
+	}
+
+	end := tableEnd(lines, start)
+	b.WriteString("<table>\n<thead>\n")
+	r.renderTableRow(b, "th", splitTableRow(lines[start]), align)
+	b.WriteString("</thead>\n")
+	if end > start+2 {
+		b.WriteString("<tbody>\n")
+		for _, row := range lines[start+2 : end] {
+			r.renderTableRow(b, "td", splitTableRow(row), align)
+		}
+		b.WriteString("</tbody>\n")
+	}
+	b.WriteString("</table>\n")
+
+	return end
+}
+
+func (r *htmlRenderer) renderTableRow(b *strings.Builder, tag string, cells, align []string) {
+	b.WriteString("<tr>")
+	for col := range align {
+		b.WriteString("<" + tag)
+		if align[col] != "" {
+			b.WriteString(` style="text-align: ` + align[col] + `"`)
+		}
+		b.WriteString(">")
+		if col < len(cells) {
+			b.WriteString(r.inline(cells[col]))
+		}
+		b.WriteString("</" + tag + ">")
+	}
+	b.WriteString("</tr>\n")
+}
+
+// inline renders the inline markdown of a block. Text that is not markup
+// is escaped.
+func (r *htmlRenderer) inline(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		if n := r.inlineToken(&b, text, i); n > 0 {
+			i += n
+			continue
+		}
+		b.WriteString(html.EscapeString(text[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// inlineToken renders the markup starting at text[i], if any, and returns
+// the number of bytes it consumed
+func (r *htmlRenderer) inlineToken(b *strings.Builder, text string, i int) int {
+	rest := text[i:]
+	switch rest[0] {
+	case '\\':
+		if len(rest) > 1 && rest[1] == '\n' {
+			b.WriteString("<br>\n")
+			return 2
+		}
+		if len(rest) > 1 && isASCIIPunct(rest[1]) {
+			b.WriteString(html.EscapeString(rest[1:2]))
+			return 2
+		}
+	case '\n':
+		if strings.HasSuffix(text[:i], "  ") {
+			b.WriteString("<br>\n")
+			return 1
+		}
+	case '`':
+		return r.codeSpan(b, rest)
+	case '$':
+		return r.inlineMath(b, rest)
+	case '!':
+		if strings.HasPrefix(rest, "![[") {
+			return r.wikiLink(b, rest)
+		}
+		if strings.HasPrefix(rest, "![") {
+			return r.markdownLink(b, rest)
+		}
+	case '[':
+		if strings.HasPrefix(rest, "[[") {
+			return r.wikiLink(b, rest)
+		}
+		return r.markdownLink(b, rest)
+	case '<':
+		return r.autolink(b, rest)
+	case 'h':
+		if i == 0 || !isWordByte(text[i-1]) {
+			return r.bareURL(b, rest)
+		}
+	case '#':
+		if i == 0 || isSpaceByte(text[i-1]) {
+			return r.inlineTag(b, rest)
+		}
+	case '*', '_', '~', '=':
+		return r.emphasis(b, text, i)
+	}
+	return 0
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && (unicode.IsPunct(rune(c)) || unicode.IsSymbol(rune(c)))
+}
+
+func isWordByte(c byte) bool {
+	return c >= utf8.RuneSelf || c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// codeSpan renders a code span opened by a run of backticks, which is
+// closed by a run of the same length
+func (r *htmlRenderer) codeSpan(b *strings.Builder, rest string) int {
+	n := len(rest) - len(strings.TrimLeft(rest, "`"))
+	for j := n; j < len(rest); {
+		k := strings.Index(rest[j:], rest[:n])
+		if k < 0 {
+			break
+		}
+		k += j
+		run := len(rest[k:]) - len(strings.TrimLeft(rest[k:], "`"))
+		if run == n {
+			code := strings.ReplaceAll(rest[n:k], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+			return k + n
+		}
+		j = k + run
+	}
+
+	b.WriteString(rest[:n])
+	return n
+}
+
+// inlineMath renders $x$ and $$x$$ within a line. As in Obsidian the TeX
+// may not start or end with a space, and a closing $ may not be followed by
+// a digit, so that amounts like $5 and $10 stay text.
+func (r *htmlRenderer) inlineMath(b *strings.Builder, rest string) int {
+	if strings.HasPrefix(rest, "$$") {
+		if end := strings.Index(rest[2:], "$$"); end > 0 {
+			b.WriteString(`<span class="math">\[` + html.EscapeString(rest[2:2+end]) + `\]</span>`)
+			return end + 4
+		}
+		b.WriteString("$$")
+		return 2
+	}
+
+	if len(rest) < 3 || isSpaceByte(rest[1]) {
+		return 0
+	}
+	for j := 2; j < len(rest) && rest[j] != '\n'; j++ {
+		if rest[j] == '$' && !isSpaceByte(rest[j-1]) && rest[j-1] != '\\' && (j+1 == len(rest) || rest[j+1] < '0' || rest[j+1] > '9') {
+			b.WriteString(`<span class="math">\(` + html.EscapeString(rest[1:j]) + `\)</span>`)
+			return j + 1
+		}
+	}
+	return 0
+}
+
+func (r *htmlRenderer) wikiLink(b *strings.Builder, rest string) int {
+	m := wikiLinkPrefix.FindStringSubmatchIndex(rest)
+	if m == nil {
+		return 0
+	}
+
+	link := rawLink{kind: linkKindWiki, embed: m[3] > m[2], target: strings.TrimSpace(rest[m[4]:m[5]])}
+	if m[6] >= 0 {
+		link.heading = strings.TrimSpace(rest[m[6]+1 : m[7]])
+	}
+
+	label := link.target
+	if link.heading != "" {
+		if label != "" {
+			label += " > "
+		}
+		label += link.heading
+	}
+	if m[8] >= 0 {
+		label = strings.TrimSpace(rest[m[8]+1 : m[9]])
+	}
+
+	r.writeLink(b, link, html.EscapeString(label), label)
+	return m[1]
+}
+
+func (r *htmlRenderer) markdownLink(b *strings.Builder, rest string) int {
+	m := markdownLinkPrefix.FindStringSubmatchIndex(rest)
+	if m == nil {
+		return 0
+	}
+
+	embed := m[3] > m[2]
+	text := rest[m[4]:m[5]]
+	target := rest[m[6]:m[7]]
+	content := r.inline(text)
+
+	switch {
+	case urlSchemePattern.MatchString(target):
+		switch {
+		case !isSafeURL(target):
+			b.WriteString(content)
+		case embed:
+			fmt.Fprintf(b, `<img src="%s" alt="%s">`, html.EscapeString(target), html.EscapeString(text))
+		default:
+			fmt.Fprintf(b, `<a href="%s">%s</a>`, html.EscapeString(target), content)
+		}
+	case strings.HasPrefix(target, "#"):
+		fmt.Fprintf(b, `<a href="%s">%s</a>`, html.EscapeString(target), content)
+	default:
+		link := rawLink{kind: linkKindMarkdown, embed: embed}
+		path, heading, _ := strings.Cut(target, "#")
+		if decoded, err := url.PathUnescape(path); err == nil {
+			path = decoded
+		}
+		if decoded, err := url.PathUnescape(heading); err == nil {
+			heading = decoded
+		}
+		link.target, link.heading = path, heading
+		r.writeLink(b, link, content, text)
+	}
+
+	return m[1]
+}
+
+// writeLink writes a link to a note or attachment with the given HTML
+// content, or an image with the given alt text when the link embeds one
+func (r *htmlRenderer) writeLink(b *strings.Builder, link rawLink, content, alt string) {
+	href, image, ok := r.link(link)
+	switch {
+	case !ok:
+		b.WriteString(`<span class="unresolved">` + content + "</span>")
+	case image && link.embed:
+		fmt.Fprintf(b, `<img src="%s" alt="%s">`, html.EscapeString(href), html.EscapeString(alt))
+	default:
+		fmt.Fprintf(b, `<a href="%s">%s</a>`, html.EscapeString(href), content)
+	}
+}
+
+func (r *htmlRenderer) autolink(b *strings.Builder, rest string) int {
+	m := autolinkPrefix.FindStringSubmatch(rest)
+	if m == nil || !isSafeURL(m[1]) {
+		return 0
+	}
+	fmt.Fprintf(b, `<a href="%s">%s</a>`, html.EscapeString(m[1]), html.EscapeString(m[1]))
+	return len(m[0])
+}
+
+// bareURL links a URL written without markup. Trailing punctuation is left
+// out of the URL, as are closing parentheses without an opening one.
+func (r *htmlRenderer) bareURL(b *strings.Builder, rest string) int {
+	link := bareURLPrefix.FindString(rest)
+	for link != "" {
+		last := link[len(link)-1]
+		if !strings.ContainsRune(".,:;!?'\"*_~)", rune(last)) ||
+			last == ')' && strings.Count(link, "(") >= strings.Count(link, ")") {
+			break
+		}
+		link = link[:len(link)-1]
+	}
+	if link == "" || strings.HasSuffix(link, "://") {
+		return 0
+	}
+
+	fmt.Fprintf(b, `<a href="%s">%s</a>`, html.EscapeString(link), html.EscapeString(link))
+	return len(link)
+}
+
+func (r *htmlRenderer) inlineTag(b *strings.Builder, rest string) int {
+	m := inlineTagPrefix.FindStringSubmatch(rest)
+	if m == nil {
+		return 0
+	}
+	tag := strings.TrimRight(m[1], "/")
+	href, ok := r.tag(tag)
+	if tag == "" || !ok {
+		return 0
+	}
+
+	fmt.Fprintf(b, `<a class="tag" href="%s">#%s</a>`, html.EscapeString(href), html.EscapeString(tag))
+	return len(tag) + 1
+}
+
+// emphasis renders **strong**, *em*, ~~del~~ and ==mark== text. Underscores
+// inside words do not emphasize.
+func (r *htmlRenderer) emphasis(b *strings.Builder, text string, i int) int {
+	rest := text[i:]
+	delim := rest[:1]
+	if len(rest) > 1 && rest[1] == rest[0] {
+		delim = rest[:2]
+	}
+
+	var tag string
+	switch {
+	case delim == "**" || delim == "__":
+		tag = "strong"
+	case delim == "~~":
+		tag = "del"
+	case delim == "==":
+		tag = "mark"
+	case delim == "*" || delim == "_":
+		tag = "em"
+	default:
+		return 0
+	}
+
+	n := len(delim)
+	if n >= len(rest) || isSpaceByte(rest[n]) || delim[0] == '_' && i > 0 && isWordByte(text[i-1]) {
+		return 0
+	}
+	end := closingDelimiter(rest, delim)
+	if end < 0 {
+		return 0
+	}
+
+	b.WriteString("<" + tag + ">" + r.inline(rest[n:end]) + "</" + tag + ">")
+	return end + n
+}
+
+// closingDelimiter returns the offset of the delimiter closing the one
+// rest starts with, or -1. Code spans and escaped characters are skipped,
+// and a ** run does not close a single *.
+func closingDelimiter(rest, delim string) int {
+	n := len(delim)
+	for j := n + 1; j < len(rest); j++ {
+		switch rest[j] {
+		case '\\':
+			j++
+			continue
+		case '`':
+			run := len(rest[j:]) - len(strings.TrimLeft(rest[j:], "`"))
+			if k := strings.Index(rest[j+run:], rest[j:j+run]); k >= 0 {
+				j += run + k + run - 1
+			}
+			continue
+		}
+		if rest[j] != delim[0] {
+			continue
+		}
+
+		runEnd := j
+		for runEnd < len(rest) && rest[runEnd] == delim[0] {
+			runEnd++
+		}
+		run := runEnd - j
+		closes := run >= n && !isSpaceByte(rest[j-1]) && !(n == 1 && run == 2) &&
+			!(delim[0] == '_' && runEnd < len(rest) && isWordByte(rest[runEnd]))
+		if closes {
+			return runEnd - n
+		}
+		j = runEnd - 1
+	}
+	return -1
+}
+
+// isSafeURL reports whether an external URL may be linked from the site
+func isSafeURL(link string) bool {
+	scheme, _, _ := strings.Cut(strings.ToLower(link), ":")
+	return scheme == "http" || scheme == "https" || scheme == "mailto"
+}
+
+// htmlText reduces rendered HTML to its text. Blocks end with a newline,
+// so only table cells need a space in place of their tags.
+func htmlText(rendered string) string {
+	text := htmlTagRegex.ReplaceAllStringFunc(rendered, func(tag string) string {
+		if strings.HasPrefix(tag, "<t") || strings.HasPrefix(tag, "</t") {
+			return " "
+		}
+		return ""
+	})
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
@@ -0,0 +1,115 @@
+package notes
+
+import (
+	"strings"
+	"testing"
+)
+
+func testHTMLRenderer() *htmlRenderer {
+	return &htmlRenderer{
+		link: func(link rawLink) (string, bool, bool) {
+			if link.target == "missing" {
+				return "", false, false
+			}
+			href := link.target + ".html"
+			if link.heading != "" {
+				href += "#" + headingID(link.heading)
+			}
+			return href, strings.HasSuffix(link.target, ".png"), true
+		},
+		tag: func(tag string) (string, bool) {
+			return "tags/" + tag + ".html", true
+		},
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		expected string
+	}{
+		{
+			"Headings",
+			"# Title #\n\n## Title\n\nSetext\n---\n",
+			"<h1 id=\"title\">Title</h1>\n<h2 id=\"title-1\">Title</h2>\n<h2 id=\"setext\">Setext</h2>\n",
+		},
+		{
+			"Emphasis",
+			"*em* **strong** ***both*** ~~del~~ ==mark== snake_case_name 2 * 3 * 4\n",
+			"<p><em>em</em> <strong>strong</strong> <strong><em>both</em></strong> <del>del</del> <mark>mark</mark> snake_case_name 2 * 3 * 4</p>\n",
+		},
+		{
+			"Code and escaping",
+			"`<b>` & <script>alert(1)</script> \\*not em\\*\n\n```go\nif a < b {}\n```\n",
+			"<p><code>&lt;b&gt;</code> &amp; &lt;script&gt;alert(1)&lt;/script&gt; *not em*</p>\n<pre><code class=\"language-go\">if a &lt; b {}\n</code></pre>\n",
+		},
+		{
+			"Line breaks",
+			"one  \ntwo\\\nthree\nfour\n",
+			"<p>one  <br>\ntwo<br>\nthree\nfour</p>\n",
+		},
+		{
+			"Math",
+			"$x^2$ costs $5 and $10\n\n$$\na < b\n$$\n",
+			"<p><span class=\"math\">\\(x^2\\)</span> costs $5 and $10</p>\n<div class=\"math\">\\[a &lt; b\\]</div>\n",
+		},
+		{
+			"Links",
+			"[[note]] [[note#Some Heading|alias]] [[missing]] ![[chart.png]] [doc](doc.pdf) [site](https://example.com/?a=1&b=2) [bad](javascript:alert) <https://go.dev> see https://go.dev/doc.\n",
+			"<p><a href=\"note.html\">note</a> <a href=\"note.html#some-heading\">alias</a> <span class=\"unresolved\">missing</span> " +
+				"<img src=\"chart.png.html\" alt=\"chart.png\"> <a href=\"doc.pdf.html\">doc</a> <a href=\"https://example.com/?a=1&amp;b=2\">site</a> bad " +
+				"<a href=\"https://go.dev\">https://go.dev</a> see <a href=\"https://go.dev/doc\">https://go.dev/doc</a>.</p>\n",
+		},
+		{
+			"Tags",
+			"#project/alpha and issue#1 and #2024 `#code`\n",
+			"<p><a class=\"tag\" href=\"tags/project/alpha.html\">#project/alpha</a> and issue#1 and #2024 <code>#code</code></p>\n",
+		},
+		{
+			"Lists",
+			"- one\n- [ ] task\n  - nested\n- [x] done\n\n1. first\n2. second\n\n3) new list\n",
+			"<ul>\n<li>one\n</li>\n<li class=\"task\"><input type=\"checkbox\" disabled> task\n<ul>\n<li>nested\n</li>\n</ul>\n</li>\n" +
+				"<li class=\"task\"><input type=\"checkbox\" disabled checked> done\n</li>\n</ul>\n" +
+				"<ol>\n<li>first\n</li>\n<li>second\n</li>\n</ol>\n<ol start=\"3\">\n<li>new list\n</li>\n</ol>\n",
+		},
+		{
+			"Loose list",
+			"- one\n\n- two\n  continued\n",
+			"<ul>\n<li><p>one</p>\n</li>\n<li><p>two\ncontinued</p>\n</li>\n</ul>\n",
+		},
+		{
+			"Quotes and callouts",
+			"> quoted\n> text\n\n> [!tip]\n> body\n",
+			"<blockquote>\n<p>quoted\ntext</p>\n</blockquote>\n<blockquote class=\"callout callout-tip\">\n<p class=\"callout-title\">Tip</p>\n<p>body</p>\n</blockquote>\n",
+		},
+		{
+			"Tables",
+			"| a | b |\n|:--|--:|\n| 1 | `x|y` |\n| 2 |\n",
+			"<table>\n<thead>\n<tr><th style=\"text-align: left\">a</th><th style=\"text-align: right\">b</th></tr>\n</thead>\n<tbody>\n" +
+				"<tr><td style=\"text-align: left\">1</td><td style=\"text-align: right\"><code>x|y</code></td></tr>\n" +
+				"<tr><td style=\"text-align: left\">2</td><td style=\"text-align: right\"></td></tr>\n</tbody>\n</table>\n",
+		},
+		{
+			"Thematic break",
+			"Text\n\n* * *\n",
+			"<p>Text</p>\n<hr>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered := testHTMLRenderer().render(tt.markdown)
+			if rendered != tt.expected {
+				t.Errorf("Unexpected HTML:\n%s\nexpected:\n%s", rendered, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHTMLText(t *testing.T) {
+	text := htmlText("<h1 id=\"a\">Title</h1>\n<p>Fish &amp; <em>chips</em>, <a href=\"x\">peas</a>.</p>\n<table>\n<tr><td>1</td><td>2</td></tr>\n</table>\n")
+	if text != "Title Fish & chips, peas. 1 2" {
+		t.Errorf("Unexpected text: %q", text)
+	}
+}
@@ -0,0 +1,430 @@
+package notes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+)
+
+const (
+	// sibylDirName is the vault folder that holds server managed state
+	sibylDirName = ".sibyl"
+
+	// indexFileName is the name of the persisted search index inside sibylDirName
+	indexFileName = "index.json"
+
+	// indexVersion must be bumped whenever the on-disk index format or tokenizer changes
+	indexVersion = 1
+
+	// gramLength is the longest term substring kept in the gram index
+	gramLength = 3
+
+	// BM25 tuning parameters
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// indexedDoc is the per-note entry in the search index
+type indexedDoc struct {
+	ModTime time.Time      `json:"mod_time"`
+	Size    int64          `json:"size"`
+	Length  int            `json:"length"`
+	Terms   map[string]int `json:"terms"`
+}
+
+// searchIndex is an inverted index over every note in the vault. Documents are
+// keyed by their vault-relative path and kept up to date by comparing the
+// modification time and size recorded for each note.
+type searchIndex struct {
+	Version int                    `json:"version"`
+	Docs    map[string]*indexedDoc `json:"docs"`
+
+	// postings and totalLength are derived from Docs and rebuilt on load
+	postings    map[string]map[string]int
+	totalLength int
+
+	// grams maps every run of up to gramLength runes in an indexed term to
+	// the terms containing it, so substring lookups need not scan the
+	// vocabulary. It is derived from postings.
+	grams map[string]map[string]bool
+}
+
+// scoredDoc is a document matched by a query along with its BM25 score
+type scoredDoc struct {
+	Path     string
+	Score    float64
+	Modified time.Time
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		Version:  indexVersion,
+		Docs:     make(map[string]*indexedDoc),
+		postings: make(map[string]map[string]int),
+		grams:    make(map[string]map[string]bool),
+	}
+}
+
+// tokenize splits text into lower-cased terms made of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// addDoc indexes the content of a note, replacing any previous entry for the path
+func (idx *searchIndex) addDoc(path string, info fs.FileInfo, content string) {
+	idx.removeDoc(path)
+
+	terms := make(map[string]int)
+	tokens := tokenize(content)
+	for _, token := range tokens {
+		terms[token]++
+	}
+
+	doc := &indexedDoc{
+		ModTime: info.ModTime(),
+		Size:    info.Size(),
+		Length:  len(tokens),
+		Terms:   terms,
+	}
+	idx.Docs[path] = doc
+	idx.link(path, doc)
+}
+
+// removeDoc drops a note from the index
+func (idx *searchIndex) removeDoc(path string) {
+	doc, exists := idx.Docs[path]
+	if !exists {
+		return
+	}
+
+	for term := range doc.Terms {
+		delete(idx.postings[term], path)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+			idx.unlinkGrams(term)
+		}
+	}
+	idx.totalLength -= doc.Length
+	delete(idx.Docs, path)
+}
+
+// link adds a document's terms to the derived posting lists
+func (idx *searchIndex) link(path string, doc *indexedDoc) {
+	for term, freq := range doc.Terms {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]int)
+			idx.linkGrams(term)
+		}
+		idx.postings[term][path] = freq
+	}
+	idx.totalLength += doc.Length
+}
+
+// linkGrams adds a new vocabulary term to the gram index
+func (idx *searchIndex) linkGrams(term string) {
+	for _, gram := range termGrams(term) {
+		if idx.grams[gram] == nil {
+			idx.grams[gram] = make(map[string]bool)
+		}
+		idx.grams[gram][term] = true
+	}
+}
+
+// unlinkGrams drops a term that left the vocabulary from the gram index
+func (idx *searchIndex) unlinkGrams(term string) {
+	for _, gram := range termGrams(term) {
+		delete(idx.grams[gram], term)
+		if len(idx.grams[gram]) == 0 {
+			delete(idx.grams, gram)
+		}
+	}
+}
+
+// termGrams returns the distinct runs of one to gramLength runes in a term
+func termGrams(term string) []string {
+	runes := []rune(term)
+	seen := make(map[string]bool)
+	var grams []string
+	for i := range runes {
+		for n := 1; n <= gramLength && i+n <= len(runes); n++ {
+			gram := string(runes[i : i+n])
+			if !seen[gram] {
+				seen[gram] = true
+				grams = append(grams, gram)
+			}
+		}
+	}
+
+	return grams
+}
+
+// rebuildPostings regenerates the derived posting lists after loading from disk
+func (idx *searchIndex) rebuildPostings() {
+	idx.postings = make(map[string]map[string]int)
+	idx.grams = make(map[string]map[string]bool)
+	idx.totalLength = 0
+	for path, doc := range idx.Docs {
+		idx.link(path, doc)
+	}
+}
+
+// isStale reports whether the indexed entry for a note no longer matches the file
+func (idx *searchIndex) isStale(path string, info fs.FileInfo) bool {
+	doc, exists := idx.Docs[path]
+	if !exists {
+		return true
+	}
+
+	return !doc.ModTime.Equal(info.ModTime()) || doc.Size != info.Size()
+}
+
+// idf returns the BM25 inverse document frequency of a term
+func (idx *searchIndex) idf(term string) float64 {
+	n := float64(len(idx.Docs))
+	df := float64(len(idx.postings[term]))
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+// bm25 scores a single document against the given query terms
+func (idx *searchIndex) bm25(path string, terms []string) float64 {
+	doc := idx.Docs[path]
+	if doc == nil || len(idx.Docs) == 0 {
+		return 0
+	}
+
+	avgLength := float64(idx.totalLength) / float64(len(idx.Docs))
+	if avgLength == 0 {
+		avgLength = 1
+	}
+
+	score := 0.0
+	for _, term := range terms {
+		tf := float64(doc.Terms[term])
+		if tf == 0 {
+			continue
+		}
+		norm := tf + bm25K1*(1-bm25B+bm25B*float64(doc.Length)/avgLength)
+		score += idx.idf(term) * tf * (bm25K1 + 1) / norm
+	}
+
+	return score
+}
+
+// rank returns the documents containing every required term (see
+// docsContaining), ordered by the BM25 score of the scoring terms. When there
+// are no required terms every document is a candidate. The accept function
+// can be used to restrict the candidate set.
+func (idx *searchIndex) rank(terms, scoring []string, accept func(path string) bool) []scoredDoc {
+	var candidates []string
+	if len(terms) == 0 {
+		for path := range idx.Docs {
+			candidates = append(candidates, path)
+		}
+	} else {
+		matching := idx.docsContaining(terms[0])
+		for _, term := range terms[1:] {
+			if len(matching) == 0 {
+				break
+			}
+			next := idx.docsContaining(term)
+			for path := range matching {
+				if !next[path] {
+					delete(matching, path)
+				}
+			}
+		}
+
+		for path := range matching {
+			candidates = append(candidates, path)
+		}
+	}
+
+	var results []scoredDoc
+	for _, path := range candidates {
+		if accept != nil && !accept(path) {
+			continue
+		}
+		results = append(results, scoredDoc{
+			Path:     path,
+			Score:    idx.bm25(path, scoring),
+			Modified: idx.Docs[path].ModTime,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Path < results[j].Path
+	})
+
+	return results
+}
+
+// docsContaining returns the documents with an indexed term that contains the
+// given term. Matching on substrings of the vocabulary keeps the index in line
+// with the substring semantics of the line matcher, so "test" finds "testing".
+// Short terms are looked up in the gram index directly; longer ones check only
+// the terms sharing their rarest trigram.
+func (idx *searchIndex) docsContaining(term string) map[string]bool {
+	runes := []rune(term)
+
+	var terms map[string]bool
+	if len(runes) <= gramLength {
+		terms = idx.grams[term]
+	} else {
+		for i := 0; i+gramLength <= len(runes); i++ {
+			candidates := idx.grams[string(runes[i:i+gramLength])]
+			if terms == nil || len(candidates) < len(terms) {
+				terms = candidates
+			}
+			if len(terms) == 0 {
+				break
+			}
+		}
+	}
+
+	docs := make(map[string]bool)
+	for indexed := range terms {
+		if len(runes) > gramLength && !strings.Contains(indexed, term) {
+			continue
+		}
+		for path := range idx.postings[indexed] {
+			docs[path] = true
+		}
+	}
+
+	return docs
+}
+
+// indexPath returns the location of the persisted index for the vault
+func (ns *NotesServer) indexPath() string {
+	return filepath.Join(ns.vaultDir, sibylDirName, indexFileName)
+}
+
+// loadIndex reads the persisted index from disk. A missing, unreadable or
+// out-of-date index is discarded so the next refresh performs a full rescan.
+func (ns *NotesServer) loadIndex() *searchIndex {
+	data, err := utils.ReadFile(ns.indexPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("Failed to read search index, rebuilding", "error", err)
+		}
+		return newSearchIndex()
+	}
+
+	idx := newSearchIndex()
+	if err := json.Unmarshal(data, idx); err != nil || idx.Version != indexVersion || idx.Docs == nil {
+		slog.Warn("Search index is stale or corrupt, rebuilding", "error", err)
+		return newSearchIndex()
+	}
+	idx.rebuildPostings()
+
+	return idx
+}
+
+// saveIndex persists the index under the vault's .sibyl folder
+func (ns *NotesServer) saveIndex(idx *searchIndex) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search index: %w", err)
+	}
+
+	if err := utils.MkdirAll(filepath.Dir(ns.indexPath()), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	return utils.WriteFile(ns.indexPath(), data, 0644)
+}
+
+// withIndex refreshes the search index and calls fn while holding the index
+// lock, so fn sees a consistent view of the vault.
+func (ns *NotesServer) withIndex(fn func(idx *searchIndex) error) error {
+	ns.indexMu.Lock()
+	defer ns.indexMu.Unlock()
+
+	idx, err := ns.refreshIndex()
+	if err != nil {
+		return err
+	}
+
+	return fn(idx)
+}
+
+// refreshIndex brings the search index up to date with the vault. Only notes
+// whose modification time or size changed since the last refresh are re-read.
+// The caller must hold indexMu.
+func (ns *NotesServer) refreshIndex() (*searchIndex, error) {
+	if ns.index == nil {
+		ns.index = ns.loadIndex()
+	}
+	idx := ns.index
+
+	seen := make(map[string]bool)
+	changed := false
+
+	err := utils.WalkDir(ns.vaultDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != ns.vaultDir && isHiddenDir(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !isMarkdownFile(info.Name()) {
+			return nil
+		}
+
+		relativePath, _ := filepath.Rel(ns.vaultDir, path)
+		seen[relativePath] = true
+
+		if !idx.isStale(relativePath, info) {
+			return nil
+		}
+
+		content, err := utils.ReadFile(path)
+		if err != nil {
+			return nil // Skip files we can't read
+		}
+
+		idx.addDoc(relativePath, info, string(content))
+		changed = true
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh search index: %w", err)
+	}
+
+	for path := range idx.Docs {
+		if !seen[path] {
+			idx.removeDoc(path)
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := ns.saveIndex(idx); err != nil {
+			// The in-memory index is still valid so the search can proceed
+			slog.Warn("Failed to persist search index", "error", err)
+		}
+	}
+
+	return idx, nil
+}
@@ -0,0 +1,194 @@
+package notes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KyleBrandon/sibyl/pkg/dto"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func searchForTest(t *testing.T, ns *NotesServer, params SearchNotesRequest) []dto.SearchResult {
+	t.Helper()
+
+	var results []dto.SearchResult
+	result, err := ns.SearchNotes(context.Background(), mcp.CallToolRequest{}, params)
+	decodeToolResult(t, result, err, &results)
+	return results
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := tokenize("Hello, World! MCP-servers v2 café")
+
+	expected := []string{"hello", "world", "mcp", "servers", "v2", "café"}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d: %v", len(expected), len(tokens), tokens)
+	}
+
+	for i, token := range expected {
+		if tokens[i] != token {
+			t.Errorf("Token %d mismatch: expected %s, got %s", i, token, tokens[i])
+		}
+	}
+}
+
+func TestSearchNotes_RankedByRelevance(t *testing.T) {
+	testNotes := map[string]string{
+		"a-passing.md": "# Passing\n\nA single mention of golang here among lots of other words about cooking and travel and music.",
+		"b-focused.md": "# Golang\n\ngolang tips\ngolang tooling\ngolang testing",
+		"c-none.md":    "# Nothing\n\nThis note is unrelated.",
+	}
+
+	tempDir := createVaultForTest(t, testNotes)
+
+	ns := &NotesServer{vaultDir: tempDir}
+	results := searchForTest(t, ns, SearchNotesRequest{Query: "golang"})
+
+	if len(results) != 5 {
+		t.Fatalf("Expected 5 matching lines, got %d", len(results))
+	}
+
+	if results[0].Path != "b-focused.md" {
+		t.Errorf("Expected most relevant note first, got %s", results[0].Path)
+	}
+
+	last := results[len(results)-1]
+	if last.Path != "a-passing.md" {
+		t.Errorf("Expected least relevant note last, got %s", last.Path)
+	}
+
+	if results[0].Score <= last.Score {
+		t.Errorf("Expected descending scores, got %f then %f", results[0].Score, last.Score)
+	}
+
+	if !strings.Contains(last.Highlight, "**golang**") {
+		t.Errorf("Expected highlighted match, got %q", last.Highlight)
+	}
+}
+
+func TestSearchIndex_DocsContaining(t *testing.T) {
+	info, err := os.Stat(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to stat: %v", err)
+	}
+
+	idx := newSearchIndex()
+	idx.addDoc("a.md", info, "testing the café")
+	idx.addDoc("b.md", info, "contest results")
+	idx.addDoc("c.md", info, "latest attest")
+
+	tests := []struct {
+		term     string
+		expected string
+	}{
+		{"test", "a.md,b.md,c.md"},
+		{"testi", "a.md"},
+		{"tes", "a.md,b.md,c.md"},
+		{"afé", "a.md"},
+		{"é", "a.md"},
+		{"results", "b.md"},
+		{"tesla", ""},
+		{"xyz", ""},
+	}
+	for _, tt := range tests {
+		var paths []string
+		for path := range idx.docsContaining(tt.term) {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		if got := strings.Join(paths, ","); got != tt.expected {
+			t.Errorf("docsContaining(%q) = %q, expected %q", tt.term, got, tt.expected)
+		}
+	}
+
+	// Terms that leave the vocabulary leave the gram index too
+	idx.removeDoc("a.md")
+	if len(idx.docsContaining("testi")) != 0 || len(idx.grams["caf"]) != 0 {
+		t.Errorf("Expected the terms of a.md to be dropped, got %v", idx.grams)
+	}
+}
+
+func TestSearchIndex_PersistedAndIncremental(t *testing.T) {
+	tempDir := t.TempDir()
+
+	notePath := filepath.Join(tempDir, "note.md")
+	if err := os.WriteFile(notePath, []byte("original alpha content"), 0644); err != nil {
+		t.Fatalf("Failed to create test note: %v", err)
+	}
+
+	ns := &NotesServer{vaultDir: tempDir}
+	if results := searchForTest(t, ns, SearchNotesRequest{Query: "alpha"}); len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, sibylDirName, indexFileName)); err != nil {
+		t.Fatalf("Expected index to be persisted: %v", err)
+	}
+
+	// Change the note and make sure the mtime moves forward
+	if err := os.WriteFile(notePath, []byte("replaced beta content here"), 0644); err != nil {
+		t.Fatalf("Failed to update test note: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(notePath, future, future); err != nil {
+		t.Fatalf("Failed to update mtime: %v", err)
+	}
+
+	if results := searchForTest(t, ns, SearchNotesRequest{Query: "alpha"}); len(results) != 0 {
+		t.Errorf("Expected stale term to be dropped, got %d results", len(results))
+	}
+
+	// A fresh server should load the persisted index and see the new content
+	fresh := &NotesServer{vaultDir: tempDir}
+	if results := searchForTest(t, fresh, SearchNotesRequest{Query: "beta"}); len(results) != 1 {
+		t.Errorf("Expected 1 result from persisted index, got %d", len(results))
+	}
+
+	// Removed notes are dropped from the index
+	if err := os.Remove(notePath); err != nil {
+		t.Fatalf("Failed to remove test note: %v", err)
+	}
+	if results := searchForTest(t, fresh, SearchNotesRequest{Query: "beta"}); len(results) != 0 {
+		t.Errorf("Expected removed note to be dropped, got %d results", len(results))
+	}
+}
+
+func TestSearchIndex_CorruptIndexRebuilt(t *testing.T) {
+	tempDir := t.TempDir()
+
+	writeNoteForTest(t, tempDir, "note.md", "gamma")
+
+	if err := os.MkdirAll(filepath.Join(tempDir, sibylDirName), 0755); err != nil {
+		t.Fatalf("Failed to create index directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, sibylDirName, indexFileName), []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to write corrupt index: %v", err)
+	}
+
+	ns := &NotesServer{vaultDir: tempDir}
+	if results := searchForTest(t, ns, SearchNotesRequest{Query: "gamma"}); len(results) != 1 {
+		t.Errorf("Expected corrupt index to be rebuilt, got %d results", len(results))
+	}
+}
+
+func TestSearchNotes_ScopedToPath(t *testing.T) {
+	testNotes := map[string]string{
+		"work/a.md":   "delta",
+		"work2/b.md":  "delta",
+		"personal.md": "delta",
+	}
+
+	tempDir := createVaultForTest(t, testNotes)
+
+	ns := &NotesServer{vaultDir: tempDir}
+	results := searchForTest(t, ns, SearchNotesRequest{Query: "delta", Path: "work"})
+
+	if len(results) != 1 || results[0].Path != filepath.Join("work", "a.md") {
+		t.Errorf("Expected only work/a.md, got %+v", results)
+	}
+}
@@ -0,0 +1,599 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	linkKindWiki     = "wikilink"
+	linkKindMarkdown = "markdown"
+)
+
+var (
+	// [[target#heading|alias]] with an optional ! for embeds
+	wikiLinkPattern = regexp.MustCompile(`(!?)\[\[([^\[\]|#\n]*)(#[^\[\]|\n]*)?(\|[^\[\]\n]*)?\]\]`)
+
+	// [text](target "title") with an optional ! for images
+	markdownLinkPattern = regexp.MustCompile(`(!?)\[([^\[\]\n]*)\]\(([^()\s]+)(\s+"[^"\n]*")?\)`)
+
+	// Link targets with a URL scheme point outside the vault
+	urlSchemePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// NoteLink is a link from one note to a note or attachment in the vault
+type NoteLink struct {
+	Source  string `json:"source"`
+	Target  string `json:"target,omitempty"`
+	Link    string `json:"link"`
+	Kind    string `json:"kind"`
+	Line    int    `json:"line"`
+	Heading string `json:"heading,omitempty"`
+	Embed   bool   `json:"embed,omitempty"`
+	Broken  bool   `json:"broken,omitempty"`
+	Context string `json:"context,omitempty"`
+}
+
+// GetLinksRequest represents a request for the links to or from a note
+type GetLinksRequest struct {
+	Path string `json:"path" mcp:"Path to the note"`
+}
+
+// GraphNode is a note in the link graph
+type GraphNode struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Incoming int    `json:"incoming"`
+	Outgoing int    `json:"outgoing"`
+}
+
+// GraphEdge is a resolved link between two notes
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Kind   string `json:"kind"`
+	Count  int    `json:"count"`
+}
+
+// LinkGraphResponse is the JSON document served by notes://graph
+type LinkGraphResponse struct {
+	Nodes       []GraphNode `json:"nodes"`
+	Edges       []GraphEdge `json:"edges"`
+	BrokenLinks []NoteLink  `json:"broken_links"`
+	Orphans     []string    `json:"orphans"`
+}
+
+// rawLink is a link as written in a note, before it is resolved
+type rawLink struct {
+	kind    string
+	target  string
+	heading string
+	embed   bool
+	line    int
+	text    string
+
+	// Byte offsets of the target within the note, used to rewrite links
+	targetStart int
+	targetEnd   int
+}
+
+// parseLinks finds wikilinks and relative markdown links in a note. Links in
+// fenced code blocks and inline code spans are ignored, as are external URLs
+// and pure #anchor links.
+func parseLinks(content string) []rawLink {
+	var links []rawLink
+
+	offset := 0
+	var fences fenceTracker
+	for lineNum, line := range strings.SplitAfter(content, "\n") {
+		lineStart := offset
+		offset += len(line)
+
+		if fences.inCode(line) {
+			continue
+		}
+
+		masked := maskInlineCode(line)
+		text := strings.TrimRight(line, "\r\n")
+
+		for _, m := range wikiLinkPattern.FindAllStringSubmatchIndex(masked, -1) {
+			link := rawLink{
+				kind:        linkKindWiki,
+				embed:       m[3] > m[2],
+				target:      strings.TrimSpace(line[m[4]:m[5]]),
+				line:        lineNum + 1,
+				text:        text,
+				targetStart: lineStart + m[4],
+				targetEnd:   lineStart + m[5],
+			}
+			if m[6] >= 0 {
+				link.heading = line[m[6]+1 : m[7]]
+			}
+			links = append(links, link)
+		}
+
+		for _, m := range markdownLinkPattern.FindAllStringSubmatchIndex(masked, -1) {
+			target := line[m[6]:m[7]]
+			if target == "" || strings.HasPrefix(target, "#") || urlSchemePattern.MatchString(target) {
+				continue
+			}
+
+			link := rawLink{
+				kind:        linkKindMarkdown,
+				embed:       m[3] > m[2],
+				line:        lineNum + 1,
+				text:        text,
+				targetStart: lineStart + m[6],
+				targetEnd:   lineStart + m[7],
+			}
+			if path, heading, found := strings.Cut(target, "#"); found {
+				link.heading = heading
+				link.targetEnd = link.targetStart + len(path)
+				target = path
+			}
+			if decoded, err := url.PathUnescape(target); err == nil {
+				target = decoded
+			}
+			link.target = target
+			links = append(links, link)
+		}
+	}
+
+	return links
+}
+
+// fenceStart returns the fence, the run of three or more backticks or tildes,
+// if the trimmed line opens a fenced code block. As in CommonMark the info
+// string after a backtick fence may not contain backticks.
+func fenceStart(trimmed string) string {
+	if !strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~") {
+		return ""
+	}
+
+	fence := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+	if fence[0] == '`' && strings.Contains(trimmed[len(fence):], "`") {
+		return ""
+	}
+	return fence
+}
+
+// closesFence reports whether the trimmed line closes a code block opened
+// with fence: a run of the same character at least as long, with no info string
+func closesFence(trimmed, fence string) bool {
+	return strings.HasPrefix(trimmed, fence) && strings.TrimRight(trimmed, fence[:1]) == ""
+}
+
+// fenceTracker follows the fenced code blocks of a note line by line
+type fenceTracker struct {
+	fence string
+}
+
+// inCode reports whether a line belongs to a fenced code block, counting the
+// opening and closing fences as part of it
+func (f *fenceTracker) inCode(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if f.fence != "" {
+		if closesFence(trimmed, f.fence) {
+			f.fence = ""
+		}
+		return true
+	}
+
+	f.fence = fenceStart(trimmed)
+	return f.fence != ""
+}
+
+// maskInlineCode replaces inline code spans with spaces, keeping byte offsets intact
+func maskInlineCode(line string) string {
+	if !strings.Contains(line, "`") {
+		return line
+	}
+
+	masked := []byte(line)
+	for i := 0; i < len(masked); {
+		if masked[i] != '`' {
+			i++
+			continue
+		}
+
+		// Count the opening backtick run and find a closing run of the same length
+		run := 0
+		for i+run < len(masked) && masked[i+run] == '`' {
+			run++
+		}
+		closing := strings.Index(line[i+run:], strings.Repeat("`", run))
+		if closing < 0 {
+			break
+		}
+
+		end := i + run + closing + run
+		for j := i; j < end; j++ {
+			masked[j] = ' '
+		}
+		i = end
+	}
+
+	return string(masked)
+}
+
+// linkResolver resolves link targets against the files in the vault
+type linkResolver struct {
+	files  map[string]string   // lower-cased relative path -> relative path
+	byName map[string][]string // lower-cased file name (and note name without extension) -> relative paths
+}
+
+func newLinkResolver(paths []string) *linkResolver {
+	r := &linkResolver{
+		files:  make(map[string]string),
+		byName: make(map[string][]string),
+	}
+
+	for _, path := range paths {
+		slashed := filepath.ToSlash(path)
+		r.files[strings.ToLower(slashed)] = path
+
+		name := strings.ToLower(filepath.Base(slashed))
+		r.byName[name] = append(r.byName[name], path)
+		if isMarkdownFile(name) {
+			base := strings.TrimSuffix(name, filepath.Ext(name))
+			r.byName[base] = append(r.byName[base], path)
+		}
+	}
+
+	for _, candidates := range r.byName {
+		sort.Slice(candidates, func(i, j int) bool {
+			if len(candidates[i]) != len(candidates[j]) {
+				return len(candidates[i]) < len(candidates[j])
+			}
+			return candidates[i] < candidates[j]
+		})
+	}
+
+	return r
+}
+
+// lookup finds a vault file by its slash separated relative path, trying the
+// markdown extensions when the path has none
+func (r *linkResolver) lookup(path string) (string, bool) {
+	key := strings.ToLower(strings.TrimPrefix(path, "/"))
+	if found, ok := r.files[key]; ok {
+		return found, true
+	}
+
+	for _, ext := range []string{".md", ".markdown"} {
+		if found, ok := r.files[key+ext]; ok {
+			return found, true
+		}
+	}
+
+	return "", false
+}
+
+// resolve returns the vault-relative path a link points to
+func (r *linkResolver) resolve(source string, link rawLink) (string, bool) {
+	sourceDir := filepath.ToSlash(filepath.Dir(source))
+	if sourceDir == "." {
+		sourceDir = ""
+	}
+
+	if link.target == "" {
+		// [[#heading]] and [text](#anchor) point at the note itself
+		return source, true
+	}
+
+	if link.kind == linkKindMarkdown {
+		target := link.target
+		if !strings.HasPrefix(target, "/") {
+			target = pathJoin(sourceDir, target)
+		}
+		target = filepathClean(target)
+		if strings.HasPrefix(target, "../") || target == ".." {
+			return "", false
+		}
+		return r.lookup(target)
+	}
+
+	// Wikilinks with a folder are resolved from the vault root first, then
+	// relative to the source note; bare names match any note with that name,
+	// preferring one in the same folder as the source.
+	target := strings.TrimPrefix(filepath.ToSlash(link.target), "./")
+	if strings.Contains(target, "/") {
+		if found, ok := r.lookup(target); ok {
+			return found, true
+		}
+		return r.lookup(filepathClean(pathJoin(sourceDir, target)))
+	}
+
+	candidates := r.byName[strings.ToLower(target)]
+	if len(candidates) == 0 {
+		return "", false
+	}
+	for _, candidate := range candidates {
+		if filepath.ToSlash(filepath.Dir(candidate)) == filepath.ToSlash(filepath.Dir(source)) {
+			return candidate, true
+		}
+	}
+	return candidates[0], true
+}
+
+func pathJoin(dir, target string) string {
+	if dir == "" {
+		return target
+	}
+	return dir + "/" + target
+}
+
+// filepathClean cleans a slash separated path
+func filepathClean(path string) string {
+	return filepath.ToSlash(filepath.Clean(filepath.FromSlash(strings.TrimPrefix(path, "/"))))
+}
+
+// linkGraph is the resolved set of links between notes in the vault
+type linkGraph struct {
+	notes    []string
+	titles   map[string]string
+	links    []NoteLink
+	outgoing map[string][]NoteLink
+	incoming map[string][]NoteLink
+}
+
+// vaultFiles returns every file in the vault, skipping hidden folders
+func (ns *NotesServer) vaultFiles() ([]string, error) {
+	var files []string
+	err := utils.WalkDir(ns.vaultDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != ns.vaultDir && isHiddenDir(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relativePath, _ := filepath.Rel(ns.vaultDir, path)
+		files = append(files, relativePath)
+		return nil
+	})
+
+	return files, err
+}
+
+// buildLinkGraph reads every note in the vault and resolves its links
+func (ns *NotesServer) buildLinkGraph() (*linkGraph, error) {
+	files, err := ns.vaultFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan vault: %w", err)
+	}
+
+	graph := newLinkGraph()
+	resolver := newLinkResolver(files)
+
+	for _, source := range files {
+		if !isMarkdownFile(source) {
+			continue
+		}
+
+		content, err := utils.ReadFile(filepath.Join(ns.vaultDir, source))
+		if err != nil {
+			continue // Skip files we can't read
+		}
+
+		graph.addNote(source, string(content), resolver)
+	}
+
+	return graph, nil
+}
+
+func newLinkGraph() *linkGraph {
+	return &linkGraph{
+		titles:   make(map[string]string),
+		outgoing: make(map[string][]NoteLink),
+		incoming: make(map[string][]NoteLink),
+	}
+}
+
+// addNote adds a note to the graph and resolves its links
+func (g *linkGraph) addNote(source, content string, resolver *linkResolver) {
+	g.notes = append(g.notes, source)
+	g.titles[source] = noteTitle(content, filepath.Base(source))
+
+	for _, raw := range parseLinks(content) {
+		link := NoteLink{
+			Source:  source,
+			Link:    raw.target,
+			Kind:    raw.kind,
+			Line:    raw.line,
+			Heading: raw.heading,
+			Embed:   raw.embed,
+			Context: strings.TrimSpace(raw.text),
+		}
+
+		target, ok := resolver.resolve(source, raw)
+		if ok {
+			link.Target = target
+			g.incoming[target] = append(g.incoming[target], link)
+		} else {
+			link.Broken = true
+		}
+
+		g.outgoing[source] = append(g.outgoing[source], link)
+		g.links = append(g.links, link)
+	}
+}
+
+// orphans returns notes without any resolved links in or out, ignoring self links
+func (g *linkGraph) orphans() []string {
+	orphans := []string{}
+	for _, note := range g.notes {
+		connected := false
+		for _, link := range g.outgoing[note] {
+			if !link.Broken && link.Target != note {
+				connected = true
+				break
+			}
+		}
+		for _, link := range g.incoming[note] {
+			if link.Source != note {
+				connected = true
+				break
+			}
+		}
+		if !connected {
+			orphans = append(orphans, note)
+		}
+	}
+
+	return orphans
+}
+
+func (ns *NotesServer) NewGetBacklinksTool() {
+	tool := mcp.NewTool(
+		"get_backlinks",
+		mcp.WithDescription("List the notes that link to the given note, with the line containing each link"),
+		mcp.WithString("path", mcp.Description("Path to the note"), mcp.Required()),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.GetBacklinks))
+}
+
+func (ns *NotesServer) NewGetOutgoingLinksTool() {
+	tool := mcp.NewTool(
+		"get_outgoing_links",
+		mcp.WithDescription("List the wikilinks and relative markdown links in a note and where they resolve to"),
+		mcp.WithString("path", mcp.Description("Path to the note"), mcp.Required()),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.GetOutgoingLinks))
+}
+
+// GetBacklinks returns the links pointing at a note
+func (ns *NotesServer) GetBacklinks(ctx context.Context, req mcp.CallToolRequest, params GetLinksRequest) (*mcp.CallToolResult, error) {
+	return ns.noteLinks(params.Path, func(graph *linkGraph, path string) []NoteLink {
+		return graph.incoming[path]
+	})
+}
+
+// GetOutgoingLinks returns the links found in a note, including broken ones
+func (ns *NotesServer) GetOutgoingLinks(ctx context.Context, req mcp.CallToolRequest, params GetLinksRequest) (*mcp.CallToolResult, error) {
+	return ns.noteLinks(params.Path, func(graph *linkGraph, path string) []NoteLink {
+		return graph.outgoing[path]
+	})
+}
+
+func (ns *NotesServer) noteLinks(path string, selectLinks func(graph *linkGraph, path string) []NoteLink) (*mcp.CallToolResult, error) {
+	fullPath, err := utils.ValidatePath(ns.vaultDir, path)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(fmt.Sprintf("Invalid path: %v", err)),
+			},
+		}, nil
+	}
+
+	if _, err := utils.Stat(fullPath); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(fmt.Sprintf("Note not found: %s", path)),
+			},
+		}, nil
+	}
+
+	graph, err := ns.buildLinkGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	links := selectLinks(graph, relativePath)
+	if links == nil {
+		links = []NoteLink{}
+	}
+
+	linksJSON, _ := json.MarshalIndent(links, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(linksJSON)),
+		},
+	}, nil
+}
+
+// GetLinkGraph serves the vault's link graph as nodes and edges
+func (ns *NotesServer) GetLinkGraph(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	graph, err := ns.buildLinkGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	response := LinkGraphResponse{
+		Nodes:       []GraphNode{},
+		Edges:       []GraphEdge{},
+		BrokenLinks: []NoteLink{},
+		Orphans:     graph.orphans(),
+	}
+
+	isNote := make(map[string]bool)
+	for _, note := range graph.notes {
+		isNote[note] = true
+	}
+
+	edgeIndex := make(map[string]int)
+	incoming := make(map[string]int)
+	outgoing := make(map[string]int)
+	for _, link := range graph.links {
+		if link.Broken {
+			response.BrokenLinks = append(response.BrokenLinks, link)
+			continue
+		}
+
+		// Attachments are not nodes in the graph, and heading links within
+		// a note are not edges
+		if !isNote[link.Target] || link.Target == link.Source {
+			continue
+		}
+
+		key := link.Source + "\x00" + link.Target + "\x00" + link.Kind
+		if i, exists := edgeIndex[key]; exists {
+			response.Edges[i].Count++
+			continue
+		}
+
+		edgeIndex[key] = len(response.Edges)
+		response.Edges = append(response.Edges, GraphEdge{Source: link.Source, Target: link.Target, Kind: link.Kind, Count: 1})
+		outgoing[link.Source]++
+		incoming[link.Target]++
+	}
+
+	for _, note := range graph.notes {
+		response.Nodes = append(response.Nodes, GraphNode{
+			ID:       note,
+			Title:    graph.titles[note],
+			Incoming: incoming[note],
+			Outgoing: outgoing[note],
+		})
+	}
+
+	graphJSON, _ := json.MarshalIndent(response, "", "  ")
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      "notes://graph",
+			MIMEType: "application/json",
+			Text:     string(graphJSON),
+		},
+	}, nil
+}
@@ -0,0 +1,220 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseLinks(t *testing.T) {
+	content := "# Links\n" +
+		"See [[Project Alpha]] and [[Folder/Note#Tasks|the tasks]].\n" +
+		"Embed: ![[diagram.png]]\n" +
+		"Markdown [spec](../docs/My%20Spec.md#intro) and [site](https://example.com).\n" +
+		"Anchor [top](#links) and mail [me](mailto:a@b.c)\n" +
+		"Inline `[[not a link]]` code.\n" +
+		"```\n" +
+		"[[also not a link]]\n" +
+		"```\n"
+
+	links := parseLinks(content)
+	if len(links) != 4 {
+		t.Fatalf("Expected 4 links, got %d: %+v", len(links), links)
+	}
+
+	expected := []struct {
+		kind    string
+		target  string
+		heading string
+		embed   bool
+		line    int
+	}{
+		{linkKindWiki, "Project Alpha", "", false, 2},
+		{linkKindWiki, "Folder/Note", "Tasks", false, 2},
+		{linkKindWiki, "diagram.png", "", true, 3},
+		{linkKindMarkdown, "../docs/My Spec.md", "intro", false, 4},
+	}
+
+	for i, exp := range expected {
+		link := links[i]
+		if link.kind != exp.kind || link.target != exp.target || link.heading != exp.heading || link.embed != exp.embed || link.line != exp.line {
+			t.Errorf("Link %d: expected %+v, got %+v", i, exp, link)
+		}
+	}
+
+	// Offsets point at the target text so it can be rewritten in place
+	if got := content[links[1].targetStart:links[1].targetEnd]; got != "Folder/Note" {
+		t.Errorf("Unexpected wikilink target span: %q", got)
+	}
+	if got := content[links[3].targetStart:links[3].targetEnd]; got != "../docs/My%20Spec.md" {
+		t.Errorf("Unexpected markdown target span: %q", got)
+	}
+}
+
+func TestLinkResolver(t *testing.T) {
+	resolver := newLinkResolver([]string{
+		"Alpha.md",
+		"projects/Alpha.md",
+		"projects/Beta.md",
+		"docs/spec.md",
+		"assets/diagram.png",
+	})
+
+	tests := []struct {
+		name     string
+		source   string
+		link     rawLink
+		expected string
+		ok       bool
+	}{
+		{"Bare name prefers same folder", "projects/Beta.md", rawLink{kind: linkKindWiki, target: "Alpha"}, "projects/Alpha.md", true},
+		{"Bare name prefers shortest path", "docs/spec.md", rawLink{kind: linkKindWiki, target: "alpha"}, "Alpha.md", true},
+		{"Path from vault root", "docs/spec.md", rawLink{kind: linkKindWiki, target: "projects/Beta"}, "projects/Beta.md", true},
+		{"Attachment", "Alpha.md", rawLink{kind: linkKindWiki, target: "diagram.png"}, "assets/diagram.png", true},
+		{"Self heading link", "Alpha.md", rawLink{kind: linkKindWiki, target: ""}, "Alpha.md", true},
+		{"Missing note", "Alpha.md", rawLink{kind: linkKindWiki, target: "Gamma"}, "", false},
+		{"Relative markdown", "projects/Beta.md", rawLink{kind: linkKindMarkdown, target: "../docs/spec.md"}, "docs/spec.md", true},
+		{"Markdown without extension", "projects/Beta.md", rawLink{kind: linkKindMarkdown, target: "Alpha"}, "projects/Alpha.md", true},
+		{"Markdown escaping vault", "Alpha.md", rawLink{kind: linkKindMarkdown, target: "../outside.md"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := resolver.resolve(tt.source, tt.link)
+			if ok != tt.ok || filepath.ToSlash(got) != tt.expected {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.expected, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+var linkedVaultNotes = map[string]string{
+	"index.md":          "# Index\n\n- [[Alpha]]\n- [Beta](projects/beta.md)\n- [[Missing Note]]",
+	"projects/alpha.md": "# Alpha\n\nBack to [[index]]. See also [[beta]].",
+	"projects/beta.md":  "# Beta\n\nNo links here.",
+	"lonely.md":         "# Lonely\n\nNobody links here. [[#Lonely|self]]",
+	".trash/old.md":     "[[index]]",
+}
+
+func TestGetBacklinks(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, linkedVaultNotes)}
+	ctx := context.Background()
+
+	result, err := ns.GetBacklinks(ctx, mcp.CallToolRequest{}, GetLinksRequest{Path: "projects/beta.md"})
+	var links []NoteLink
+	decodeToolResult(t, result, err, &links)
+	if len(links) != 2 {
+		t.Fatalf("Expected 2 backlinks to beta, got %d: %+v", len(links), links)
+	}
+
+	sources := map[string]bool{}
+	for _, link := range links {
+		sources[filepath.ToSlash(link.Source)] = true
+		if link.Context == "" {
+			t.Error("Backlinks should include the line containing the link")
+		}
+	}
+	if !sources["index.md"] || !sources["projects/alpha.md"] {
+		t.Errorf("Unexpected backlink sources: %v", sources)
+	}
+
+	// Links from hidden folders such as .trash are ignored
+	result, err = ns.GetBacklinks(ctx, mcp.CallToolRequest{}, GetLinksRequest{Path: "index.md"})
+	links = nil
+	decodeToolResult(t, result, err, &links)
+	if len(links) != 1 {
+		t.Errorf("Expected 1 backlink to index, got %d", len(links))
+	}
+}
+
+func TestGetOutgoingLinks(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, linkedVaultNotes)}
+
+	result, err := ns.GetOutgoingLinks(context.Background(), mcp.CallToolRequest{}, GetLinksRequest{Path: "index.md"})
+	var links []NoteLink
+	decodeToolResult(t, result, err, &links)
+	if len(links) != 3 {
+		t.Fatalf("Expected 3 outgoing links, got %d", len(links))
+	}
+
+	broken := 0
+	for _, link := range links {
+		if link.Broken {
+			broken++
+			if link.Link != "Missing Note" {
+				t.Errorf("Unexpected broken link: %+v", link)
+			}
+		}
+	}
+	if broken != 1 {
+		t.Errorf("Expected 1 broken link, got %d", broken)
+	}
+}
+
+func TestGetOutgoingLinks_MissingNote(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, linkedVaultNotes)}
+
+	result, err := ns.GetOutgoingLinks(context.Background(), mcp.CallToolRequest{}, GetLinksRequest{Path: "nope.md"})
+	if err != nil {
+		t.Fatalf("Expected MCP error, not Go error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("Expected error for missing note")
+	}
+}
+
+func TestGetLinkGraph(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, linkedVaultNotes)}
+
+	resources, err := ns.GetLinkGraph(context.Background(), mcp.ReadResourceRequest{})
+	if err != nil {
+		t.Fatalf("GetLinkGraph failed: %v", err)
+	}
+
+	var graph LinkGraphResponse
+	if err := json.Unmarshal([]byte(resources[0].(mcp.TextResourceContents).Text), &graph); err != nil {
+		t.Fatalf("Invalid JSON returned: %v", err)
+	}
+
+	if len(graph.Nodes) != 4 {
+		t.Errorf("Expected 4 nodes, got %d", len(graph.Nodes))
+	}
+
+	if len(graph.Edges) != 4 {
+		t.Errorf("Expected 4 edges, got %d: %+v", len(graph.Edges), graph.Edges)
+	}
+
+	if len(graph.BrokenLinks) != 1 || graph.BrokenLinks[0].Link != "Missing Note" {
+		t.Errorf("Unexpected broken links: %+v", graph.BrokenLinks)
+	}
+
+	if len(graph.Orphans) != 1 || graph.Orphans[0] != "lonely.md" {
+		t.Errorf("Expected lonely.md to be the only orphan, got %v", graph.Orphans)
+	}
+}
+
+func TestFenceTracker(t *testing.T) {
+	lines := []string{
+		"text",
+		"```go",
+		"```python",
+		"``` ",
+		"~~~~",
+		"~~~",
+		"````",
+		"~~~~~",
+		"```inline``` code",
+		"text",
+	}
+	expected := []bool{false, true, true, true, true, true, true, true, false, false}
+
+	var fences fenceTracker
+	for i, line := range lines {
+		if got := fences.inCode(line); got != expected[i] {
+			t.Errorf("Line %d %q: expected in code %v, got %v", i+1, line, expected[i], got)
+		}
+	}
+}
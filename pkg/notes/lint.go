@@ -0,0 +1,424 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// Lint rules
+const (
+	lintBrokenLink          = "broken_link"
+	lintUnclosedFrontmatter = "unclosed_frontmatter"
+	lintInvalidFrontmatter  = "invalid_frontmatter"
+	lintDuplicateTitle      = "duplicate_title"
+	lintMissingField        = "missing_field"
+	lintSkippedHeading      = "skipped_heading_level"
+	lintPlaceholder         = "unfilled_placeholder"
+)
+
+// Severities a lint rule can be set to
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityOff     = "off"
+)
+
+// lintConfigFileName is the lint configuration inside sibylDirName, used
+// unless WithLintConfig names another file
+const lintConfigFileName = "lint.yaml"
+
+// defaultLintRules are the severities of the rules when not configured
+var defaultLintRules = map[string]string{
+	lintBrokenLink:          severityError,
+	lintUnclosedFrontmatter: severityError,
+	lintInvalidFrontmatter:  severityError,
+	lintMissingField:        severityError,
+	lintDuplicateTitle:      severityWarning,
+	lintSkippedHeading:      severityWarning,
+	lintPlaceholder:         severityWarning,
+}
+
+// placeholderRegex matches a {{NAME}} or {{.NAME}} placeholder left in a
+// note created from a template
+var placeholderRegex = regexp.MustCompile(`\{\{-?\s*\.?[A-Za-z_][A-Za-z0-9_]*\s*-?\}\}`)
+
+// LintConfig configures lint_vault. Rules set the severity of each rule to
+// error, warning or off. RequiredFields lists the frontmatter fields a note
+// must have by its type field, with * applying to every note. Folders
+// override both for the notes below them, the deepest folder last.
+type LintConfig struct {
+	Rules          map[string]string           `yaml:"rules"`
+	RequiredFields map[string][]string         `yaml:"required_fields"`
+	Folders        map[string]LintFolderConfig `yaml:"folders"`
+}
+
+// LintFolderConfig overrides the lint configuration for a folder
+type LintFolderConfig struct {
+	Rules          map[string]string   `yaml:"rules"`
+	RequiredFields map[string][]string `yaml:"required_fields"`
+}
+
+// LintVaultRequest represents a request to lint the vault
+type LintVaultRequest struct {
+	Path string `json:"path,omitempty" mcp:"Folder or note to report problems for (defaults to the whole vault)"`
+}
+
+// LintIssue is a problem found in a note
+type LintIssue struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// LintReport is the response of lint_vault and the output of --check
+type LintReport struct {
+	Checked  int         `json:"checked"`
+	Errors   int         `json:"errors"`
+	Warnings int         `json:"warnings"`
+	Issues   []LintIssue `json:"issues"`
+}
+
+// lintNote is a note read for linting
+type lintNote struct {
+	path    string
+	content string
+	title   string
+	line    int
+}
+
+func (ns *NotesServer) NewLintVaultTool() {
+	tool := mcp.NewTool(
+		"lint_vault",
+		mcp.WithDescription("Check the vault for problems: broken links, unclosed or invalid frontmatter, duplicate titles, missing required frontmatter fields, headings that skip levels and unfilled {{PLACEHOLDER}} tokens. Rules are configured per folder in .sibyl/lint.yaml"),
+		mcp.WithString("path", mcp.Description("Folder or note to report problems for (defaults to the whole vault)")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.LintVault))
+}
+
+// LintVault reports the problems found in the notes of the vault
+func (ns *NotesServer) LintVault(ctx context.Context, req mcp.CallToolRequest, params LintVaultRequest) (*mcp.CallToolResult, error) {
+	report, err := ns.Lint(params.Path)
+	if err != nil {
+		return toolErrorResult("%v", err), nil
+	}
+
+	reportJSON, _ := json.MarshalIndent(report, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(reportJSON)),
+		},
+	}, nil
+}
+
+// Lint checks the notes under path, or the whole vault when path is empty.
+// Links and titles are checked against every note in the vault. Notes in
+// the templates folder are not checked.
+func (ns *NotesServer) Lint(path string) (*LintReport, error) {
+	scope := ""
+	if path != "" {
+		fullPath, err := utils.ValidatePath(ns.vaultDir, path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := utils.Stat(fullPath); err != nil {
+			return nil, fmt.Errorf("path not found: %s", path)
+		}
+		scope, _ = filepath.Rel(ns.vaultDir, fullPath)
+	}
+
+	config, err := ns.loadLintConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ns.vaultFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan vault: %w", err)
+	}
+
+	templates := ""
+	if dir, err := ns.templatesDir(); err == nil {
+		templates, _ = filepath.Rel(ns.vaultDir, dir)
+	}
+
+	var notes []lintNote
+	for _, file := range files {
+		if !isMarkdownFile(file) || isInFolder(file, templates) {
+			continue
+		}
+		content, err := utils.ReadFile(filepath.Join(ns.vaultDir, file))
+		if err != nil {
+			continue // Skip files we can't read
+		}
+		note := lintNote{path: file, content: string(content)}
+		note.title, note.line = explicitTitle(note.content)
+		notes = append(notes, note)
+	}
+
+	// Notes sharing a title, ignoring case
+	byTitle := make(map[string][]string)
+	for _, note := range notes {
+		if note.title != "" {
+			key := strings.ToLower(note.title)
+			byTitle[key] = append(byTitle[key], filepath.ToSlash(note.path))
+		}
+	}
+
+	report := &LintReport{Issues: []LintIssue{}}
+	resolver := newLinkResolver(files)
+	for _, note := range notes {
+		if scope != "" && scope != "." && note.path != scope && !isInFolder(note.path, scope) {
+			continue
+		}
+		report.Checked++
+
+		rules, required := config.forNote(note.path)
+		add := func(line int, rule, message string) {
+			severity := rules[rule]
+			if severity == severityOff {
+				return
+			}
+			report.Issues = append(report.Issues, LintIssue{
+				File:     filepath.ToSlash(note.path),
+				Line:     line,
+				Rule:     rule,
+				Severity: severity,
+				Message:  message,
+			})
+		}
+
+		lintFrontmatter(note, required, add)
+
+		for _, link := range parseLinks(note.content) {
+			if _, ok := resolver.resolve(note.path, link); !ok {
+				add(link.line, lintBrokenLink, fmt.Sprintf("Link target not found: %s", link.target))
+			}
+		}
+
+		if others := byTitle[strings.ToLower(note.title)]; note.title != "" && len(others) > 1 {
+			var rest []string
+			for _, other := range others {
+				if other != filepath.ToSlash(note.path) {
+					rest = append(rest, other)
+				}
+			}
+			add(note.line, lintDuplicateTitle, fmt.Sprintf("Title %q is also used by %s", note.title, strings.Join(rest, ", ")))
+		}
+
+		previous := 0
+		for _, heading := range parseHeadings(note.content) {
+			if previous > 0 && heading.level > previous+1 {
+				add(heading.line, lintSkippedHeading, fmt.Sprintf("Heading level %d follows level %d: %s", heading.level, previous, heading.text))
+			}
+			previous = heading.level
+		}
+
+		for _, placeholder := range findPlaceholders(note.content) {
+			add(placeholder.line, lintPlaceholder, fmt.Sprintf("Unfilled placeholder %s", placeholder.text))
+		}
+	}
+
+	for _, issue := range report.Issues {
+		if issue.Severity == severityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Rule < b.Rule
+	})
+
+	return report, nil
+}
+
+// lintFrontmatter checks that the frontmatter of a note is closed, parses
+// and has the fields required for its type
+func lintFrontmatter(note lintNote, required map[string][]string, add func(line int, rule, message string)) {
+	fm, err := parseFrontmatter(note.content)
+	if err != nil {
+		var fmErr *FrontmatterError
+		if !errors.As(err, &fmErr) || fm.EndLine == 0 {
+			add(1, lintUnclosedFrontmatter, "Frontmatter is not closed with ---")
+			return
+		}
+
+		add(fmErr.Line, lintInvalidFrontmatter, fmt.Sprintf("Invalid frontmatter YAML: %s", fmErr.Message))
+		return
+	}
+
+	noteType := frontmatterScalar(fm.Fields["type"])
+	var fields []string
+	fields = append(fields, required["*"]...)
+	if noteType != "" {
+		fields = append(fields, required[noteType]...)
+	}
+
+	line := fm.EndLine
+	if line == 0 {
+		line = 1
+	}
+	for _, field := range fields {
+		if value, ok := fm.Fields[field]; !ok || frontmatterScalar(value) == "" {
+			message := fmt.Sprintf("Missing required frontmatter field %q", field)
+			if noteType != "" {
+				message += fmt.Sprintf(" for type %s", noteType)
+			}
+			add(line, lintMissingField, message)
+		}
+	}
+}
+
+// titleKeyRegex matches the top-level title key of the frontmatter, which
+// may be quoted
+var titleKeyRegex = regexp.MustCompile(`^(?:title|"title"|'title')\s*:`)
+
+// explicitTitle returns the frontmatter title or first level one heading of
+// a note and the line it is on, or an empty title when the note has neither
+func explicitTitle(content string) (string, int) {
+	fm, _ := parseFrontmatter(content)
+	if title := frontmatterScalar(fm.Fields["title"]); title != "" {
+		lines := strings.Split(content, "\n")
+		for i := 1; i < fm.EndLine-1; i++ {
+			if titleKeyRegex.MatchString(lines[i]) {
+				return title, i + 1
+			}
+		}
+		return title, 1
+	}
+
+	for _, heading := range parseHeadings(content) {
+		if heading.level == 1 && heading.text != "" {
+			return heading.text, heading.line
+		}
+	}
+	return "", 0
+}
+
+// placeholder is an unfilled template placeholder and its line
+type placeholder struct {
+	text string
+	line int
+}
+
+// findPlaceholders returns the {{NAME}} placeholders of a note outside
+// fenced code blocks and inline code
+func findPlaceholders(content string) []placeholder {
+	var placeholders []placeholder
+
+	var fences fenceTracker
+	for lineNum, line := range strings.Split(content, "\n") {
+		if fences.inCode(line) {
+			continue
+		}
+
+		for _, m := range placeholderRegex.FindAllStringIndex(maskInlineCode(line), -1) {
+			placeholders = append(placeholders, placeholder{text: line[m[0]:m[1]], line: lineNum + 1})
+		}
+	}
+
+	return placeholders
+}
+
+// loadLintConfig reads the lint configuration, falling back to the default
+// rules when no configuration file exists
+func (ns *NotesServer) loadLintConfig() (*LintConfig, error) {
+	path := ns.lintConfigPath
+	if path == "" {
+		path = filepath.Join(ns.vaultDir, sibylDirName, lintConfigFileName)
+	}
+
+	config := &LintConfig{}
+	data, err := utils.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && ns.lintConfigPath == "" {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lint config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid lint config %s: %w", path, err)
+	}
+
+	check := func(rules map[string]string) error {
+		for rule, severity := range rules {
+			if _, ok := defaultLintRules[rule]; !ok {
+				return fmt.Errorf("invalid lint config %s: unknown rule %q", path, rule)
+			}
+			if severity != severityError && severity != severityWarning && severity != severityOff {
+				return fmt.Errorf("invalid lint config %s: rule %s must be error, warning or off", path, rule)
+			}
+		}
+		return nil
+	}
+	if err := check(config.Rules); err != nil {
+		return nil, err
+	}
+	for _, folder := range config.Folders {
+		if err := check(folder.Rules); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// forNote returns the rule severities and required fields that apply to a
+// note, applying the folder overrides from the shallowest to the deepest
+func (c *LintConfig) forNote(path string) (map[string]string, map[string][]string) {
+	rules := make(map[string]string)
+	for rule, severity := range defaultLintRules {
+		rules[rule] = severity
+	}
+	required := make(map[string][]string)
+
+	apply := func(overrides map[string]string, fields map[string][]string) {
+		for rule, severity := range overrides {
+			rules[rule] = severity
+		}
+		for noteType, names := range fields {
+			required[noteType] = names
+		}
+	}
+	apply(c.Rules, c.RequiredFields)
+
+	var folders []string
+	for folder := range c.Folders {
+		if isInFolder(path, filepath.FromSlash(strings.Trim(folder, "/"))) {
+			folders = append(folders, folder)
+		}
+	}
+	sort.Slice(folders, func(i, j int) bool { return len(folders[i]) < len(folders[j]) })
+	for _, folder := range folders {
+		apply(c.Folders[folder].Rules, c.Folders[folder].RequiredFields)
+	}
+
+	return rules, required
+}
+
+// isInFolder reports whether a vault-relative path lies below folder
+func isInFolder(path, folder string) bool {
+	return folder != "" && strings.HasPrefix(path, folder+string(filepath.Separator))
+}
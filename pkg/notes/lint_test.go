@@ -0,0 +1,194 @@
+package notes
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var lintVaultNotes = map[string]string{
+	"index.md":             "# Index\n\nSee [[alpha]], [[missing]] and [beta](Projects/beta.md).\n\n```\n[[not a link]] {{TITLE}}\n```\n",
+	"alpha.md":             "---\ntitle: Alpha\ntype: meeting\ndate: 2025-01-10\n---\n# Alpha\n\n### Skipped\n\nWith {{ATTENDEES}} and `{{CODE}}`.\n",
+	"Projects/beta.md":     "---\ntype: meeting\n---\n# Alpha\n\n## Fine\n### Also fine\n# Top\n### Skipped again\n",
+	"Projects/unclosed.md": "---\ntitle: Open\n\n# Unclosed\n",
+	"Projects/invalid.md":  "---\ntitle: Bad\nstatus: done\n  nested: value\n---\n# Invalid\n\n[Gone](../nowhere.md)\n",
+	"Templates/meeting.md": "# {{TITLE}}\n\n[[missing template link]]\n",
+}
+
+func lintIssues(report *LintReport) []string {
+	var issues []string
+	for _, issue := range report.Issues {
+		issues = append(issues, issue.File+":"+strconv.Itoa(issue.Line)+":"+issue.Rule)
+	}
+	return issues
+}
+
+func TestLint_DefaultRules(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, lintVaultNotes)}
+
+	report, err := ns.Lint("")
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+
+	expected := []string{
+		"Projects/beta.md:4:duplicate_title",
+		"Projects/beta.md:9:skipped_heading_level",
+		"Projects/invalid.md:4:invalid_frontmatter",
+		"Projects/invalid.md:8:broken_link",
+		"Projects/unclosed.md:1:unclosed_frontmatter",
+		"alpha.md:2:duplicate_title",
+		"alpha.md:8:skipped_heading_level",
+		"alpha.md:10:unfilled_placeholder",
+		"index.md:3:broken_link",
+	}
+	if strings.Join(lintIssues(report), "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected issues:\n%s", strings.Join(lintIssues(report), "\n"))
+	}
+	if report.Checked != 5 || report.Errors != 4 || report.Warnings != 5 {
+		t.Errorf("Unexpected counts: checked %d, errors %d, warnings %d", report.Checked, report.Errors, report.Warnings)
+	}
+
+	for _, issue := range report.Issues {
+		switch issue.Rule {
+		case lintBrokenLink:
+			if issue.File == "index.md" && issue.Message != "Link target not found: missing" {
+				t.Errorf("Unexpected message: %s", issue.Message)
+			}
+		case lintDuplicateTitle:
+			if issue.File == "alpha.md" && issue.Message != `Title "Alpha" is also used by Projects/beta.md` {
+				t.Errorf("Unexpected message: %s", issue.Message)
+			}
+		case lintPlaceholder:
+			if issue.Message != "Unfilled placeholder {{ATTENDEES}}" {
+				t.Errorf("Unexpected message: %s", issue.Message)
+			}
+		}
+	}
+}
+
+func TestFindPlaceholders_SkipsFencedCode(t *testing.T) {
+	placeholders := findPlaceholders("{{BEFORE}}\n```\n```go\n{{INSIDE}}\n```\n````\n```\n{{NESTED}}\n```\n````\n{{AFTER}}\n")
+
+	if len(placeholders) != 2 || placeholders[0].text != "{{BEFORE}}" || placeholders[1].text != "{{AFTER}}" || placeholders[1].line != 11 {
+		t.Errorf("Unexpected placeholders: %+v", placeholders)
+	}
+}
+
+func TestLint_FolderConfig(t *testing.T) {
+	tempDir := createVaultForTest(t, lintVaultNotes)
+	writeNoteForTest(t, tempDir, ".sibyl/lint.yaml", `rules:
+  duplicate_title: off
+required_fields:
+  meeting: [date]
+folders:
+  Projects:
+    rules:
+      skipped_heading_level: error
+      broken_link: warning
+    required_fields:
+      "*": [status]
+`)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	report, err := ns.Lint("Projects")
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+
+	expected := []string{
+		"Projects/beta.md:3:missing_field:error",
+		"Projects/beta.md:3:missing_field:error",
+		"Projects/beta.md:9:skipped_heading_level:error",
+		"Projects/invalid.md:4:invalid_frontmatter:error",
+		"Projects/invalid.md:8:broken_link:warning",
+		"Projects/unclosed.md:1:unclosed_frontmatter:error",
+	}
+	var issues []string
+	for _, issue := range report.Issues {
+		issues = append(issues, issue.File+":"+strconv.Itoa(issue.Line)+":"+issue.Rule+":"+issue.Severity)
+	}
+	if strings.Join(issues, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected issues:\n%s", strings.Join(issues, "\n"))
+	}
+	if report.Checked != 3 {
+		t.Errorf("Expected only the notes under Projects to be checked, got %d", report.Checked)
+	}
+
+	var messages []string
+	for _, issue := range report.Issues {
+		if issue.Rule == lintMissingField {
+			messages = append(messages, issue.Message)
+		}
+	}
+	if strings.Join(messages, "|") != `Missing required frontmatter field "status" for type meeting|Missing required frontmatter field "date" for type meeting` {
+		t.Errorf("Unexpected messages: %v", messages)
+	}
+}
+
+func TestLint_InvalidConfig(t *testing.T) {
+	for _, config := range []string{
+		"rules:\n  unknown_rule: error\n",
+		"rules:\n  broken_link: loud\n",
+		"rules: [broken\n",
+	} {
+		tempDir := createVaultForTest(t, lintVaultNotes)
+		writeNoteForTest(t, tempDir, ".sibyl/lint.yaml", config)
+		ns := &NotesServer{vaultDir: tempDir}
+
+		result, err := ns.LintVault(context.Background(), mcp.CallToolRequest{}, LintVaultRequest{})
+		if err != nil || !result.IsError {
+			t.Errorf("Expected an error for config %q, got %v %v", config, result, err)
+		}
+	}
+
+	ns := &NotesServer{vaultDir: createVaultForTest(t, lintVaultNotes), lintConfigPath: filepath.Join(t.TempDir(), "missing.yaml")}
+	if _, err := ns.Lint(""); err == nil {
+		t.Error("Expected an error for a missing configured lint file")
+	}
+}
+
+func TestLintVault(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, lintVaultNotes)}
+
+	var report LintReport
+	result, err := ns.LintVault(context.Background(), mcp.CallToolRequest{}, LintVaultRequest{Path: "alpha.md"})
+	decodeToolResult(t, result, err, &report)
+	if report.Checked != 1 || len(report.Issues) != 3 || report.Issues[0].File != "alpha.md" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	for _, path := range []string{"../outside", "missing.md"} {
+		result, err := ns.LintVault(context.Background(), mcp.CallToolRequest{}, LintVaultRequest{Path: path})
+		if err != nil || !result.IsError {
+			t.Errorf("Expected an error for %s, got %v %v", path, result, err)
+		}
+	}
+}
+
+func TestExplicitTitle(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		title   string
+		line    int
+	}{
+		{"Frontmatter title", "---\ntype: note\ntitle: Plan\n---\n# Heading\n", "Plan", 3},
+		{"Quoted key after a nested one", "---\nmeta:\n  title: inner\n\"title\": Quoted\n---\ntitle: body\n", "Quoted", 4},
+		{"Body line is not the title", "# Heading\n\ntitle: body\n", "Heading", 1},
+		{"Neither", "text\n", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, line := explicitTitle(tt.content)
+			if title != tt.title || line != tt.line {
+				t.Errorf("Expected %q on line %d, got %q on line %d", tt.title, tt.line, title, line)
+			}
+		})
+	}
+}
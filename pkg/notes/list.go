@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io/fs"
 	"path/filepath"
-	"strings"
 
 	"github.com/KyleBrandon/sibyl/pkg/dto"
 	"github.com/KyleBrandon/sibyl/pkg/utils"
@@ -54,16 +53,13 @@ func (ns *NotesServer) ListNotes(ctx context.Context, req mcp.CallToolRequest, p
 				return err
 			}
 
+			if info.IsDir() && path != fullPath && isHiddenDir(info.Name()) {
+				return filepath.SkipDir
+			}
+
 			// Only include markdown files for notes
-			if !info.IsDir() && (strings.HasSuffix(strings.ToLower(info.Name()), ".md") || strings.HasSuffix(strings.ToLower(info.Name()), ".markdown")) {
-				relativePath, _ := filepath.Rel(ns.vaultDir, path)
-				notes = append(notes, dto.NoteMetadata{
-					Name:     info.Name(),
-					Path:     relativePath,
-					Size:     info.Size(),
-					Modified: info.ModTime(),
-					IsDir:    false,
-				})
+			if !info.IsDir() && isMarkdownFile(info.Name()) {
+				notes = append(notes, ns.noteMetadata(path, info))
 			}
 			return nil
 		})
@@ -80,16 +76,8 @@ func (ns *NotesServer) ListNotes(ctx context.Context, req mcp.CallToolRequest, p
 			}
 
 			// Only include markdown files for notes
-			if !info.IsDir() && (strings.HasSuffix(strings.ToLower(info.Name()), ".md") || strings.HasSuffix(strings.ToLower(info.Name()), ".markdown")) {
-				entryPath := filepath.Join(fullPath, info.Name())
-				relativePath, _ := filepath.Rel(ns.vaultDir, entryPath)
-				notes = append(notes, dto.NoteMetadata{
-					Name:     info.Name(),
-					Path:     relativePath,
-					Size:     info.Size(),
-					Modified: info.ModTime(),
-					IsDir:    false,
-				})
+			if !info.IsDir() && isMarkdownFile(info.Name()) {
+				notes = append(notes, ns.noteMetadata(filepath.Join(fullPath, info.Name()), info))
 			}
 		}
 	}
@@ -110,6 +98,27 @@ func (ns *NotesServer) ListNotes(ctx context.Context, req mcp.CallToolRequest, p
 	}, nil
 }
 
+// noteMetadata builds the listing entry for a note, including its frontmatter
+func (ns *NotesServer) noteMetadata(path string, info fs.FileInfo) dto.NoteMetadata {
+	relativePath, _ := filepath.Rel(ns.vaultDir, path)
+	metadata := dto.NoteMetadata{
+		Name:     info.Name(),
+		Path:     relativePath,
+		Size:     info.Size(),
+		Modified: info.ModTime(),
+		IsDir:    false,
+	}
+
+	if content, err := utils.ReadFile(path); err == nil {
+		// Invalid frontmatter is reported by query_frontmatter, not the listing
+		if fm, err := parseFrontmatter(string(content)); err == nil && len(fm.Fields) > 0 {
+			metadata.Frontmatter = fm.Fields
+		}
+	}
+
+	return metadata
+}
+
 func (ns *NotesServer) NewListFoldersTool() {
 	tool := mcp.NewTool(
 		"list_folders",
@@ -139,6 +148,10 @@ func (ns *NotesServer) ListFolders(ctx context.Context, req mcp.CallToolRequest,
 				return err
 			}
 
+			if info.IsDir() && path != fullPath && isHiddenDir(info.Name()) {
+				return filepath.SkipDir
+			}
+
 			if info.IsDir() && path != ns.vaultDir {
 				relativePath, _ := filepath.Rel(ns.vaultDir, path)
 				folders = append(folders, dto.NoteMetadata{
@@ -158,7 +171,7 @@ func (ns *NotesServer) ListFolders(ctx context.Context, req mcp.CallToolRequest,
 		}
 
 		for _, entry := range entries {
-			if entry.IsDir() {
+			if entry.IsDir() && !isHiddenDir(entry.Name()) {
 				info, err := entry.Info()
 				if err != nil {
 					continue
@@ -0,0 +1,65 @@
+package notes
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// pathLock is a mutex shared by every caller working on the same path
+type pathLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lockPaths serializes read-modify-write operations on the given files. Paths
+// are locked in sorted order so tools locking several notes cannot deadlock.
+// The returned function releases every lock.
+func (ns *NotesServer) lockPaths(paths ...string) func() {
+	unique := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		unique[filepath.Clean(path)] = true
+	}
+
+	keys := make([]string, 0, len(unique))
+	for path := range unique {
+		keys = append(keys, path)
+	}
+	sort.Strings(keys)
+
+	locks := make([]*pathLock, len(keys))
+	ns.locksMu.Lock()
+	if ns.locks == nil {
+		ns.locks = make(map[string]*pathLock)
+	}
+	for i, key := range keys {
+		lock, ok := ns.locks[key]
+		if !ok {
+			lock = &pathLock{}
+			ns.locks[key] = lock
+		}
+		lock.refs++
+		locks[i] = lock
+	}
+	ns.locksMu.Unlock()
+
+	for _, lock := range locks {
+		lock.mu.Lock()
+	}
+
+	return func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].mu.Unlock()
+		}
+
+		// Drop locks nobody is waiting on so the map does not grow with every note
+		ns.locksMu.Lock()
+		for i, key := range keys {
+			locks[i].refs--
+			if locks[i].refs == 0 {
+				delete(ns.locks, key)
+			}
+		}
+		ns.locksMu.Unlock()
+	}
+}
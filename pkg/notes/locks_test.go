@@ -0,0 +1,104 @@
+package notes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestConcurrentMerges_NoLostUpdates(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	writeNoteForTest(t, tempDir, "log.md", "# Log")
+
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			params := MergeNoteRequest{Path: "log.md", Content: fmt.Sprintf("entry %d", i), Strategy: MergeAppend}
+			if result, err := ns.MergeNote(ctx, mcp.CallToolRequest{}, params); err != nil || result.IsError {
+				t.Errorf("MergeNote failed: %v %v", err, result)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	content := readForTest(t, tempDir, "log.md")
+	for i := 0; i < writers; i++ {
+		if !strings.Contains(content, fmt.Sprintf("entry %d\n", i)) && !strings.HasSuffix(content, fmt.Sprintf("entry %d", i)) {
+			t.Errorf("Lost update for entry %d", i)
+		}
+	}
+
+	if len(ns.locks) != 0 {
+		t.Errorf("Expected idle locks to be released, %d remain", len(ns.locks))
+	}
+}
+
+func TestWriteNote_KeepsPermissionsAndLeavesNoTempFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	notePath := filepath.Join(tempDir, "private.md")
+
+	if err := os.WriteFile(notePath, []byte("secret"), 0600); err != nil {
+		t.Fatalf("Failed to create test note: %v", err)
+	}
+	if err := os.Chmod(notePath, 0600); err != nil {
+		t.Fatalf("Failed to chmod test note: %v", err)
+	}
+
+	if _, err := ns.WriteNote(context.Background(), mcp.CallToolRequest{}, WriteNoteRequest{Path: "private.md", Content: "updated"}); err != nil {
+		t.Fatalf("WriteNote failed: %v", err)
+	}
+
+	info, err := os.Stat(notePath)
+	if err != nil {
+		t.Fatalf("Failed to stat note: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600 to be kept, got %o", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(tempDir)
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("Temporary file left behind: %s", entry.Name())
+		}
+	}
+}
+
+func TestLockPaths_SerializesSamePath(t *testing.T) {
+	ns := &NotesServer{}
+
+	unlock := ns.lockPaths("/vault/a.md", "/vault/b.md", "/vault/a.md")
+
+	acquired := make(chan struct{})
+	go func() {
+		release := ns.lockPaths("/vault/b.md")
+		close(acquired)
+		release()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Lock on b.md should wait for the holder")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Other paths are not blocked
+	ns.lockPaths("/vault/c.md")()
+
+	unlock()
+	<-acquired
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -31,6 +32,9 @@ type MergeNoteRequest struct {
 	Content  string        `json:"content" mcp:"Content to merge"`
 	Strategy MergeStrategy `json:"strategy,omitempty" mcp:"Merge strategy: append, prepend, date_section, topic_merge, replace"`
 	Title    string        `json:"title,omitempty" mcp:"Title for the new section (used with date_section)"`
+
+	ExpectedHash string `json:"expected_hash,omitempty" mcp:"Hash from read_note; the merge is refused if the note changed since"`
+	DryRun       bool   `json:"dry_run,omitempty" mcp:"Return the diff of the change without writing it"`
 }
 
 // PreviewMergeRequest represents a request to preview a merge operation
@@ -38,25 +42,45 @@ type PreviewMergeRequest struct {
 	Path     string        `json:"path" mcp:"Path to the note file"`
 	Content  string        `json:"content" mcp:"Content to merge"`
 	Strategy MergeStrategy `json:"strategy,omitempty" mcp:"Merge strategy to preview"`
+
+	IncludeContent bool `json:"include_content,omitempty" mcp:"Also return the full merged content"`
+}
+
+// Actions reported for the sections touched by a topic merge
+const (
+	SectionMerged   = "merged"
+	SectionCreated  = "created"
+	SectionAppended = "appended"
+)
+
+// MergeSection describes a section touched by a topic merge
+type MergeSection struct {
+	Heading string `json:"heading,omitempty"`
+	Action  string `json:"action"`
+	Skipped int    `json:"skipped,omitempty"`
 }
 
 // MergeResult represents the result of a merge operation
 type MergeResult struct {
-	Success      bool   `json:"success"`
-	Path         string `json:"path"`
-	Strategy     string `json:"strategy"`
-	BytesWritten int    `json:"bytes_written"`
-	Message      string `json:"message"`
+	Success      bool           `json:"success"`
+	Path         string         `json:"path"`
+	Strategy     string         `json:"strategy"`
+	BytesWritten int            `json:"bytes_written"`
+	Hash         string         `json:"hash"`
+	Sections     []MergeSection `json:"sections,omitempty"`
+	Message      string         `json:"message"`
 }
 
 // MergePreview represents a preview of what a merge would look like
 type MergePreview struct {
-	Path           string `json:"path"`
-	Strategy       string `json:"strategy"`
-	PreviewContent string `json:"preview_content"`
-	OriginalLength int    `json:"original_length"`
-	NewLength      int    `json:"new_length"`
-	WouldOverwrite bool   `json:"would_overwrite"`
+	Path           string          `json:"path"`
+	Strategy       string          `json:"strategy"`
+	Diff           string          `json:"diff"`
+	Sections       []SectionChange `json:"sections,omitempty"`
+	PreviewContent string          `json:"preview_content,omitempty"`
+	OriginalLength int             `json:"original_length"`
+	NewLength      int             `json:"new_length"`
+	WouldOverwrite bool            `json:"would_overwrite"`
 }
 
 func (ns *NotesServer) NewMergeNoteTool() {
@@ -67,6 +91,8 @@ func (ns *NotesServer) NewMergeNoteTool() {
 		mcp.WithString("content", mcp.Description("Content to merge"), mcp.Required()),
 		mcp.WithString("strategy", mcp.Description("Merge strategy: append, prepend, date_section, topic_merge, replace")),
 		mcp.WithString("title", mcp.Description("Title for the new section (used with date_section)")),
+		mcp.WithString("expected_hash", mcp.Description("Hash from read_note; the merge is refused if the note changed since")),
+		mcp.WithBoolean("dry_run", mcp.Description("Return the diff of the change without writing it")),
 	)
 
 	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.MergeNote))
@@ -79,6 +105,7 @@ func (ns *NotesServer) NewPreviewMergeTool() {
 		mcp.WithString("path", mcp.Description("Path to the note file"), mcp.Required()),
 		mcp.WithString("content", mcp.Description("Content to merge"), mcp.Required()),
 		mcp.WithString("strategy", mcp.Description("Merge strategy to preview")),
+		mcp.WithBoolean("include_content", mcp.Description("Also return the full merged content")),
 	)
 
 	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.PreviewMerge))
@@ -101,8 +128,15 @@ func (ns *NotesServer) MergeNote(ctx context.Context, req mcp.CallToolRequest, p
 		}, nil
 	}
 
+	// A dry run only reads the note
+	if !params.DryRun {
+		unlock := ns.lockPaths(fullPath)
+		defer unlock()
+	}
+
 	// Read existing content if file exists
-	var existingContent string
+	var existingContent, existingHash string
+	fileExists := false
 	if _, err := os.Stat(fullPath); err == nil {
 		content, err := utils.ReadFile(fullPath)
 		if err != nil {
@@ -114,10 +148,17 @@ func (ns *NotesServer) MergeNote(ctx context.Context, req mcp.CallToolRequest, p
 			}, nil
 		}
 		existingContent = string(content)
+		existingHash = contentHash(content)
+		fileExists = true
+	}
+
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	if params.ExpectedHash != "" && params.ExpectedHash != existingHash {
+		return conflictResult(&ConflictError{Path: relativePath, ExpectedHash: params.ExpectedHash, CurrentHash: existingHash}), nil
 	}
 
 	// Perform merge based on strategy
-	mergedContent, err := ns.performMerge(existingContent, params.Content, params.Strategy, params.Title)
+	mergedContent, sections, err := ns.performMerge(existingContent, params.Content, params.Strategy, params.Title)
 	if err != nil {
 		return &mcp.CallToolResult{
 			IsError: true,
@@ -127,6 +168,12 @@ func (ns *NotesServer) MergeNote(ctx context.Context, req mcp.CallToolRequest, p
 		}, nil
 	}
 
+	mergedContent = ns.formatOnWriteContent(fullPath, mergedContent)
+
+	if params.DryRun {
+		return changePreviewResult(newChangePreview(filepath.ToSlash(relativePath), []byte(existingContent), fileExists, mergedContent)), nil
+	}
+
 	// Ensure directory exists
 	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 		return &mcp.CallToolResult{
@@ -137,6 +184,16 @@ func (ns *NotesServer) MergeNote(ctx context.Context, req mcp.CallToolRequest, p
 		}, nil
 	}
 
+	// Save the previous content before overwriting it
+	if err := ns.snapshotNote(fullPath, "merge_note"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				mcp.NewTextContent(fmt.Sprintf("Failed to save version history: %v", err)),
+			},
+		}, nil
+	}
+
 	// Write merged content
 	if err := utils.WriteFile(fullPath, []byte(mergedContent), 0644); err != nil {
 		return &mcp.CallToolResult{
@@ -152,6 +209,8 @@ func (ns *NotesServer) MergeNote(ctx context.Context, req mcp.CallToolRequest, p
 		Path:         params.Path,
 		Strategy:     string(params.Strategy),
 		BytesWritten: len(mergedContent),
+		Hash:         contentHash([]byte(mergedContent)),
+		Sections:     sections,
 		Message:      fmt.Sprintf("Successfully merged content using %s strategy", params.Strategy),
 	}
 
@@ -198,7 +257,7 @@ func (ns *NotesServer) PreviewMerge(ctx context.Context, req mcp.CallToolRequest
 	}
 
 	// Perform merge preview
-	mergedContent, err := ns.performMerge(existingContent, params.Content, params.Strategy, "")
+	mergedContent, _, err := ns.performMerge(existingContent, params.Content, params.Strategy, "")
 	if err != nil {
 		return &mcp.CallToolResult{
 			IsError: true,
@@ -211,11 +270,15 @@ func (ns *NotesServer) PreviewMerge(ctx context.Context, req mcp.CallToolRequest
 	preview := MergePreview{
 		Path:           params.Path,
 		Strategy:       string(params.Strategy),
-		PreviewContent: mergedContent,
+		Diff:           numberedDiff("a/"+params.Path, "b/"+params.Path, existingContent, mergedContent),
+		Sections:       sectionChanges(existingContent, mergedContent),
 		OriginalLength: len(existingContent),
 		NewLength:      len(mergedContent),
 		WouldOverwrite: fileExists && params.Strategy == MergeReplace,
 	}
+	if params.IncludeContent {
+		preview.PreviewContent = mergedContent
+	}
 
 	previewJSON, _ := json.MarshalIndent(preview, "", "  ")
 
@@ -226,19 +289,21 @@ func (ns *NotesServer) PreviewMerge(ctx context.Context, req mcp.CallToolRequest
 	}, nil
 }
 
-func (ns *NotesServer) performMerge(existing, newContent string, strategy MergeStrategy, title string) (string, error) {
+// performMerge combines existing and new content using strategy. The
+// sections touched are reported for the topic_merge strategy.
+func (ns *NotesServer) performMerge(existing, newContent string, strategy MergeStrategy, title string) (string, []MergeSection, error) {
 	switch strategy {
 	case MergeAppend:
 		if existing == "" {
-			return newContent, nil
+			return newContent, nil, nil
 		}
-		return existing + "\n\n" + newContent, nil
+		return existing + "\n\n" + newContent, nil, nil
 
 	case MergePrepend:
 		if existing == "" {
-			return newContent, nil
+			return newContent, nil, nil
 		}
-		return newContent + "\n\n" + existing, nil
+		return newContent + "\n\n" + existing, nil, nil
 
 	case MergeDateSection:
 		sectionTitle := title
@@ -250,32 +315,220 @@ func (ns *NotesServer) performMerge(existing, newContent string, strategy MergeS
 		sectionContent := dateHeader + newContent
 
 		if existing == "" {
-			return sectionContent, nil
+			return sectionContent, nil, nil
 		}
-		return existing + "\n\n---\n\n" + sectionContent, nil
+		return existing + "\n\n---\n\n" + sectionContent, nil, nil
 
 	case MergeTopicMerge:
-		// Simple topic-based merging - could be enhanced with more sophisticated logic
-		if existing == "" {
-			return newContent, nil
+		merged, sections := topicMerge(existing, newContent)
+		return merged, sections, nil
+
+	case MergeReplace:
+		return newContent, nil, nil
+
+	default:
+		return "", nil, fmt.Errorf("unknown merge strategy: %s", strategy)
+	}
+}
+
+// listItemRegex matches a bullet, numbered or task list item and captures its text
+var listItemRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?(.*)$`)
+
+// topicMerge merges content into existing section by section. Sections of
+// the new content whose heading already exists in the note are merged in
+// place, skipping list items and paragraphs that are already present;
+// missing sections are created under the matching parent heading, or at the
+// end of the note. Content before the first heading is appended to the note.
+func topicMerge(existing, content string) (string, []MergeSection) {
+	if strings.TrimSpace(existing) == "" {
+		var sections []MergeSection
+		incoming := parseHeadings(content)
+		for _, h := range incoming {
+			if h.parent < 0 {
+				sections = append(sections, MergeSection{Heading: h.text, Action: SectionCreated})
+			}
 		}
+		return content, sections
+	}
+
+	m := &topicMerger{
+		existing:         existing,
+		existingHeadings: parseHeadings(existing),
+		content:          content,
+		incoming:         parseHeadings(content),
+	}
+
+	preambleEnd := len(content)
+	if len(m.incoming) > 0 {
+		preambleEnd = m.incoming[0].start
+	}
+	if blocks, skipped := m.newBlocks(content[:preambleEnd], existing); len(blocks) > 0 {
+		m.insertBlocks(len(trimTrailingBlankLines(existing)), blocks)
+		m.sections = append(m.sections, MergeSection{Action: SectionAppended, Skipped: skipped})
+	}
 
-		// Add as a new section with topic detection
-		topicHeader := "## New Content\n\n"
-		if strings.Contains(strings.ToLower(newContent), "meeting") {
-			topicHeader = "## Meeting Notes\n\n"
-		} else if strings.Contains(strings.ToLower(newContent), "research") {
-			topicHeader = "## Research Notes\n\n"
-		} else if strings.Contains(strings.ToLower(newContent), "todo") || strings.Contains(strings.ToLower(newContent), "task") {
-			topicHeader = "## Tasks\n\n"
+	for i, h := range m.incoming {
+		if h.parent < 0 {
+			m.mergeSection(i, -1)
 		}
+	}
 
-		return existing + "\n\n---\n\n" + topicHeader + newContent, nil
+	return applyTextEdits(existing, m.edits), m.sections
+}
 
-	case MergeReplace:
-		return newContent, nil
+// topicMerger collects the insertions made by a topic merge
+type topicMerger struct {
+	existing         string
+	existingHeadings []noteHeading
+	content          string
+	incoming         []noteHeading
+	edits            []textEdit
+	sections         []MergeSection
+}
 
-	default:
-		return "", fmt.Errorf("unknown merge strategy: %s", strategy)
+// mergeSection merges the incoming section at index into the existing section
+// with the same heading under parent (anywhere in the note when parent is -1)
+func (m *topicMerger) mergeSection(index, parent int) {
+	h := m.incoming[index]
+	match := m.findHeading(h, parent)
+
+	if match < 0 {
+		at := len(trimTrailingBlankLines(m.existing))
+		heading := h.text
+		if parent >= 0 {
+			p := m.existingHeadings[parent]
+			at = p.start + len(trimTrailingBlankLines(m.existing[p.start:p.end]))
+			heading = headingPath(m.existingHeadings, parent) + " " + headingPathSeparator + " " + h.text
+		}
+		section := strings.TrimRight(trimTrailingBlankLines(m.content[h.start:h.end]), "\n")
+		m.insertBlocks(at, []string{section})
+		m.sections = append(m.sections, MergeSection{Heading: heading, Action: SectionCreated})
+		return
+	}
+
+	e := m.existingHeadings[match]
+	ownEnd := ownBodyEnd(m.existingHeadings, match)
+	blocks, skipped := m.newBlocks(m.content[h.bodyStart:ownBodyEnd(m.incoming, index)], m.existing[e.bodyStart:ownEnd])
+	if len(blocks) > 0 {
+		m.insertBlocks(e.bodyStart+len(trimTrailingBlankLines(m.existing[e.bodyStart:ownEnd])), blocks)
+		m.sections = append(m.sections, MergeSection{Heading: headingPath(m.existingHeadings, match), Action: SectionMerged, Skipped: skipped})
+	}
+
+	for i := index + 1; i < len(m.incoming) && m.incoming[i].start < h.end; i++ {
+		if m.incoming[i].parent == index {
+			m.mergeSection(i, match)
+		}
+	}
+}
+
+// findHeading returns the existing heading matching an incoming one,
+// preferring direct children of parent and headings of the same level
+func (m *topicMerger) findHeading(h noteHeading, parent int) int {
+	best, bestScore := -1, -1
+	for i, e := range m.existingHeadings {
+		if !strings.EqualFold(e.text, h.text) {
+			continue
+		}
+		if parent >= 0 && (i <= parent || e.start >= m.existingHeadings[parent].end) {
+			continue
+		}
+
+		score := 0
+		if parent < 0 || e.parent == parent {
+			score += 2
+		}
+		if e.level == h.level {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// newBlocks splits body into blank-line separated blocks and drops the list
+// items and paragraphs that already appear in existing
+func (m *topicMerger) newBlocks(body, existing string) ([]string, int) {
+	present := make(map[string]bool)
+	for _, line := range splitLines(existing) {
+		if item := listItemRegex.FindStringSubmatch(line); item != nil {
+			present[normalizeListItem(item[1])] = true
+		}
+	}
+
+	var blocks []string
+	skipped := 0
+	for _, block := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n\n") {
+		block = strings.Trim(block, "\n")
+		if strings.TrimSpace(block) == "" {
+			continue
+		}
+
+		if !listItemRegex.MatchString(strings.SplitN(block, "\n", 2)[0]) {
+			if strings.Contains(existing, strings.TrimSpace(block)) {
+				skipped++
+				continue
+			}
+			blocks = append(blocks, block)
+			continue
+		}
+
+		// Keep the items that are new, with their continuation lines
+		var kept []string
+		keep := false
+		for _, line := range strings.Split(block, "\n") {
+			if item := listItemRegex.FindStringSubmatch(line); item != nil {
+				key := normalizeListItem(item[1])
+				keep = !present[key]
+				if !keep {
+					skipped++
+				}
+				present[key] = true
+			}
+			if keep {
+				kept = append(kept, line)
+			}
+		}
+		if len(kept) > 0 {
+			blocks = append(blocks, strings.Join(kept, "\n"))
+		}
 	}
+
+	return blocks, skipped
+}
+
+// insertBlocks inserts blocks at an offset of the existing note, continuing
+// a list directly and separating anything else with a blank line
+func (m *topicMerger) insertBlocks(at int, blocks []string) {
+	before := m.existing[:at]
+
+	// Blocks inserted at the same offset follow each other with one blank line
+	if last := len(m.edits) - 1; last >= 0 && m.edits[last].start == at {
+		m.edits[last].text = strings.TrimRight(m.edits[last].text, "\n") + "\n"
+		before += m.edits[last].text
+	}
+
+	separator := "\n"
+	if lines := splitLines(before); len(lines) > 0 && listItemRegex.MatchString(lines[len(lines)-1]) && listItemRegex.MatchString(blocks[0]) {
+		separator = ""
+	}
+	if strings.TrimSpace(before) == "" {
+		separator = ""
+	}
+	if before != "" && !strings.HasSuffix(before, "\n") {
+		separator = "\n" + separator
+	}
+
+	text := separator + strings.Join(blocks, "\n\n") + "\n"
+	if rest := m.existing[at:]; rest != "" && !strings.HasPrefix(strings.TrimLeft(rest, " \t"), "\n") {
+		text += "\n"
+	}
+
+	m.edits = append(m.edits, textEdit{start: at, end: at, text: text})
+}
+
+// normalizeListItem returns the comparison key of a list item's text
+func normalizeListItem(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
 }
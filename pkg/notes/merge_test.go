@@ -471,3 +471,128 @@ func BenchmarkLargeMerge(b *testing.B) {
 		performMerge(existingContent, newContent, "append", "")
 	}
 }
+
+func TestTopicMerge_MergesMatchingSections(t *testing.T) {
+	existing := `# Project X
+
+Intro.
+
+## Tasks
+
+- [ ] Write spec
+- [x] Review design
+
+## Notes
+
+Kickoff went well.
+`
+
+	incoming := `## Tasks
+
+- [ ] Write spec
+- [ ] Ship beta
+
+## Notes
+
+Kickoff went well.
+
+## Risks
+
+- Timeline
+`
+
+	merged, sections := topicMerge(existing, incoming)
+
+	expected := `# Project X
+
+Intro.
+
+## Tasks
+
+- [ ] Write spec
+- [x] Review design
+- [ ] Ship beta
+
+## Notes
+
+Kickoff went well.
+
+## Risks
+
+- Timeline
+`
+	if merged != expected {
+		t.Errorf("Unexpected merge result:\n%s", merged)
+	}
+
+	expectedSections := []MergeSection{
+		{Heading: "Project X > Tasks", Action: SectionMerged, Skipped: 1},
+		{Heading: "Risks", Action: SectionCreated},
+	}
+	if len(sections) != len(expectedSections) {
+		t.Fatalf("Expected sections %+v, got %+v", expectedSections, sections)
+	}
+	for i := range sections {
+		if sections[i] != expectedSections[i] {
+			t.Errorf("Expected section %+v, got %+v", expectedSections[i], sections[i])
+		}
+	}
+}
+
+func TestTopicMerge_NestedSections(t *testing.T) {
+	existing := "# Project X\n\n## Tasks\n\n- a\n\n# Project Y\n\n## Tasks\n\n- b\n"
+	incoming := "# Project Y\n\n## Tasks\n\n- c\n\n## Decisions\n\nUse Go.\n"
+
+	merged, sections := topicMerge(existing, incoming)
+
+	expected := "# Project X\n\n## Tasks\n\n- a\n\n# Project Y\n\n## Tasks\n\n- b\n- c\n\n## Decisions\n\nUse Go.\n"
+	if merged != expected {
+		t.Errorf("Expected %q, got %q", expected, merged)
+	}
+	if len(sections) != 2 || sections[0].Heading != "Project Y > Tasks" || sections[1].Heading != "Project Y > Decisions" {
+		t.Errorf("Unexpected sections: %+v", sections)
+	}
+}
+
+func TestTopicMerge_ContentWithoutHeadings(t *testing.T) {
+	existing := "# Log\n\n- first\n"
+
+	merged, sections := topicMerge(existing, "- first\n- second")
+	if merged != "# Log\n\n- first\n- second\n" {
+		t.Errorf("Unexpected merge result: %q", merged)
+	}
+	if len(sections) != 1 || sections[0].Action != SectionAppended || sections[0].Skipped != 1 {
+		t.Errorf("Unexpected sections: %+v", sections)
+	}
+
+	// Nothing new leaves the note untouched
+	if merged, sections := topicMerge(existing, "- first"); merged != existing || len(sections) != 0 {
+		t.Errorf("Duplicate content should not change the note, got %q %+v", merged, sections)
+	}
+}
+
+func TestMergeNote_TopicMergeReportsSections(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "project.md"), []byte("# Project\n\n## Tasks\n\n- a\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test note: %v", err)
+	}
+
+	var result MergeResult
+	toolResult, err := ns.MergeNote(context.Background(), mcp.CallToolRequest{}, MergeNoteRequest{
+		Path:     "project.md",
+		Content:  "## Tasks\n\n- b\n\n## Notes\n\nSomething.",
+		Strategy: MergeTopicMerge,
+	})
+	decodeToolResult(t, toolResult, err, &result)
+
+	if len(result.Sections) != 2 || result.Sections[0].Action != SectionMerged || result.Sections[1].Action != SectionCreated {
+		t.Errorf("Unexpected sections: %+v", result.Sections)
+	}
+
+	expected := "# Project\n\n## Tasks\n\n- a\n- b\n\n## Notes\n\nSomething.\n"
+	if got := readForTest(t, tempDir, "project.md"); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
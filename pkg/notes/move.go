@@ -0,0 +1,375 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MoveNoteRequest represents a request to move a note or folder
+type MoveNoteRequest struct {
+	Source      string `json:"source" mcp:"Path of the note or folder to move"`
+	Destination string `json:"destination" mcp:"New path, or an existing folder to move into"`
+}
+
+// RenameNoteRequest represents a request to rename a note or folder in place
+type RenameNoteRequest struct {
+	Path    string `json:"path" mcp:"Path of the note or folder to rename"`
+	NewName string `json:"new_name" mcp:"New file or folder name"`
+}
+
+// MoveResult reports what a move changed in the vault
+type MoveResult struct {
+	Source       string        `json:"source"`
+	Destination  string        `json:"destination"`
+	MovedFiles   []MovedFile   `json:"moved_files"`
+	UpdatedFiles []UpdatedFile `json:"updated_files"`
+}
+
+// MovedFile is a file that was relocated by a move
+type MovedFile struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// UpdatedFile is a note whose links were rewritten by a move
+type UpdatedFile struct {
+	Path  string `json:"path"`
+	Links int    `json:"links"`
+}
+
+func (ns *NotesServer) NewMoveNoteTool() {
+	tool := mcp.NewTool(
+		"move_note",
+		mcp.WithDescription("Move a note or folder to a new path and update every wikilink and relative link that points at it"),
+		mcp.WithString("source", mcp.Description("Path of the note or folder to move"), mcp.Required()),
+		mcp.WithString("destination", mcp.Description("New path, or an existing folder to move into"), mcp.Required()),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.MoveNote))
+}
+
+func (ns *NotesServer) NewRenameNoteTool() {
+	tool := mcp.NewTool(
+		"rename_note",
+		mcp.WithDescription("Rename a note or folder in place and update every wikilink and relative link that points at it"),
+		mcp.WithString("path", mcp.Description("Path of the note or folder to rename"), mcp.Required()),
+		mcp.WithString("new_name", mcp.Description("New file or folder name; a note keeps its extension when none is given"), mcp.Required()),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.RenameNote))
+}
+
+// MoveNote moves a note or folder and rewrites the links pointing at it
+func (ns *NotesServer) MoveNote(ctx context.Context, req mcp.CallToolRequest, params MoveNoteRequest) (*mcp.CallToolResult, error) {
+	sourcePath, err := utils.ValidatePath(ns.vaultDir, params.Source)
+	if err != nil {
+		return toolErrorResult("Invalid source path: %v", err), nil
+	}
+
+	destinationPath, err := utils.ValidatePath(ns.vaultDir, params.Destination)
+	if err != nil {
+		return toolErrorResult("Invalid destination path: %v", err), nil
+	}
+
+	// Moving onto an existing folder moves the source into it
+	if info, err := utils.Stat(destinationPath); err == nil && info.IsDir() {
+		destinationPath = filepath.Join(destinationPath, filepath.Base(sourcePath))
+	}
+
+	return ns.moveNote(sourcePath, destinationPath)
+}
+
+// RenameNote renames a note or folder without changing its parent folder
+func (ns *NotesServer) RenameNote(ctx context.Context, req mcp.CallToolRequest, params RenameNoteRequest) (*mcp.CallToolResult, error) {
+	sourcePath, err := utils.ValidatePath(ns.vaultDir, params.Path)
+	if err != nil {
+		return toolErrorResult("Invalid path: %v", err), nil
+	}
+
+	newName := strings.TrimSpace(params.NewName)
+	if newName == "" || newName == "." || newName == ".." || strings.ContainsAny(newName, `/\`) {
+		return toolErrorResult("Invalid name %q: use move_note to change folders", params.NewName), nil
+	}
+
+	// Notes keep their extension when the new name leaves it out
+	if info, err := utils.Stat(sourcePath); err == nil && !info.IsDir() && filepath.Ext(newName) == "" {
+		newName += filepath.Ext(sourcePath)
+	}
+
+	return ns.moveNote(sourcePath, filepath.Join(filepath.Dir(sourcePath), newName))
+}
+
+// moveNote relocates a file or folder between two validated absolute paths
+// and rewrites every link in the vault whose target moved with it
+func (ns *NotesServer) moveNote(sourcePath, destinationPath string) (*mcp.CallToolResult, error) {
+	source, _ := filepath.Rel(ns.vaultDir, sourcePath)
+	destination, _ := filepath.Rel(ns.vaultDir, destinationPath)
+
+	if source == "." {
+		return toolErrorResult("Cannot move the vault root"), nil
+	}
+
+	info, err := utils.Stat(sourcePath)
+	if err != nil {
+		return toolErrorResult("Note not found: %s", source), nil
+	}
+
+	if source == destination {
+		return toolErrorResult("Source and destination are the same: %s", source), nil
+	}
+
+	if _, err := utils.Stat(destinationPath); err == nil {
+		return toolErrorResult("Destination already exists: %s", destination), nil
+	}
+
+	if info.IsDir() && strings.HasPrefix(destination+string(filepath.Separator), source+string(filepath.Separator)) {
+		return toolErrorResult("Cannot move a folder into itself: %s", destination), nil
+	}
+
+	files, err := ns.vaultFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan vault: %w", err)
+	}
+
+	// Map every affected file to its new location
+	renamed := make(map[string]string)
+	result := MoveResult{Source: source, Destination: destination, MovedFiles: []MovedFile{}, UpdatedFiles: []UpdatedFile{}}
+	if info.IsDir() {
+		prefix := source + string(filepath.Separator)
+		for _, file := range files {
+			if strings.HasPrefix(file, prefix) {
+				renamed[file] = filepath.Join(destination, strings.TrimPrefix(file, prefix))
+			}
+		}
+	} else {
+		renamed[source] = destination
+	}
+
+	movedTo := func(path string) string {
+		if newPath, ok := renamed[path]; ok {
+			return newPath
+		}
+		return path
+	}
+
+	newFiles := make([]string, 0, len(files))
+	for _, file := range files {
+		newFiles = append(newFiles, movedTo(file))
+	}
+
+	// Hold every note the move may read or rewrite until it is done
+	lockedPaths := []string{sourcePath, destinationPath}
+	for _, file := range files {
+		if isMarkdownFile(file) {
+			lockedPaths = append(lockedPaths, filepath.Join(ns.vaultDir, file), filepath.Join(ns.vaultDir, movedTo(file)))
+		}
+	}
+	unlock := ns.lockPaths(lockedPaths...)
+	defer unlock()
+
+	before := newLinkResolver(files)
+	after := newLinkResolver(newFiles)
+
+	// Work out the link edits before touching the file system so a failed
+	// read leaves the vault unchanged
+	rewrites := make(map[string]string)
+	originals := make(map[string]string)
+	for _, file := range files {
+		if !isMarkdownFile(file) {
+			continue
+		}
+
+		content, err := utils.ReadFile(filepath.Join(ns.vaultDir, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		newSource := movedTo(file)
+		var edits []textEdit
+		for _, link := range parseLinks(string(content)) {
+			if link.target == "" {
+				continue
+			}
+
+			target, ok := before.resolve(file, link)
+			if !ok {
+				continue
+			}
+
+			// Leave links that still reach the same file untouched
+			newTarget := movedTo(target)
+			if resolved, ok := after.resolve(newSource, link); ok && resolved == newTarget {
+				continue
+			}
+
+			raw := string(content[link.targetStart:link.targetEnd])
+			edits = append(edits, textEdit{
+				start: link.targetStart,
+				end:   link.targetEnd,
+				text:  rewriteLinkTarget(after, link, raw, newSource, newTarget),
+			})
+		}
+
+		if len(edits) > 0 {
+			rewrites[newSource] = applyTextEdits(string(content), edits)
+			originals[newSource] = string(content)
+			result.UpdatedFiles = append(result.UpdatedFiles, UpdatedFile{Path: newSource, Links: len(edits)})
+		}
+	}
+
+	if err := utils.MkdirAll(filepath.Dir(destinationPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := utils.Rename(sourcePath, destinationPath); err != nil {
+		return nil, fmt.Errorf("failed to move %s: %w", source, err)
+	}
+
+	for from, to := range renamed {
+		if err := ns.moveHistory(from, to); err != nil {
+			slog.Warn("Failed to move note history", "from", from, "to", to, "error", err)
+		}
+	}
+
+	// Rewrite the links in a fixed order, and undo the move when a write
+	// fails so the vault is not left with half of its links updated
+	paths := make([]string, 0, len(rewrites))
+	for path := range rewrites {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var written []string
+	for _, path := range paths {
+		fullPath := filepath.Join(ns.vaultDir, path)
+		err := ns.snapshotNote(fullPath, "move_note")
+		if err == nil {
+			err = utils.WriteFile(fullPath, []byte(rewrites[path]), 0644)
+		}
+		if err != nil {
+			return ns.rollbackMove(sourcePath, destinationPath, renamed, originals, written, fmt.Errorf("failed to update links in %s: %w", path, err)), nil
+		}
+		written = append(written, path)
+	}
+
+	for from, to := range renamed {
+		result.MovedFiles = append(result.MovedFiles, MovedFile{From: from, To: to})
+	}
+	sort.Slice(result.MovedFiles, func(i, j int) bool {
+		return result.MovedFiles[i].From < result.MovedFiles[j].From
+	})
+	sort.Slice(result.UpdatedFiles, func(i, j int) bool {
+		return result.UpdatedFiles[i].Path < result.UpdatedFiles[j].Path
+	})
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// rollbackMove undoes a move whose link rewrites failed part way: the notes
+// already rewritten get their old content back and the source is moved back.
+// When the rollback fails too, the result lists what is left moved and
+// rewritten so the vault can be repaired by hand.
+func (ns *NotesServer) rollbackMove(sourcePath, destinationPath string, renamed, originals map[string]string, written []string, cause error) *mcp.CallToolResult {
+	source, _ := filepath.Rel(ns.vaultDir, sourcePath)
+	destination, _ := filepath.Rel(ns.vaultDir, destinationPath)
+	result := MoveResult{Source: source, Destination: destination, MovedFiles: []MovedFile{}, UpdatedFiles: []UpdatedFile{}}
+
+	var failures []string
+	for _, path := range written {
+		if err := utils.WriteFile(filepath.Join(ns.vaultDir, path), []byte(originals[path]), 0644); err != nil {
+			failures = append(failures, err.Error())
+			result.UpdatedFiles = append(result.UpdatedFiles, UpdatedFile{Path: path})
+		}
+	}
+
+	if err := utils.Rename(destinationPath, sourcePath); err != nil {
+		failures = append(failures, err.Error())
+		for from, to := range renamed {
+			result.MovedFiles = append(result.MovedFiles, MovedFile{From: from, To: to})
+		}
+		sort.Slice(result.MovedFiles, func(i, j int) bool {
+			return result.MovedFiles[i].From < result.MovedFiles[j].From
+		})
+	} else {
+		for from, to := range renamed {
+			if err := ns.moveHistory(to, from); err != nil {
+				slog.Warn("Failed to move note history back", "from", to, "to", from, "error", err)
+			}
+		}
+	}
+
+	if len(failures) == 0 {
+		return toolErrorResult("Failed to move %s, the move was undone: %v", source, cause)
+	}
+
+	slog.Error("Failed to undo a move", "source", source, "destination", destination, "errors", failures)
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			mcp.NewTextContent(fmt.Sprintf("Failed to move %s: %v. Undoing the move also failed (%s), these changes remain:", source, cause, strings.Join(failures, "; "))),
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}
+}
+
+// rewriteLinkTarget returns the new target text for a link, keeping the
+// style it was written in: bare or full wikilink names, relative or root
+// markdown paths, and whether the .md extension was included
+func rewriteLinkTarget(resolver *linkResolver, link rawLink, raw, source, target string) string {
+	slashed := filepath.ToSlash(target)
+	if isMarkdownFile(slashed) && !isMarkdownFile(link.target) {
+		slashed = strings.TrimSuffix(slashed, filepath.Ext(slashed))
+	}
+
+	if link.kind == linkKindWiki {
+		if !strings.Contains(link.target, "/") {
+			name := slashed[strings.LastIndex(slashed, "/")+1:]
+			candidate := rawLink{kind: linkKindWiki, target: name}
+			if resolved, ok := resolver.resolve(source, candidate); ok && resolved == target {
+				return name
+			}
+		}
+		return slashed
+	}
+
+	path := "/" + slashed
+	if !strings.HasPrefix(link.target, "/") {
+		sourceDir := filepath.Dir(filepath.FromSlash(source))
+		relative, err := filepath.Rel(sourceDir, filepath.FromSlash(slashed))
+		if err == nil {
+			path = filepath.ToSlash(relative)
+		}
+		if strings.HasPrefix(raw, "./") && !strings.HasPrefix(path, "../") {
+			path = "./" + path
+		}
+	}
+
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+// toolErrorResult builds an MCP tool error with a formatted message
+func toolErrorResult(format string, args ...interface{}) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			mcp.NewTextContent(fmt.Sprintf(format, args...)),
+		},
+	}
+}
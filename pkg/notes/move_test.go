@@ -0,0 +1,189 @@
+package notes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var moveVaultNotes = map[string]string{
+	"index.md":              "# Index\n\n- [[Alpha]]\n- [[projects/alpha|Alpha project]]\n- [Alpha](projects/alpha.md#goals)\n- [Beta](./projects/beta.md)\n- `[[Alpha]]` in code",
+	"projects/alpha.md":     "# Alpha\n\nBack to [index](../index.md). See [[beta#Plan]].\n\n![[diagram.png]]",
+	"projects/beta.md":      "# Beta\n\nLinks to [[Alpha]].",
+	"projects/diagram.png":  "png",
+	"archive/unrelated.md":  "# Unrelated\n\nNo links.",
+	"journal/2025-01-01.md": "Met about [alpha](/projects/alpha.md).",
+}
+
+func TestRenameNote_RewritesInboundLinks(t *testing.T) {
+	tempDir := createVaultForTest(t, moveVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	result, err := ns.RenameNote(context.Background(), mcp.CallToolRequest{}, RenameNoteRequest{
+		Path:    "projects/alpha.md",
+		NewName: "Alpha Launch",
+	})
+	var moveResult MoveResult
+	decodeToolResult(t, result, err, &moveResult)
+
+	if _, err := os.Stat(filepath.Join(tempDir, "projects", "Alpha Launch.md")); err != nil {
+		t.Fatalf("Renamed note should exist: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "projects", "alpha.md")); !os.IsNotExist(err) {
+		t.Error("Old note should be gone")
+	}
+
+	expectedIndex := "# Index\n\n- [[Alpha Launch]]\n- [[projects/Alpha Launch|Alpha project]]\n- [Alpha](projects/Alpha%20Launch.md#goals)\n- [Beta](./projects/beta.md)\n- `[[Alpha]]` in code"
+	if got := readForTest(t, tempDir, "index.md"); got != expectedIndex {
+		t.Errorf("Unexpected index.md:\n%s", got)
+	}
+
+	if got := readForTest(t, tempDir, "projects/beta.md"); got != "# Beta\n\nLinks to [[Alpha Launch]]." {
+		t.Errorf("Unexpected beta.md: %q", got)
+	}
+
+	if got := readForTest(t, tempDir, "journal/2025-01-01.md"); got != "Met about [alpha](/projects/Alpha%20Launch.md)." {
+		t.Errorf("Unexpected journal note: %q", got)
+	}
+
+	if len(moveResult.UpdatedFiles) != 3 {
+		t.Errorf("Expected 3 updated files, got %+v", moveResult.UpdatedFiles)
+	}
+	for _, updated := range moveResult.UpdatedFiles {
+		if filepath.ToSlash(updated.Path) == "index.md" && updated.Links != 3 {
+			t.Errorf("Expected 3 links updated in index.md, got %d", updated.Links)
+		}
+	}
+}
+
+func TestMoveNote_IntoFolder(t *testing.T) {
+	tempDir := createVaultForTest(t, moveVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	// Moving into an existing folder keeps the file name
+	result, err := ns.MoveNote(context.Background(), mcp.CallToolRequest{}, MoveNoteRequest{
+		Source:      "projects/alpha.md",
+		Destination: "archive",
+	})
+	var moveResult MoveResult
+	decodeToolResult(t, result, err, &moveResult)
+
+	// Only the path based links in index.md and the journal need updating
+	if len(moveResult.UpdatedFiles) != 2 {
+		t.Errorf("Expected 2 updated files, got %+v", moveResult.UpdatedFiles)
+	}
+
+	if filepath.ToSlash(moveResult.Destination) != "archive/alpha.md" {
+		t.Errorf("Unexpected destination: %s", moveResult.Destination)
+	}
+
+	// Relative links stay valid from the new folder and bare wikilinks
+	// resolve by name, so nothing needs rewriting
+	expected := "# Alpha\n\nBack to [index](../index.md). See [[beta#Plan]].\n\n![[diagram.png]]"
+	if got := readForTest(t, tempDir, "archive/alpha.md"); got != expected {
+		t.Errorf("Unexpected moved note:\n%s", got)
+	}
+
+	// Bare wikilinks still resolve by name and are left alone
+	if got := readForTest(t, tempDir, "projects/beta.md"); got != "# Beta\n\nLinks to [[Alpha]]." {
+		t.Errorf("Unexpected beta.md: %q", got)
+	}
+}
+
+func TestMoveNote_Folder(t *testing.T) {
+	tempDir := createVaultForTest(t, moveVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	result, err := ns.MoveNote(context.Background(), mcp.CallToolRequest{}, MoveNoteRequest{
+		Source:      "projects",
+		Destination: "work/projects",
+	})
+	var moveResult MoveResult
+	decodeToolResult(t, result, err, &moveResult)
+
+	if len(moveResult.MovedFiles) != 3 {
+		t.Errorf("Expected 3 moved files, got %+v", moveResult.MovedFiles)
+	}
+
+	expectedIndex := "# Index\n\n- [[Alpha]]\n- [[work/projects/alpha|Alpha project]]\n- [Alpha](work/projects/alpha.md#goals)\n- [Beta](./work/projects/beta.md)\n- `[[Alpha]]` in code"
+	if got := readForTest(t, tempDir, "index.md"); got != expectedIndex {
+		t.Errorf("Unexpected index.md:\n%s", got)
+	}
+
+	// Links between notes that moved together keep working unchanged, while
+	// the link back to the root is adjusted
+	expected := "# Alpha\n\nBack to [index](../../index.md). See [[beta#Plan]].\n\n![[diagram.png]]"
+	if got := readForTest(t, tempDir, "work/projects/alpha.md"); got != expected {
+		t.Errorf("Unexpected moved note:\n%s", got)
+	}
+}
+
+func TestMoveNote_Errors(t *testing.T) {
+	tempDir := createVaultForTest(t, moveVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		params MoveNoteRequest
+	}{
+		{"Missing source", MoveNoteRequest{Source: "nope.md", Destination: "other.md"}},
+		{"Existing destination", MoveNoteRequest{Source: "index.md", Destination: "projects/beta.md"}},
+		{"Outside vault", MoveNoteRequest{Source: "index.md", Destination: "../escape.md"}},
+		{"Folder into itself", MoveNoteRequest{Source: "projects", Destination: "projects/sub"}},
+		{"Vault root", MoveNoteRequest{Source: "", Destination: "elsewhere"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ns.MoveNote(ctx, mcp.CallToolRequest{}, tt.params)
+			if err != nil {
+				t.Fatalf("Expected MCP error, not Go error: %v", err)
+			}
+			if !result.IsError {
+				t.Errorf("Expected error for %+v", tt.params)
+			}
+		})
+	}
+
+	result, err := ns.RenameNote(ctx, mcp.CallToolRequest{}, RenameNoteRequest{Path: "index.md", NewName: "sub/index.md"})
+	if err != nil || !result.IsError {
+		t.Error("Rename should reject names containing a folder")
+	}
+}
+
+func TestRenameNote_UndoneWhenALinkUpdateFails(t *testing.T) {
+	tempDir := createVaultForTest(t, moveVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	// A corrupt history log makes the snapshot of the last note to update fail
+	logPath := ns.historyLogPath("projects/beta.md")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(logPath, []byte("{"), 0644); err != nil {
+		t.Fatalf("Failed to write history log: %v", err)
+	}
+
+	before := map[string]string{}
+	for _, name := range []string{"index.md", "journal/2025-01-01.md", "projects/alpha.md", "projects/beta.md"} {
+		before[name] = readForTest(t, tempDir, name)
+	}
+
+	result, err := ns.RenameNote(context.Background(), mcp.CallToolRequest{}, RenameNoteRequest{Path: "projects/alpha.md", NewName: "Alpha Launch"})
+	if err != nil || !result.IsError {
+		t.Fatalf("Expected the rename to fail, got %v %v", result, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "projects", "Alpha Launch.md")); !os.IsNotExist(err) {
+		t.Error("The rename should have been undone")
+	}
+	for name, content := range before {
+		if got := readForTest(t, tempDir, name); got != content {
+			t.Errorf("Expected %s to be restored, got %q", name, got)
+		}
+	}
+}
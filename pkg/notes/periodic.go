@@ -0,0 +1,693 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Periods of the periodic notes
+const (
+	PeriodDaily     = "daily"
+	PeriodWeekly    = "weekly"
+	PeriodMonthly   = "monthly"
+	PeriodQuarterly = "quarterly"
+)
+
+// periods lists the periods in the order they are reported
+var periods = []string{PeriodDaily, PeriodWeekly, PeriodMonthly, PeriodQuarterly}
+
+// defaultPeriodicPatterns are the vault paths of the periodic notes unless
+// configured with WithPeriodicNotePattern
+var defaultPeriodicPatterns = map[string]string{
+	PeriodDaily:     "Journal/{{yyyy}}/{{yyyy-MM-dd}}.md",
+	PeriodWeekly:    "Journal/{{gggg}}/{{gggg}}-W{{ww}}.md",
+	PeriodMonthly:   "Journal/{{yyyy}}/{{yyyy-MM}}.md",
+	PeriodQuarterly: "Journal/{{yyyy}}/{{yyyy}}-Q{{Q}}.md",
+}
+
+// carryOverLookback is how many periods back open_periodic_note looks for
+// a previous note to carry unfinished tasks over from
+const carryOverLookback = 31
+
+// periodicTasksHeading is the heading carried over tasks are added under
+const periodicTasksHeading = "Tasks"
+
+// dateTokens are the date tokens a path pattern can use, longest first so
+// that e.g. MMMM is not read as two MM tokens
+var dateTokens = []string{"yyyy", "gggg", "MMMM", "dddd", "MMM", "ddd", "yy", "MM", "dd", "ww", "M", "d", "w", "Q"}
+
+// dateTokenRegex is the text each date token matches in a path
+var dateTokenRegex = map[string]string{
+	"yyyy": `(\d{4})`, "gggg": `(\d{4})`, "yy": `(\d{2})`,
+	"MMMM": `([A-Za-z]+)`, "MMM": `([A-Za-z]+)`, "MM": `(\d{2})`, "M": `(\d{1,2})`,
+	"dddd": `([A-Za-z]+)`, "ddd": `([A-Za-z]+)`, "dd": `(\d{2})`, "d": `(\d{1,2})`,
+	"ww": `(\d{2})`, "w": `(\d{1,2})`, "Q": `([1-4])`,
+}
+
+// openTaskRegex matches an unfinished task and captures its text
+var openTaskRegex = regexp.MustCompile(`^\s*[-*+]\s+\[ \]\s+(\S.*?)\s*$`)
+
+// emptyTaskRegex matches a task placeholder without text, as in the templates
+var emptyTaskRegex = regexp.MustCompile(`^\s*[-*+]\s+\[ \]\s*$`)
+
+// OpenPeriodicNoteRequest represents a request to open a periodic note
+type OpenPeriodicNoteRequest struct {
+	Period string `json:"period" mcp:"Period of the note: daily, weekly, monthly or quarterly"`
+	Date   string `json:"date,omitempty" mcp:"A date in the period as YYYY-MM-DD, or today, yesterday or tomorrow (default today)"`
+}
+
+// PeriodicNoteResult is the response of open_periodic_note
+type PeriodicNoteResult struct {
+	Period  string `json:"period"`
+	Date    string `json:"date"`
+	Path    string `json:"path"`
+	Created bool   `json:"created"`
+
+	// CarriedFrom is the previous note unfinished tasks were copied from
+	CarriedFrom  string   `json:"carried_from,omitempty"`
+	CarriedTasks []string `json:"carried_tasks,omitempty"`
+
+	Hash    string `json:"hash"`
+	Content string `json:"content"`
+}
+
+// PeriodicNoteRef is a periodic note and the date its period starts
+type PeriodicNoteRef struct {
+	Date   string `json:"date"`
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
+// PeriodicNotesListing lists the notes of one period
+type PeriodicNotesListing struct {
+	Pattern  string            `json:"pattern"`
+	Template string            `json:"template"`
+	Current  PeriodicNoteRef   `json:"current"`
+	Notes    []PeriodicNoteRef `json:"notes"`
+}
+
+// patternPart is a literal or a date token of a path pattern
+type patternPart struct {
+	literal string
+	token   string
+}
+
+func (ns *NotesServer) NewOpenPeriodicNoteTool() {
+	tool := mcp.NewTool(
+		"open_periodic_note",
+		mcp.WithDescription("Open the daily, weekly, monthly or quarterly note for a date, creating it from its template with the unfinished tasks of the previous note if it does not exist"),
+		mcp.WithString("period", mcp.Description("Period of the note: daily, weekly, monthly or quarterly"), mcp.Required()),
+		mcp.WithString("date", mcp.Description("A date in the period as YYYY-MM-DD, or today, yesterday or tomorrow (default today)")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.OpenPeriodicNote))
+}
+
+// OpenPeriodicNote returns the periodic note for a date, creating it if needed
+func (ns *NotesServer) OpenPeriodicNote(ctx context.Context, req mcp.CallToolRequest, params OpenPeriodicNoteRequest) (*mcp.CallToolResult, error) {
+	period := strings.ToLower(strings.TrimSpace(params.Period))
+	if !isPeriod(period) {
+		return toolErrorResult("invalid period %q, expected one of: %s", params.Period, strings.Join(periods, ", ")), nil
+	}
+
+	date, err := parsePeriodDate(params.Date, time.Now())
+	if err != nil {
+		return toolErrorResult("%v", err), nil
+	}
+	start := periodStart(period, date)
+
+	relativePath, err := ns.periodicNotePath(period, start)
+	if err != nil {
+		return toolErrorResult("%v", err), nil
+	}
+	fullPath, err := utils.ValidatePath(ns.vaultDir, relativePath)
+	if err != nil {
+		return toolErrorResult("%v", err), nil
+	}
+
+	unlock := ns.lockPaths(fullPath)
+	defer unlock()
+
+	result := PeriodicNoteResult{
+		Period: period,
+		Date:   start.Format(templateDateLayout),
+		Path:   relativePath,
+	}
+
+	content, err := utils.ReadFile(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		created, err := ns.newPeriodicNote(period, start, &result)
+		if err != nil {
+			var missing *MissingVariablesError
+			if errors.As(err, &missing) {
+				return missingVariablesResult(missing), nil
+			}
+			return toolErrorResult("%v", err), nil
+		}
+
+		if err := utils.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := utils.WriteFile(fullPath, []byte(created), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write file: %w", err)
+		}
+
+		content = []byte(created)
+		result.Created = true
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read note: %w", err)
+	}
+
+	result.Content = string(content)
+	result.Hash = contentHash(content)
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Result: hashMeta(content),
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// newPeriodicNote renders the template of a period for the period starting
+// at start and adds the unfinished tasks of the most recent previous note
+func (ns *NotesServer) newPeriodicNote(period string, start time.Time, result *PeriodicNoteResult) (string, error) {
+	template, ok := ns.getTemplates()[period]
+	if !ok {
+		return "", fmt.Errorf("template %q not found", period)
+	}
+
+	variables := map[string]interface{}{
+		"DATE":     start.Format(templateDateLayout),
+		"END_DATE": nextPeriod(period, start).AddDate(0, 0, -1).Format(templateDateLayout),
+	}
+	variables = withNoteTitle(variables, result.Path)
+	rendered, err := renderTemplate(period, template, variables, time.Now())
+	if err != nil {
+		return "", err
+	}
+	content := rendered.Content
+
+	previous := start
+	for range carryOverLookback {
+		previous = previousPeriod(period, previous)
+		previousPath, err := ns.periodicNotePath(period, previous)
+		if err != nil {
+			return "", err
+		}
+		fullPath, err := utils.ValidatePath(ns.vaultDir, previousPath)
+		if err != nil {
+			return "", err
+		}
+
+		previousContent, err := utils.ReadFile(fullPath)
+		if err != nil {
+			continue
+		}
+
+		var tasks []string
+		content, tasks = carryOverTasks(content, openTasks(string(previousContent)))
+		if len(tasks) > 0 {
+			result.CarriedFrom = previousPath
+			result.CarriedTasks = tasks
+		}
+		break
+	}
+
+	return content, nil
+}
+
+// ListPeriodicNotes serves the periodic notes found in the vault
+func (ns *NotesServer) ListPeriodicNotes(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	files, err := ns.vaultFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan vault: %w", err)
+	}
+
+	existing := make(map[string]bool, len(files))
+	for i, file := range files {
+		files[i] = filepath.ToSlash(file)
+		existing[files[i]] = true
+	}
+
+	listings := make(map[string]PeriodicNotesListing, len(periods))
+	for _, period := range periods {
+		pattern := ns.periodicPattern(period)
+		listing := PeriodicNotesListing{
+			Pattern:  pattern,
+			Template: period,
+			Notes:    []PeriodicNoteRef{},
+		}
+
+		start := periodStart(period, time.Now())
+		if path, err := ns.periodicNotePath(period, start); err == nil {
+			listing.Current = PeriodicNoteRef{Date: start.Format(templateDateLayout), Path: path, Exists: existing[path]}
+		}
+
+		matcher := newPeriodicNoteMatcher(period, pattern)
+		for _, file := range files {
+			if date, ok := matcher.match(file); ok {
+				listing.Notes = append(listing.Notes, PeriodicNoteRef{Date: date.Format(templateDateLayout), Path: file, Exists: true})
+			}
+		}
+		sort.Slice(listing.Notes, func(i, j int) bool {
+			return listing.Notes[i].Date > listing.Notes[j].Date
+		})
+
+		listings[period] = listing
+	}
+
+	listingsJSON, _ := json.MarshalIndent(listings, "", "  ")
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      "notes://periodic/",
+			MIMEType: "application/json",
+			Text:     string(listingsJSON),
+		},
+	}, nil
+}
+
+// periodicPattern returns the path pattern of a period
+func (ns *NotesServer) periodicPattern(period string) string {
+	if pattern := ns.periodicPatterns[period]; pattern != "" {
+		return pattern
+	}
+	return defaultPeriodicPatterns[period]
+}
+
+// periodicNotePath returns the vault path of the note of the period starting at start
+func (ns *NotesServer) periodicNotePath(period string, start time.Time) (string, error) {
+	pattern := ns.periodicPattern(period)
+
+	parts := parsePathPattern(pattern)
+	hasToken := false
+	for _, part := range parts {
+		hasToken = hasToken || part.token != ""
+	}
+	if !hasToken {
+		return "", fmt.Errorf("path pattern %q of %s notes has no date tokens", pattern, period)
+	}
+
+	path := formatPathPattern(parts, start)
+	if !isMarkdownFile(path) {
+		path += ".md"
+	}
+	return path, nil
+}
+
+// isPeriod reports whether period is one of the supported periods
+func isPeriod(period string) bool {
+	for _, p := range periods {
+		if p == period {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePeriodDate reads the date argument of open_periodic_note
+func parsePeriodDate(value string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	date, err := time.ParseInLocation(templateDateLayout, strings.TrimSpace(value), now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD, today, yesterday or tomorrow", value)
+	}
+	return date, nil
+}
+
+// periodStart returns the first day of the period containing date. Weeks
+// start on Monday.
+func periodStart(period string, date time.Time) time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	switch period {
+	case PeriodWeekly:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case PeriodMonthly:
+		return day.AddDate(0, 0, 1-day.Day())
+	case PeriodQuarterly:
+		month := time.Month((int(day.Month())-1)/3*3 + 1)
+		return time.Date(day.Year(), month, 1, 0, 0, 0, 0, day.Location())
+	default:
+		return day
+	}
+}
+
+// previousPeriod returns the start of the period before the one starting at start
+func previousPeriod(period string, start time.Time) time.Time {
+	switch period {
+	case PeriodWeekly:
+		return start.AddDate(0, 0, -7)
+	case PeriodMonthly:
+		return start.AddDate(0, -1, 0)
+	case PeriodQuarterly:
+		return start.AddDate(0, -3, 0)
+	default:
+		return start.AddDate(0, 0, -1)
+	}
+}
+
+// nextPeriod returns the start of the period after the one starting at start
+func nextPeriod(period string, start time.Time) time.Time {
+	switch period {
+	case PeriodWeekly:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonthly:
+		return start.AddDate(0, 1, 0)
+	case PeriodQuarterly:
+		return start.AddDate(0, 3, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// parsePathPattern splits a path pattern such as
+// Journal/{{yyyy}}/{{yyyy-MM-dd}}.md into literals and date tokens. Only the
+// text between {{ and }} is read as a date format.
+func parsePathPattern(pattern string) []patternPart {
+	var parts []patternPart
+	literal := func(text string) {
+		if text == "" {
+			return
+		}
+		if n := len(parts); n > 0 && parts[n-1].token == "" {
+			parts[n-1].literal += text
+			return
+		}
+		parts = append(parts, patternPart{literal: text})
+	}
+
+	rest := pattern
+	for {
+		open := strings.Index(rest, "{{")
+		if open < 0 {
+			break
+		}
+		end := strings.Index(rest[open:], "}}")
+		if end < 0 {
+			break
+		}
+		literal(rest[:open])
+
+		format := rest[open+2 : open+end]
+	tokens:
+		for format != "" {
+			for _, token := range dateTokens {
+				if strings.HasPrefix(format, token) {
+					parts = append(parts, patternPart{token: token})
+					format = format[len(token):]
+					continue tokens
+				}
+			}
+			literal(format[:1])
+			format = format[1:]
+		}
+
+		rest = rest[open+end+2:]
+	}
+	literal(rest)
+
+	return parts
+}
+
+// formatPathPattern fills in the date tokens of a path pattern
+func formatPathPattern(parts []patternPart, date time.Time) string {
+	isoYear, isoWeek := date.ISOWeek()
+
+	var b strings.Builder
+	for _, part := range parts {
+		switch part.token {
+		case "":
+			b.WriteString(part.literal)
+		case "yyyy":
+			fmt.Fprintf(&b, "%04d", date.Year())
+		case "gggg":
+			fmt.Fprintf(&b, "%04d", isoYear)
+		case "yy":
+			fmt.Fprintf(&b, "%02d", date.Year()%100)
+		case "MMMM":
+			b.WriteString(date.Month().String())
+		case "MMM":
+			b.WriteString(date.Month().String()[:3])
+		case "MM":
+			fmt.Fprintf(&b, "%02d", int(date.Month()))
+		case "M":
+			fmt.Fprint(&b, int(date.Month()))
+		case "dddd":
+			b.WriteString(date.Weekday().String())
+		case "ddd":
+			b.WriteString(date.Weekday().String()[:3])
+		case "dd":
+			fmt.Fprintf(&b, "%02d", date.Day())
+		case "d":
+			fmt.Fprint(&b, date.Day())
+		case "ww":
+			fmt.Fprintf(&b, "%02d", isoWeek)
+		case "w":
+			fmt.Fprint(&b, isoWeek)
+		case "Q":
+			fmt.Fprint(&b, (int(date.Month())-1)/3+1)
+		}
+	}
+
+	return b.String()
+}
+
+// periodicNoteMatcher recognizes the notes of a period written with a path
+// pattern. It is built once and matched against every file of the vault.
+type periodicNoteMatcher struct {
+	period  string
+	pattern string
+	parts   []patternPart
+	regex   *regexp.Regexp
+}
+
+// newPeriodicNoteMatcher parses pattern and compiles the expression its paths
+// match
+func newPeriodicNoteMatcher(period, pattern string) *periodicNoteMatcher {
+	parts := parsePathPattern(pattern)
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, part := range parts {
+		if part.token == "" {
+			expr.WriteString(regexp.QuoteMeta(part.literal))
+		} else {
+			expr.WriteString(dateTokenRegex[part.token])
+		}
+	}
+	if !isMarkdownFile(pattern) {
+		expr.WriteString(`\.md`)
+	}
+	expr.WriteString("$")
+
+	return &periodicNoteMatcher{
+		period:  period,
+		pattern: pattern,
+		parts:   parts,
+		regex:   regexp.MustCompile(expr.String()),
+	}
+}
+
+// match reports whether path is a note of the period and returns the start
+// of its period
+func (m *periodicNoteMatcher) match(path string) (time.Time, bool) {
+	period, pattern, parts := m.period, m.pattern, m.parts
+
+	match := m.regex.FindStringSubmatch(path)
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	var year, isoYear, month, day, week, quarter int
+	group := 1
+	for _, part := range parts {
+		if part.token == "" {
+			continue
+		}
+		value := match[group]
+		group++
+
+		n, _ := strconv.Atoi(value)
+		switch part.token {
+		case "yyyy":
+			year = n
+		case "gggg":
+			isoYear = n
+		case "yy":
+			year = 2000 + n
+		case "MMMM", "MMM":
+			month = parseMonthName(value)
+		case "MM", "M":
+			month = n
+		case "dd", "d":
+			day = n
+		case "ww", "w":
+			week = n
+		case "Q":
+			quarter = n
+		}
+	}
+	if year == 0 {
+		year = isoYear
+	}
+
+	var date time.Time
+	switch {
+	case year != 0 && month != 0 && day != 0:
+		date = time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+	case week != 0:
+		if isoYear == 0 {
+			isoYear = year
+		}
+		date = isoWeekStart(isoYear, week)
+	case year != 0 && month != 0:
+		date = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+	case year != 0 && quarter != 0:
+		date = time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, time.Local)
+	default:
+		return time.Time{}, false
+	}
+	date = periodStart(period, date)
+
+	// Reject paths that only look like the pattern, e.g. 2025-02-31
+	expected := formatPathPattern(parts, date)
+	if !isMarkdownFile(pattern) {
+		expected += ".md"
+	}
+	return date, expected == path
+}
+
+// parseMonthName returns the month number of a full or abbreviated month name
+func parseMonthName(name string) int {
+	for month := time.January; month <= time.December; month++ {
+		if strings.EqualFold(name, month.String()) || strings.EqualFold(name, month.String()[:3]) {
+			return int(month)
+		}
+	}
+	return 0
+}
+
+// isoWeekStart returns the Monday of an ISO week
+func isoWeekStart(year, week int) time.Time {
+	// January 4th is always in the first ISO week
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.Local)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, (week-1)*7)
+}
+
+// openTasks returns the unfinished tasks of a note as "- [ ] text" lines
+func openTasks(content string) []string {
+	var tasks []string
+	for _, task := range parseTasks(content) {
+		if !task.done {
+			tasks = append(tasks, "- [ ] "+task.text)
+		}
+	}
+	return tasks
+}
+
+// carryOverTasks adds tasks to the Tasks section of content, replacing the
+// empty task placeholders of the template. Tasks the note already has are
+// skipped, and the section is added at the end of the note if it has none.
+// It returns the new content and the tasks that were added.
+func carryOverTasks(content string, tasks []string) (string, []string) {
+	existing := make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		if match := openTaskRegex.FindStringSubmatch(line); match != nil {
+			existing[normalizeListItem(match[1])] = true
+		}
+	}
+
+	var added []string
+	for _, task := range tasks {
+		key := normalizeListItem(strings.TrimPrefix(task, "- [ ] "))
+		if !existing[key] {
+			existing[key] = true
+			added = append(added, task)
+		}
+	}
+	if len(added) == 0 {
+		return content, nil
+	}
+	text := strings.Join(added, "\n") + "\n"
+
+	headings := parseHeadings(content)
+	index := -1
+	for i, h := range headings {
+		if strings.EqualFold(h.text, periodicTasksHeading) {
+			index = i
+			break
+		}
+	}
+
+	if index < 0 {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if content != "" {
+			content += "\n"
+		}
+		return content + "## " + periodicTasksHeading + "\n\n" + text, added
+	}
+
+	h := headings[index]
+	end := ownBodyEnd(headings, index)
+
+	section := content[h.bodyStart:end]
+	var kept []string
+	for _, line := range strings.SplitAfter(section, "\n") {
+		if !emptyTaskRegex.MatchString(strings.TrimSuffix(line, "\n")) {
+			kept = append(kept, line)
+		}
+	}
+	body := strings.Join(kept, "")
+
+	filled := trimTrailingBlankLines(body)
+	var updated string
+	if filled == "" {
+		if first, _, _ := strings.Cut(section, "\n"); strings.TrimSpace(first) == "" && strings.Contains(section, "\n") {
+			updated = "\n"
+		}
+		updated += text
+		if end < len(content) {
+			updated += "\n"
+		}
+	} else {
+		if !strings.HasSuffix(filled, "\n") {
+			filled += "\n"
+		}
+		updated = filled + text + body[len(trimTrailingBlankLines(body)):]
+	}
+
+	if h.bodyStart > 0 && content[h.bodyStart-1] != '\n' {
+		// The heading is the last line of a note without a final newline
+		updated = "\n" + updated
+	}
+
+	return content[:h.bodyStart] + updated + content[end:], added
+}
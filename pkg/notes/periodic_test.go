@@ -0,0 +1,242 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func openPeriodicForTest(t *testing.T, ns *NotesServer, period, date string) PeriodicNoteResult {
+	t.Helper()
+
+	var note PeriodicNoteResult
+	result, err := ns.OpenPeriodicNote(context.Background(), mcp.CallToolRequest{}, OpenPeriodicNoteRequest{Period: period, Date: date})
+	decodeToolResult(t, result, err, &note)
+	return note
+}
+
+func TestFormatPathPattern(t *testing.T) {
+	// Monday of ISO week 1 of 2026
+	date := time.Date(2025, 12, 29, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"Journal/{{yyyy}}/{{yyyy-MM-dd}}.md", "Journal/2025/2025-12-29.md"},
+		{"{{gggg}}-W{{ww}}", "2026-W01"},
+		{"{{yyyy}}-Q{{Q}}", "2025-Q4"},
+		{"{{MMMM}} {{d}}, {{dddd}}/{{MMM}}-{{ddd}}-{{yy}}", "December 29, Monday/Dec-Mon-25"},
+		{"{{yyyy}}/week {{w}} of {{M}}", "2025/week 1 of 12"},
+		{"Notes/{{yyyy}}_{{MM}}", "Notes/2025_12"},
+	}
+
+	for _, tt := range tests {
+		if got := formatPathPattern(parsePathPattern(tt.pattern), date); got != tt.expected {
+			t.Errorf("Pattern %q: expected %q, got %q", tt.pattern, tt.expected, got)
+		}
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	date := time.Date(2025, 8, 14, 15, 30, 0, 0, time.Local)
+
+	tests := map[string]string{
+		PeriodDaily:     "2025-08-14",
+		PeriodWeekly:    "2025-08-11",
+		PeriodMonthly:   "2025-08-01",
+		PeriodQuarterly: "2025-07-01",
+	}
+	for period, expected := range tests {
+		if got := periodStart(period, date).Format(templateDateLayout); got != expected {
+			t.Errorf("%s: expected %s, got %s", period, expected, got)
+		}
+	}
+}
+
+func TestPeriodicNoteMatcher(t *testing.T) {
+	tests := []struct {
+		period   string
+		pattern  string
+		path     string
+		expected string
+	}{
+		{PeriodDaily, defaultPeriodicPatterns[PeriodDaily], "Journal/2025/2025-01-15.md", "2025-01-15"},
+		{PeriodDaily, defaultPeriodicPatterns[PeriodDaily], "Journal/2025/2025-02-31.md", ""},
+		{PeriodDaily, defaultPeriodicPatterns[PeriodDaily], "Journal/2024/2025-01-15.md", ""},
+		{PeriodWeekly, defaultPeriodicPatterns[PeriodWeekly], "Journal/2026/2026-W01.md", "2025-12-29"},
+		{PeriodMonthly, defaultPeriodicPatterns[PeriodMonthly], "Journal/2025/2025-03.md", "2025-03-01"},
+		{PeriodMonthly, "{{MMMM}} {{yyyy}}", "March 2025.md", "2025-03-01"},
+		{PeriodQuarterly, defaultPeriodicPatterns[PeriodQuarterly], "Journal/2025/2025-Q3.md", "2025-07-01"},
+		{PeriodQuarterly, defaultPeriodicPatterns[PeriodQuarterly], "Journal/2025/2025-Q3 copy.md", ""},
+	}
+
+	for _, tt := range tests {
+		date, ok := newPeriodicNoteMatcher(tt.period, tt.pattern).match(tt.path)
+		if tt.expected == "" {
+			if ok {
+				t.Errorf("%s should not match %q", tt.path, tt.pattern)
+			}
+			continue
+		}
+		if !ok || date.Format(templateDateLayout) != tt.expected {
+			t.Errorf("%s: expected %s, got %v %v", tt.path, tt.expected, date, ok)
+		}
+	}
+}
+
+func TestCarryOverTasks(t *testing.T) {
+	content := "# Day\n\n## Tasks\n- [ ] \n- [ ] Existing\n\n## Notes\n"
+
+	updated, added := carryOverTasks(content, []string{"- [ ] Existing", "- [ ] Call Bob"})
+	if expected := "# Day\n\n## Tasks\n- [ ] Existing\n- [ ] Call Bob\n\n## Notes\n"; updated != expected {
+		t.Errorf("Expected %q, got %q", expected, updated)
+	}
+	if len(added) != 1 || added[0] != "- [ ] Call Bob" {
+		t.Errorf("Unexpected added tasks: %v", added)
+	}
+
+	// Only placeholders in the section
+	updated, _ = carryOverTasks("## Tasks\n\n- [ ] \n- [ ] \n\n## Notes\n", []string{"- [ ] A"})
+	if expected := "## Tasks\n\n- [ ] A\n\n## Notes\n"; updated != expected {
+		t.Errorf("Expected %q, got %q", expected, updated)
+	}
+
+	// No Tasks section
+	updated, _ = carryOverTasks("# Day", []string{"- [ ] A"})
+	if expected := "# Day\n\n## Tasks\n\n- [ ] A\n"; updated != expected {
+		t.Errorf("Expected %q, got %q", expected, updated)
+	}
+}
+
+func TestOpenTasks(t *testing.T) {
+	content := "---\ntodo: \"- [ ] not a task\"\n---\n- [ ] Open\n  * [ ] Nested\n- [x] Done\n- [ ] \n```\n- [ ] In code\n```\n"
+
+	tasks := openTasks(content)
+	if strings.Join(tasks, "|") != "- [ ] Open|- [ ] Nested" {
+		t.Errorf("Unexpected open tasks: %v", tasks)
+	}
+}
+
+func TestOpenPeriodicNote_CreatesAndCarriesOverTasks(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+
+	// The previous note is two days back, there is no note for the day before
+	writeNoteForTest(t, tempDir, "Journal/2025/2025-01-13.md", "# Monday\n\n## Tasks\n- [ ] Write report\n- [x] Send invoice\n")
+
+	note := openPeriodicForTest(t, ns, "daily", "2025-01-15")
+	if !note.Created || note.Path != "Journal/2025/2025-01-15.md" || note.Date != "2025-01-15" {
+		t.Errorf("Unexpected result: %+v", note)
+	}
+	if note.CarriedFrom != "Journal/2025/2025-01-13.md" || len(note.CarriedTasks) != 1 {
+		t.Errorf("Expected the open task to be carried over: %+v", note)
+	}
+	if !strings.Contains(note.Content, "# Daily Note - 2025-01-15") || !strings.Contains(note.Content, "## Tasks\n- [ ] Write report\n\n## Notes") {
+		t.Errorf("Unexpected note content:\n%s", note.Content)
+	}
+	if strings.Contains(note.Content, "Send invoice") {
+		t.Error("Finished tasks must not be carried over")
+	}
+	if got := readForTest(t, tempDir, "Journal/2025/2025-01-15.md"); got != note.Content || note.Hash != contentHash([]byte(got)) {
+		t.Error("Returned content does not match the note on disk")
+	}
+
+	// Opening the note again returns it unchanged
+	writeNoteForTest(t, tempDir, "Journal/2025/2025-01-15.md", "edited")
+	note = openPeriodicForTest(t, ns, "daily", "2025-01-15")
+	if note.Created || note.Content != "edited" || note.CarriedFrom != "" {
+		t.Errorf("Expected the existing note, got %+v", note)
+	}
+}
+
+func TestOpenPeriodicNote_CustomPatternAndTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	WithPeriodicNotePattern(PeriodWeekly, "Weeks/{{gggg}}-W{{ww}}")(ns)
+
+	writeNoteForTest(t, filepath.Join(tempDir, defaultTemplatesFolder), "weekly.md", "# {{.DATE}} to {{.END_DATE}}\n")
+
+	note := openPeriodicForTest(t, ns, "Weekly", "2025-01-15")
+	if note.Path != "Weeks/2025-W03.md" || note.Content != "# 2025-01-13 to 2025-01-19\n" {
+		t.Errorf("Unexpected weekly note: %+v", note)
+	}
+
+	for _, period := range []string{PeriodMonthly, PeriodQuarterly} {
+		if note := openPeriodicForTest(t, ns, period, "2025-08-14"); !note.Created || strings.Contains(note.Content, "{{") {
+			t.Errorf("Unexpected %s note: %+v", period, note)
+		}
+	}
+	if got := readForTest(t, tempDir, "Journal/2025/2025-Q3.md"); !strings.HasPrefix(got, "# 2025 Q3\n") {
+		t.Errorf("Unexpected quarterly note: %q", got)
+	}
+}
+
+func TestOpenPeriodicNote_Errors(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+
+	tests := []OpenPeriodicNoteRequest{
+		{Period: "yearly"},
+		{Period: "daily", Date: "15/01/2025"},
+	}
+	for _, params := range tests {
+		result, err := ns.OpenPeriodicNote(context.Background(), mcp.CallToolRequest{}, params)
+		if err != nil || !result.IsError {
+			t.Errorf("Expected an error for %+v, got %v %v", params, result, err)
+		}
+	}
+
+	WithPeriodicNotePattern(PeriodDaily, "Journal/today.md")(ns)
+	result, err := ns.OpenPeriodicNote(context.Background(), mcp.CallToolRequest{}, OpenPeriodicNoteRequest{Period: "daily"})
+	if err != nil || !result.IsError {
+		t.Errorf("Expected an error for a pattern without date tokens, got %v %v", result, err)
+	}
+}
+
+func TestListPeriodicNotes(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+
+	for _, name := range []string{
+		"Journal/2025/2025-01-14.md",
+		"Journal/2025/2025-01-15.md",
+		"Journal/2025/2025-W03.md",
+		"Journal/2025/2025-01.md",
+		"Journal/2025/notes.md",
+	} {
+		writeNoteForTest(t, tempDir, name, "# Note\n")
+	}
+
+	contents, err := ns.ListPeriodicNotes(context.Background(), mcp.ReadResourceRequest{})
+	if err != nil {
+		t.Fatalf("ListPeriodicNotes failed: %v", err)
+	}
+
+	var listings map[string]PeriodicNotesListing
+	if err := json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &listings); err != nil {
+		t.Fatalf("Invalid JSON returned: %v", err)
+	}
+
+	daily := listings[PeriodDaily]
+	if len(daily.Notes) != 2 || daily.Notes[0].Date != "2025-01-15" || daily.Notes[1].Path != "Journal/2025/2025-01-14.md" {
+		t.Errorf("Unexpected daily notes: %+v", daily.Notes)
+	}
+	if daily.Pattern != defaultPeriodicPatterns[PeriodDaily] || daily.Current.Exists {
+		t.Errorf("Unexpected daily listing: %+v", daily)
+	}
+	if notes := listings[PeriodWeekly].Notes; len(notes) != 1 || notes[0].Date != "2025-01-13" {
+		t.Errorf("Unexpected weekly notes: %+v", notes)
+	}
+	if notes := listings[PeriodMonthly].Notes; len(notes) != 1 || notes[0].Path != "Journal/2025/2025-01.md" {
+		t.Errorf("Unexpected monthly notes: %+v", notes)
+	}
+	if notes := listings[PeriodQuarterly].Notes; len(notes) != 0 {
+		t.Errorf("Unexpected quarterly notes: %+v", notes)
+	}
+}
@@ -0,0 +1,168 @@
+package notes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Section change kinds reported in a change preview
+const (
+	SectionAdded    = "added"
+	SectionRemoved  = "removed"
+	SectionModified = "modified"
+)
+
+// SectionChange summarizes the lines changed under one heading
+type SectionChange struct {
+	Heading      string `json:"heading,omitempty"`
+	Change       string `json:"change"`
+	LinesAdded   int    `json:"lines_added"`
+	LinesRemoved int    `json:"lines_removed"`
+}
+
+// ChangePreview describes what a write would do to a note without doing it
+type ChangePreview struct {
+	Path        string          `json:"path"`
+	DryRun      bool            `json:"dry_run"`
+	Exists      bool            `json:"exists"`
+	Changed     bool            `json:"changed"`
+	Diff        string          `json:"diff"`
+	Sections    []SectionChange `json:"sections,omitempty"`
+	CurrentHash string          `json:"current_hash,omitempty"`
+	NewHash     string          `json:"new_hash"`
+}
+
+// newChangePreview compares the current and the updated content of a note
+func newChangePreview(relativePath string, current []byte, exists bool, updated string) ChangePreview {
+	preview := ChangePreview{
+		Path:     relativePath,
+		DryRun:   true,
+		Exists:   exists,
+		Changed:  !exists || string(current) != updated,
+		Diff:     numberedDiff("a/"+relativePath, "b/"+relativePath, string(current), updated),
+		Sections: sectionChanges(string(current), updated),
+		NewHash:  contentHash([]byte(updated)),
+	}
+	if exists {
+		preview.CurrentHash = contentHash(current)
+	}
+
+	return preview
+}
+
+// changePreviewResult wraps a change preview in a tool result
+func changePreviewResult(preview ChangePreview) *mcp.CallToolResult {
+	previewJSON, _ := json.MarshalIndent(preview, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(previewJSON)),
+		},
+	}
+}
+
+// numberedDiff renders a unified diff in which every line is prefixed with
+// its line number in the old and the new text. It returns an empty string
+// when the texts are equal.
+func numberedDiff(fromName, toName, from, to string) string {
+	hunks := diffHunks(diffLines(diffTextLines(from), diffTextLines(to)), diffContextLines)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	lineNumber := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return fmt.Sprint(n)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", hunk.OldStart, hunk.OldCount, hunk.NewStart, hunk.NewCount)
+		for _, op := range hunk.Ops {
+			fmt.Fprintf(&b, "%c%5s %5s | %s\n", op.Kind, lineNumber(op.OldLine), lineNumber(op.NewLine), op.Text)
+		}
+	}
+
+	return b.String()
+}
+
+// sectionChanges groups the changed lines between two texts by the heading
+// they fall under, in the order the sections first change
+func sectionChanges(from, to string) []SectionChange {
+	oldSections := lineSections(from)
+	newSections := lineSections(to)
+	oldHeadings := headingPaths(from)
+	newHeadings := headingPaths(to)
+
+	var changes []SectionChange
+	byHeading := make(map[string]int)
+	for _, op := range diffLines(splitLines(from), splitLines(to)) {
+		var heading string
+		switch op.Kind {
+		case '-':
+			heading = oldSections[op.OldLine-1]
+		case '+':
+			heading = newSections[op.NewLine-1]
+		default:
+			continue
+		}
+
+		i, ok := byHeading[heading]
+		if !ok {
+			change := SectionModified
+			switch {
+			case heading == "":
+			case !oldHeadings[heading]:
+				change = SectionAdded
+			case !newHeadings[heading]:
+				change = SectionRemoved
+			}
+			i = len(changes)
+			byHeading[heading] = i
+			changes = append(changes, SectionChange{Heading: heading, Change: change})
+		}
+
+		if op.Kind == '+' {
+			changes[i].LinesAdded++
+		} else {
+			changes[i].LinesRemoved++
+		}
+	}
+
+	return changes
+}
+
+// lineSections returns the heading path each line of content falls under
+func lineSections(content string) []string {
+	lines := splitLines(content)
+	sections := make([]string, len(lines))
+
+	headings := parseHeadings(content)
+	next := 0
+	current := ""
+	for i := range lines {
+		if next < len(headings) && headings[next].line == i+1 {
+			current = headingPath(headings, next)
+			next++
+		}
+		sections[i] = current
+	}
+
+	return sections
+}
+
+// headingPaths returns the set of heading paths in content
+func headingPaths(content string) map[string]bool {
+	headings := parseHeadings(content)
+	paths := make(map[string]bool, len(headings))
+	for i := range headings {
+		paths[headingPath(headings, i)] = true
+	}
+	return paths
+}
@@ -0,0 +1,127 @@
+package notes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestNumberedDiff(t *testing.T) {
+	got := numberedDiff("a/note.md", "b/note.md", "a\nb\nc\n", "a\nB\nc\nd\n")
+
+	expected := "--- a/note.md\n+++ b/note.md\n@@ -1,3 +1,4 @@\n" +
+		"     1     1 | a\n" +
+		"-    2       | b\n" +
+		"+          2 | B\n" +
+		"     3     3 | c\n" +
+		"+          4 | d\n"
+	if got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	if numberedDiff("a", "b", "same\n", "same\n") != "" {
+		t.Error("Equal texts should produce an empty diff")
+	}
+}
+
+func TestSectionChanges(t *testing.T) {
+	from := "Intro\n\n# Project\n\n## Tasks\n\n- a\n\n## Old\n\ngone\n"
+	to := "Intro changed\n\n# Project\n\n## Tasks\n\n- a\n- b\n- c\n\n## New\n\nhere\n"
+
+	changes := sectionChanges(from, to)
+
+	expected := []SectionChange{
+		{Heading: "", Change: SectionModified, LinesAdded: 1, LinesRemoved: 1},
+		{Heading: "Project > Tasks", Change: SectionModified, LinesAdded: 2},
+		{Heading: "Project > Old", Change: SectionRemoved, LinesRemoved: 2},
+		{Heading: "Project > New", Change: SectionAdded, LinesAdded: 2},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], changes[i])
+		}
+	}
+}
+
+func TestDryRun_DoesNotTouchDisk(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+	original := "# Note\n\n## Tasks\n\n- a\n"
+
+	writeNoteForTest(t, tempDir, "note.md", original)
+
+	var preview ChangePreview
+	result, err := ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "note.md", Content: "# Note\n", DryRun: true})
+	decodeToolResult(t, result, err, &preview)
+	if !preview.DryRun || !preview.Changed || !strings.Contains(preview.Diff, "-    5       | - a") {
+		t.Errorf("Unexpected write preview: %+v", preview)
+	}
+	if preview.CurrentHash != contentHash([]byte(original)) || preview.NewHash != contentHash([]byte("# Note\n")) {
+		t.Errorf("Unexpected preview hashes: %+v", preview)
+	}
+
+	result, err = ns.AppendNote(ctx, mcp.CallToolRequest{}, AppendNoteRequest{Path: "note.md", Content: "- b\n", DryRun: true})
+	decodeToolResult(t, result, err, &preview)
+	if len(preview.Sections) != 1 || preview.Sections[0].Heading != "Note > Tasks" || preview.Sections[0].LinesAdded != 1 {
+		t.Errorf("Unexpected append preview: %+v", preview)
+	}
+
+	result, err = ns.MergeNote(ctx, mcp.CallToolRequest{}, MergeNoteRequest{Path: "note.md", Content: "## Tasks\n\n- c", Strategy: MergeTopicMerge, DryRun: true})
+	decodeToolResult(t, result, err, &preview)
+	if !strings.Contains(preview.Diff, "+          6 | - c") {
+		t.Errorf("Unexpected merge preview: %s", preview.Diff)
+	}
+
+	preview = ChangePreview{}
+	result, err = ns.CreateNoteFromTemplate(ctx, mcp.CallToolRequest{}, CreateFromTemplateRequest{Path: "meeting.md", TemplateType: "meeting", DryRun: true})
+	decodeToolResult(t, result, err, &preview)
+	if preview.Exists || preview.CurrentHash != "" || preview.Diff == "" {
+		t.Errorf("Unexpected template preview: %+v", preview)
+	}
+
+	if got := readForTest(t, tempDir, "note.md"); got != original {
+		t.Errorf("Dry runs must not change the note, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "meeting.md")); !os.IsNotExist(err) {
+		t.Error("Dry run must not create the note")
+	}
+	if _, err := os.Stat(ns.historyDir()); !os.IsNotExist(err) {
+		t.Error("Dry runs must not save versions")
+	}
+
+	// A stale hash is reported just like a real write would
+	result, err = ns.WriteNote(ctx, mcp.CallToolRequest{}, WriteNoteRequest{Path: "note.md", Content: "x", ExpectedHash: "stale", DryRun: true})
+	assertConflict(t, result, err)
+}
+
+func TestPreviewMerge_ReturnsDiff(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+
+	writeNoteForTest(t, tempDir, "note.md", "# Note\n\n## Tasks\n\n- a\n")
+
+	var preview MergePreview
+	result, err := ns.PreviewMerge(context.Background(), mcp.CallToolRequest{}, PreviewMergeRequest{Path: "note.md", Content: "## Tasks\n\n- b", Strategy: MergeTopicMerge})
+	decodeToolResult(t, result, err, &preview)
+
+	if !strings.Contains(preview.Diff, "+          6 | - b") || preview.PreviewContent != "" {
+		t.Errorf("Unexpected preview: %+v", preview)
+	}
+	if len(preview.Sections) != 1 || preview.Sections[0].Heading != "Note > Tasks" {
+		t.Errorf("Unexpected section summary: %+v", preview.Sections)
+	}
+
+	result, err = ns.PreviewMerge(context.Background(), mcp.CallToolRequest{}, PreviewMergeRequest{Path: "note.md", Content: "- b", IncludeContent: true})
+	decodeToolResult(t, result, err, &preview)
+	if !strings.HasSuffix(preview.PreviewContent, "- b") {
+		t.Errorf("Expected the merged content, got %q", preview.PreviewContent)
+	}
+}
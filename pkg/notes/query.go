@@ -0,0 +1,667 @@
+package notes
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryError describes a search query that could not be parsed
+type QueryError struct {
+	Query    string `json:"query"`
+	Position int    `json:"position"`
+	Message  string `json:"message"`
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Position, e.Message)
+}
+
+// Supported field filters. Fields of the form frontmatter.<key> are also accepted.
+var queryFields = map[string]bool{
+	"tag":      true,
+	"path":     true,
+	"title":    true,
+	"modified": true,
+}
+
+type queryTokenKind int
+
+const (
+	tokenWord queryTokenKind = iota
+	tokenPhrase
+	tokenRegex
+	tokenField
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type queryToken struct {
+	kind  queryTokenKind
+	pos   int
+	text  string
+	regex *regexp.Regexp
+	field *fieldNode
+}
+
+// queryNode is a node in a parsed search query
+type queryNode interface {
+	match(doc *queryDoc) bool
+}
+
+type andNode struct{ children []queryNode }
+type orNode struct{ children []queryNode }
+type notNode struct{ child queryNode }
+
+// textNode matches a literal word or phrase anywhere in the note
+type textNode struct {
+	text    string
+	pattern *regexp.Regexp
+}
+
+// regexNode matches a regular expression anywhere in the note
+type regexNode struct {
+	pattern *regexp.Regexp
+}
+
+// fieldNode filters notes on metadata such as tags, path or frontmatter
+type fieldNode struct {
+	field string
+	key   string
+	op    string
+	value string
+}
+
+func (n *andNode) match(doc *queryDoc) bool {
+	for _, child := range n.children {
+		if !child.match(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *orNode) match(doc *queryDoc) bool {
+	for _, child := range n.children {
+		if child.match(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *notNode) match(doc *queryDoc) bool {
+	return !n.child.match(doc)
+}
+
+func (n *textNode) match(doc *queryDoc) bool {
+	return n.pattern.MatchString(doc.content)
+}
+
+func (n *regexNode) match(doc *queryDoc) bool {
+	return n.pattern.MatchString(doc.content)
+}
+
+func (n *fieldNode) match(doc *queryDoc) bool {
+	switch n.field {
+	case "tag":
+		for _, tag := range doc.getTags() {
+			if _, ok := cutTagPrefix(tag, strings.TrimPrefix(n.value, "#")); ok {
+				return true
+			}
+		}
+		return false
+
+	case "path":
+		path := filepath.ToSlash(doc.path)
+		if strings.ContainsAny(n.value, "*?[") {
+			matched, _ := filepath.Match(n.value, path)
+			return matched
+		}
+		return strings.Contains(strings.ToLower(path), strings.ToLower(n.value))
+
+	case "title":
+		return strings.Contains(strings.ToLower(doc.getTitle()), strings.ToLower(n.value))
+
+	case "modified":
+		// Plain dates compare by calendar day, full timestamps compare exactly
+		if len(n.value) == len("2006-01-02") {
+			return compareValues(strings.Compare(doc.modified.Format("2006-01-02"), n.value), n.op)
+		}
+		value, _ := parseQueryDate(n.value)
+		return compareValues(doc.modified.Compare(value), n.op)
+
+	case "frontmatter":
+		value, exists := lookupFrontmatter(doc.getFrontmatter(), n.key)
+		if !exists {
+			return false
+		}
+		return matchFrontmatterValue(value, n.value, n.op)
+	}
+
+	return false
+}
+
+// queryDoc is a note being evaluated against a query. Metadata is computed lazily.
+type queryDoc struct {
+	path     string
+	content  string
+	modified time.Time
+
+	tags        []string
+	tagsLoaded  bool
+	title       string
+	frontmatter map[string]interface{}
+}
+
+func (d *queryDoc) getTags() []string {
+	if !d.tagsLoaded {
+		d.tags = extractTags(d.content)
+		d.tagsLoaded = true
+	}
+	return d.tags
+}
+
+func (d *queryDoc) getTitle() string {
+	if d.title == "" {
+		d.title = noteTitle(d.content, filepath.Base(d.path))
+	}
+	return d.title
+}
+
+func (d *queryDoc) getFrontmatter() map[string]interface{} {
+	if d.frontmatter == nil {
+		// Notes with invalid frontmatter simply have no fields to filter on
+		fm, _ := parseFrontmatter(d.content)
+		d.frontmatter = fm.Fields
+	}
+	return d.frontmatter
+}
+
+// parseQuery parses a search query into a tree of query nodes. Bare words
+// that follow each other are matched as a single phrase, exactly like the
+// plain text search; AND, OR, NOT, parentheses, quoted phrases, re:/.../
+// regular expressions and field filters combine them. An empty query
+// returns a nil node which matches every note.
+func parseQuery(query string, caseSensitive bool) (queryNode, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	p := &queryParser{query: query, tokens: tokens, caseSensitive: caseSensitive}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.done() {
+		tok := p.peek()
+		if tok.kind == tokenRParen {
+			return nil, p.errorAt(tok.pos, "unmatched closing parenthesis")
+		}
+		return nil, p.errorAt(tok.pos, fmt.Sprintf("unexpected %q", tok.text))
+	}
+
+	return node, nil
+}
+
+func lexQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	i := 0
+
+	for i < len(query) {
+		r := query[i]
+		switch {
+		case isQuerySpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenLParen, pos: i, text: "("})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenRParen, pos: i, text: ")"})
+			i++
+
+		case r == '"':
+			text, next, err := lexQuoted(query, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, queryToken{kind: tokenPhrase, pos: i, text: text})
+			i = next
+
+		case r == '-' && i+1 < len(query) && !isQuerySpace(query[i+1]) &&
+			(i == 0 || isQuerySpace(query[i-1]) || query[i-1] == '('):
+			// -term is shorthand for NOT term
+			tokens = append(tokens, queryToken{kind: tokenNot, pos: i, text: "-"})
+			i++
+
+		case strings.HasPrefix(query[i:], "re:/"):
+			tok, next, err := lexRegex(query, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i = next
+
+		default:
+			start := i
+			for i < len(query) && !isQuerySpace(query[i]) && !strings.ContainsRune("()\"", rune(query[i])) {
+				i++
+			}
+			word := query[start:i]
+
+			switch word {
+			case "AND":
+				tokens = append(tokens, queryToken{kind: tokenAnd, pos: start, text: word})
+				continue
+			case "OR":
+				tokens = append(tokens, queryToken{kind: tokenOr, pos: start, text: word})
+				continue
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: tokenNot, pos: start, text: word})
+				continue
+			}
+
+			name, value, isField := strings.Cut(word, ":")
+			if isField && (queryFields[name] || strings.HasPrefix(name, "frontmatter.")) {
+				// Allow quoted values such as title:"Project X"
+				if value == "" && i < len(query) && query[i] == '"' {
+					quoted, next, err := lexQuoted(query, i)
+					if err != nil {
+						return nil, err
+					}
+					value = quoted
+					i = next
+				}
+
+				field, err := newFieldNode(query, start, name, value)
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, queryToken{kind: tokenField, pos: start, text: word, field: field})
+				continue
+			}
+
+			tokens = append(tokens, queryToken{kind: tokenWord, pos: start, text: word})
+		}
+	}
+
+	return tokens, nil
+}
+
+// isQuerySpace reports whether the byte separates query tokens. Only ASCII
+// whitespace is considered so multi-byte UTF-8 sequences are never split.
+func isQuerySpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// lexQuoted reads a double quoted string starting at query[start]
+func lexQuoted(query string, start int) (string, int, error) {
+	var sb strings.Builder
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if i+1 < len(query) {
+				i++
+				sb.WriteByte(query[i])
+			}
+		case '"':
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(query[i])
+		}
+	}
+
+	return "", 0, &QueryError{Query: query, Position: start, Message: "unterminated quoted phrase"}
+}
+
+// lexRegex reads a re:/pattern/flags term starting at query[start]
+func lexRegex(query string, start int) (queryToken, int, error) {
+	i := start + len("re:/")
+	var sb strings.Builder
+	for ; i < len(query); i++ {
+		if query[i] == '\\' && i+1 < len(query) && query[i+1] == '/' {
+			sb.WriteByte('/')
+			i++
+			continue
+		}
+		if query[i] == '/' {
+			break
+		}
+		sb.WriteByte(query[i])
+	}
+
+	if i >= len(query) {
+		return queryToken{}, 0, &QueryError{Query: query, Position: start, Message: "unterminated regular expression, expected closing '/'"}
+	}
+	i++
+
+	flags := ""
+	for i < len(query) && query[i] >= 'a' && query[i] <= 'z' {
+		if query[i] != 'i' {
+			return queryToken{}, 0, &QueryError{Query: query, Position: i, Message: fmt.Sprintf("unsupported regular expression flag %q", query[i])}
+		}
+		flags = "i"
+		i++
+	}
+
+	// Multi-line mode so ^ and $ anchor to lines as they do in the line matcher
+	pattern, err := regexp.Compile("(?m" + flags + ")" + sb.String())
+	if err != nil {
+		return queryToken{}, 0, &QueryError{Query: query, Position: start, Message: fmt.Sprintf("invalid regular expression: %v", err)}
+	}
+
+	return queryToken{kind: tokenRegex, pos: start, text: query[start:i], regex: pattern}, i, nil
+}
+
+func newFieldNode(query string, pos int, name, value string) (*fieldNode, error) {
+	field := &fieldNode{field: name, op: "="}
+	if key, ok := strings.CutPrefix(name, "frontmatter."); ok {
+		if key == "" {
+			return nil, &QueryError{Query: query, Position: pos, Message: "frontmatter filter needs a key, e.g. frontmatter.status:done"}
+		}
+		field.field = "frontmatter"
+		field.key = key
+	}
+
+	field.op, value = parseFilterExpression(value)
+
+	if value == "" {
+		return nil, &QueryError{Query: query, Position: pos, Message: fmt.Sprintf("missing value for %s filter", name)}
+	}
+	field.value = value
+
+	if field.op != "=" && field.field != "modified" && field.field != "frontmatter" {
+		return nil, &QueryError{Query: query, Position: pos, Message: fmt.Sprintf("comparison %q is not supported for %s filter", field.op, name)}
+	}
+
+	if field.field == "modified" {
+		if _, err := parseQueryDate(value); err != nil {
+			return nil, &QueryError{Query: query, Position: pos, Message: fmt.Sprintf("invalid date %q, expected YYYY-MM-DD", value)}
+		}
+	}
+
+	return field, nil
+}
+
+type queryParser struct {
+	query         string
+	tokens        []queryToken
+	pos           int
+	caseSensitive bool
+}
+
+func (p *queryParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) errorAt(pos int, message string) error {
+	return &QueryError{Query: p.query, Position: pos, Message: message}
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	children := []queryNode{left}
+	for !p.done() && p.peek().kind == tokenOr {
+		op := p.peek()
+		p.pos++
+		if p.done() {
+			return nil, p.errorAt(op.pos, "OR must be followed by a term")
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+
+	if len(children) == 1 {
+		return left, nil
+	}
+	return &orNode{children: children}, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	var children []queryNode
+
+	for !p.done() {
+		tok := p.peek()
+		if tok.kind == tokenOr || tok.kind == tokenRParen {
+			break
+		}
+
+		if tok.kind == tokenAnd {
+			if len(children) == 0 {
+				return nil, p.errorAt(tok.pos, "AND must follow a term")
+			}
+			p.pos++
+			if p.done() || p.peek().kind == tokenOr || p.peek().kind == tokenRParen {
+				return nil, p.errorAt(tok.pos, "AND must be followed by a term")
+			}
+			continue
+		}
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, node)
+	}
+
+	if len(children) == 0 {
+		if p.done() {
+			return nil, p.errorAt(len(p.query), "expected a term")
+		}
+		return nil, p.errorAt(p.peek().pos, "expected a term")
+	}
+
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &andNode{children: children}, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	tok := p.peek()
+	p.pos++
+
+	switch tok.kind {
+	case tokenNot:
+		if p.done() {
+			return nil, p.errorAt(tok.pos, "NOT must be followed by a term")
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{child: child}, nil
+
+	case tokenLParen:
+		if !p.done() && p.peek().kind == tokenRParen {
+			return nil, p.errorAt(tok.pos, "empty parentheses")
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.done() || p.peek().kind != tokenRParen {
+			return nil, p.errorAt(tok.pos, "unclosed parenthesis")
+		}
+		p.pos++
+		return node, nil
+
+	case tokenWord:
+		// Consecutive bare words form a phrase
+		words := []string{tok.text}
+		for !p.done() && p.peek().kind == tokenWord {
+			words = append(words, p.peek().text)
+			p.pos++
+		}
+		return p.newTextNode(strings.Join(words, " ")), nil
+
+	case tokenPhrase:
+		return p.newTextNode(tok.text), nil
+
+	case tokenRegex:
+		return &regexNode{pattern: tok.regex}, nil
+
+	case tokenField:
+		return tok.field, nil
+	}
+
+	return nil, p.errorAt(tok.pos, fmt.Sprintf("unexpected %q", tok.text))
+}
+
+func (p *queryParser) newTextNode(text string) *textNode {
+	prefix := "(?i)"
+	if p.caseSensitive {
+		prefix = ""
+	}
+	return &textNode{text: text, pattern: regexp.MustCompile(prefix + regexp.QuoteMeta(text))}
+}
+
+// requiredTerms returns index terms that every note matching the query must
+// contain. It is used to narrow the candidate set before evaluating the query.
+func requiredTerms(node queryNode) []string {
+	switch n := node.(type) {
+	case *andNode:
+		var terms []string
+		for _, child := range n.children {
+			terms = append(terms, requiredTerms(child)...)
+		}
+		return uniqueTerms(terms)
+	case *textNode:
+		return uniqueTerms(tokenize(n.text))
+	}
+
+	return nil
+}
+
+// scoringTerms returns the index terms used to rank notes matching the query
+func scoringTerms(node queryNode) []string {
+	var terms []string
+	for _, pattern := range positiveTextNodes(node) {
+		terms = append(terms, tokenize(pattern.text)...)
+	}
+	return uniqueTerms(terms)
+}
+
+// positiveTextNodes returns the text nodes that are not negated
+func positiveTextNodes(node queryNode) []*textNode {
+	switch n := node.(type) {
+	case *andNode:
+		var nodes []*textNode
+		for _, child := range n.children {
+			nodes = append(nodes, positiveTextNodes(child)...)
+		}
+		return nodes
+	case *orNode:
+		var nodes []*textNode
+		for _, child := range n.children {
+			nodes = append(nodes, positiveTextNodes(child)...)
+		}
+		return nodes
+	case *textNode:
+		return []*textNode{n}
+	}
+
+	return nil
+}
+
+// linePatterns returns the patterns used to pick matching lines out of a note
+func linePatterns(node queryNode) []*regexp.Regexp {
+	switch n := node.(type) {
+	case *andNode:
+		var patterns []*regexp.Regexp
+		for _, child := range n.children {
+			patterns = append(patterns, linePatterns(child)...)
+		}
+		return patterns
+	case *orNode:
+		var patterns []*regexp.Regexp
+		for _, child := range n.children {
+			patterns = append(patterns, linePatterns(child)...)
+		}
+		return patterns
+	case *textNode:
+		return []*regexp.Regexp{n.pattern}
+	case *regexNode:
+		return []*regexp.Regexp{n.pattern}
+	}
+
+	return nil
+}
+
+// parseQueryDate parses the dates accepted by the modified: filter
+func parseQueryDate(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %s", value)
+}
+
+// compareFieldValue compares a metadata value with a filter value, numerically
+// or as dates when both sides allow it and case-insensitively otherwise
+func compareFieldValue(actual, expected, op string) bool {
+	if a, err := strconv.ParseFloat(actual, 64); err == nil {
+		if e, err := strconv.ParseFloat(expected, 64); err == nil {
+			switch {
+			case a < e:
+				return compareValues(-1, op)
+			case a > e:
+				return compareValues(1, op)
+			default:
+				return compareValues(0, op)
+			}
+		}
+	}
+
+	if a, err := parseQueryDate(actual); err == nil {
+		if e, err := parseQueryDate(expected); err == nil {
+			return compareValues(a.Compare(e), op)
+		}
+	}
+
+	return compareValues(strings.Compare(strings.ToLower(actual), strings.ToLower(expected)), op)
+}
+
+// compareValues applies a comparison operator to the result of a three-way compare
+func compareValues(cmp int, op string) bool {
+	switch op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
@@ -0,0 +1,166 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseQuery_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		position int
+		message  string
+	}{
+		{"Unterminated phrase", `foo "bar`, 4, "unterminated quoted phrase"},
+		{"Unterminated regex", `re:/abc`, 0, "unterminated regular expression"},
+		{"Invalid regex", `re:/a(b/`, 0, "invalid regular expression"},
+		{"Unsupported regex flag", `re:/ab/x`, 7, "unsupported regular expression flag"},
+		{"Dangling AND", `foo AND`, 4, "AND must be followed by a term"},
+		{"Leading OR", `OR foo`, 0, "expected a term"},
+		{"Dangling NOT", `foo NOT`, 4, "NOT must be followed by a term"},
+		{"Unclosed parenthesis", `(foo OR bar`, 0, "unclosed parenthesis"},
+		{"Unmatched parenthesis", `foo)`, 3, "unmatched closing parenthesis"},
+		{"Empty parentheses", `()`, 0, "empty parentheses"},
+		{"Missing field value", `tag:`, 0, "missing value for tag filter"},
+		{"Invalid date", `modified:>yesterday`, 0, "invalid date"},
+		{"Comparison on tag", `tag:>foo`, 0, "comparison \">\" is not supported"},
+		{"Empty frontmatter key", `frontmatter.:done`, 0, "frontmatter filter needs a key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseQuery(tt.query, false)
+			if err == nil {
+				t.Fatalf("Expected error for query %q", tt.query)
+			}
+
+			var queryErr *QueryError
+			if !errors.As(err, &queryErr) {
+				t.Fatalf("Expected QueryError, got %T", err)
+			}
+
+			if queryErr.Position != tt.position {
+				t.Errorf("Expected position %d, got %d", tt.position, queryErr.Position)
+			}
+
+			if !strings.Contains(queryErr.Message, tt.message) {
+				t.Errorf("Expected message containing %q, got %q", tt.message, queryErr.Message)
+			}
+		})
+	}
+}
+
+func TestParseQuery_NonFieldColonIsText(t *testing.T) {
+	node, err := parseQuery("TODO: call", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	text, ok := node.(*textNode)
+	if !ok {
+		t.Fatalf("Expected a text node, got %T", node)
+	}
+
+	if text.text != "TODO: call" {
+		t.Errorf("Expected phrase 'TODO: call', got %q", text.text)
+	}
+}
+
+func TestSearchNotes_QueryLanguage(t *testing.T) {
+	testNotes := map[string]string{
+		"projects/alpha.md": "---\nstatus: done\npriority: 2\ntags: [project]\n---\n\n# Alpha Launch\n\nThe rocket design is final.\nBudget approved.",
+		"projects/beta.md":  "---\nstatus: active\npriority: 5\n---\n\n# Beta Plan\n\nRocket engine testing.\n#project",
+		"journal/today.md":  "# Journal\n\nBought a new bicycle. Design is nice.",
+		"journal/old.md":    "# Old Journal\n\nNothing about rockets, error code E1234.\n#project/archive",
+	}
+
+	tempDir := createVaultForTest(t, testNotes)
+
+	old := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+	if err := os.Chtimes(filepath.Join(tempDir, "journal", "old.md"), old, old); err != nil {
+		t.Fatalf("Failed to set mtime: %v", err)
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"rocket", []string{"journal/old.md", "projects/alpha.md", "projects/beta.md"}},
+		{"rocket design", []string{"projects/alpha.md"}},
+		{"rocket AND design", []string{"projects/alpha.md"}},
+		{"design OR engine", []string{"journal/today.md", "projects/alpha.md", "projects/beta.md"}},
+		{"rocket NOT engine", []string{"journal/old.md", "projects/alpha.md"}},
+		{"rocket -engine -path:journal", []string{"projects/alpha.md"}},
+		{`"Budget approved"`, []string{"projects/alpha.md"}},
+		{"(design OR engine) AND tag:project", []string{"projects/alpha.md", "projects/beta.md"}},
+		{"tag:project", []string{"journal/old.md", "projects/alpha.md", "projects/beta.md"}},
+		{"tag:project/archive", []string{"journal/old.md"}},
+		{"re:/E\\d{4}/", []string{"journal/old.md"}},
+		{"re:/^budget/i", []string{"projects/alpha.md"}},
+		{"path:journal", []string{"journal/old.md", "journal/today.md"}},
+		{"path:projects/*.md", []string{"projects/alpha.md", "projects/beta.md"}},
+		{`title:"beta plan"`, []string{"projects/beta.md"}},
+		{"frontmatter.status:done", []string{"projects/alpha.md"}},
+		{"frontmatter.priority:>3", []string{"projects/beta.md"}},
+		{"modified:<2025-01-01", []string{"journal/old.md"}},
+		{"modified:>=2025-01-01 path:journal", []string{"journal/today.md"}},
+	}
+
+	ns := &NotesServer{vaultDir: tempDir}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results := searchForTest(t, ns, SearchNotesRequest{Query: tt.query})
+
+			paths := make(map[string]bool)
+			for _, result := range results {
+				paths[filepath.ToSlash(result.Path)] = true
+			}
+
+			var got []string
+			for path := range paths {
+				got = append(got, path)
+			}
+			sort.Strings(got)
+
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Query %q: expected %v, got %v", tt.query, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSearchNotes_InvalidQueryReturnsStructuredError(t *testing.T) {
+	ns := &NotesServer{vaultDir: t.TempDir()}
+
+	result, err := ns.SearchNotes(context.Background(), mcp.CallToolRequest{}, SearchNotesRequest{Query: "re:/(unclosed/"})
+	if err != nil {
+		t.Fatalf("Expected MCP error, not Go error: %v", err)
+	}
+
+	if !result.IsError {
+		t.Fatal("Expected IsError for invalid query")
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &payload); err != nil {
+		t.Fatalf("Expected JSON error payload: %v", err)
+	}
+
+	if payload["error"] != "invalid query" {
+		t.Errorf("Unexpected error payload: %v", payload)
+	}
+
+	if _, ok := payload["position"]; !ok {
+		t.Error("Error payload should include the position")
+	}
+}
@@ -16,7 +16,7 @@ type ReadNoteRequest struct {
 func (ns *NotesServer) NewReadNoteTool() {
 	tool := mcp.NewTool(
 		"read_note",
-		mcp.WithDescription("Read the contents of the note from the file location"),
+		mcp.WithDescription("Read the contents of the note from the file location. The note content is followed by a second text item with its content hash, to pass as expected_hash when writing the note back"),
 		mcp.WithString("path", mcp.Description("Path to the note file"), mcp.Required()),
 	)
 
@@ -53,9 +53,14 @@ func (ns *NotesServer) ReadNote(ctx context.Context, req mcp.CallToolRequest, pa
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
+	// The content hash follows the note so callers can pass it back as
+	// expected_hash when they write the note. It is also in _meta, which not
+	// every client shows to the model.
 	return &mcp.CallToolResult{
+		Result: hashMeta(content),
 		Content: []mcp.Content{
 			mcp.NewTextContent(string(content)),
+			mcp.NewTextContent(fmt.Sprintf("hash: %s", contentHash(content))),
 		},
 	}, nil
 }
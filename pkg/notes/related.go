@@ -0,0 +1,251 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// defaultRelatedLimit is the number of related notes returned by default
+	defaultRelatedLimit = 10
+
+	// relatedQueryTerms is how many of the source's most distinctive terms
+	// are used to find related notes
+	relatedQueryTerms = 25
+
+	// relatedSharedTerms is how many shared terms are reported per note
+	relatedSharedTerms = 5
+)
+
+// relatedStopWords are common English words that say nothing about what a
+// note is about
+var relatedStopWords = map[string]bool{
+	"about": true, "after": true, "all": true, "also": true, "and": true, "any": true, "are": true,
+	"because": true, "been": true, "before": true, "but": true, "can": true, "could": true,
+	"did": true, "does": true, "for": true, "from": true, "had": true, "has": true, "have": true,
+	"her": true, "his": true, "how": true, "into": true, "its": true, "just": true, "more": true,
+	"not": true, "now": true, "one": true, "only": true, "other": true, "our": true, "out": true,
+	"should": true, "some": true, "than": true, "that": true, "the": true, "their": true,
+	"them": true, "then": true, "there": true, "these": true, "they": true, "this": true,
+	"was": true, "were": true, "what": true, "when": true, "which": true, "who": true,
+	"will": true, "with": true, "would": true, "you": true, "your": true,
+}
+
+// FindRelatedNotesRequest represents a request for notes similar to a note
+// or to a piece of text
+type FindRelatedNotesRequest struct {
+	Path   string `json:"path,omitempty" mcp:"Note to find related notes for"`
+	Text   string `json:"text,omitempty" mcp:"Free text to find related notes for, used when no path is given"`
+	Folder string `json:"folder,omitempty" mcp:"Only return notes under this folder"`
+	Limit  int    `json:"limit,omitempty" mcp:"Maximum number of notes to return (default 10)"`
+}
+
+// RelatedNote is a note similar to the source and the terms that matched
+type RelatedNote struct {
+	Path        string   `json:"path"`
+	Score       float64  `json:"score"`
+	SharedTerms []string `json:"shared_terms"`
+}
+
+// RelatedNotesResult is the response of find_related_notes
+type RelatedNotesResult struct {
+	Source string        `json:"source,omitempty"`
+	Terms  []string      `json:"terms"`
+	Count  int           `json:"count"`
+	Notes  []RelatedNote `json:"notes"`
+}
+
+// weightedTerm is a term of the source with its TF-IDF weight
+type weightedTerm struct {
+	term   string
+	weight float64
+}
+
+func (ns *NotesServer) NewFindRelatedNotesTool() {
+	tool := mcp.NewTool(
+		"find_related_notes",
+		mcp.WithDescription("Find the notes most similar to a note or to free text, scored with BM25 over the local search index, with the shared terms behind each match"),
+		mcp.WithString("path", mcp.Description("Note to find related notes for")),
+		mcp.WithString("text", mcp.Description("Free text to find related notes for, used when no path is given")),
+		mcp.WithString("folder", mcp.Description("Only return notes under this folder")),
+		mcp.WithNumber("limit", mcp.Description("Maximum number of notes to return (default 10)")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.FindRelatedNotes))
+}
+
+// FindRelatedNotes ranks the notes of the vault by their similarity to a note
+// or to free text. The most distinctive terms of the source, by TF-IDF, form
+// a weighted query that is scored with BM25 against the search index.
+func (ns *NotesServer) FindRelatedNotes(ctx context.Context, req mcp.CallToolRequest, params FindRelatedNotesRequest) (*mcp.CallToolResult, error) {
+	if params.Path == "" && strings.TrimSpace(params.Text) == "" {
+		return toolErrorResult("either path or text is required"), nil
+	}
+
+	source := ""
+	if params.Path != "" {
+		fullPath, err := utils.ValidatePath(ns.vaultDir, params.Path)
+		if err != nil {
+			return nil, err
+		}
+		source, _ = filepath.Rel(ns.vaultDir, fullPath)
+	}
+
+	scope := ""
+	if params.Folder != "" {
+		fullPath, err := utils.ValidatePath(ns.vaultDir, params.Folder)
+		if err != nil {
+			return nil, err
+		}
+		scope, _ = filepath.Rel(ns.vaultDir, fullPath)
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultRelatedLimit
+	}
+
+	result := RelatedNotesResult{Source: source, Terms: []string{}, Notes: []RelatedNote{}}
+	missing := false
+	err := ns.withIndex(func(idx *searchIndex) error {
+		var frequencies map[string]int
+		if source != "" {
+			doc, exists := idx.Docs[source]
+			if !exists {
+				missing = true
+				return nil
+			}
+			frequencies = doc.Terms
+		} else {
+			frequencies = make(map[string]int)
+			for _, term := range tokenize(params.Text) {
+				frequencies[term]++
+			}
+		}
+
+		terms := idx.distinctiveTerms(frequencies, source, relatedQueryTerms)
+		for _, term := range terms {
+			result.Terms = append(result.Terms, term.term)
+		}
+
+		inScope := func(path string) bool {
+			return scope == "" || scope == "." || strings.HasPrefix(path, scope+string(filepath.Separator))
+		}
+		result.Notes = idx.related(terms, func(path string) bool {
+			return path != source && inScope(path)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find related notes: %w", err)
+	}
+	if missing {
+		return toolErrorResult("note not found: %s", params.Path), nil
+	}
+
+	if len(result.Notes) > limit {
+		result.Notes = result.Notes[:limit]
+	}
+	result.Count = len(result.Notes)
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// distinctiveTerms returns up to limit terms of a document with the highest
+// TF-IDF weight. Stop words, numbers and terms shorter than three letters are
+// skipped, as are terms that no indexed note other than source contains.
+func (idx *searchIndex) distinctiveTerms(frequencies map[string]int, source string, limit int) []weightedTerm {
+	var terms []weightedTerm
+	for term, freq := range frequencies {
+		if utf8.RuneCountInString(term) < 3 || relatedStopWords[term] || isNumber(term) {
+			continue
+		}
+		others := len(idx.postings[term])
+		if _, ok := idx.postings[term][source]; ok {
+			others--
+		}
+		if others == 0 {
+			continue
+		}
+		terms = append(terms, weightedTerm{term: term, weight: (1 + math.Log(float64(freq))) * idx.idf(term)})
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].weight != terms[j].weight {
+			return terms[i].weight > terms[j].weight
+		}
+		return terms[i].term < terms[j].term
+	})
+	if len(terms) > limit {
+		terms = terms[:limit]
+	}
+	return terms
+}
+
+// related scores every accepted document containing one of the terms with
+// BM25, weighting each term by its weight in the source
+func (idx *searchIndex) related(terms []weightedTerm, accept func(path string) bool) []RelatedNote {
+	type contribution struct {
+		term  string
+		score float64
+	}
+	contributions := make(map[string][]contribution)
+
+	for _, term := range terms {
+		for path := range idx.postings[term.term] {
+			if !accept(path) {
+				continue
+			}
+			score := term.weight * idx.bm25(path, []string{term.term})
+			contributions[path] = append(contributions[path], contribution{term.term, score})
+		}
+	}
+
+	notes := []RelatedNote{}
+	for path, parts := range contributions {
+		sort.Slice(parts, func(i, j int) bool {
+			if parts[i].score != parts[j].score {
+				return parts[i].score > parts[j].score
+			}
+			return parts[i].term < parts[j].term
+		})
+
+		note := RelatedNote{Path: path}
+		for i, part := range parts {
+			note.Score += part.score
+			if i < relatedSharedTerms {
+				note.SharedTerms = append(note.SharedTerms, part.term)
+			}
+		}
+		note.Score = math.Round(note.Score*1000) / 1000
+		notes = append(notes, note)
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].Score != notes[j].Score {
+			return notes[i].Score > notes[j].Score
+		}
+		return notes[i].Path < notes[j].Path
+	})
+	return notes
+}
+
+// isNumber reports whether a term is made of digits only
+func isNumber(term string) bool {
+	return strings.Trim(term, "0123456789") == ""
+}
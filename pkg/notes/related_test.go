@@ -0,0 +1,98 @@
+package notes
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var relatedVaultNotes = map[string]string{
+	"Garden/tomatoes.md": "# Tomatoes\n\nTomato seedlings need compost, sunlight and regular watering. Tomato blight is a risk.\n",
+	"Garden/compost.md":  "# Compost\n\nCompost feeds tomato seedlings. Turn the compost pile weekly and keep the pile moist.\n",
+	"Garden/peppers.md":  "# Peppers\n\nPepper seedlings like sunlight and the same compost as the tomatoes.\n",
+	"Work/roadmap.md":    "# Roadmap\n\nThe roadmap covers the release, the budget and hiring for the team.\n",
+	"Work/budget.md":     "# Budget\n\nThe budget for the release and the hiring plan for the team.\n",
+}
+
+func findRelatedForTest(t *testing.T, ns *NotesServer, params FindRelatedNotesRequest) RelatedNotesResult {
+	t.Helper()
+
+	var result RelatedNotesResult
+	response, err := ns.FindRelatedNotes(context.Background(), mcp.CallToolRequest{}, params)
+	decodeToolResult(t, response, err, &result)
+	if result.Count != len(result.Notes) {
+		t.Errorf("Count %d does not match %d notes", result.Count, len(result.Notes))
+	}
+	return result
+}
+
+func relatedPaths(notes []RelatedNote) []string {
+	var paths []string
+	for _, note := range notes {
+		paths = append(paths, note.Path)
+	}
+	return paths
+}
+
+func TestFindRelatedNotes_ByPath(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, relatedVaultNotes)}
+
+	result := findRelatedForTest(t, ns, FindRelatedNotesRequest{Path: "Garden/tomatoes.md"})
+	paths := relatedPaths(result.Notes)
+	if len(paths) < 2 || paths[0] != "Garden/compost.md" && paths[0] != "Garden/peppers.md" {
+		t.Fatalf("Expected the garden notes first, got %v", paths)
+	}
+	if slices.Contains(paths, "Garden/tomatoes.md") {
+		t.Error("The source note must not be returned")
+	}
+	if slices.Contains(paths, "Work/roadmap.md") || slices.Contains(paths, "Work/budget.md") {
+		t.Errorf("Unrelated notes were returned: %v", paths)
+	}
+
+	for _, note := range result.Notes {
+		if note.Score <= 0 || len(note.SharedTerms) == 0 {
+			t.Errorf("Expected a score and shared terms, got %+v", note)
+		}
+	}
+	if !slices.Contains(result.Notes[0].SharedTerms, "seedlings") && !slices.Contains(result.Notes[0].SharedTerms, "compost") {
+		t.Errorf("Unexpected shared terms: %v", result.Notes[0].SharedTerms)
+	}
+
+	// Stop words and terms only the source contains are not used
+	for _, term := range result.Terms {
+		if term == "the" || term == "blight" || term == "and" {
+			t.Errorf("Unexpected query term %q in %v", term, result.Terms)
+		}
+	}
+}
+
+func TestFindRelatedNotes_ByText(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, relatedVaultNotes)}
+
+	result := findRelatedForTest(t, ns, FindRelatedNotesRequest{Text: "hiring budget for next year", Limit: 1})
+	if paths := relatedPaths(result.Notes); len(paths) != 1 || paths[0] != "Work/budget.md" {
+		t.Errorf("Expected the budget note, got %v", paths)
+	}
+
+	result = findRelatedForTest(t, ns, FindRelatedNotesRequest{Text: "compost", Folder: "Work"})
+	if len(result.Notes) != 0 {
+		t.Errorf("Expected no notes outside the folder, got %v", relatedPaths(result.Notes))
+	}
+}
+
+func TestFindRelatedNotes_Errors(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, relatedVaultNotes)}
+
+	for _, params := range []FindRelatedNotesRequest{
+		{},
+		{Text: "   "},
+		{Path: "Garden/missing.md"},
+	} {
+		result, err := ns.FindRelatedNotes(context.Background(), mcp.CallToolRequest{}, params)
+		if err != nil || !result.IsError {
+			t.Errorf("Expected an error for %+v, got %v %v", params, result, err)
+		}
+	}
+}
@@ -0,0 +1,496 @@
+package notes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// templateDateLayout is how dates produced by the template helpers print
+const templateDateLayout = "2006-01-02"
+
+// legacyPlaceholderRegex matches {{NAME}} placeholders written before
+// templates were rendered with text/template
+var legacyPlaceholderRegex = regexp.MustCompile(`\{\{(-?\s*)([A-Za-z_][A-Za-z0-9_]*)(\s*-?)\}\}`)
+
+// obsidianDateRegex matches the {{date}}, {{time}}, {{date:FORMAT}} and
+// {{time:FORMAT}} placeholders of Obsidian core templates
+var obsidianDateRegex = regexp.MustCompile(`\{\{\s*(date|time)(?::([^{}]*?))?\s*\}\}`)
+
+// obsidianDefaultFormats are the formats {{date}} and {{time}} print with
+var obsidianDefaultFormats = map[string]string{"date": "YYYY-MM-DD", "time": "HH:mm"}
+
+// momentTokens maps the Moment.js format tokens used by Obsidian templates to
+// Go layouts, longest token first
+var momentTokens = []struct{ token, layout string }{
+	{"YYYY", "2006"}, {"YY", "06"},
+	{"MMMM", "January"}, {"MMM", "Jan"}, {"MM", "01"}, {"M", "1"},
+	{"DDDD", "002"}, {"DD", "02"}, {"D", "2"},
+	{"dddd", "Monday"}, {"ddd", "Mon"},
+	{"HH", "15"}, {"H", "15"}, {"hh", "03"}, {"h", "3"},
+	{"mm", "04"}, {"m", "4"}, {"ss", "05"}, {"s", "5"},
+	{"A", "PM"}, {"a", "pm"}, {"ZZ", "-0700"}, {"Z", "-07:00"},
+}
+
+// templateKeywords are the identifiers text/template treats as actions
+var templateKeywords = map[string]bool{
+	"if": true, "else": true, "end": true, "range": true, "with": true, "define": true,
+	"template": true, "block": true, "break": true, "continue": true, "nil": true,
+	"true": true, "false": true,
+}
+
+// MissingVariablesError reports required template variables that were not given
+type MissingVariablesError struct {
+	Template string
+	Missing  []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return fmt.Sprintf("template %s is missing required variables: %s", e.Template, strings.Join(e.Missing, ", "))
+}
+
+// missingVariablesResult converts a MissingVariablesError into an MCP tool
+// error with a JSON payload listing the missing variables
+func missingVariablesResult(err *MissingVariablesError) *mcp.CallToolResult {
+	payload := map[string]interface{}{
+		"error":    "missing_variables",
+		"message":  err.Error(),
+		"template": err.Template,
+		"missing":  err.Missing,
+	}
+	payloadJSON, _ := json.MarshalIndent(payload, "", "  ")
+
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(payloadJSON)),
+		},
+	}
+}
+
+// templateDate is a date exposed to templates. It prints as YYYY-MM-DD and
+// keeps the time.Time methods, e.g. {{today.Format "Monday"}}.
+type templateDate struct {
+	time.Time
+}
+
+func (d templateDate) String() string {
+	return d.Format(templateDateLayout)
+}
+
+// renderedTemplate is the output of renderTemplate
+type renderedTemplate struct {
+	Content string
+
+	// Unfilled lists variables the template prints that were neither
+	// declared nor given, and so rendered empty
+	Unfilled []string
+}
+
+// renderTemplate renders a note template with text/template. Declared
+// variables take their default when not given, and missing required
+// variables are reported as a *MissingVariablesError. Legacy {{NAME}}
+// placeholders are treated as {{.NAME}}, and the {{date}} and {{time}}
+// placeholders of Obsidian core templates print the current date and time.
+func renderTemplate(key string, tmpl NoteTemplate, variables map[string]interface{}, now time.Time) (*renderedTemplate, error) {
+	funcs := templateFuncs(now)
+
+	data := make(map[string]interface{}, len(variables))
+	names := make(map[string]bool, len(variables)+len(tmpl.Variables))
+	for name, value := range variables {
+		data[name] = value
+		names[name] = true
+	}
+	for _, variable := range tmpl.Variables {
+		names[variable.Name] = true
+	}
+
+	text := convertLegacyPlaceholders(tmpl.Content, funcs, names)
+	parsed, err := template.New(key).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", key, err)
+	}
+
+	refs := templateRefs{fields: map[string]bool{}, printed: map[string]bool{}, ranged: map[string]bool{}}
+	if parsed.Tree != nil {
+		refs.collect(parsed.Tree.Root, true, false)
+	}
+
+	// Variables without a value render empty rather than as "<no value>",
+	// and as an empty list when the template ranges over them
+	empty := func(name string) interface{} {
+		if refs.ranged[name] {
+			return nil
+		}
+		return ""
+	}
+
+	var missing []string
+	for _, variable := range tmpl.Variables {
+		if value, ok := data[variable.Name]; ok && value != nil && value != "" {
+			continue
+		}
+
+		switch {
+		case variable.Default != "":
+			value, err := executeTemplate("default of "+variable.Name, variable.Default, funcs, nil)
+			if err != nil {
+				return nil, err
+			}
+			data[variable.Name] = value
+		case variable.Required:
+			missing = append(missing, variable.Name)
+		default:
+			data[variable.Name] = empty(variable.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, &MissingVariablesError{Template: key, Missing: missing}
+	}
+
+	rendered := &renderedTemplate{}
+	for name := range refs.fields {
+		if _, ok := data[name]; !ok {
+			data[name] = empty(name)
+			if refs.printed[name] {
+				rendered.Unfilled = append(rendered.Unfilled, name)
+			}
+		}
+	}
+	sort.Strings(rendered.Unfilled)
+
+	var b bytes.Buffer
+	if err := parsed.Execute(&b, data); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", key, err)
+	}
+	rendered.Content = b.String()
+
+	return rendered, nil
+}
+
+// executeTemplate renders a short template text
+func executeTemplate(name, text string, funcs template.FuncMap, data interface{}) (string, error) {
+	parsed, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %s: %w", name, err)
+	}
+
+	var b bytes.Buffer
+	if err := parsed.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+	return b.String(), nil
+}
+
+// convertLegacyPlaceholders rewrites {{NAME}} to {{.NAME}} unless NAME is a
+// template keyword or a helper function that is not also a variable, and
+// rewrites Obsidian {{date:FORMAT}} placeholders into date helper calls. A
+// date or time variable takes precedence over the current time.
+func convertLegacyPlaceholders(text string, funcs template.FuncMap, variables map[string]bool) string {
+	text = obsidianDateRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := obsidianDateRegex.FindStringSubmatch(match)
+		date := "now"
+		if variables[m[1]] {
+			if m[2] == "" {
+				return match
+			}
+			date = "." + m[1]
+		}
+
+		format := m[2]
+		if format == "" {
+			format = obsidianDefaultFormats[m[1]]
+		}
+		return momentTemplate(format, date)
+	})
+
+	return legacyPlaceholderRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := legacyPlaceholderRegex.FindStringSubmatch(match)
+		if templateKeywords[m[2]] || (funcs[m[2]] != nil && !variables[m[2]]) {
+			return match
+		}
+		return "{{" + m[1] + "." + m[2] + m[3] + "}}"
+	})
+}
+
+// momentTemplate converts a Moment.js date format into template text that
+// prints date with it. Go layouts cannot escape literal text, so [bracketed]
+// text and letters that are not tokens are written outside the actions.
+func momentTemplate(format, date string) string {
+	var b, layout strings.Builder
+	flush := func() {
+		if layout.Len() > 0 {
+			fmt.Fprintf(&b, "{{date %s %s}}", strconv.Quote(layout.String()), date)
+			layout.Reset()
+		}
+	}
+
+	for rest := format; rest != ""; {
+		if rest[0] == '[' {
+			if end := strings.IndexByte(rest, ']'); end > 0 {
+				flush()
+				b.WriteString(rest[1:end])
+				rest = rest[end+1:]
+				continue
+			}
+		}
+
+		matched := false
+		for _, token := range momentTokens {
+			if strings.HasPrefix(rest, token.token) {
+				layout.WriteString(token.layout)
+				rest = rest[len(token.token):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			flush()
+			b.WriteRune(r)
+		} else {
+			layout.WriteRune(r)
+		}
+		rest = rest[size:]
+	}
+	flush()
+
+	return b.String()
+}
+
+// withNoteTitle adds the title variable of Obsidian core templates, the file
+// name of the note without its extension, unless one was given
+func withNoteTitle(variables map[string]interface{}, path string) map[string]interface{} {
+	if _, ok := variables["title"]; ok {
+		return variables
+	}
+
+	withTitle := make(map[string]interface{}, len(variables)+1)
+	for name, value := range variables {
+		withTitle[name] = value
+	}
+	withTitle["title"] = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return withTitle
+}
+
+// templateRefs are the top-level variables a template refers to
+type templateRefs struct {
+	fields map[string]bool
+
+	// printed holds variables output directly by an action and ranged the
+	// variables a range loops over
+	printed map[string]bool
+	ranged  map[string]bool
+}
+
+// collect walks a template parse tree. Inside range and with the dot is no
+// longer the variables map, so only $.name references count there.
+func (r *templateRefs) collect(node parse.Node, rootDot, inAction bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			r.collect(child, rootDot, false)
+		}
+	case *parse.ActionNode:
+		r.collect(n.Pipe, rootDot, true)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				r.collect(arg, rootDot, inAction)
+			}
+		}
+	case *parse.FieldNode:
+		if rootDot {
+			r.add(n.Ident[0], inAction)
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			r.add(n.Ident[1], inAction)
+		}
+	case *parse.ChainNode:
+		r.collect(n.Node, rootDot, inAction)
+	case *parse.IfNode:
+		r.collect(n.Pipe, rootDot, false)
+		r.collect(n.List, rootDot, false)
+		r.collect(n.ElseList, rootDot, false)
+	case *parse.RangeNode:
+		if cmds := n.Pipe.Cmds; rootDot && len(cmds) == 1 && len(cmds[0].Args) == 1 {
+			if field, ok := cmds[0].Args[0].(*parse.FieldNode); ok {
+				r.ranged[field.Ident[0]] = true
+			}
+		}
+		r.collect(n.Pipe, rootDot, false)
+		r.collect(n.List, false, false)
+		r.collect(n.ElseList, rootDot, false)
+	case *parse.WithNode:
+		r.collect(n.Pipe, rootDot, false)
+		r.collect(n.List, false, false)
+		r.collect(n.ElseList, rootDot, false)
+	case *parse.TemplateNode:
+		r.collect(n.Pipe, rootDot, false)
+	}
+}
+
+func (r *templateRefs) add(name string, printed bool) {
+	r.fields[name] = true
+	if printed {
+		r.printed[name] = true
+	}
+}
+
+// templateFuncs returns the helper functions available to templates
+func templateFuncs(now time.Time) template.FuncMap {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	return template.FuncMap{
+		"now":   func() templateDate { return templateDate{now} },
+		"today": func() templateDate { return templateDate{today} },
+		"addDays": func(days int, date interface{}) (templateDate, error) {
+			t, err := toTemplateDate(date)
+			return templateDate{t.AddDate(0, 0, days)}, err
+		},
+		"addMonths": func(months int, date interface{}) (templateDate, error) {
+			t, err := toTemplateDate(date)
+			return templateDate{t.AddDate(0, months, 0)}, err
+		},
+		"weekStart": func(date interface{}) (templateDate, error) {
+			t, err := toTemplateDate(date)
+			offset := (int(t.Weekday()) + 6) % 7
+			return templateDate{t.AddDate(0, 0, -offset)}, err
+		},
+		"quarter": func(date interface{}) (int, error) {
+			t, err := toTemplateDate(date)
+			return (int(t.Month())-1)/3 + 1, err
+		},
+		"date": func(layout string, date interface{}) (string, error) {
+			t, err := toTemplateDate(date)
+			return t.Format(layout), err
+		},
+		"default": func(fallback, value interface{}) interface{} {
+			if value == nil || value == "" {
+				return fallback
+			}
+			return value
+		},
+		"list": templateList,
+		"join": func(value interface{}, separator string) string {
+			if text, ok := value.(string); ok {
+				return text
+			}
+			var parts []string
+			for _, item := range templateList(value) {
+				parts = append(parts, fmt.Sprint(item))
+			}
+			return strings.Join(parts, separator)
+		},
+		"add":   templateAdd,
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"trim":  strings.TrimSpace,
+	}
+}
+
+// templateAdd adds two numbers. Variables decoded from JSON are float64, so
+// any numeric type is accepted; whole numbers add up to an int.
+func templateAdd(a, b interface{}) (interface{}, error) {
+	x, err := toTemplateNumber(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := toTemplateNumber(b)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := x + y
+	if sum == math.Trunc(sum) && math.Abs(sum) < 1<<53 {
+		return int(sum), nil
+	}
+	return sum, nil
+}
+
+// toTemplateNumber converts a template value into a number
+func toTemplateNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case json.Number:
+		return v.Float64()
+	default:
+		return 0, fmt.Errorf("invalid number %v", value)
+	}
+}
+
+// toTemplateDate converts a template value into a time
+func toTemplateDate(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case templateDate:
+		return v.Time, nil
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range []string{templateDateLayout, time.RFC3339, "2006-01-02 15:04"} {
+			if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", v)
+	default:
+		return time.Time{}, fmt.Errorf("invalid date %v", value)
+	}
+}
+
+// templateList turns a variable into a list for range: lists are returned as
+// is, text is split into lines and empty values give an empty list
+func templateList(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+		return list
+	case string:
+		var list []interface{}
+		for _, line := range strings.Split(v, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				list = append(list, line)
+			}
+		}
+		return list
+	default:
+		return []interface{}{v}
+	}
+}
@@ -0,0 +1,292 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// renderNowForTest is a Wednesday
+var renderNowForTest = time.Date(2025, 1, 15, 9, 30, 0, 0, time.UTC)
+
+func renderForTest(t *testing.T, content string, variables map[string]interface{}, declared ...TemplateVariable) *renderedTemplate {
+	t.Helper()
+
+	rendered, err := renderTemplate("test", NoteTemplate{Content: content, Variables: declared}, variables, renderNowForTest)
+	if err != nil {
+		t.Fatalf("renderTemplate failed: %v", err)
+	}
+	return rendered
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		variables map[string]interface{}
+		expected  string
+	}{
+		{
+			name:      "Legacy placeholders",
+			content:   "# {{TITLE}} - {{ team }}",
+			variables: map[string]interface{}{"TITLE": "Standup", "team": "Core"},
+			expected:  "# Standup - Core",
+		},
+		{
+			name:      "Conditionals",
+			content:   "{{if .urgent}}URGENT {{else}}normal {{end}}{{.title}}",
+			variables: map[string]interface{}{"urgent": true, "title": "Fix"},
+			expected:  "URGENT Fix",
+		},
+		{
+			name:      "Loop over list",
+			content:   "{{range .attendees}}- {{.}}\n{{end}}",
+			variables: map[string]interface{}{"attendees": []interface{}{"Alice", "Bob"}},
+			expected:  "- Alice\n- Bob\n",
+		},
+		{
+			name:      "List from text",
+			content:   "{{range list .items}}[{{.}}]{{end}} {{join .names \", \"}}",
+			variables: map[string]interface{}{"items": "a\n\nb", "names": []interface{}{"x", "y"}},
+			expected:  "[a][b] x, y",
+		},
+		{
+			name:     "Date helpers",
+			content:  "{{today}} {{today | addDays 7}} {{weekStart today}} {{addMonths 1 today}} {{now | date \"15:04\"}}",
+			expected: "2025-01-15 2025-01-22 2025-01-13 2025-02-15 09:30",
+		},
+		{
+			name:      "Date helpers on variables",
+			content:   "{{addDays -1 .due}} {{(weekStart .due).Format \"Mon Jan 2\"}}",
+			variables: map[string]interface{}{"due": "2025-03-02"},
+			expected:  "2025-03-01 Mon Feb 24",
+		},
+		{
+			name:      "Add helper on numbers from JSON",
+			content:   "{{add .count 1}} {{add .ratio 1}} {{add .total .count}} {{add 2 3}}",
+			variables: map[string]interface{}{"count": float64(3), "ratio": 0.5, "total": json.Number("10")},
+			expected:  "4 1.5 13 5",
+		},
+		{
+			name:     "Default helper",
+			content:  "{{.owner | default \"nobody\"}}",
+			expected: "nobody",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderForTest(t, tt.content, tt.variables).Content; got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate_DeclaredVariables(t *testing.T) {
+	declared := []TemplateVariable{
+		{Name: "title", Required: true},
+		{Name: "date", Default: "{{today | addDays 1}}"},
+		{Name: "notes"},
+	}
+
+	rendered := renderForTest(t, "{{.title}} {{.date}} [{{.notes}}] {{.extra}}", map[string]interface{}{"title": "Plan"}, declared...)
+	if rendered.Content != "Plan 2025-01-16 [] " {
+		t.Errorf("Unexpected content: %q", rendered.Content)
+	}
+
+	// Undeclared variables without a value are reported, declared ones are not
+	if len(rendered.Unfilled) != 1 || rendered.Unfilled[0] != "extra" {
+		t.Errorf("Expected extra to be reported as unfilled, got %v", rendered.Unfilled)
+	}
+
+	// Variables only used inside range or with refer to the element, not the variables
+	rendered = renderForTest(t, "{{range .items}}{{.name}}{{end}}{{with .owner}}{{.}}{{end}}", nil)
+	if len(rendered.Unfilled) != 0 {
+		t.Errorf("Expected no unfilled variables, got %v", rendered.Unfilled)
+	}
+}
+
+func TestRenderTemplate_MissingRequired(t *testing.T) {
+	declared := []TemplateVariable{
+		{Name: "title", Required: true},
+		{Name: "owner", Required: true},
+		{Name: "team", Required: true, Default: "Core"},
+	}
+
+	_, err := renderTemplate("standup", NoteTemplate{Content: "x", Variables: declared}, map[string]interface{}{"owner": ""}, renderNowForTest)
+
+	var missing *MissingVariablesError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected MissingVariablesError, got %v", err)
+	}
+	if missing.Template != "standup" || strings.Join(missing.Missing, ",") != "title,owner" {
+		t.Errorf("Unexpected missing variables: %+v", missing)
+	}
+}
+
+func TestCreateNoteFromTemplate_AddVariable(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+
+	writeNoteForTest(t, filepath.Join(tempDir, defaultTemplatesFolder), "sprint.md", "# Sprint {{add .SPRINT 1}}\n")
+
+	var request CreateFromTemplateRequest
+	if err := json.Unmarshal([]byte(`{"path": "sprint.md", "template_type": "sprint", "variables": {"SPRINT": 41}}`), &request); err != nil {
+		t.Fatalf("Failed to decode request: %v", err)
+	}
+
+	result, err := ns.CreateNoteFromTemplate(context.Background(), mcp.CallToolRequest{}, request)
+	if err != nil || result.IsError {
+		t.Fatalf("CreateNoteFromTemplate failed: %v %v", err, result)
+	}
+	if got := readForTest(t, tempDir, "sprint.md"); got != "# Sprint 42\n" {
+		t.Errorf("Unexpected note content: %q", got)
+	}
+}
+
+func TestRenderTemplate_Errors(t *testing.T) {
+	if _, err := renderTemplate("bad", NoteTemplate{Content: "{{if .x}}"}, nil, renderNowForTest); err == nil {
+		t.Error("Expected a parse error")
+	}
+
+	if _, err := renderTemplate("bad", NoteTemplate{Content: "{{addDays 1 .due}}"}, map[string]interface{}{"due": "soon"}, renderNowForTest); err == nil {
+		t.Error("Expected an invalid date error")
+	}
+}
+
+func TestBuiltinTemplates_Render(t *testing.T) {
+	ns := &NotesServer{}
+
+	for key, template := range ns.getBuiltinTemplates() {
+		rendered, err := renderTemplate(key, template, nil, renderNowForTest)
+		if err != nil {
+			t.Errorf("Template %s failed to render: %v", key, err)
+			continue
+		}
+		if strings.Contains(rendered.Content, "{{") || strings.Contains(rendered.Content, "<no value>") {
+			t.Errorf("Template %s left placeholders behind:\n%s", key, rendered.Content)
+		}
+		if len(rendered.Unfilled) != 0 {
+			t.Errorf("Template %s uses undeclared variables %v", key, rendered.Unfilled)
+		}
+	}
+
+	rendered, err := renderTemplate("meeting", ns.getBuiltinTemplates()["meeting"], map[string]interface{}{
+		"TITLE":     "Sync",
+		"ATTENDEES": []interface{}{"Alice", "Bob"},
+		"AGENDA":    []interface{}{"Roadmap", "Hiring"},
+	}, renderNowForTest)
+	if err != nil {
+		t.Fatalf("Meeting template failed to render: %v", err)
+	}
+	for _, expected := range []string{"# Meeting Notes - Sync", "**Date:** 2025-01-15", "**Attendees:** Alice, Bob", "1. Roadmap\n2. Hiring\n"} {
+		if !strings.Contains(rendered.Content, expected) {
+			t.Errorf("Expected %q in meeting note:\n%s", expected, rendered.Content)
+		}
+	}
+}
+
+func TestCreateNoteFromTemplate_MissingRequiredVariables(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+
+	writeNoteForTest(t, filepath.Join(tempDir, defaultTemplatesFolder), "standup.md", standupTemplate)
+
+	result, err := ns.CreateNoteFromTemplate(context.Background(), mcp.CallToolRequest{}, CreateFromTemplateRequest{Path: "standup.md", TemplateType: "standup"})
+	if err != nil || !result.IsError {
+		t.Fatalf("Expected a missing variables error, got %v %v", result, err)
+	}
+
+	var payload struct {
+		Error   string   `json:"error"`
+		Missing []string `json:"missing"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &payload); err != nil {
+		t.Fatalf("Expected JSON error payload: %v", err)
+	}
+	if payload.Error != "missing_variables" || len(payload.Missing) != 1 || payload.Missing[0] != "team" {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+}
+
+func TestCreateNoteFromTemplate_WarnsAboutUnfilledVariables(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+
+	writeNoteForTest(t, filepath.Join(tempDir, defaultTemplatesFolder), "plain.md", "# {{TITLE}}\n\nWith {{ATTENDEES}}\n")
+
+	result, err := ns.CreateNoteFromTemplate(context.Background(), mcp.CallToolRequest{}, CreateFromTemplateRequest{
+		Path:         "plain.md",
+		TemplateType: "plain",
+		Variables:    map[string]interface{}{"TITLE": "Review"},
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("CreateNoteFromTemplate failed: %v %v", err, result)
+	}
+
+	if len(result.Content) != 2 || !strings.Contains(result.Content[1].(mcp.TextContent).Text, "ATTENDEES") {
+		t.Errorf("Expected a warning about ATTENDEES, got %+v", result.Content)
+	}
+	if got := readForTest(t, tempDir, "plain.md"); got != "# Review\n\nWith \n" {
+		t.Errorf("Unexpected note content: %q", got)
+	}
+}
+
+func TestRenderTemplate_ObsidianCoreSyntax(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		variables map[string]interface{}
+		expected  string
+	}{
+		{
+			name:      "Title, date and time",
+			content:   "# {{title}}\nCreated {{date}} {{ time }}",
+			variables: map[string]interface{}{"title": "Plan"},
+			expected:  "# Plan\nCreated 2025-01-15 09:30",
+		},
+		{
+			name:     "Date formats",
+			content:  "{{date:YYYY}} {{date:dddd, MMMM D}} {{time:h:mm A}} {{date:[Week of] MMM D}}",
+			expected: "2025 Wednesday, January 15 9:30 AM Week of Jan 15",
+		},
+		{
+			name:      "Date variable",
+			content:   "{{date}} {{date:DD/MM/YYYY}}",
+			variables: map[string]interface{}{"date": "2025-03-02"},
+			expected:  "2025-03-02 02/03/2025",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderForTest(t, tt.content, tt.variables).Content; got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCreateNoteFromTemplate_ObsidianTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+
+	writeNoteForTest(t, filepath.Join(tempDir, defaultTemplatesFolder), "note.md", "# {{title}}\nCreated {{date}} {{time}}\n")
+
+	result, err := ns.CreateNoteFromTemplate(context.Background(), mcp.CallToolRequest{}, CreateFromTemplateRequest{Path: "Projects/Launch plan.md", TemplateType: "note"})
+	if err != nil || result.IsError {
+		t.Fatalf("CreateNoteFromTemplate failed: %v %v", err, result)
+	}
+
+	got := readForTest(t, tempDir, "Projects/Launch plan.md")
+	if !strings.HasPrefix(got, "# Launch plan\nCreated "+time.Now().Format(templateDateLayout)) {
+		t.Errorf("Unexpected note content: %q", got)
+	}
+}
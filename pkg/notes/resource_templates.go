@@ -0,0 +1,262 @@
+package notes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// noteResourceTemplate serves a single file of the vault. The reserved
+	// expansion lets the path span folders.
+	noteResourceTemplate = "notes://files/{+path}"
+
+	// templateResourceTemplate serves the body of a single note template
+	templateResourceTemplate = "notes://templates/{name}"
+
+	// tagResourceTemplate serves the tags equal to or nested below a prefix
+	tagResourceTemplate = "notes://collections/tags/{+prefix}"
+
+	methodCompletionComplete = "completion/complete"
+
+	// maxCompletions is the most values a completion response may hold
+	maxCompletions = 100
+
+	markdownMIMEType = "text/markdown"
+)
+
+// metaResourceContents is text resource contents with metadata in _meta,
+// which the MCP library's contents types do not carry
+type metaResourceContents struct {
+	mcp.TextResourceContents
+	Meta map[string]any `json:"_meta,omitempty"`
+}
+
+// completionRequest is a completion/complete request from the client
+type completionRequest struct {
+	ID     *mcp.RequestId `json:"id"`
+	Method string         `json:"method"`
+	Params struct {
+		Ref      mcp.ResourceReference `json:"ref"`
+		Argument struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"argument"`
+	} `json:"params"`
+}
+
+func (ns *NotesServer) addResourceTemplates() {
+	noteTemplate := mcp.NewResourceTemplate(
+		noteResourceTemplate,
+		"Note File",
+		mcp.WithTemplateDescription("A single note or attachment in the vault. Notes are returned as markdown with their frontmatter, tags, size, modification time and hash in _meta"),
+		mcp.WithTemplateMIMEType(markdownMIMEType),
+	)
+	ns.McpServer.AddResourceTemplate(noteTemplate, ns.ReadNoteResource)
+
+	templateTemplate := mcp.NewResourceTemplate(
+		templateResourceTemplate,
+		"Note Template",
+		mcp.WithTemplateDescription("The body of a single note template, with its description and variables in _meta"),
+		mcp.WithTemplateMIMEType(markdownMIMEType),
+	)
+	ns.McpServer.AddResourceTemplate(templateTemplate, ns.ReadTemplateResource)
+
+	tagTemplate := mcp.NewResourceTemplate(
+		tagResourceTemplate,
+		"Tag Collection",
+		mcp.WithTemplateDescription("Tags equal to or nested below a prefix, such as project for #project/alpha, with the notes that use them"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+	ns.McpServer.AddResourceTemplate(tagTemplate, ns.ReadTagPrefix)
+}
+
+// ReadNoteResource serves notes://files/{+path}. Markdown notes are returned
+// as text with their frontmatter in _meta, other text files as text and
+// anything else as a base64 blob.
+func (ns *NotesServer) ReadNoteResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	path := resourceArgument(request, "path")
+	if path == "" {
+		return nil, fmt.Errorf("resource URI does not name a file: %s", request.Params.URI)
+	}
+
+	// Hidden folders hold the index, history and trash, not vault content
+	for _, part := range strings.Split(path, "/") {
+		if isHiddenDir(part) {
+			return nil, fmt.Errorf("file not found: %s", path)
+		}
+	}
+
+	fullPath, err := utils.ValidatePath(ns.vaultDir, filepath.FromSlash(path))
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := utils.Stat(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("path is a directory, not a file: %s", path)
+	}
+
+	content, err := utils.ReadFile(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	uri := request.Params.URI
+	if !isMarkdownFile(info.Name()) {
+		mimeType := mime.TypeByExtension(filepath.Ext(info.Name()))
+		if strings.HasPrefix(mimeType, "text/") && utf8.Valid(content) {
+			return []mcp.ResourceContents{
+				mcp.TextResourceContents{URI: uri, MIMEType: mimeType, Text: string(content)},
+			}, nil
+		}
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		return []mcp.ResourceContents{
+			mcp.BlobResourceContents{URI: uri, MIMEType: mimeType, Blob: base64.StdEncoding.EncodeToString(content)},
+		}, nil
+	}
+
+	meta := map[string]any{
+		"path":     filepath.ToSlash(path),
+		"size":     info.Size(),
+		"modified": info.ModTime().Format(time.RFC3339),
+		"hash":     contentHash(content),
+		"tags":     extractTags(string(content)),
+	}
+	fm, err := parseFrontmatter(string(content))
+	if err != nil {
+		meta["frontmatter_error"] = err.Error()
+	} else if len(fm.Fields) > 0 {
+		meta["frontmatter"] = fm.Fields
+	}
+
+	return []mcp.ResourceContents{
+		metaResourceContents{
+			TextResourceContents: mcp.TextResourceContents{URI: uri, MIMEType: markdownMIMEType, Text: string(content)},
+			Meta:                 meta,
+		},
+	}, nil
+}
+
+// ReadTemplateResource serves notes://templates/{name}
+func (ns *NotesServer) ReadTemplateResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	name := resourceArgument(request, "name")
+
+	template, exists := ns.getTemplates()[name]
+	if !exists {
+		return nil, fmt.Errorf("template not found: %s", name)
+	}
+
+	return []mcp.ResourceContents{
+		metaResourceContents{
+			TextResourceContents: mcp.TextResourceContents{URI: request.Params.URI, MIMEType: markdownMIMEType, Text: template.Content},
+			Meta: map[string]any{
+				"name":        template.Name,
+				"description": template.Description,
+				"use_case":    template.UseCase,
+				"variables":   template.Variables,
+				"source":      template.Source,
+			},
+		},
+	}, nil
+}
+
+// resourceArgument returns a variable matched from a resource template,
+// percent-decoded
+func resourceArgument(request mcp.ReadResourceRequest, name string) string {
+	var value string
+	switch v := request.Params.Arguments[name].(type) {
+	case string:
+		value = v
+	case []string:
+		value = strings.Join(v, "/")
+	}
+
+	if decoded, err := url.PathUnescape(value); err == nil {
+		return decoded
+	}
+	return value
+}
+
+// handleCompletion answers completion/complete requests for the path of
+// notes://files/{+path}, the name of notes://templates/{name} and the prefix
+// of notes://collections/tags/{+prefix}. It reports false for any other
+// message.
+func (ns *NotesServer) handleCompletion(line []byte) (mcp.JSONRPCMessage, bool) {
+	var request completionRequest
+	if err := json.Unmarshal(line, &request); err != nil || request.ID == nil || request.Method != methodCompletionComplete {
+		return nil, false
+	}
+
+	var candidates []string
+	ref, argument := request.Params.Ref, request.Params.Argument
+	switch {
+	case ref.URI == noteResourceTemplate && argument.Name == "path":
+		files, err := ns.vaultFiles()
+		if err != nil {
+			return mcp.NewJSONRPCError(*request.ID, mcp.INTERNAL_ERROR, err.Error(), nil), true
+		}
+		for _, file := range files {
+			candidates = append(candidates, filepath.ToSlash(file))
+		}
+	case ref.URI == templateResourceTemplate && argument.Name == "name":
+		for name := range ns.getTemplates() {
+			candidates = append(candidates, name)
+		}
+	case ref.URI == tagResourceTemplate && argument.Name == "prefix":
+		scan, err := ns.scanVault()
+		if err != nil {
+			return mcp.NewJSONRPCError(*request.ID, mcp.INTERNAL_ERROR, err.Error(), nil), true
+		}
+		seen := make(map[string]bool)
+		for _, note := range scan.notes {
+			for _, tag := range note.tags {
+				if !seen[tag] {
+					seen[tag] = true
+					candidates = append(candidates, tag)
+				}
+			}
+		}
+	}
+
+	var result mcp.CompleteResult
+	result.Completion.Values = completeValues(candidates, argument.Value)
+	result.Completion.Total = len(result.Completion.Values)
+	if result.Completion.Total > maxCompletions {
+		result.Completion.Values = result.Completion.Values[:maxCompletions]
+		result.Completion.HasMore = true
+	}
+
+	return mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: *request.ID, Result: result}, true
+}
+
+// completeValues returns the candidates starting with prefix, ignoring case,
+// in sorted order
+func completeValues(candidates []string, prefix string) []string {
+	prefix = strings.ToLower(prefix)
+
+	values := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToLower(candidate), prefix) {
+			values = append(values, candidate)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,162 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readResourceForTest reads uri through the MCP server so the resource
+// templates are matched the way a client would see them
+func readResourceForTest(t *testing.T, ns *NotesServer, uri string) (map[string]any, map[string]any) {
+	t.Helper()
+
+	request := `{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"` + uri + `"}}`
+	data, err := json.Marshal(ns.McpServer.HandleMessage(context.Background(), json.RawMessage(request)))
+	if err != nil {
+		t.Fatalf("Failed to encode response: %v", err)
+	}
+
+	var response struct {
+		Result struct {
+			Contents []map[string]any `json:"contents"`
+		} `json:"result"`
+		Error map[string]any `json:"error"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		t.Fatalf("Invalid JSON returned: %v", err)
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+	if len(response.Result.Contents) != 1 {
+		t.Fatalf("Expected one contents item, got %s", data)
+	}
+	return response.Result.Contents[0], nil
+}
+
+func TestReadNoteResource(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := NewNotesServer(context.Background(), tempDir)
+
+	note := "---\ntitle: Alpha\ntags: [project]\n---\n# Alpha\n\nSee #review\n"
+	writeNoteForTest(t, tempDir, "Projects/Q1 plans/alpha.md", note)
+	writeNoteForTest(t, tempDir, "Projects/data.csv", "a,b\n1,2\n")
+	writeNoteForTest(t, tempDir, "Projects/image.png", "\x89PNG\x00")
+	writeNoteForTest(t, tempDir, ".sibyl/index.json", "{}")
+
+	contents, errResponse := readResourceForTest(t, ns, "notes://files/Projects/Q1%20plans/alpha.md")
+	if errResponse != nil {
+		t.Fatalf("Failed to read the note: %v", errResponse)
+	}
+	if contents["text"] != note || contents["mimeType"] != "text/markdown" {
+		t.Errorf("Unexpected contents: %v", contents)
+	}
+
+	meta, _ := contents["_meta"].(map[string]any)
+	frontmatter, _ := meta["frontmatter"].(map[string]any)
+	if frontmatter["title"] != "Alpha" || meta["path"] != "Projects/Q1 plans/alpha.md" || meta["hash"] != contentHash([]byte(note)) {
+		t.Errorf("Unexpected metadata: %v", meta)
+	}
+	if tags, _ := json.Marshal(meta["tags"]); string(tags) != `["project","review"]` {
+		t.Errorf("Unexpected tags: %s", tags)
+	}
+
+	if contents, _ := readResourceForTest(t, ns, "notes://files/Projects/data.csv"); contents["text"] != "a,b\n1,2\n" || !strings.HasPrefix(contents["mimeType"].(string), "text/csv") {
+		t.Errorf("Expected the CSV as text, got %v", contents)
+	}
+	if contents, _ := readResourceForTest(t, ns, "notes://files/Projects/image.png"); contents["mimeType"] != "image/png" || contents["blob"] == nil {
+		t.Errorf("Expected the image as a blob, got %v", contents)
+	}
+
+	for _, uri := range []string{
+		"notes://files/missing.md",
+		"notes://files/Projects",
+		"notes://files/.sibyl/index.json",
+		"notes://files/../outside.md",
+	} {
+		if _, errResponse := readResourceForTest(t, ns, uri); errResponse == nil {
+			t.Errorf("Expected an error reading %s", uri)
+		}
+	}
+
+	// The collection resource is still served by its own handler
+	if contents, errResponse := readResourceForTest(t, ns, "notes://files/"); errResponse != nil || contents["mimeType"] != "application/json" {
+		t.Errorf("Expected the note files listing, got %v %v", contents, errResponse)
+	}
+}
+
+func TestReadTemplateResource(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := NewNotesServer(context.Background(), tempDir)
+	writeNoteForTest(t, filepath.Join(tempDir, defaultTemplatesFolder), "standup.md", "---\ndescription: Team standup\n---\n# Standup\n")
+
+	contents, errResponse := readResourceForTest(t, ns, "notes://templates/standup")
+	if errResponse != nil {
+		t.Fatalf("Failed to read the template: %v", errResponse)
+	}
+	meta, _ := contents["_meta"].(map[string]any)
+	if contents["text"] != "# Standup\n" || meta["description"] != "Team standup" || meta["source"] != "Templates/standup.md" {
+		t.Errorf("Unexpected template contents: %v", contents)
+	}
+
+	if contents, _ := readResourceForTest(t, ns, "notes://templates/daily"); !strings.Contains(contents["text"].(string), "# Daily Note") {
+		t.Errorf("Expected the built-in daily template, got %v", contents)
+	}
+	if _, errResponse := readResourceForTest(t, ns, "notes://templates/missing"); errResponse == nil {
+		t.Error("Expected an error for a missing template")
+	}
+}
+
+func TestHandleCompletion(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	for _, name := range []string{"Projects/alpha.md", "Projects/beta.md", "projects.md", "Archive/old.md"} {
+		writeNoteForTest(t, tempDir, name, "# Note\n")
+	}
+
+	complete := func(ref, argument, value string) []string {
+		t.Helper()
+		line := `{"jsonrpc":"2.0","id":7,"method":"completion/complete","params":{"ref":{"type":"ref/resource","uri":"` + ref + `"},"argument":{"name":"` + argument + `","value":"` + value + `"}}}`
+		response, ok := ns.handleCompletion([]byte(line))
+		if !ok {
+			t.Fatalf("Expected %s to be handled", line)
+		}
+
+		data, _ := json.Marshal(response)
+		var decoded struct {
+			Result struct {
+				Completion struct {
+					Values []string `json:"values"`
+					Total  int      `json:"total"`
+				} `json:"completion"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Invalid JSON returned: %v", err)
+		}
+		if decoded.Result.Completion.Total != len(decoded.Result.Completion.Values) {
+			t.Errorf("Unexpected total in %s", data)
+		}
+		return decoded.Result.Completion.Values
+	}
+
+	if got := strings.Join(complete(noteResourceTemplate, "path", "proj"), ","); got != "Projects/alpha.md,Projects/beta.md,projects.md" {
+		t.Errorf("Unexpected path completions: %s", got)
+	}
+	if got := strings.Join(complete(noteResourceTemplate, "path", ""), ","); got != "Archive/old.md,Projects/alpha.md,Projects/beta.md,projects.md" {
+		t.Errorf("Unexpected path completions: %s", got)
+	}
+	if got := strings.Join(complete(templateResourceTemplate, "name", "da"), ","); got != "daily" {
+		t.Errorf("Unexpected template completions: %s", got)
+	}
+	if got := complete("notes://other/{x}", "x", ""); len(got) != 0 {
+		t.Errorf("Expected no completions for an unknown reference, got %v", got)
+	}
+
+	if _, ok := ns.handleCompletion([]byte(`{"jsonrpc":"2.0","id":8,"method":"ping"}`)); ok {
+		t.Error("Other requests must be passed on")
+	}
+}
@@ -3,8 +3,8 @@ package notes
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/fs"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -16,88 +16,88 @@ import (
 
 type SearchNotesRequest struct {
 	Path          string `json:"path,omitempty" mcp:"Directory path (optional, defaults to vault root)"`
-	Query         string `json:"query,omitempty" mcp:"Search query to perform on the notes"`
+	Query         string `json:"query,omitempty" mcp:"Search query: words, \"phrases\", AND/OR/NOT, re:/regex/, tag:, path:, title:, modified:>YYYY-MM-DD, frontmatter.<key>:<value>"`
 	CaseSensitive bool   `json:"case_sensitive,omitempty" mcp:"Whether search should be case sensitive"`
 }
 
 func (ns *NotesServer) NewSearchNotesTool() {
 	tool := mcp.NewTool(
 		"search_notes",
-		mcp.WithDescription("Find all notes that contain the given text"),
+		mcp.WithDescription("Find all notes that contain the given text, most relevant notes first"),
 		mcp.WithString("path", mcp.Description("Directory path (option, defaults to vault root)")),
-		mcp.WithString("query", mcp.Description("Search query to perform on the notes"), mcp.Required()),
+		mcp.WithString("query",
+			mcp.Description(`Search query. Adjacent words match as a phrase; combine terms with AND, OR, NOT (or -term) and parentheses. `+
+				`Also supports "quoted phrases", re:/regex/i, tag:name, path:folder, title:text, modified:>2025-01-01 and frontmatter.status:done`),
+			mcp.Required()),
 		mcp.WithBoolean("case_sensitive", mcp.Description("Whether search should be case sensitive")),
 	)
 
 	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.SearchNotes))
 }
 
-// SearchNotes searches for text within notes
+// SearchNotes searches for text within notes. The query language supports
+// AND, OR, NOT, quoted phrases, re:/.../ regular expressions and field
+// filters. Candidate notes are looked up in the persistent search index and
+// ranked with BM25 before their matching lines are extracted, so the most
+// relevant notes are returned first.
 func (ns *NotesServer) SearchNotes(ctx context.Context, req mcp.CallToolRequest, params SearchNotesRequest) (*mcp.CallToolResult, error) {
 	path := params.Path
 	if path == "" {
 		path = ns.vaultDir
 	}
 
-	query := params.Query
-	caseSensitive := params.CaseSensitive
-
 	fullPath, err := utils.ValidatePath(ns.vaultDir, path)
 	if err != nil {
 		return nil, err
 	}
 
-	var results []dto.SearchResult
-	if !caseSensitive {
-		query = strings.ToLower(query)
+	node, err := parseQuery(params.Query, params.CaseSensitive)
+	if err != nil {
+		var queryErr *QueryError
+		if errors.As(err, &queryErr) {
+			return queryErrorResult(queryErr), nil
+		}
+		return nil, err
 	}
 
-	// Compile regex for search
-	var pattern *regexp.Regexp
-	if caseSensitive {
-		pattern = regexp.MustCompile(regexp.QuoteMeta(query))
-	} else {
-		pattern = regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+	scope, _ := filepath.Rel(ns.vaultDir, fullPath)
+	inScope := func(relativePath string) bool {
+		return scope == "." || relativePath == scope || strings.HasPrefix(relativePath, scope+string(filepath.Separator))
 	}
 
-	err = utils.WalkDir(fullPath, func(path string, info fs.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Only search in markdown files
-		if info.IsDir() || (!strings.HasSuffix(strings.ToLower(info.Name()), ".md") && !strings.HasSuffix(strings.ToLower(info.Name()), ".markdown")) {
-			return nil
-		}
+	var ranked []scoredDoc
+	err = ns.withIndex(func(idx *searchIndex) error {
+		ranked = idx.rank(requiredTerms(node), scoringTerms(node), inScope)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search notes: %w", err)
+	}
 
-		content, err := utils.ReadFile(path)
+	patterns := linePatterns(node)
+	results := []dto.SearchResult{}
+	for _, doc := range ranked {
+		content, err := utils.ReadFile(filepath.Join(ns.vaultDir, doc.Path))
 		if err != nil {
-			return nil // Skip files we can't read
+			continue // Skip files we can't read
 		}
 
-		lines := strings.Split(string(content), "\n")
-		for lineNum, line := range lines {
-			if pattern.MatchString(line) {
-				relativePath, _ := filepath.Rel(ns.vaultDir, path)
-
-				// Get context (3 lines before and after)
-				contextStart := max(0, lineNum-3)
-				contextEnd := min(len(lines), lineNum+4)
-				context := strings.Join(lines[contextStart:contextEnd], "\n")
-
-				results = append(results, dto.SearchResult{
-					Path:    relativePath,
-					Line:    lineNum + 1,
-					Content: strings.TrimSpace(line),
-					Context: context,
-				})
-			}
+		qd := &queryDoc{path: doc.Path, content: string(content), modified: doc.Modified}
+		if node != nil && !node.match(qd) {
+			continue
 		}
 
-		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to search notes: %w", err)
+		matches := matchLines(doc, qd.content, patterns)
+		if len(matches) == 0 {
+			// Notes selected only by filters are reported once as a whole
+			matches = append(matches, dto.SearchResult{
+				Path:    doc.Path,
+				Content: qd.getTitle(),
+				Context: getContentPreview(qd.content),
+				Score:   doc.Score,
+			})
+		}
+		results = append(results, matches...)
 	}
 
 	result, err := json.MarshalIndent(results, "", "  ")
@@ -111,3 +111,71 @@ func (ns *NotesServer) SearchNotes(ctx context.Context, req mcp.CallToolRequest,
 		},
 	}, nil
 }
+
+// matchLines returns a search result for every line matching one of the patterns
+func matchLines(doc scoredDoc, content string, patterns []*regexp.Regexp) []dto.SearchResult {
+	var results []dto.SearchResult
+
+	lines := strings.Split(content, "\n")
+	for lineNum, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		highlight := trimmed
+		matched := false
+		for _, pattern := range patterns {
+			if pattern.MatchString(line) {
+				matched = true
+				highlight = pattern.ReplaceAllString(highlight, "**${0}**")
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		// Get context (3 lines before and after)
+		contextStart := max(0, lineNum-3)
+		contextEnd := min(len(lines), lineNum+4)
+		context := strings.Join(lines[contextStart:contextEnd], "\n")
+
+		results = append(results, dto.SearchResult{
+			Path:      doc.Path,
+			Line:      lineNum + 1,
+			Content:   trimmed,
+			Context:   context,
+			Score:     doc.Score,
+			Highlight: highlight,
+		})
+	}
+
+	return results
+}
+
+// queryErrorResult explains an invalid search query to the client
+func queryErrorResult(queryErr *QueryError) *mcp.CallToolResult {
+	errorJSON, _ := json.MarshalIndent(map[string]interface{}{
+		"error":    "invalid query",
+		"message":  queryErr.Message,
+		"position": queryErr.Position,
+		"query":    queryErr.Query,
+	}, "", "  ")
+
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(errorJSON)),
+		},
+	}
+}
+
+// uniqueTerms removes duplicate terms while preserving order
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+
+	return unique
+}
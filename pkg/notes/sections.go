@@ -0,0 +1,464 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// headingPathSeparator separates nested headings in a heading path
+const headingPathSeparator = ">"
+
+// Section insert positions
+const (
+	SectionStart = "start"
+	SectionEnd   = "end"
+)
+
+// atxHeadingRegex matches an ATX heading line and captures its marker and text
+var atxHeadingRegex = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?[ \t]*$`)
+
+// closingHashesRegex matches the optional closing sequence of an ATX heading
+var closingHashesRegex = regexp.MustCompile(`(?:^|[ \t]+)#+$`)
+
+// noteHeading is a heading and the byte range of the section it opens
+type noteHeading struct {
+	level int
+	text  string
+	line  int
+
+	// start is the offset of the heading line, bodyStart the offset of the
+	// line after it and end the offset of the next heading of the same or a
+	// higher level (or the end of the note)
+	start     int
+	bodyStart int
+	end       int
+
+	// parent is the index of the enclosing heading, -1 at the top level
+	parent int
+}
+
+// ReadSectionRequest represents a request to read a section of a note
+type ReadSectionRequest struct {
+	Path    string `json:"path" mcp:"Path to the note file"`
+	Heading string `json:"heading" mcp:"Heading path, e.g. \"Project X > Tasks\""`
+}
+
+// ReplaceSectionRequest represents a request to replace the content of a section
+type ReplaceSectionRequest struct {
+	Path           string `json:"path" mcp:"Path to the note file"`
+	Heading        string `json:"heading" mcp:"Heading path, e.g. \"Project X > Tasks\""`
+	Content        string `json:"content" mcp:"New content of the section"`
+	ReplaceHeading bool   `json:"replace_heading,omitempty" mcp:"Content includes the heading line and replaces it too"`
+
+	ExpectedHash string `json:"expected_hash,omitempty" mcp:"Hash from read_note; the edit is refused if the note changed since"`
+}
+
+// InsertUnderHeadingRequest represents a request to insert content into a section
+type InsertUnderHeadingRequest struct {
+	Path     string `json:"path" mcp:"Path to the note file"`
+	Heading  string `json:"heading" mcp:"Heading path, e.g. \"Project X > Tasks\""`
+	Content  string `json:"content" mcp:"Content to insert"`
+	Position string `json:"position,omitempty" mcp:"Where to insert: start or end (default end)"`
+
+	ExpectedHash string `json:"expected_hash,omitempty" mcp:"Hash from read_note; the edit is refused if the note changed since"`
+}
+
+// DeleteSectionRequest represents a request to delete a section
+type DeleteSectionRequest struct {
+	Path    string `json:"path" mcp:"Path to the note file"`
+	Heading string `json:"heading" mcp:"Heading path, e.g. \"Project X > Tasks\""`
+
+	ExpectedHash string `json:"expected_hash,omitempty" mcp:"Hash from read_note; the edit is refused if the note changed since"`
+}
+
+// SectionResult is the response of read_section
+type SectionResult struct {
+	Path      string `json:"path"`
+	Heading   string `json:"heading"`
+	Level     int    `json:"level"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Content   string `json:"content"`
+	Hash      string `json:"hash"`
+}
+
+// SectionEditResult is the response of the section editing tools
+type SectionEditResult struct {
+	Success bool   `json:"success"`
+	Path    string `json:"path"`
+	Heading string `json:"heading"`
+	Hash    string `json:"hash"`
+	Message string `json:"message"`
+}
+
+func (ns *NotesServer) NewReadSectionTool() {
+	tool := mcp.NewTool(
+		"read_section",
+		mcp.WithDescription("Read the section of a note under a heading path such as \"Project X > Tasks\""),
+		mcp.WithString("path", mcp.Description("Path to the note file"), mcp.Required()),
+		mcp.WithString("heading", mcp.Description("Heading path, e.g. \"Project X > Tasks\""), mcp.Required()),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.ReadSection))
+}
+
+func (ns *NotesServer) NewReplaceSectionTool() {
+	tool := mcp.NewTool(
+		"replace_section",
+		mcp.WithDescription("Replace the content under a heading, leaving the rest of the note unchanged"),
+		mcp.WithString("path", mcp.Description("Path to the note file"), mcp.Required()),
+		mcp.WithString("heading", mcp.Description("Heading path, e.g. \"Project X > Tasks\""), mcp.Required()),
+		mcp.WithString("content", mcp.Description("New content of the section"), mcp.Required()),
+		mcp.WithBoolean("replace_heading", mcp.Description("Content includes the heading line and replaces it too")),
+		mcp.WithString("expected_hash", mcp.Description("Hash from read_note; the edit is refused if the note changed since")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.ReplaceSection))
+}
+
+func (ns *NotesServer) NewInsertUnderHeadingTool() {
+	tool := mcp.NewTool(
+		"insert_under_heading",
+		mcp.WithDescription("Insert content at the start or end of the section under a heading"),
+		mcp.WithString("path", mcp.Description("Path to the note file"), mcp.Required()),
+		mcp.WithString("heading", mcp.Description("Heading path, e.g. \"Project X > Tasks\""), mcp.Required()),
+		mcp.WithString("content", mcp.Description("Content to insert"), mcp.Required()),
+		mcp.WithString("position", mcp.Description("Where to insert: start or end (default end)")),
+		mcp.WithString("expected_hash", mcp.Description("Hash from read_note; the edit is refused if the note changed since")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.InsertUnderHeading))
+}
+
+func (ns *NotesServer) NewDeleteSectionTool() {
+	tool := mcp.NewTool(
+		"delete_section",
+		mcp.WithDescription("Delete a heading and everything under it, including subsections"),
+		mcp.WithString("path", mcp.Description("Path to the note file"), mcp.Required()),
+		mcp.WithString("heading", mcp.Description("Heading path, e.g. \"Project X > Tasks\""), mcp.Required()),
+		mcp.WithString("expected_hash", mcp.Description("Hash from read_note; the edit is refused if the note changed since")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.DeleteSection))
+}
+
+func (ns *NotesServer) ReadSection(ctx context.Context, req mcp.CallToolRequest, params ReadSectionRequest) (*mcp.CallToolResult, error) {
+	fullPath, err := utils.ValidatePath(ns.vaultDir, params.Path)
+	if err != nil {
+		return toolErrorResult("Invalid path: %v", err), nil
+	}
+
+	content, err := utils.ReadFile(fullPath)
+	if err != nil {
+		return toolErrorResult("Note not found: %s", params.Path), nil
+	}
+
+	text := string(content)
+	headings := parseHeadings(text)
+	index, err := findSection(headings, params.Heading)
+	if err != nil {
+		return toolErrorResult("%v", err), nil
+	}
+
+	h := headings[index]
+	section := SectionResult{
+		Path:      params.Path,
+		Heading:   headingPath(headings, index),
+		Level:     h.level,
+		StartLine: h.line,
+		EndLine:   h.line + strings.Count(strings.TrimSuffix(text[h.start:h.end], "\n"), "\n"),
+		Content:   text[h.start:h.end],
+		Hash:      contentHash(content),
+	}
+
+	sectionJSON, _ := json.MarshalIndent(section, "", "  ")
+
+	return &mcp.CallToolResult{
+		Result: hashMeta(content),
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(sectionJSON)),
+		},
+	}, nil
+}
+
+func (ns *NotesServer) ReplaceSection(ctx context.Context, req mcp.CallToolRequest, params ReplaceSectionRequest) (*mcp.CallToolResult, error) {
+	return ns.editSection(params.Path, params.Heading, params.ExpectedHash, "replace_section", func(content string, headings []noteHeading, index int) string {
+		return replaceSection(content, headings[index], params.Content, params.ReplaceHeading)
+	})
+}
+
+func (ns *NotesServer) InsertUnderHeading(ctx context.Context, req mcp.CallToolRequest, params InsertUnderHeadingRequest) (*mcp.CallToolResult, error) {
+	if params.Position == "" {
+		params.Position = SectionEnd
+	}
+	if params.Position != SectionStart && params.Position != SectionEnd {
+		return toolErrorResult("Invalid position %q: must be start or end", params.Position), nil
+	}
+
+	return ns.editSection(params.Path, params.Heading, params.ExpectedHash, "insert_under_heading", func(content string, headings []noteHeading, index int) string {
+		return insertUnderHeading(content, headings, index, params.Content, params.Position)
+	})
+}
+
+func (ns *NotesServer) DeleteSection(ctx context.Context, req mcp.CallToolRequest, params DeleteSectionRequest) (*mcp.CallToolResult, error) {
+	return ns.editSection(params.Path, params.Heading, params.ExpectedHash, "delete_section", func(content string, headings []noteHeading, index int) string {
+		h := headings[index]
+		return content[:h.start] + content[h.end:]
+	})
+}
+
+// editSection locates the section under heading and rewrites the note with
+// the content returned by edit
+func (ns *NotesServer) editSection(path, heading, expectedHash, tool string, edit func(content string, headings []noteHeading, index int) string) (*mcp.CallToolResult, error) {
+	fullPath, err := utils.ValidatePath(ns.vaultDir, path)
+	if err != nil {
+		return toolErrorResult("Invalid path: %v", err), nil
+	}
+
+	var sectionPath string
+	updated, err := ns.modifyNote(fullPath, expectedHash, tool, func(content string) (string, error) {
+		headings := parseHeadings(content)
+		index, err := findSection(headings, heading)
+		if err != nil {
+			return "", err
+		}
+		sectionPath = headingPath(headings, index)
+		return edit(content, headings, index), nil
+	})
+	if err != nil {
+		return editErrorResult(err), nil
+	}
+
+	result := SectionEditResult{
+		Success: true,
+		Path:    path,
+		Heading: sectionPath,
+		Hash:    contentHash([]byte(updated)),
+		Message: fmt.Sprintf("Successfully updated section: %s", sectionPath),
+	}
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Result: hashMeta([]byte(updated)),
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// parseHeadings returns the ATX headings of a note in document order.
+// Headings inside the frontmatter and fenced code blocks are ignored.
+func parseHeadings(content string) []noteHeading {
+	var headings []noteHeading
+
+	skip := 0
+	if fm, _ := parseFrontmatter(content); fm.EndLine > 0 {
+		skip = fm.EndLine
+	}
+
+	var stack []int
+	var fences fenceTracker
+	offset := 0
+	for lineNum, line := range strings.SplitAfter(content, "\n") {
+		start := offset
+		offset += len(line)
+		if lineNum < skip {
+			continue
+		}
+
+		if fences.inCode(line) {
+			continue
+		}
+
+		m := atxHeadingRegex.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if m == nil {
+			continue
+		}
+
+		h := noteHeading{
+			level:     len(m[1]),
+			text:      strings.TrimSpace(closingHashesRegex.ReplaceAllString(m[2], "")),
+			line:      lineNum + 1,
+			start:     start,
+			bodyStart: offset,
+			end:       len(content),
+			parent:    -1,
+		}
+
+		// Close every open section at the same or a deeper level
+		for len(stack) > 0 && headings[stack[len(stack)-1]].level >= h.level {
+			headings[stack[len(stack)-1]].end = start
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 {
+			h.parent = stack[len(stack)-1]
+		}
+
+		stack = append(stack, len(headings))
+		headings = append(headings, h)
+	}
+
+	return headings
+}
+
+// findSection resolves a heading path such as "Project X > Tasks". The first
+// part may match a heading at any level and each following part must match
+// a heading nested under the previous one. Matching ignores case.
+func findSection(headings []noteHeading, path string) (int, error) {
+	var parts []string
+	for _, part := range strings.Split(path, headingPathSeparator) {
+		part = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(part), "#"))
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return -1, fmt.Errorf("heading path is required")
+	}
+
+	var matches []int
+	for i, h := range headings {
+		if strings.EqualFold(h.text, parts[0]) {
+			matches = append(matches, i)
+		}
+	}
+
+	for _, part := range parts[1:] {
+		var next []int
+		for _, m := range matches {
+			for i := m + 1; i < len(headings) && headings[i].start < headings[m].end; i++ {
+				if strings.EqualFold(headings[i].text, part) {
+					next = append(next, i)
+				}
+			}
+		}
+		matches = next
+	}
+
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		available := make([]string, len(headings))
+		for i := range headings {
+			available[i] = headingPath(headings, i)
+		}
+		return -1, fmt.Errorf("heading %q not found; available headings: %s", path, strings.Join(available, "; "))
+	default:
+		candidates := make([]string, len(matches))
+		for i, m := range matches {
+			candidates[i] = fmt.Sprintf("%s (line %d)", headingPath(headings, m), headings[m].line)
+		}
+		return -1, fmt.Errorf("heading %q is ambiguous; use a longer path: %s", path, strings.Join(candidates, "; "))
+	}
+}
+
+// headingPath returns the full heading path of a heading
+func headingPath(headings []noteHeading, index int) string {
+	var parts []string
+	for i := index; i >= 0; i = headings[i].parent {
+		parts = append([]string{headings[i].text}, parts...)
+	}
+	return strings.Join(parts, " "+headingPathSeparator+" ")
+}
+
+// replaceSection swaps the body of a section (or the whole section when
+// replaceHeading is set) for content. Blank lines around the old body are
+// kept so the spacing to the neighbouring headings does not change.
+func replaceSection(content string, h noteHeading, replacement string, replaceHeading bool) string {
+	start := h.bodyStart
+	if replaceHeading {
+		start = h.start
+	}
+
+	body := content[start:h.end]
+	lead := ""
+	if !replaceHeading {
+		lead = body[:len(body)-len(trimLeadingBlankLines(body))]
+	}
+	trail := body[len(lead):]
+	trail = trail[len(trimTrailingBlankLines(trail)):]
+
+	replacement = strings.TrimRight(replacement, "\n")
+	if replacement != "" {
+		replacement += "\n"
+	}
+
+	// Keep a note that did not end with a newline that way
+	if h.end == len(content) && trail == "" && !strings.HasSuffix(body, "\n") {
+		replacement = strings.TrimSuffix(replacement, "\n")
+	}
+
+	prefix := content[:start]
+	if replacement != "" && prefix != "" && !strings.HasSuffix(prefix, "\n") {
+		prefix += "\n"
+	}
+
+	return prefix + lead + replacement + trail + content[h.end:]
+}
+
+// insertUnderHeading inserts content after the heading line (start) or after
+// the last non-blank line of the heading's own body, before any subsection (end)
+func insertUnderHeading(content string, headings []noteHeading, index int, insert, position string) string {
+	h := headings[index]
+
+	insert = strings.TrimRight(insert, "\n") + "\n"
+
+	var at int
+	if position == SectionStart {
+		body := content[h.bodyStart:h.end]
+		at = h.bodyStart + len(body) - len(trimLeadingBlankLines(body))
+	} else {
+		at = h.bodyStart + len(trimTrailingBlankLines(content[h.bodyStart:ownBodyEnd(headings, index)]))
+	}
+
+	if at > 0 && content[at-1] != '\n' {
+		// The insertion point is the end of a note without a final newline
+		insert = "\n" + strings.TrimSuffix(insert, "\n")
+	}
+
+	return content[:at] + insert + content[at:]
+}
+
+// ownBodyEnd returns the offset where the body of a heading ends, which is
+// its first subsection or the end of the section
+func ownBodyEnd(headings []noteHeading, index int) int {
+	if index+1 < len(headings) && headings[index+1].start < headings[index].end {
+		return headings[index+1].start
+	}
+	return headings[index].end
+}
+
+// trimLeadingBlankLines removes whole blank lines from the start of s
+func trimLeadingBlankLines(s string) string {
+	for {
+		line, rest, found := strings.Cut(s, "\n")
+		if !found || strings.TrimSpace(line) != "" {
+			return s
+		}
+		s = rest
+	}
+}
+
+// trimTrailingBlankLines removes whole blank lines from the end of s, keeping
+// the newline that terminates the last non-blank line
+func trimTrailingBlankLines(s string) string {
+	for strings.HasSuffix(s, "\n") {
+		i := strings.LastIndex(s[:len(s)-1], "\n")
+		if strings.TrimSpace(s[i+1:]) != "" {
+			return s
+		}
+		s = s[:i+1]
+	}
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	return s
+}
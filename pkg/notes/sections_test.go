@@ -0,0 +1,268 @@
+package notes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const sectionsTestNote = `---
+title: Projects
+---
+# Projects
+
+Intro text.
+
+## Project X
+
+Overview of X.
+
+### Tasks
+
+- [ ] Write spec
+- [ ] Review
+
+### Notes
+
+` + "```" + `
+# not a heading
+` + "```" + `
+
+## Project Y
+
+### Tasks
+
+- [ ] Plan
+`
+
+func TestParseHeadings(t *testing.T) {
+	headings := parseHeadings(sectionsTestNote)
+
+	var paths []string
+	for i := range headings {
+		paths = append(paths, headingPath(headings, i))
+	}
+
+	expected := []string{
+		"Projects",
+		"Projects > Project X",
+		"Projects > Project X > Tasks",
+		"Projects > Project X > Notes",
+		"Projects > Project Y",
+		"Projects > Project Y > Tasks",
+	}
+	if strings.Join(paths, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected headings %v, got %v", expected, paths)
+	}
+
+	// Frontmatter and code blocks are skipped and sections end at the next sibling
+	if headings[0].line != 4 || headings[0].end != len(sectionsTestNote) {
+		t.Errorf("Unexpected top-level section: %+v", headings[0])
+	}
+	if !strings.HasPrefix(sectionsTestNote[headings[3].end:], "## Project Y") {
+		t.Errorf("Project X > Notes should end at Project Y, ends at %q", sectionsTestNote[headings[3].end:])
+	}
+}
+
+func TestParseHeadings_ClosingHashes(t *testing.T) {
+	headings := parseHeadings("## Title ##\n#hashtag\n#\n")
+
+	if len(headings) != 2 || headings[0].text != "Title" || headings[1].text != "" {
+		t.Errorf("Unexpected headings: %+v", headings)
+	}
+}
+
+func TestParseHeadings_NestedFences(t *testing.T) {
+	headings := parseHeadings("# Before\n\n````md\n```go\n# Inside\n```\n# Still inside\n````\n\n```\n```go\n# Not a heading\n```\n# After\n")
+
+	if len(headings) != 2 || headings[0].text != "Before" || headings[1].text != "After" {
+		t.Errorf("Unexpected headings: %+v", headings)
+	}
+}
+
+func TestFindSection(t *testing.T) {
+	headings := parseHeadings(sectionsTestNote)
+
+	index, err := findSection(headings, "project x > tasks")
+	if err != nil || headingPath(headings, index) != "Projects > Project X > Tasks" {
+		t.Errorf("Expected Project X > Tasks, got %d %v", index, err)
+	}
+
+	if _, err := findSection(headings, "Tasks"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Expected ambiguous heading error, got %v", err)
+	}
+
+	if _, err := findSection(headings, "Project Z"); err == nil || !strings.Contains(err.Error(), "Projects > Project Y > Tasks") {
+		t.Errorf("Expected not found error listing headings, got %v", err)
+	}
+
+	// Later parts must be nested under the previous one
+	if _, err := findSection(headings, "Project Y > Notes"); err == nil {
+		t.Error("Notes is not under Project Y")
+	}
+}
+
+func TestReadSection(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, map[string]string{"projects.md": sectionsTestNote})}
+
+	var section SectionResult
+	result, err := ns.ReadSection(context.Background(), mcp.CallToolRequest{}, ReadSectionRequest{Path: "projects.md", Heading: "Project X > Tasks"})
+	decodeToolResult(t, result, err, &section)
+
+	if section.Content != "### Tasks\n\n- [ ] Write spec\n- [ ] Review\n\n" {
+		t.Errorf("Unexpected section content: %q", section.Content)
+	}
+	if section.Level != 3 || section.StartLine != 12 || section.EndLine != 16 {
+		t.Errorf("Unexpected section position: %+v", section)
+	}
+	if section.Hash != contentHash([]byte(sectionsTestNote)) {
+		t.Error("ReadSection should return the note hash")
+	}
+}
+
+func TestReplaceSection_LeavesRestUnchanged(t *testing.T) {
+	tempDir := createVaultForTest(t, map[string]string{"projects.md": sectionsTestNote})
+	ns := &NotesServer{vaultDir: tempDir}
+
+	var edit SectionEditResult
+	result, err := ns.ReplaceSection(context.Background(), mcp.CallToolRequest{}, ReplaceSectionRequest{
+		Path:    "projects.md",
+		Heading: "Project X > Tasks",
+		Content: "- [x] Write spec",
+	})
+	decodeToolResult(t, result, err, &edit)
+
+	expected := strings.Replace(sectionsTestNote, "- [ ] Write spec\n- [ ] Review\n", "- [x] Write spec\n", 1)
+	if got := readForTest(t, tempDir, "projects.md"); got != expected {
+		t.Errorf("Unexpected content after replace:\n%s", got)
+	}
+	if edit.Heading != "Projects > Project X > Tasks" || edit.Hash != contentHash([]byte(expected)) {
+		t.Errorf("Unexpected edit result: %+v", edit)
+	}
+}
+
+func TestReplaceSection_ReplaceHeading(t *testing.T) {
+	tempDir := createVaultForTest(t, map[string]string{"projects.md": "# A\n\nbody\n\n# B\n\nkeep\n"})
+	ns := &NotesServer{vaultDir: tempDir}
+
+	result, err := ns.ReplaceSection(context.Background(), mcp.CallToolRequest{}, ReplaceSectionRequest{
+		Path:           "projects.md",
+		Heading:        "A",
+		Content:        "# Renamed\n\nnew body\n",
+		ReplaceHeading: true,
+	})
+	decodeToolResult(t, result, err, &SectionEditResult{})
+
+	if got := readForTest(t, tempDir, "projects.md"); got != "# Renamed\n\nnew body\n\n# B\n\nkeep\n" {
+		t.Errorf("Unexpected content after replace: %q", got)
+	}
+}
+
+func TestInsertUnderHeading(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		heading  string
+		position string
+		expected string
+	}{
+		{
+			name:     "end before subsections",
+			content:  "# A\n\nfirst\n\n## Sub\n\nsub\n",
+			heading:  "A",
+			position: SectionEnd,
+			expected: "# A\n\nfirst\nadded\n\n## Sub\n\nsub\n",
+		},
+		{
+			name:     "start after blank line",
+			content:  "# A\n\nfirst\n",
+			heading:  "A",
+			position: SectionStart,
+			expected: "# A\n\nadded\nfirst\n",
+		},
+		{
+			name:     "empty section",
+			content:  "# A\n# B\n",
+			heading:  "A",
+			position: SectionEnd,
+			expected: "# A\nadded\n# B\n",
+		},
+		{
+			name:     "end of note without newline",
+			content:  "# A\nlast",
+			heading:  "A",
+			position: SectionEnd,
+			expected: "# A\nlast\nadded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := createVaultForTest(t, map[string]string{"projects.md": tt.content})
+			ns := &NotesServer{vaultDir: tempDir}
+
+			result, err := ns.InsertUnderHeading(context.Background(), mcp.CallToolRequest{}, InsertUnderHeadingRequest{
+				Path:     "projects.md",
+				Heading:  tt.heading,
+				Content:  "added",
+				Position: tt.position,
+			})
+			decodeToolResult(t, result, err, &SectionEditResult{})
+
+			if got := readForTest(t, tempDir, "projects.md"); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDeleteSection(t *testing.T) {
+	tempDir := createVaultForTest(t, map[string]string{"projects.md": sectionsTestNote})
+	ns := &NotesServer{vaultDir: tempDir}
+
+	result, err := ns.DeleteSection(context.Background(), mcp.CallToolRequest{}, DeleteSectionRequest{Path: "projects.md", Heading: "Project X"})
+	decodeToolResult(t, result, err, &SectionEditResult{})
+
+	start := strings.Index(sectionsTestNote, "## Project X")
+	end := strings.Index(sectionsTestNote, "## Project Y")
+	expected := sectionsTestNote[:start] + sectionsTestNote[end:]
+	if got := readForTest(t, tempDir, "projects.md"); got != expected {
+		t.Errorf("Unexpected content after delete:\n%s", got)
+	}
+
+	// The previous content is kept in the version history
+	versions, err := ns.loadHistory("projects.md")
+	if err != nil || len(versions.Versions) != 1 || versions.Versions[0].Tool != "delete_section" {
+		t.Errorf("Expected a delete_section snapshot, got %+v %v", versions, err)
+	}
+}
+
+func TestSectionEdits_Errors(t *testing.T) {
+	tempDir := createVaultForTest(t, map[string]string{"projects.md": sectionsTestNote})
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	result, err := ns.ReplaceSection(ctx, mcp.CallToolRequest{}, ReplaceSectionRequest{Path: "projects.md", Heading: "Tasks", Content: "x"})
+	if err != nil || !result.IsError {
+		t.Errorf("Expected an ambiguous heading error, got %v %v", result, err)
+	}
+
+	result, err = ns.DeleteSection(ctx, mcp.CallToolRequest{}, DeleteSectionRequest{Path: "projects.md", Heading: "Project X", ExpectedHash: "stale"})
+	assertConflict(t, result, err)
+
+	result, err = ns.InsertUnderHeading(ctx, mcp.CallToolRequest{}, InsertUnderHeadingRequest{Path: "projects.md", Heading: "Project X", Content: "x", Position: "middle"})
+	if err != nil || !result.IsError {
+		t.Errorf("Expected an invalid position error, got %v %v", result, err)
+	}
+
+	result, err = ns.ReadSection(ctx, mcp.CallToolRequest{}, ReadSectionRequest{Path: "missing.md", Heading: "A"})
+	if err != nil || !result.IsError {
+		t.Errorf("Expected a missing note error, got %v %v", result, err)
+	}
+
+	if got := readForTest(t, tempDir, "projects.md"); got != sectionsTestNote {
+		t.Error("Failed edits must leave the note untouched")
+	}
+}
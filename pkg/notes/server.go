@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -18,10 +19,126 @@ type NotesServer struct {
 	ctx       context.Context
 	McpServer *server.MCPServer
 	vaultDir  string
+
+	// search index, loaded lazily on first use
+	indexMu sync.Mutex
+	index   *searchIndex
+
+	// serializes updates to the note version logs
+	historyMu sync.Mutex
+
+	// per-path locks for read-modify-write tools, see lockPaths
+	locksMu sync.Mutex
+	locks   map[string]*pathLock
+
+	// number of days items stay in the trash, defaults to defaultTrashRetentionDays
+	trashRetentionDays int
+
+	// vault folder holding user templates, defaults to defaultTemplatesFolder
+	templatesFolder string
+	templatesMu     sync.Mutex
+	templateCache   map[string]cachedTemplate
+
+	// path patterns of the periodic notes by period, see defaultPeriodicPatterns
+	periodicPatterns map[string]string
+
+	// tasks of each note, reread when the note changes
+	tasksMu   sync.Mutex
+	taskCache map[string]cachedTasks
+
+	// how often the vault is polled for outside changes, zero disables watching
+	watchInterval time.Duration
+
+	// lint configuration file, defaults to .sibyl/lint.yaml in the vault
+	lintConfigPath string
+
+	// markdown style of format_note, also applied to the notes written by
+	// write_note and merge_note when formatOnWrite is set
+	formatStyle   FormatStyle
+	formatOnWrite bool
+
+	// directory export_site writes sites under, defaults to site in the
+	// vault's .sibyl folder
+	exportDir string
+
+	// resource URIs the client subscribed to, see handleSubscription
+	subscriptionsMu sync.Mutex
+	subscriptions   map[string]bool
 }
 
-func NewNotesServer(ctx context.Context, notesFolder string) *NotesServer {
+// Option configures optional NotesServer behavior
+type Option func(*NotesServer)
+
+// WithTrashRetention sets how many days deleted notes are kept in the trash
+// before empty_trash removes them. Note history snapshots are kept as long.
+func WithTrashRetention(days int) Option {
+	return func(ns *NotesServer) {
+		ns.trashRetentionDays = days
+	}
+}
+
+// WithTemplatesFolder sets the vault folder user templates are loaded from
+func WithTemplatesFolder(folder string) Option {
+	return func(ns *NotesServer) {
+		ns.templatesFolder = folder
+	}
+}
+
+// WithPeriodicNotePattern sets the vault path pattern of the daily, weekly,
+// monthly or quarterly notes, e.g. Journal/{{yyyy}}/{{yyyy-MM-dd}}.md
+func WithPeriodicNotePattern(period, pattern string) Option {
+	return func(ns *NotesServer) {
+		if ns.periodicPatterns == nil {
+			ns.periodicPatterns = make(map[string]string)
+		}
+		ns.periodicPatterns[period] = pattern
+	}
+}
+
+// WithWatchInterval sets how often the vault is checked for notes created,
+// changed or removed outside the server. Zero disables watching.
+func WithWatchInterval(interval time.Duration) Option {
+	return func(ns *NotesServer) {
+		ns.watchInterval = interval
+	}
+}
+
+// WithLintConfig sets the YAML file lint_vault reads its rules from. By
+// default .sibyl/lint.yaml in the vault is used when it exists.
+func WithLintConfig(path string) Option {
+	return func(ns *NotesServer) {
+		ns.lintConfigPath = path
+	}
+}
+
+// WithFormatStyle sets the markdown style format_note normalizes notes to
+func WithFormatStyle(style FormatStyle) Option {
+	return func(ns *NotesServer) {
+		ns.formatStyle = style
+	}
+}
+
+// WithFormatOnWrite formats the markdown written by write_note and
+// merge_note in the format style
+func WithFormatOnWrite(enabled bool) Option {
+	return func(ns *NotesServer) {
+		ns.formatOnWrite = enabled
+	}
+}
+
+// WithExportDir sets the directory export_site writes sites under. By
+// default sites are written to .sibyl/site in the vault.
+func WithExportDir(dir string) Option {
+	return func(ns *NotesServer) {
+		ns.exportDir = dir
+	}
+}
+
+func NewNotesServer(ctx context.Context, notesFolder string, opts ...Option) *NotesServer {
 	ns := &NotesServer{}
+	for _, opt := range opts {
+		opt(ns)
+	}
 
 	// serverOptions := mcp.ServerOptions{
 	// 	InitializedHandler:      ns.handleInitialized,
@@ -32,14 +149,18 @@ func NewNotesServer(ctx context.Context, notesFolder string) *NotesServer {
 	ns.vaultDir = notesFolder
 	ns.McpServer = server.NewMCPServer("note-server", "v1.0.0",
 		server.WithToolCapabilities(true),
-		server.WithResourceCapabilities(false, false))
+		server.WithResourceCapabilities(true, true))
 	ns.addTools()
 	ns.addResources()
+	ns.addResourceTemplates()
+
+	if ns.watchInterval > 0 {
+		go ns.watchVault(ctx, ns.watchInterval)
+	}
 
 	return ns
 }
 
-
 func (ns *NotesServer) addResources() {
 	// Resource 1: Note Files collection
 	filesResource := mcp.NewResource(
@@ -67,6 +188,42 @@ func (ns *NotesServer) addResources() {
 		mcp.WithMIMEType("application/json"),
 	)
 	ns.McpServer.AddResource(collectionsResource, ns.ListNoteCollections)
+
+	// Resource 4: Link graph
+	graphResource := mcp.NewResource(
+		"notes://graph",
+		"Note Link Graph",
+		mcp.WithResourceDescription("Wikilink and markdown link graph of the vault with broken links and orphan notes"),
+		mcp.WithMIMEType("application/json"),
+	)
+	ns.McpServer.AddResource(graphResource, ns.GetLinkGraph)
+
+	// Resource 5: Periodic notes
+	periodicResource := mcp.NewResource(
+		"notes://periodic/",
+		"Periodic Notes",
+		mcp.WithResourceDescription("Daily, weekly, monthly and quarterly notes in the vault and their path patterns"),
+		mcp.WithMIMEType("application/json"),
+	)
+	ns.McpServer.AddResource(periodicResource, ns.ListPeriodicNotes)
+
+	// Resource 6: Tasks
+	tasksResource := mcp.NewResource(
+		"notes://tasks/",
+		"Note Tasks",
+		mcp.WithResourceDescription("Open checkbox tasks across the vault with counts of open, done and overdue tasks"),
+		mcp.WithMIMEType("application/json"),
+	)
+	ns.McpServer.AddResource(tasksResource, ns.ListVaultTasks)
+
+	// Resource 7: Vault statistics
+	statsResource := mcp.NewResource(
+		"notes://stats",
+		"Vault Statistics",
+		mcp.WithResourceDescription("Note, word and folder counts, growth, tag frequencies, largest, untagged and orphaned notes, empty folders and duplicate file names"),
+		mcp.WithMIMEType("application/json"),
+	)
+	ns.McpServer.AddResource(statsResource, ns.GetVaultStats)
 }
 
 // addTools adds all the tools to the server
@@ -76,9 +233,34 @@ func (ns *NotesServer) addTools() {
 	ns.NewWriteNoteTool()
 	ns.NewAppendNoteTool()
 	ns.NewCreateFolderTool()
+	ns.NewMoveNoteTool()
+	ns.NewRenameNoteTool()
 	ns.NewListNotesTool()
 	ns.NewListFoldersTool()
 	ns.NewSearchNotesTool()
+	ns.NewQueryFrontmatterTool()
+
+	// Section editing
+	ns.NewReadSectionTool()
+	ns.NewReplaceSectionTool()
+	ns.NewInsertUnderHeadingTool()
+	ns.NewDeleteSectionTool()
+
+	// Version history
+	ns.NewListNoteVersionsTool()
+	ns.NewDiffNoteVersionsTool()
+	ns.NewRestoreNoteVersionTool()
+
+	// Trash
+	ns.NewDeleteNoteTool()
+	ns.NewDeleteFolderTool()
+	ns.NewListTrashTool()
+	ns.NewRestoreNoteTool()
+	ns.NewEmptyTrashTool()
+
+	// Link graph
+	ns.NewGetBacklinksTool()
+	ns.NewGetOutgoingLinksTool()
 
 	// Enhanced merge capabilities
 	ns.NewMergeNoteTool()
@@ -87,8 +269,35 @@ func (ns *NotesServer) addTools() {
 	// Template capabilities
 	ns.NewGetTemplatesTools()
 	ns.NewCreateFromTemplateTool()
-}
 
+	// Related notes
+	ns.NewFindRelatedNotesTool()
+
+	// Vault statistics
+	ns.NewVaultReportTool()
+
+	// Vault lint
+	ns.NewLintVaultTool()
+
+	// Formatting
+	ns.NewFormatNoteTool()
+
+	// Tags
+	ns.NewRenameTagTool()
+	ns.NewMergeTagsTool()
+	ns.NewDeleteTagTool()
+
+	// Site export
+	ns.NewExportSiteTool()
+
+	// Periodic notes
+	ns.NewOpenPeriodicNoteTool()
+
+	// Tasks
+	ns.NewListTasksTool()
+	ns.NewToggleTaskTool()
+	ns.NewUpdateTaskTool()
+}
 
 // Resource handlers
 
@@ -100,6 +309,11 @@ func (ns *NotesServer) ListNoteFiles(ctx context.Context, request mcp.ReadResour
 			return err
 		}
 
+		// Skip hidden folders such as .trash and .sibyl
+		if info.IsDir() && path != ns.vaultDir && isHiddenDir(info.Name()) {
+			return filepath.SkipDir
+		}
+
 		// Only process markdown files
 		if !info.IsDir() && (strings.HasSuffix(strings.ToLower(info.Name()), ".md") ||
 			strings.HasSuffix(strings.ToLower(info.Name()), ".markdown")) {
@@ -146,8 +360,8 @@ func (ns *NotesServer) ListNoteFiles(ctx context.Context, request mcp.ReadResour
 }
 
 func (ns *NotesServer) ListNoteTemplates(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-	// Get available templates from the builtin templates
-	templates := ns.getBuiltinTemplates()
+	// Get available templates from the builtin and user templates
+	templates := ns.getTemplates()
 
 	templatesMap := make(map[string]interface{})
 	for name, template := range templates {
@@ -157,6 +371,8 @@ func (ns *NotesServer) ListNoteTemplates(ctx context.Context, request mcp.ReadRe
 			"use_case":    template.UseCase,
 			"uri":         fmt.Sprintf("notes://templates/%s", name),
 			"content":     template.Content,
+			"variables":   template.Variables,
+			"source":      template.Source,
 		}
 	}
 	templatesJSON, _ := json.MarshalIndent(templatesMap, "", "  ")
@@ -173,45 +389,30 @@ func (ns *NotesServer) ListNoteTemplates(ctx context.Context, request mcp.ReadRe
 func (ns *NotesServer) ListNoteCollections(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
 	collections := make(map[string]interface{})
 
-	// Group by folders
+	scan, err := ns.scanVault()
+	if err != nil {
+		return nil, err
+	}
+
+	// Group by folders and tags
 	folderMap := make(map[string][]string)
 	tagMap := make(map[string][]string)
-
-	err := filepath.Walk(ns.vaultDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	for _, note := range scan.notes {
+		folder := filepath.Dir(note.path)
+		if folder == "." {
+			folder = "root"
 		}
+		folderMap[folder] = append(folderMap[folder], note.path)
 
-		if !info.IsDir() && (strings.HasSuffix(strings.ToLower(info.Name()), ".md") ||
-			strings.HasSuffix(strings.ToLower(info.Name()), ".markdown")) {
-
-			relPath, _ := filepath.Rel(ns.vaultDir, path)
-			folder := filepath.Dir(relPath)
-			if folder == "." {
-				folder = "root"
-			}
-
-			folderMap[folder] = append(folderMap[folder], relPath)
-
-			// Extract tags
-			content, err := os.ReadFile(path)
-			if err == nil {
-				tags := extractTags(string(content))
-				for _, tag := range tags {
-					tagMap[tag] = append(tagMap[tag], relPath)
-				}
-			}
+		for _, tag := range note.tags {
+			tagMap[tag] = append(tagMap[tag], note.path)
 		}
-		return nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("error walking notes directory: %w", err)
 	}
 
 	// Build collections
 	collections["folders"] = folderMap
 	collections["tags"] = tagMap
+	collections["tag_tree"] = buildTagTree(tagMap)
 
 	collectionsJSON, _ := json.MarshalIndent(collections, "", "  ")
 
@@ -226,74 +427,40 @@ func (ns *NotesServer) ListNoteCollections(ctx context.Context, request mcp.Read
 
 // Helper functions
 
-func extractTags(content string) []string {
-	var tags []string
-	lines := strings.Split(content, "\n")
-
-	// Look for tags in frontmatter
-	inFrontmatter := false
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "---" {
-			inFrontmatter = !inFrontmatter
-			continue
-		}
+// isMarkdownFile reports whether the file name has a markdown extension
+func isMarkdownFile(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".md") || strings.HasSuffix(lower, ".markdown")
+}
 
-		if inFrontmatter && strings.HasPrefix(line, "tags:") {
-			// Parse YAML tags array
-			tagLine := strings.TrimPrefix(line, "tags:")
-			tagLine = strings.Trim(tagLine, " []")
-			if tagLine != "" {
-				for _, tag := range strings.Split(tagLine, ",") {
-					tag = strings.Trim(tag, " \"'")
-					if tag != "" {
-						tags = append(tags, tag)
-					}
-				}
-			}
-		}
+// isHiddenDir reports whether a directory should be skipped when scanning the
+// vault, e.g. .sibyl, .obsidian or .git
+func isHiddenDir(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// noteTitle returns the frontmatter title or the first level one heading of
+// a note, falling back to the file name without its extension
+func noteTitle(content, fileName string) string {
+	fm, _ := parseFrontmatter(content)
+	if title := frontmatterScalar(fm.Fields["title"]); title != "" {
+		return title
 	}
 
-	// Also look for hashtags in content
-	for _, line := range lines {
-		words := strings.Fields(line)
-		for _, word := range words {
-			if strings.HasPrefix(word, "#") && len(word) > 1 {
-				tag := strings.TrimPrefix(word, "#")
-				tag = strings.Trim(tag, ".,!?;:")
-				if tag != "" {
-					// Avoid duplicates
-					found := false
-					for _, existing := range tags {
-						if existing == tag {
-							found = true
-							break
-						}
-					}
-					if !found {
-						tags = append(tags, tag)
-					}
-				}
-			}
+	for _, line := range strings.Split(fm.Body, "\n") {
+		if title, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+			return strings.TrimSpace(title)
 		}
 	}
 
-	return tags
+	return strings.TrimSuffix(fileName, filepath.Ext(fileName))
 }
 
 func getContentPreview(content string) string {
-	lines := strings.Split(content, "\n")
-
 	// Skip frontmatter
+	fm, _ := parseFrontmatter(content)
+	lines := strings.Split(fm.Body, "\n")
 	startIdx := 0
-	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
-		for i := 1; i < len(lines); i++ {
-			if strings.TrimSpace(lines[i]) == "---" {
-				startIdx = i + 1
-				break
-			}
-		}
-	}
 
 	// Get first few lines of actual content
 	var previewLines []string
@@ -0,0 +1,321 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultLargestNotes is how many of the largest notes are reported by default
+const defaultLargestNotes = 10
+
+// vaultScan is the result of a single walk over the vault, reading every
+// note once
+type vaultScan struct {
+	// files holds every file and folders every folder below the root, as
+	// vault-relative paths
+	files   []string
+	folders []string
+	notes   []scannedNote
+
+	// contents holds the text of every note until the link graph is built
+	contents map[string]string
+	graph    *linkGraph
+}
+
+// scannedNote is what a vault scan records about a note
+type scannedNote struct {
+	path    string
+	size    int64
+	created time.Time
+	words   int
+	tags    []string
+}
+
+// VaultReportRequest represents a request for the vault statistics
+type VaultReportRequest struct {
+	Limit int `json:"limit,omitempty" mcp:"Number of largest notes to list (default 10)"`
+}
+
+// VaultStats is the content of notes://stats and the response of vault_report
+type VaultStats struct {
+	Notes          int              `json:"notes"`
+	Words          int              `json:"words"`
+	Folders        int              `json:"folders"`
+	Attachments    int              `json:"attachments"`
+	Growth         []GrowthPeriod   `json:"growth"`
+	Tags           []TagCount       `json:"tags"`
+	Largest        []NoteSize       `json:"largest"`
+	Untagged       []string         `json:"untagged"`
+	Orphans        []string         `json:"orphans"`
+	EmptyFolders   []string         `json:"empty_folders"`
+	DuplicateNames []DuplicateNames `json:"duplicate_names"`
+}
+
+// GrowthPeriod is the number of notes added in a month and the running total
+type GrowthPeriod struct {
+	Month string `json:"month"`
+	Added int    `json:"added"`
+	Total int    `json:"total"`
+}
+
+// TagCount is the number of notes with a tag
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// NoteSize is the size of a note in bytes and words
+type NoteSize struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Words int    `json:"words"`
+}
+
+// DuplicateNames lists notes in different folders sharing a file name, which
+// makes wikilinks to that name ambiguous
+type DuplicateNames struct {
+	Name  string   `json:"name"`
+	Paths []string `json:"paths"`
+}
+
+func (ns *NotesServer) NewVaultReportTool() {
+	tool := mcp.NewTool(
+		"vault_report",
+		mcp.WithDescription("Report vault statistics and health: note, word and folder counts, growth by month, tag frequencies, the largest notes, untagged and orphaned notes, empty folders and duplicate file names"),
+		mcp.WithNumber("limit", mcp.Description("Number of largest notes to list (default 10)")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.VaultReport))
+}
+
+// VaultReport returns the vault statistics
+func (ns *NotesServer) VaultReport(ctx context.Context, req mcp.CallToolRequest, params VaultReportRequest) (*mcp.CallToolResult, error) {
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultLargestNotes
+	}
+
+	scan, err := ns.scanVault()
+	if err != nil {
+		return nil, err
+	}
+
+	statsJSON, _ := json.MarshalIndent(scan.stats(limit), "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(statsJSON)),
+		},
+	}, nil
+}
+
+// GetVaultStats serves notes://stats
+func (ns *NotesServer) GetVaultStats(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	scan, err := ns.scanVault()
+	if err != nil {
+		return nil, err
+	}
+
+	statsJSON, _ := json.MarshalIndent(scan.stats(defaultLargestNotes), "", "  ")
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      "notes://stats",
+			MIMEType: "application/json",
+			Text:     string(statsJSON),
+		},
+	}, nil
+}
+
+// scanVault walks the vault once, reading every note to record its size,
+// words and tags. Hidden folders are skipped.
+func (ns *NotesServer) scanVault() (*vaultScan, error) {
+	scan := &vaultScan{contents: make(map[string]string)}
+
+	err := utils.WalkDir(ns.vaultDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, _ := filepath.Rel(ns.vaultDir, path)
+		if info.IsDir() {
+			if path != ns.vaultDir {
+				if isHiddenDir(info.Name()) {
+					return filepath.SkipDir
+				}
+				scan.folders = append(scan.folders, relPath)
+			}
+			return nil
+		}
+
+		scan.files = append(scan.files, relPath)
+		if !isMarkdownFile(info.Name()) {
+			return nil
+		}
+
+		content, err := utils.ReadFile(path)
+		if err != nil {
+			return nil // Skip files we can't read
+		}
+
+		note := scannedNote{
+			path:    relPath,
+			size:    info.Size(),
+			created: info.ModTime(),
+			tags:    extractTags(string(content)),
+		}
+
+		// Prefer a created or date field to the modification time, which
+		// changes with every edit
+		fm, _ := parseFrontmatter(string(content))
+		for _, key := range []string{"created", "date"} {
+			value := frontmatterScalar(fm.Fields[key])
+			if len(value) < len(templateDateLayout) {
+				continue
+			}
+			if created, err := time.ParseInLocation(templateDateLayout, value[:len(templateDateLayout)], time.Local); err == nil {
+				note.created = created
+				break
+			}
+		}
+		note.words = countWords(fm.Body)
+
+		scan.notes = append(scan.notes, note)
+		scan.contents[relPath] = string(content)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking notes directory: %w", err)
+	}
+
+	return scan, nil
+}
+
+// linkGraph resolves the links of the scanned notes. The graph is built on
+// first use, since only the statistics need it.
+func (scan *vaultScan) linkGraph() *linkGraph {
+	if scan.graph == nil {
+		scan.graph = newLinkGraph()
+		resolver := newLinkResolver(scan.files)
+		for _, note := range scan.notes {
+			scan.graph.addNote(note.path, scan.contents[note.path], resolver)
+		}
+		scan.contents = nil
+	}
+
+	return scan.graph
+}
+
+// stats summarizes the scan, listing up to largest of the largest notes
+func (scan *vaultScan) stats(largest int) VaultStats {
+	stats := VaultStats{
+		Notes:          len(scan.notes),
+		Folders:        len(scan.folders),
+		Attachments:    len(scan.files) - len(scan.notes),
+		Growth:         []GrowthPeriod{},
+		Tags:           []TagCount{},
+		Largest:        []NoteSize{},
+		Untagged:       []string{},
+		Orphans:        scan.linkGraph().orphans(),
+		EmptyFolders:   []string{},
+		DuplicateNames: []DuplicateNames{},
+	}
+
+	added := make(map[string]int)
+	tagCounts := make(map[string]int)
+	byName := make(map[string][]string)
+	for _, note := range scan.notes {
+		stats.Words += note.words
+		added[note.created.Format("2006-01")]++
+
+		if len(note.tags) == 0 {
+			stats.Untagged = append(stats.Untagged, note.path)
+		}
+		for _, tag := range note.tags {
+			tagCounts[tag]++
+		}
+
+		name := strings.ToLower(filepath.Base(note.path))
+		byName[name] = append(byName[name], note.path)
+
+		stats.Largest = append(stats.Largest, NoteSize{Path: note.path, Size: note.size, Words: note.words})
+	}
+
+	months := make([]string, 0, len(added))
+	for month := range added {
+		months = append(months, month)
+	}
+	sort.Strings(months)
+	total := 0
+	for _, month := range months {
+		total += added[month]
+		stats.Growth = append(stats.Growth, GrowthPeriod{Month: month, Added: added[month], Total: total})
+	}
+
+	for tag, count := range tagCounts {
+		stats.Tags = append(stats.Tags, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(stats.Tags, func(i, j int) bool {
+		if stats.Tags[i].Count != stats.Tags[j].Count {
+			return stats.Tags[i].Count > stats.Tags[j].Count
+		}
+		return stats.Tags[i].Tag < stats.Tags[j].Tag
+	})
+
+	sort.Slice(stats.Largest, func(i, j int) bool {
+		if stats.Largest[i].Size != stats.Largest[j].Size {
+			return stats.Largest[i].Size > stats.Largest[j].Size
+		}
+		return stats.Largest[i].Path < stats.Largest[j].Path
+	})
+	if len(stats.Largest) > largest {
+		stats.Largest = stats.Largest[:largest]
+	}
+
+	// A folder is empty when no file lives anywhere below it, so collect
+	// every folder above each file
+	occupied := make(map[string]bool)
+	for _, file := range scan.files {
+		for dir := filepath.Dir(file); dir != "." && !occupied[dir]; dir = filepath.Dir(dir) {
+			occupied[dir] = true
+		}
+	}
+	for _, folder := range scan.folders {
+		if !occupied[folder] {
+			stats.EmptyFolders = append(stats.EmptyFolders, folder)
+		}
+	}
+
+	for _, paths := range byName {
+		if len(paths) > 1 {
+			stats.DuplicateNames = append(stats.DuplicateNames, DuplicateNames{Name: filepath.Base(paths[0]), Paths: paths})
+		}
+	}
+	sort.Slice(stats.DuplicateNames, func(i, j int) bool {
+		return strings.ToLower(stats.DuplicateNames[i].Name) < strings.ToLower(stats.DuplicateNames[j].Name)
+	})
+
+	return stats
+}
+
+// countWords counts the words of markdown text, ignoring markup such as
+// heading markers and list bullets
+func countWords(text string) int {
+	words := 0
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			words++
+		}
+	}
+	return words
+}
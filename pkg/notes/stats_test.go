@@ -0,0 +1,79 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var statsVaultNotes = map[string]string{
+	"index.md":             "---\ncreated: 2025-01-05\ntags: [hub]\n---\n# Index\n\nSee [[Projects/alpha]] and [[Work/beta]].\n",
+	"Projects/alpha.md":    "---\ndate: 2025-02-10\n---\n# Alpha\n\nAlpha is a #project with a long description of its goals.\n",
+	"Work/beta.md":         "---\ncreated: 2025-02-20\n---\n# Beta\n\n#project\n",
+	"Work/alpha.md":        "---\ncreated: 2025-03-01T09:00:00Z\n---\n# Other alpha\n",
+	"Projects/diagram.png": "png",
+	".trash/old.md":        "# Old\n",
+	"Empty/":               "",
+	"Archive/2024/":        "",
+}
+
+func TestVaultReport(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, statsVaultNotes)}
+
+	var stats VaultStats
+	result, err := ns.VaultReport(context.Background(), mcp.CallToolRequest{}, VaultReportRequest{Limit: 2})
+	decodeToolResult(t, result, err, &stats)
+
+	if stats.Notes != 4 || stats.Attachments != 1 || stats.Folders != 5 {
+		t.Errorf("Unexpected counts: %+v", stats)
+	}
+	if stats.Words != 5+12+2+2 {
+		t.Errorf("Expected words to be counted without frontmatter, got %d", stats.Words)
+	}
+
+	growth, _ := json.Marshal(stats.Growth)
+	if string(growth) != `[{"month":"2025-01","added":1,"total":1},{"month":"2025-02","added":2,"total":3},{"month":"2025-03","added":1,"total":4}]` {
+		t.Errorf("Unexpected growth: %s", growth)
+	}
+
+	if len(stats.Tags) != 2 || stats.Tags[0] != (TagCount{Tag: "project", Count: 2}) || stats.Tags[1] != (TagCount{Tag: "hub", Count: 1}) {
+		t.Errorf("Unexpected tags: %+v", stats.Tags)
+	}
+	if len(stats.Largest) != 2 || stats.Largest[0].Path != "Projects/alpha.md" {
+		t.Errorf("Unexpected largest notes: %+v", stats.Largest)
+	}
+	if strings.Join(stats.Untagged, ",") != "Work/alpha.md" {
+		t.Errorf("Unexpected untagged notes: %v", stats.Untagged)
+	}
+	if strings.Join(stats.Orphans, ",") != "Work/alpha.md" {
+		t.Errorf("Unexpected orphans: %v", stats.Orphans)
+	}
+	if strings.Join(stats.EmptyFolders, ",") != "Archive,Archive/2024,Empty" {
+		t.Errorf("Unexpected empty folders: %v", stats.EmptyFolders)
+	}
+	if len(stats.DuplicateNames) != 1 || stats.DuplicateNames[0].Name != "alpha.md" ||
+		strings.Join(stats.DuplicateNames[0].Paths, ",") != "Projects/alpha.md,Work/alpha.md" {
+		t.Errorf("Unexpected duplicate names: %+v", stats.DuplicateNames)
+	}
+}
+
+func TestGetVaultStats(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, statsVaultNotes)}
+
+	contents, err := ns.GetVaultStats(context.Background(), mcp.ReadResourceRequest{})
+	if err != nil {
+		t.Fatalf("GetVaultStats failed: %v", err)
+	}
+
+	text := contents[0].(mcp.TextResourceContents)
+	var stats VaultStats
+	if err := json.Unmarshal([]byte(text.Text), &stats); err != nil {
+		t.Fatalf("Invalid JSON returned: %v", err)
+	}
+	if text.URI != "notes://stats" || stats.Notes != 4 || len(stats.Largest) != 4 {
+		t.Errorf("Unexpected stats resource: %+v", stats)
+	}
+}
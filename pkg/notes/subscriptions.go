@@ -0,0 +1,263 @@
+package notes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	methodResourcesSubscribe   = "resources/subscribe"
+	methodResourcesUnsubscribe = "resources/unsubscribe"
+)
+
+// localCapabilities are the capabilities of the requests answered here
+// rather than by the MCP server library, which cannot advertise them
+var localCapabilities = map[string]json.RawMessage{
+	"completions": json.RawMessage(`{}`),
+}
+
+// subscriptionRequest is a resources/subscribe or resources/unsubscribe
+// request from the client
+type subscriptionRequest struct {
+	ID     *mcp.RequestId `json:"id"`
+	Method string         `json:"method"`
+	Params struct {
+		URI string `json:"uri"`
+	} `json:"params"`
+}
+
+// ServeStdio serves the notes server over stdin and stdout until stdin is
+// closed or the process is interrupted. Resource subscriptions and
+// completions are handled here because the MCP server library does not route
+// them.
+func (ns *NotesServer) ServeStdio() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	return ns.serve(ctx, os.Stdin, os.Stdout)
+}
+
+// serve answers subscription and completion requests read from in and passes
+// every other message on to the MCP server
+func (ns *NotesServer) serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	writer := &lockedWriter{w: out}
+	initialize := &initializeWriter{w: writer, pending: make(map[string]bool)}
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		reader := bufio.NewReader(in)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				initialize.expect(line)
+				if response, ok := ns.handleLocalRequest(line); ok {
+					if err := writeMessage(writer, response); err != nil {
+						slog.Error("Failed to write response", "error", err)
+					}
+				} else if _, err := pipeWriter.Write(line); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				pipeWriter.CloseWithError(ignoreEOF(err))
+				return
+			}
+		}
+	}()
+
+	return server.NewStdioServer(ns.McpServer).Listen(ctx, pipeReader, initialize)
+}
+
+// handleLocalRequest answers the requests the MCP server library does not
+// route. It reports false for messages to pass on to the library.
+func (ns *NotesServer) handleLocalRequest(line []byte) (mcp.JSONRPCMessage, bool) {
+	if response, ok := ns.handleSubscription(line); ok {
+		return response, true
+	}
+	return ns.handleCompletion(line)
+}
+
+// handleSubscription answers a resources/subscribe or resources/unsubscribe
+// request. It reports false for any other message.
+func (ns *NotesServer) handleSubscription(line []byte) (mcp.JSONRPCMessage, bool) {
+	var request subscriptionRequest
+	if err := json.Unmarshal(line, &request); err != nil || request.ID == nil {
+		return nil, false
+	}
+	if request.Method != methodResourcesSubscribe && request.Method != methodResourcesUnsubscribe {
+		return nil, false
+	}
+
+	uri := request.Params.URI
+	if !strings.HasPrefix(uri, "notes://") {
+		return mcp.NewJSONRPCError(*request.ID, mcp.INVALID_PARAMS, "unsupported resource URI: "+uri, nil), true
+	}
+
+	if request.Method == methodResourcesSubscribe {
+		ns.subscribe(uri)
+		slog.Debug("Subscribed to resource", "uri", uri)
+	} else {
+		ns.unsubscribe(uri)
+		slog.Debug("Unsubscribed from resource", "uri", uri)
+	}
+
+	return mcp.NewJSONRPCResponse(*request.ID, mcp.Result{}), true
+}
+
+// subscribe adds uri to the subscribed resources
+func (ns *NotesServer) subscribe(uri string) {
+	ns.subscriptionsMu.Lock()
+	defer ns.subscriptionsMu.Unlock()
+
+	if ns.subscriptions == nil {
+		ns.subscriptions = make(map[string]bool)
+	}
+	ns.subscriptions[uri] = true
+}
+
+// unsubscribe removes uri from the subscribed resources
+func (ns *NotesServer) unsubscribe(uri string) {
+	ns.subscriptionsMu.Lock()
+	defer ns.subscriptionsMu.Unlock()
+
+	delete(ns.subscriptions, uri)
+}
+
+// subscribedResources returns the subscribed resource URIs in sorted order
+func (ns *NotesServer) subscribedResources() []string {
+	ns.subscriptionsMu.Lock()
+	defer ns.subscriptionsMu.Unlock()
+
+	uris := make([]string, 0, len(ns.subscriptions))
+	for uri := range ns.subscriptions {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// lockedWriter serializes writes so messages from different goroutines are
+// not interleaved
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(p)
+}
+
+// initializeWriter adds localCapabilities to the responses the MCP server
+// library writes to initialize requests. The library writes one message per
+// Write call.
+type initializeWriter struct {
+	w io.Writer
+
+	mu sync.Mutex
+	// pending holds the IDs of initialize requests not yet answered, as raw JSON
+	pending map[string]bool
+}
+
+// initializeRequest is the part of a request needed to find initialize requests
+type initializeRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+}
+
+// expect records the ID of an initialize request read from the client
+func (iw *initializeWriter) expect(line []byte) {
+	var request initializeRequest
+	if err := json.Unmarshal(line, &request); err != nil || request.Method != string(mcp.MethodInitialize) || request.ID == nil {
+		return
+	}
+
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+	iw.pending[string(request.ID)] = true
+}
+
+func (iw *initializeWriter) Write(p []byte) (int, error) {
+	if rewritten, ok := iw.addCapabilities(p); ok {
+		if _, err := iw.w.Write(rewritten); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	return iw.w.Write(p)
+}
+
+// addCapabilities returns the message with localCapabilities added when it
+// is the result of a pending initialize request
+func (iw *initializeWriter) addCapabilities(p []byte) ([]byte, bool) {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+	if len(iw.pending) == 0 {
+		return nil, false
+	}
+
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(p, &response); err != nil || response["method"] != nil || !iw.pending[string(response["id"])] {
+		return nil, false
+	}
+	delete(iw.pending, string(response["id"]))
+
+	var result map[string]json.RawMessage
+	var capabilities map[string]json.RawMessage
+	if err := json.Unmarshal(response["result"], &result); err != nil {
+		return nil, false
+	}
+	if err := json.Unmarshal(result["capabilities"], &capabilities); err != nil || capabilities == nil {
+		return nil, false
+	}
+
+	for name, capability := range localCapabilities {
+		capabilities[name] = capability
+	}
+	result["capabilities"], _ = json.Marshal(capabilities)
+	response["result"], _ = json.Marshal(result)
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, false
+	}
+	return append(data, '\n'), true
+}
+
+// writeMessage writes a JSON-RPC message as a single line
+func writeMessage(w io.Writer, message mcp.JSONRPCMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ignoreEOF turns io.EOF into nil so closing the input ends the server cleanly
+func ignoreEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package notes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestHandleSubscription(t *testing.T) {
+	ns := &NotesServer{vaultDir: t.TempDir()}
+
+	response, ok := ns.handleSubscription([]byte(`{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"notes://files/a.md"}}`))
+	if !ok {
+		t.Fatal("Expected the subscribe request to be handled")
+	}
+	if _, isResponse := response.(mcp.JSONRPCResponse); !isResponse {
+		t.Errorf("Expected a response, got %+v", response)
+	}
+	if got := ns.subscribedResources(); len(got) != 1 || got[0] != "notes://files/a.md" {
+		t.Errorf("Unexpected subscriptions: %v", got)
+	}
+
+	response, ok = ns.handleSubscription([]byte(`{"jsonrpc":"2.0","id":"x","method":"resources/subscribe","params":{"uri":"file:///etc/passwd"}}`))
+	if errResponse, isError := response.(mcp.JSONRPCError); !ok || !isError || errResponse.Error.Code != mcp.INVALID_PARAMS {
+		t.Errorf("Expected an invalid params error, got %+v", response)
+	}
+
+	if _, ok := ns.handleSubscription([]byte(`{"jsonrpc":"2.0","id":2,"method":"resources/unsubscribe","params":{"uri":"notes://files/a.md"}}`)); !ok {
+		t.Fatal("Expected the unsubscribe request to be handled")
+	}
+	if got := ns.subscribedResources(); len(got) != 0 {
+		t.Errorf("Expected no subscriptions, got %v", got)
+	}
+
+	for _, line := range []string{
+		`{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"notes://files/"}}`,
+		`{"jsonrpc":"2.0","method":"resources/subscribe","params":{"uri":"notes://files/"}}`,
+		`not json`,
+	} {
+		if _, ok := ns.handleSubscription([]byte(line)); ok {
+			t.Errorf("%s should be passed on to the MCP server", line)
+		}
+	}
+}
+
+func TestServe_SubscriptionNotifications(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := NewNotesServer(context.Background(), tempDir)
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- ns.serve(context.Background(), inReader, outWriter)
+	}()
+
+	lines := make(chan map[string]any, 16)
+	go func() {
+		scanner := bufio.NewScanner(outReader)
+		for scanner.Scan() {
+			var message map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &message); err == nil {
+				lines <- message
+			}
+		}
+	}()
+
+	send := func(message string) {
+		if _, err := inWriter.Write([]byte(message + "\n")); err != nil {
+			t.Fatalf("Failed to send %s: %v", message, err)
+		}
+	}
+	receive := func(match func(map[string]any) bool) map[string]any {
+		t.Helper()
+		for {
+			select {
+			case message := <-lines:
+				if match(message) {
+					return message
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for a message")
+			}
+		}
+	}
+	byID := func(id float64) func(map[string]any) bool {
+		return func(message map[string]any) bool { return message["id"] == id }
+	}
+	byMethod := func(method string) func(map[string]any) bool {
+		return func(message map[string]any) bool { return message["method"] == method }
+	}
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`)
+	initialize := receive(byID(1))
+	capabilities, _ := initialize["result"].(map[string]any)["capabilities"].(map[string]any)
+	if resources, _ := capabilities["resources"].(map[string]any); resources["subscribe"] != true || resources["listChanged"] != true {
+		t.Errorf("Expected subscribe and listChanged resource capabilities, got %v", capabilities)
+	}
+	if _, ok := capabilities["completions"].(map[string]any); !ok {
+		t.Errorf("Expected the completions capability, got %v", capabilities)
+	}
+
+	send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	send(`{"jsonrpc":"2.0","id":2,"method":"resources/subscribe","params":{"uri":"notes://files/Projects/"}}`)
+	if response := receive(byID(2)); response["error"] != nil {
+		t.Fatalf("Subscribe failed: %v", response)
+	}
+	send(`{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	receive(byID(3))
+
+	writeNoteForTest(t, tempDir, "Projects/alpha.md", "# Alpha\n")
+	ns.applyVaultChanges(VaultChanges{Created: []string{"Projects/alpha.md"}})
+
+	updated := receive(byMethod(mcp.MethodNotificationResourceUpdated))
+	if params, _ := updated["params"].(map[string]any); params["uri"] != "notes://files/Projects/" {
+		t.Errorf("Unexpected update notification: %v", updated)
+	}
+	receive(byMethod(mcp.MethodNotificationResourcesListChanged))
+
+	inWriter.Close()
+	select {
+	case err := <-done:
+		if err != nil && !strings.Contains(err.Error(), "context canceled") {
+			t.Errorf("Expected the server to stop cleanly, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not stop after the input was closed")
+	}
+}
@@ -0,0 +1,610 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// inlineTagRegex matches an inline #tag that is not only digits. Tags are
+// made of letters, digits, _, - and /, which nests them as in #project/alpha.
+var inlineTagRegex = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]*[\p{L}_/-][\p{L}\p{N}_/-]*)`)
+
+// linkRegex matches the parts of links where a # is not a tag: wikilinks,
+// markdown link destinations, autolinks and bare URLs
+var linkRegex = regexp.MustCompile(`\[\[[^\]]*\]\]|\]\([^)]*\)|<[a-zA-Z][a-zA-Z0-9+.-]*:[^>\s]*>|\b[a-zA-Z][a-zA-Z0-9+.-]*://\S+`)
+
+// tagNameRegex matches a valid tag name without the leading #
+var tagNameRegex = regexp.MustCompile(`^[\p{L}\p{N}_-]+(?:/[\p{L}\p{N}_-]+)*$`)
+
+// frontmatterTagsRegex matches the tags key of the frontmatter and its value
+var frontmatterTagsRegex = regexp.MustCompile(`^(tags?:[ \t]*)(.*?)\s*$`)
+
+// frontmatterTagItemRegex matches an item of a block list of tags
+var frontmatterTagItemRegex = regexp.MustCompile(`^(\s*-[ \t]+)(.*?)\s*$`)
+
+// RenameTagRequest represents a request to rename a tag across the vault
+type RenameTagRequest struct {
+	Tag    string `json:"tag" mcp:"Tag to rename, with or without #"`
+	NewTag string `json:"new_tag" mcp:"New name of the tag"`
+	DryRun bool   `json:"dry_run,omitempty" mcp:"Report the notes that would change without writing them"`
+}
+
+// MergeTagsRequest represents a request to merge tags into one
+type MergeTagsRequest struct {
+	Tags   []string `json:"tags" mcp:"Tags to merge"`
+	Into   string   `json:"into" mcp:"Tag the others are merged into"`
+	DryRun bool     `json:"dry_run,omitempty" mcp:"Report the notes that would change without writing them"`
+}
+
+// DeleteTagRequest represents a request to remove a tag from every note
+type DeleteTagRequest struct {
+	Tag    string `json:"tag" mcp:"Tag to remove, with or without #"`
+	DryRun bool   `json:"dry_run,omitempty" mcp:"Report the notes that would change without writing them"`
+}
+
+// TagEditResult reports the notes changed by rename_tag, merge_tags and
+// delete_tag
+type TagEditResult struct {
+	Tags         []string     `json:"tags"`
+	NewTag       string       `json:"new_tag,omitempty"`
+	DryRun       bool         `json:"dry_run,omitempty"`
+	UpdatedFiles []TaggedFile `json:"updated_files"`
+}
+
+// TaggedFile is a note whose tags were rewritten and how many were changed
+type TaggedFile struct {
+	Path string `json:"path"`
+	Tags int    `json:"tags"`
+}
+
+// TagNode is a tag in the tag tree, e.g. alpha under project for
+// #project/alpha. Count is the number of notes with the tag or a tag nested
+// below it.
+type TagNode struct {
+	Name     string     `json:"name"`
+	Tag      string     `json:"tag"`
+	Count    int        `json:"count"`
+	Children []*TagNode `json:"children,omitempty"`
+}
+
+// TagPrefixResult is the content of notes://collections/tags/{+prefix}
+type TagPrefixResult struct {
+	Prefix string              `json:"prefix"`
+	Tags   map[string][]string `json:"tags"`
+	Notes  []string            `json:"notes"`
+	Tree   []*TagNode          `json:"tree"`
+}
+
+// tagRewrite returns the new name of a tag and whether the tag is changed.
+// An empty name removes the tag.
+type tagRewrite func(tag string) (string, bool)
+
+func (ns *NotesServer) NewRenameTagTool() {
+	tool := mcp.NewTool(
+		"rename_tag",
+		mcp.WithDescription("Rename a tag in the frontmatter tags and inline #hashtags of every note. Nested tags move with it, so renaming project to work turns #project/alpha into #work/alpha"),
+		mcp.WithString("tag", mcp.Description("Tag to rename, with or without #"), mcp.Required()),
+		mcp.WithString("new_tag", mcp.Description("New name of the tag"), mcp.Required()),
+		mcp.WithBoolean("dry_run", mcp.Description("Report the notes that would change without writing them")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.RenameTag))
+}
+
+func (ns *NotesServer) NewMergeTagsTool() {
+	tool := mcp.NewTool(
+		"merge_tags",
+		mcp.WithDescription("Merge tags into one in the frontmatter tags and inline #hashtags of every note, dropping duplicates. Nested tags are merged with their parent"),
+		mcp.WithArray("tags", mcp.Description("Tags to merge"), mcp.WithStringItems(), mcp.Required()),
+		mcp.WithString("into", mcp.Description("Tag the others are merged into"), mcp.Required()),
+		mcp.WithBoolean("dry_run", mcp.Description("Report the notes that would change without writing them")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.MergeTags))
+}
+
+func (ns *NotesServer) NewDeleteTagTool() {
+	tool := mcp.NewTool(
+		"delete_tag",
+		mcp.WithDescription("Remove a tag and the tags nested below it from the frontmatter tags and inline #hashtags of every note"),
+		mcp.WithString("tag", mcp.Description("Tag to remove, with or without #"), mcp.Required()),
+		mcp.WithBoolean("dry_run", mcp.Description("Report the notes that would change without writing them")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.DeleteTag))
+}
+
+// RenameTag renames a tag, and the tags nested below it, in every note
+func (ns *NotesServer) RenameTag(ctx context.Context, req mcp.CallToolRequest, params RenameTagRequest) (*mcp.CallToolResult, error) {
+	tag, newTag := normalizeTag(params.Tag), normalizeTag(params.NewTag)
+	for _, name := range []string{tag, newTag} {
+		if !isValidTag(name) {
+			return toolErrorResult("Invalid tag %q", name), nil
+		}
+	}
+	if tag == newTag {
+		return toolErrorResult("The new tag is the same as the old one: %s", tag), nil
+	}
+
+	result := TagEditResult{Tags: []string{tag}, NewTag: newTag, DryRun: params.DryRun}
+	return ns.rewriteVaultTags("rename_tag", result, func(name string) (string, bool) {
+		if rest, ok := cutTagPrefix(name, tag); ok {
+			return newTag + rest, true
+		}
+		return name, false
+	})
+}
+
+// MergeTags renames several tags, and the tags nested below them, to one
+func (ns *NotesServer) MergeTags(ctx context.Context, req mcp.CallToolRequest, params MergeTagsRequest) (*mcp.CallToolResult, error) {
+	into := normalizeTag(params.Into)
+	if !isValidTag(into) {
+		return toolErrorResult("Invalid tag %q", into), nil
+	}
+
+	var tags []string
+	for _, tag := range params.Tags {
+		tag = normalizeTag(tag)
+		if !isValidTag(tag) {
+			return toolErrorResult("Invalid tag %q", tag), nil
+		}
+		if tag != into {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return toolErrorResult("No tags to merge into %s", into), nil
+	}
+
+	result := TagEditResult{Tags: tags, NewTag: into, DryRun: params.DryRun}
+	return ns.rewriteVaultTags("merge_tags", result, func(name string) (string, bool) {
+		for _, tag := range tags {
+			if rest, ok := cutTagPrefix(name, tag); ok {
+				return into + rest, true
+			}
+		}
+		return name, false
+	})
+}
+
+// DeleteTag removes a tag, and the tags nested below it, from every note
+func (ns *NotesServer) DeleteTag(ctx context.Context, req mcp.CallToolRequest, params DeleteTagRequest) (*mcp.CallToolResult, error) {
+	tag := normalizeTag(params.Tag)
+	if !isValidTag(tag) {
+		return toolErrorResult("Invalid tag %q", tag), nil
+	}
+
+	result := TagEditResult{Tags: []string{tag}, DryRun: params.DryRun}
+	return ns.rewriteVaultTags("delete_tag", result, func(name string) (string, bool) {
+		if _, ok := cutTagPrefix(name, tag); ok {
+			return "", true
+		}
+		return name, false
+	})
+}
+
+// rewriteVaultTags applies rewrite to the tags of every note. The changes
+// are worked out before any note is written so a failed read leaves the
+// vault unchanged.
+func (ns *NotesServer) rewriteVaultTags(tool string, result TagEditResult, rewrite tagRewrite) (*mcp.CallToolResult, error) {
+	files, err := ns.vaultFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan vault: %w", err)
+	}
+
+	var lockedPaths []string
+	for _, file := range files {
+		if isMarkdownFile(file) {
+			lockedPaths = append(lockedPaths, filepath.Join(ns.vaultDir, file))
+		}
+	}
+	unlock := ns.lockPaths(lockedPaths...)
+	defer unlock()
+
+	rewrites := make(map[string]string)
+	result.UpdatedFiles = []TaggedFile{}
+	for _, file := range files {
+		if !isMarkdownFile(file) {
+			continue
+		}
+
+		content, err := utils.ReadFile(filepath.Join(ns.vaultDir, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		updated, changed := rewriteTags(string(content), rewrite)
+		if changed > 0 {
+			rewrites[file] = updated
+			result.UpdatedFiles = append(result.UpdatedFiles, TaggedFile{Path: file, Tags: changed})
+		}
+	}
+
+	if !result.DryRun {
+		for path, content := range rewrites {
+			if err := ns.snapshotNote(filepath.Join(ns.vaultDir, path), tool); err != nil {
+				return nil, err
+			}
+			if err := utils.WriteFile(filepath.Join(ns.vaultDir, path), []byte(content), 0644); err != nil {
+				return nil, fmt.Errorf("failed to update tags in %s: %w", path, err)
+			}
+		}
+	}
+
+	sort.Slice(result.UpdatedFiles, func(i, j int) bool {
+		return result.UpdatedFiles[i].Path < result.UpdatedFiles[j].Path
+	})
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// rewriteTags applies rewrite to the frontmatter tags and inline hashtags of
+// a note. It returns the new content and the number of tags changed.
+func rewriteTags(content string, rewrite tagRewrite) (string, int) {
+	lines := strings.SplitAfter(content, "\n")
+
+	changed := 0
+	fm, fmErr := parseFrontmatter(content)
+	bodyStart := fm.EndLine
+	body := strings.Join(lines[bodyStart:], "")
+
+	var edits []textEdit
+	for _, tag := range inlineTags(body) {
+		newName, ok := rewrite(tag.name)
+		if !ok {
+			continue
+		}
+
+		edit := textEdit{start: tag.start, end: tag.end, text: newName}
+		if newName == "" {
+			// Remove the # and the whitespace before the tag, or after it
+			// when the tag starts the line
+			edit.start--
+			if edit.start > 0 && body[edit.start-1] != '\n' {
+				edit.start--
+			} else if edit.end < len(body) && (body[edit.end] == ' ' || body[edit.end] == '\t') {
+				edit.end++
+			}
+			if n := len(edits); n > 0 && edits[n-1].end > edit.start {
+				edit.start = edits[n-1].end
+			}
+		}
+		edits = append(edits, edit)
+	}
+	changed += len(edits)
+
+	if bodyStart > 0 && fmErr == nil {
+		changed += rewriteFrontmatterTags(lines[1:bodyStart-1], rewrite)
+	}
+	return strings.Join(lines[:bodyStart], "") + applyTextEdits(body, edits), changed
+}
+
+// inlineTag is a #tag in the body of a note. start and end are the byte
+// offsets of its name, after the #.
+type inlineTag struct {
+	name       string
+	start, end int
+}
+
+// extractTags returns the tags of a note: those of the frontmatter tags and
+// tag fields first, then its inline hashtags in order of appearance. Tags
+// that differ only in case are returned once, as first written.
+func extractTags(content string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		if tag != "" && !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	// Frontmatter tags may be a YAML list or a comma separated string
+	fm, err := parseFrontmatter(content)
+	if err == nil {
+		for _, key := range []string{"tags", "tag"} {
+			for _, tag := range frontmatterStrings(fm.Fields[key]) {
+				add(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+			}
+		}
+	}
+
+	body := content
+	if fm.EndLine > 0 {
+		body = strings.Join(strings.SplitAfter(content, "\n")[fm.EndLine:], "")
+	}
+	for _, tag := range inlineTags(body) {
+		add(strings.TrimRight(tag.name, "/"))
+	}
+
+	return tags
+}
+
+// inlineTags finds the hashtags of a note body. A tag follows whitespace or
+// starts a line, so heading markers, issue numbers like #123, escaped \#
+// and the anchors of URLs and links are not tags. Fenced code blocks, inline
+// code, wikilinks, link destinations and URLs are skipped.
+func inlineTags(body string) []inlineTag {
+	var tags []inlineTag
+
+	offset := 0
+	var fences fenceTracker
+	for _, line := range strings.SplitAfter(body, "\n") {
+		lineStart := offset
+		offset += len(line)
+
+		if fences.inCode(line) {
+			continue
+		}
+
+		for _, tag := range lineTags(line) {
+			tag.start += lineStart
+			tag.end += lineStart
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// lineTags finds the hashtags of a line outside a code block, skipping
+// inline code, wikilinks, link destinations and URLs. Offsets are relative
+// to the line.
+func lineTags(line string) []inlineTag {
+	var tags []inlineTag
+	for _, m := range inlineTagRegex.FindAllStringSubmatchIndex(maskLinks(maskInlineCode(line)), -1) {
+		tags = append(tags, inlineTag{name: line[m[2]:m[3]], start: m[2], end: m[3]})
+	}
+	return tags
+}
+
+// maskLinks replaces the links of a line with dots, keeping byte offsets
+// intact. Dots rather than spaces keep a # right after a link from reading
+// as the start of a tag.
+func maskLinks(line string) string {
+	return linkRegex.ReplaceAllStringFunc(line, func(link string) string {
+		return strings.Repeat(".", len(link))
+	})
+}
+
+// rewriteFrontmatterTags applies rewrite to the tags key of the frontmatter
+// lines in place, keeping the list style they were written in. It returns
+// the number of tags changed.
+func rewriteFrontmatterTags(lines []string, rewrite tagRewrite) int {
+	changed := 0
+	for i := 0; i < len(lines); i++ {
+		m := frontmatterTagsRegex.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		newline := lineEnding(lines[i])
+
+		if m[2] != "" {
+			value, count := rewriteTagList(m[2], rewrite)
+			if count > 0 {
+				lines[i] = m[1] + value + newline
+				changed += count
+			}
+			continue
+		}
+
+		// A block list follows on the next lines
+		seen := make(map[string]bool)
+		for i+1 < len(lines) {
+			item := frontmatterTagItemRegex.FindStringSubmatch(lines[i+1])
+			if item == nil {
+				break
+			}
+			i++
+
+			quote, hash, name := splitTagItem(item[2])
+			newName, ok := rewrite(name)
+			if ok {
+				changed++
+			} else {
+				newName = name
+			}
+			if newName == "" || seen[strings.ToLower(newName)] {
+				lines[i] = ""
+				continue
+			}
+			seen[strings.ToLower(newName)] = true
+			if ok {
+				lines[i] = item[1] + quote + hash + newName + quote + lineEnding(lines[i])
+			}
+		}
+	}
+
+	return changed
+}
+
+// rewriteTagList applies rewrite to a flow list such as [a, b] or a comma
+// separated string of tags, dropping duplicates
+func rewriteTagList(value string, rewrite tagRewrite) (string, int) {
+	inner, flow := strings.CutPrefix(value, "[")
+	if flow {
+		inner = strings.TrimSuffix(inner, "]")
+	}
+
+	changed := 0
+	seen := make(map[string]bool)
+	var items []string
+	for _, raw := range strings.Split(inner, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		quote, hash, name := splitTagItem(raw)
+		newName, ok := rewrite(name)
+		if ok {
+			changed++
+			raw = quote + hash + newName + quote
+		} else {
+			newName = name
+		}
+		if newName == "" || seen[strings.ToLower(newName)] {
+			continue
+		}
+		seen[strings.ToLower(newName)] = true
+		items = append(items, raw)
+	}
+
+	if flow || len(items) == 0 {
+		return "[" + strings.Join(items, ", ") + "]", changed
+	}
+	return strings.Join(items, ", "), changed
+}
+
+// splitTagItem splits a YAML tag item into its quote, optional # and name
+func splitTagItem(item string) (quote, hash, name string) {
+	if len(item) >= 2 && (item[0] == '"' || item[0] == '\'') && item[len(item)-1] == item[0] {
+		quote, item = item[:1], item[1:len(item)-1]
+	}
+	if strings.HasPrefix(item, "#") {
+		hash, item = "#", item[1:]
+	}
+	return quote, hash, item
+}
+
+// lineEnding returns the line break at the end of line, if any
+func lineEnding(line string) string {
+	if strings.HasSuffix(line, "\r\n") {
+		return "\r\n"
+	}
+	if strings.HasSuffix(line, "\n") {
+		return "\n"
+	}
+	return ""
+}
+
+// normalizeTag trims whitespace, the leading # and surrounding slashes
+func normalizeTag(tag string) string {
+	return strings.Trim(strings.TrimPrefix(strings.TrimSpace(tag), "#"), "/")
+}
+
+// isValidTag reports whether tag can be written as an inline #tag
+func isValidTag(tag string) bool {
+	return tagNameRegex.MatchString(tag) && !isNumber(tag)
+}
+
+// cutTagPrefix reports whether tag is prefix or nested below it, ignoring
+// case, and returns the rest of the tag after prefix
+func cutTagPrefix(tag, prefix string) (string, bool) {
+	if len(tag) < len(prefix) || !strings.EqualFold(tag[:len(prefix)], prefix) {
+		return "", false
+	}
+	rest := tag[len(prefix):]
+	if rest != "" && !strings.HasPrefix(rest, "/") {
+		return "", false
+	}
+	return rest, true
+}
+
+// buildTagTree nests tags by their / separated parts, ignoring case. The
+// count of each node is the number of distinct notes tagged with it or a tag
+// below it.
+func buildTagTree(tagMap map[string][]string) []*TagNode {
+	type treeNode struct {
+		node     *TagNode
+		notes    map[string]bool
+		children map[string]*treeNode
+	}
+	root := &treeNode{children: make(map[string]*treeNode)}
+
+	// Tags differing only in case share a node, named after the first of
+	// them in sorted order
+	tags := make([]string, 0, len(tagMap))
+	for tag := range tagMap {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		current := root
+		parts := strings.Split(tag, "/")
+		for i, part := range parts {
+			key := strings.ToLower(part)
+			child, exists := current.children[key]
+			if !exists {
+				child = &treeNode{
+					node:     &TagNode{Name: part, Tag: strings.Join(parts[:i+1], "/")},
+					notes:    make(map[string]bool),
+					children: make(map[string]*treeNode),
+				}
+				current.children[key] = child
+			}
+			for _, note := range tagMap[tag] {
+				child.notes[note] = true
+			}
+			current = child
+		}
+	}
+
+	var build func(node *treeNode) []*TagNode
+	build = func(node *treeNode) []*TagNode {
+		nodes := []*TagNode{}
+		for _, child := range node.children {
+			child.node.Count = len(child.notes)
+			if children := build(child); len(children) > 0 {
+				child.node.Children = children
+			}
+			nodes = append(nodes, child.node)
+		}
+		sort.Slice(nodes, func(i, j int) bool { return strings.ToLower(nodes[i].Name) < strings.ToLower(nodes[j].Name) })
+		return nodes
+	}
+
+	return build(root)
+}
+
+// ReadTagPrefix serves notes://collections/tags/{+prefix}, the tags equal to
+// or nested below a prefix and the notes that use them
+func (ns *NotesServer) ReadTagPrefix(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	prefix := normalizeTag(resourceArgument(request, "prefix"))
+
+	scan, err := ns.scanVault()
+	if err != nil {
+		return nil, err
+	}
+
+	result := TagPrefixResult{Prefix: prefix, Tags: make(map[string][]string), Notes: []string{}}
+	notes := make(map[string]bool)
+	for _, note := range scan.notes {
+		for _, tag := range note.tags {
+			if _, ok := cutTagPrefix(tag, prefix); ok {
+				result.Tags[tag] = append(result.Tags[tag], note.path)
+				notes[note.path] = true
+			}
+		}
+	}
+	for note := range notes {
+		result.Notes = append(result.Notes, note)
+	}
+	sort.Strings(result.Notes)
+	result.Tree = buildTagTree(result.Tags)
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(resultJSON),
+		},
+	}, nil
+}
@@ -0,0 +1,250 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var tagVaultNotes = map[string]string{
+	"alpha.md": "---\ntitle: Alpha\ntags: [project/alpha, \"#urgent\"]\n---\n# Alpha\n\nWork on #project/alpha and #projects today.\n\n```\n#project in code\n```\n\nSee `#project` inline.\n",
+	"beta.md":  "---\ntags:\n  - project\n  - work\n---\n#project #work\nBeta note for #Project/beta.\n",
+	"gamma.md": "---\ntags: urgent, work\n---\n# Gamma\n\nNothing about the project here.\n",
+}
+
+func TestRenameTag(t *testing.T) {
+	tempDir := createVaultForTest(t, tagVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	result, err := ns.RenameTag(context.Background(), mcp.CallToolRequest{}, RenameTagRequest{Tag: "#project", NewTag: "work"})
+	var edit TagEditResult
+	decodeToolResult(t, result, err, &edit)
+
+	if len(edit.UpdatedFiles) != 2 || edit.UpdatedFiles[0] != (TaggedFile{Path: "alpha.md", Tags: 2}) ||
+		edit.UpdatedFiles[1] != (TaggedFile{Path: "beta.md", Tags: 3}) {
+		t.Errorf("Unexpected updated files: %+v", edit.UpdatedFiles)
+	}
+
+	alpha := readForTest(t, tempDir, "alpha.md")
+	expected := "---\ntitle: Alpha\ntags: [work/alpha, \"#urgent\"]\n---\n# Alpha\n\nWork on #work/alpha and #projects today.\n\n```\n#project in code\n```\n\nSee `#project` inline.\n"
+	if alpha != expected {
+		t.Errorf("Unexpected alpha.md:\n%s", alpha)
+	}
+
+	// The renamed tag merges with the existing work tag in the frontmatter
+	beta := readForTest(t, tempDir, "beta.md")
+	if beta != "---\ntags:\n  - work\n---\n#work #work\nBeta note for #work/beta.\n" {
+		t.Errorf("Unexpected beta.md:\n%s", beta)
+	}
+}
+
+func TestRenameTag_DryRun(t *testing.T) {
+	tempDir := createVaultForTest(t, tagVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	result, err := ns.RenameTag(context.Background(), mcp.CallToolRequest{}, RenameTagRequest{Tag: "urgent", NewTag: "important", DryRun: true})
+	var edit TagEditResult
+	decodeToolResult(t, result, err, &edit)
+
+	if !edit.DryRun || len(edit.UpdatedFiles) != 2 {
+		t.Errorf("Unexpected dry run result: %+v", edit)
+	}
+	if !strings.Contains(readForTest(t, tempDir, "gamma.md"), "tags: urgent, work") {
+		t.Error("A dry run must not write the notes")
+	}
+}
+
+func TestRenameTag_Invalid(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, tagVaultNotes)}
+
+	for _, params := range []RenameTagRequest{
+		{Tag: "project", NewTag: "two words"},
+		{Tag: "project", NewTag: "123"},
+		{Tag: "", NewTag: "work"},
+		{Tag: "project", NewTag: "project"},
+	} {
+		result, err := ns.RenameTag(context.Background(), mcp.CallToolRequest{}, params)
+		if err != nil || !result.IsError {
+			t.Errorf("Expected an error for %+v, got %v %v", params, result, err)
+		}
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	tempDir := createVaultForTest(t, tagVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	result, err := ns.MergeTags(context.Background(), mcp.CallToolRequest{}, MergeTagsRequest{Tags: []string{"urgent", "work"}, Into: "todo"})
+	var edit TagEditResult
+	decodeToolResult(t, result, err, &edit)
+
+	if len(edit.UpdatedFiles) != 3 {
+		t.Errorf("Unexpected updated files: %+v", edit.UpdatedFiles)
+	}
+	if gamma := readForTest(t, tempDir, "gamma.md"); !strings.HasPrefix(gamma, "---\ntags: todo\n---\n") {
+		t.Errorf("Unexpected gamma.md:\n%s", gamma)
+	}
+	if beta := readForTest(t, tempDir, "beta.md"); !strings.HasPrefix(beta, "---\ntags:\n  - project\n  - todo\n---\n#project #todo\n") {
+		t.Errorf("Unexpected beta.md:\n%s", beta)
+	}
+
+	result, err = ns.MergeTags(context.Background(), mcp.CallToolRequest{}, MergeTagsRequest{Tags: []string{"todo"}, Into: "todo"})
+	if err != nil || !result.IsError {
+		t.Errorf("Expected an error when there is nothing to merge, got %v %v", result, err)
+	}
+}
+
+func TestDeleteTag(t *testing.T) {
+	tempDir := createVaultForTest(t, tagVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	result, err := ns.DeleteTag(context.Background(), mcp.CallToolRequest{}, DeleteTagRequest{Tag: "project"})
+	var edit TagEditResult
+	decodeToolResult(t, result, err, &edit)
+	if len(edit.UpdatedFiles) != 2 {
+		t.Errorf("Unexpected updated files: %+v", edit.UpdatedFiles)
+	}
+
+	alpha := readForTest(t, tempDir, "alpha.md")
+	if !strings.HasPrefix(alpha, "---\ntitle: Alpha\ntags: [\"#urgent\"]\n---\n") || !strings.Contains(alpha, "Work on and #projects today.") {
+		t.Errorf("Unexpected alpha.md:\n%s", alpha)
+	}
+	if beta := readForTest(t, tempDir, "beta.md"); beta != "---\ntags:\n  - work\n---\n#work\nBeta note for.\n" {
+		t.Errorf("Unexpected beta.md:\n%s", beta)
+	}
+
+	result, err = ns.DeleteTag(context.Background(), mcp.CallToolRequest{}, DeleteTagRequest{Tag: "work"})
+	decodeToolResult(t, result, err, &TagEditResult{})
+	if beta := readForTest(t, tempDir, "beta.md"); beta != "---\ntags:\n---\n\nBeta note for.\n" {
+		t.Errorf("Unexpected beta.md:\n%q", beta)
+	}
+	if gamma := readForTest(t, tempDir, "gamma.md"); !strings.HasPrefix(gamma, "---\ntags: urgent\n---\n") {
+		t.Errorf("Unexpected gamma.md:\n%s", gamma)
+	}
+}
+
+func TestCutTagPrefix(t *testing.T) {
+	tests := []struct {
+		tag, prefix, rest string
+		ok                bool
+	}{
+		{"project", "project", "", true},
+		{"Project/alpha", "project", "/alpha", true},
+		{"project/alpha/x", "project/alpha", "/x", true},
+		{"projects", "project", "", false},
+		{"pro", "project", "", false},
+	}
+
+	for _, tt := range tests {
+		rest, ok := cutTagPrefix(tt.tag, tt.prefix)
+		if rest != tt.rest || ok != tt.ok {
+			t.Errorf("cutTagPrefix(%q, %q) = %q, %v", tt.tag, tt.prefix, rest, ok)
+		}
+	}
+}
+
+func TestBuildTagTree(t *testing.T) {
+	tree := buildTagTree(map[string][]string{
+		"project":       {"a.md"},
+		"project/alpha": {"a.md", "b.md"},
+		"project/beta":  {"c.md"},
+		"work":          {"d.md"},
+	})
+
+	treeJSON, _ := json.Marshal(tree)
+	expected := `[{"name":"project","tag":"project","count":3,"children":[{"name":"alpha","tag":"project/alpha","count":2},{"name":"beta","tag":"project/beta","count":1}]},{"name":"work","tag":"work","count":1}]`
+	if string(treeJSON) != expected {
+		t.Errorf("Unexpected tree:\n%s", treeJSON)
+	}
+}
+
+func TestReadTagPrefix(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, tagVaultNotes)}
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = "notes://collections/tags/project"
+	request.Params.Arguments = map[string]any{"prefix": "project"}
+	contents, err := ns.ReadTagPrefix(context.Background(), request)
+	if err != nil {
+		t.Fatalf("ReadTagPrefix failed: %v", err)
+	}
+
+	var result TagPrefixResult
+	if err := json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &result); err != nil {
+		t.Fatalf("Invalid JSON returned: %v", err)
+	}
+	if strings.Join(result.Notes, ",") != "alpha.md,beta.md" {
+		t.Errorf("Unexpected notes: %v", result.Notes)
+	}
+	if _, ok := result.Tags["projects"]; ok {
+		t.Errorf("A tag sharing only the text of the prefix was matched: %v", result.Tags)
+	}
+	if len(result.Tree) != 1 || !strings.EqualFold(result.Tree[0].Tag, "project") || result.Tree[0].Count != 2 {
+		t.Errorf("Unexpected tree: %+v", result.Tree)
+	}
+}
+
+func TestExtractTags_Markdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{"Heading markers", "# Title\n## Section\n###### Deep\n", nil},
+		{"Tag in heading text", "## Plans #roadmap\n", []string{"roadmap"}},
+		{"Tag at line start", "#inbox\n", []string{"inbox"}},
+		{"Issue numbers", "Fixed in #123 and #4567.\n", nil},
+		{"Digits with letters", "Release #2025q1 and #v2\n", []string{"2025q1", "v2"}},
+		{"Trailing punctuation", "Tagged #alpha, #beta. (#gamma) #delta!\n", []string{"alpha", "beta", "delta"}},
+		{"Nested tags", "See #project/alpha and #project/beta/\n", []string{"project/alpha", "project/beta"}},
+		{"Dashes and underscores", "#machine-learning #snake_case #-dash\n", []string{"machine-learning", "snake_case", "-dash"}},
+		{"Unicode letters", "Notes #café #日本語 #Ünïcödé\n", []string{"café", "日本語", "Ünïcödé"}},
+		{"Fenced code", "```c\n#include <stdio.h>\n#define X 1\n```\n~~~\n#notag\n~~~\nAfter #real\n", []string{"real"}},
+		{"Unclosed fence", "```\n#hidden\n", nil},
+		{"Fence with info string does not close", "```\n```go\n#hidden\n```\nAfter #real\n", []string{"real"}},
+		{"Longer outer fence", "````md\n```\n#hidden\n```\n#still\n````\n#real\n", []string{"real"}},
+		{"Inline code", "Use `#pragma once` or ``#nope`` but #yes\n", []string{"yes"}},
+		{"URL fragments", "Visit https://example.com/page#section or http://x.io/#/route #ok\n", []string{"ok"}},
+		{"URL after space", "Docs https://example.com/a #b\n", []string{"b"}},
+		{"Autolink", "<https://example.com/#anchor> #after\n", []string{"after"}},
+		{"Markdown links", "[Jump](#section) [Other](note.md#part) [site](https://x.com/ #frag) #real\n", []string{"real"}},
+		{"Wikilinks", "[[Note#Heading]] [[#Local]] [[Note| #alias]] #kept\n", []string{"kept"}},
+		{"Tag right after link", "[[Note]]#glued\n", nil},
+		{"Escaped and entities", "\\#escaped &#35; a#b\n", nil},
+		{"Case duplicates", "#Work #work #WORK\n", []string{"Work"}},
+		{"Frontmatter list", "---\ntags: [alpha, \"#beta\"]\n---\n#gamma #alpha\n", []string{"alpha", "beta", "gamma"}},
+		{"Frontmatter string", "---\ntags: alpha, beta\ntag: gamma\n---\n#Beta\n", []string{"alpha", "beta", "gamma"}},
+		{"Frontmatter is not scanned", "---\ntitle: \"#notatag\"\n---\nBody\n", nil},
+		{"Invalid frontmatter", "---\ntags: [broken\n---\n#body\n", []string{"body"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := extractTags(tt.content)
+			if strings.Join(tags, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, tags)
+			}
+		})
+	}
+}
+
+func TestRenameTag_SkipsLinksAndCode(t *testing.T) {
+	tempDir := t.TempDir()
+	content := "See [[Note#todo]], [todo](#todo), https://x.com/#todo and `#todo`.\n#todo here\n"
+	writeNoteForTest(t, tempDir, "links.md", content)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	result, err := ns.RenameTag(context.Background(), mcp.CallToolRequest{}, RenameTagRequest{Tag: "todo", NewTag: "next"})
+	var edit TagEditResult
+	decodeToolResult(t, result, err, &edit)
+
+	if len(edit.UpdatedFiles) != 1 || edit.UpdatedFiles[0].Tags != 1 {
+		t.Errorf("Expected a single tag to change, got %+v", edit.UpdatedFiles)
+	}
+	if updated := readForTest(t, tempDir, "links.md"); updated != strings.Replace(content, "#todo here", "#next here", 1) {
+		t.Errorf("Unexpected note:\n%s", updated)
+	}
+}
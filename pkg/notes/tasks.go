@@ -0,0 +1,718 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Task statuses accepted by list_tasks
+const (
+	TaskStatusOpen = "open"
+	TaskStatusDone = "done"
+	TaskStatusAll  = "all"
+)
+
+// taskPriorities are the task priorities from highest to lowest and the
+// emoji that marks them, as written by the Obsidian Tasks plugin
+var taskPriorities = []struct {
+	name  string
+	emoji string
+}{
+	{"highest", "🔺"},
+	{"high", "⏫"},
+	{"medium", "🔼"},
+	{"low", "🔽"},
+	{"lowest", "⏬"},
+}
+
+// taskLineRegex matches a checkbox list item with text and captures the
+// part before the checkbox state, the state and the text
+var taskLineRegex = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+\[)([ xX])(\]\s+)(\S.*?)\s*$`)
+
+// taskDueRegex matches a due date written as 📅 2025-01-15, due: 2025-01-15
+// or @due(2025-01-15)
+var taskDueRegex = regexp.MustCompile(`(?:📅|\bdue::?)\s*(\d{4}-\d{2}-\d{2})|@due\((\d{4}-\d{2}-\d{2})\)`)
+
+// taskAssigneeRegex matches an @name assignee
+var taskAssigneeRegex = regexp.MustCompile(`(?:^|\s)@([\p{L}\p{N}_][\p{L}\p{N}_.-]*)`)
+
+// taskPriorityRegex matches a priority emoji or a priority: field
+var taskPriorityRegex = regexp.MustCompile(`🔺|⏫|🔼|🔽|⏬|(?i:\bpriority::?\s*(highest|high|medium|low|lowest)\b)`)
+
+// taskConventionRegex matches the "action - assignee - YYYY-MM-DD" and
+// "action - YYYY-MM-DD" convention of the meeting template
+var taskConventionRegex = regexp.MustCompile(`^.*?\S(?: - ([^-]*\S))? - (\d{4}-\d{2}-\d{2})$`)
+
+// Task is a checkbox item in a note
+type Task struct {
+	Path     string   `json:"path"`
+	Line     int      `json:"line"`
+	Text     string   `json:"text"`
+	Done     bool     `json:"done"`
+	Due      string   `json:"due,omitempty"`
+	Overdue  bool     `json:"overdue,omitempty"`
+	Assignee string   `json:"assignee,omitempty"`
+	Priority string   `json:"priority,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Heading  string   `json:"heading,omitempty"`
+}
+
+// ListTasksRequest represents a request to list the tasks in the vault
+type ListTasksRequest struct {
+	Status   string `json:"status,omitempty" mcp:"open, done or all (default open)"`
+	Overdue  bool   `json:"overdue,omitempty" mcp:"Only open tasks whose due date has passed"`
+	Assignee string `json:"assignee,omitempty" mcp:"Only tasks assigned to this person"`
+	Folder   string `json:"folder,omitempty" mcp:"Only tasks in notes under this folder"`
+	Tag      string `json:"tag,omitempty" mcp:"Only tasks with this tag"`
+	Limit    int    `json:"limit,omitempty" mcp:"Maximum number of tasks to return"`
+}
+
+// ToggleTaskRequest represents a request to check or uncheck a task
+type ToggleTaskRequest struct {
+	Path         string `json:"path" mcp:"Path to the note file"`
+	Line         int    `json:"line" mcp:"Line number of the task, as returned by list_tasks"`
+	ExpectedText string `json:"expected_text,omitempty" mcp:"Current text of the task; used to find it if the line moved"`
+	ExpectedHash string `json:"expected_hash,omitempty" mcp:"Hash from read_note; the edit is refused if the note changed since"`
+}
+
+// UpdateTaskRequest represents a request to change a task. Fields that are
+// not given are left unchanged and empty strings remove the field.
+type UpdateTaskRequest struct {
+	Path         string  `json:"path" mcp:"Path to the note file"`
+	Line         int     `json:"line" mcp:"Line number of the task, as returned by list_tasks"`
+	ExpectedText string  `json:"expected_text,omitempty" mcp:"Current text of the task; used to find it if the line moved"`
+	Text         *string `json:"text,omitempty" mcp:"New text of the task"`
+	Done         *bool   `json:"done,omitempty" mcp:"Check or uncheck the task"`
+	Due          *string `json:"due,omitempty" mcp:"Due date as YYYY-MM-DD, empty to remove it"`
+	Assignee     *string `json:"assignee,omitempty" mcp:"Assignee, empty to remove it"`
+	Priority     *string `json:"priority,omitempty" mcp:"highest, high, medium, low or lowest, empty to remove it"`
+	ExpectedHash string  `json:"expected_hash,omitempty" mcp:"Hash from read_note; the edit is refused if the note changed since"`
+}
+
+// TaskListResult is the response of list_tasks
+type TaskListResult struct {
+	Count int    `json:"count"`
+	Tasks []Task `json:"tasks"`
+}
+
+// TaskEditResult is the response of toggle_task and update_task
+type TaskEditResult struct {
+	Success bool   `json:"success"`
+	Path    string `json:"path"`
+	Task    Task   `json:"task"`
+	Hash    string `json:"hash"`
+	Message string `json:"message"`
+}
+
+// TaskSummary is the content of the notes://tasks/ resource
+type TaskSummary struct {
+	Open      int            `json:"open"`
+	Done      int            `json:"done"`
+	Overdue   int            `json:"overdue"`
+	Assignees map[string]int `json:"assignees"`
+	Tasks     []Task         `json:"tasks"`
+}
+
+// taskField is a due date, assignee or priority found in a task's text
+type taskField struct {
+	value string
+
+	// start and end are the offsets of the value in the text, removeStart
+	// and removeEnd the range deleted when the field is cleared
+	start, end             int
+	removeStart, removeEnd int
+}
+
+// parsedTask is a task line of a note
+type parsedTask struct {
+	line      int
+	textStart int
+	text      string
+	done      bool
+
+	due      *taskField
+	assignee *taskField
+	priority *taskField
+	tags     []string
+}
+
+// cachedTasks are the tasks of a note and the file state they were read from
+type cachedTasks struct {
+	modTime time.Time
+	size    int64
+	tasks   []Task
+}
+
+func (ns *NotesServer) NewListTasksTool() {
+	tool := mcp.NewTool(
+		"list_tasks",
+		mcp.WithDescription("List checkbox tasks across the vault with their due dates, assignees, priorities and tags"),
+		mcp.WithString("status", mcp.Description("open, done or all (default open)")),
+		mcp.WithBoolean("overdue", mcp.Description("Only open tasks whose due date has passed")),
+		mcp.WithString("assignee", mcp.Description("Only tasks assigned to this person")),
+		mcp.WithString("folder", mcp.Description("Only tasks in notes under this folder")),
+		mcp.WithString("tag", mcp.Description("Only tasks with this tag")),
+		mcp.WithNumber("limit", mcp.Description("Maximum number of tasks to return")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.ListTasks))
+}
+
+func (ns *NotesServer) NewToggleTaskTool() {
+	tool := mcp.NewTool(
+		"toggle_task",
+		mcp.WithDescription("Check an open task or uncheck a done one, editing only its line"),
+		mcp.WithString("path", mcp.Description("Path to the note file"), mcp.Required()),
+		mcp.WithNumber("line", mcp.Description("Line number of the task, as returned by list_tasks"), mcp.Required()),
+		mcp.WithString("expected_text", mcp.Description("Current text of the task; used to find it if the line moved")),
+		mcp.WithString("expected_hash", mcp.Description("Hash from read_note; the edit is refused if the note changed since")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.ToggleTask))
+}
+
+func (ns *NotesServer) NewUpdateTaskTool() {
+	tool := mcp.NewTool(
+		"update_task",
+		mcp.WithDescription("Change the text, state, due date, assignee or priority of a task, editing only its line"),
+		mcp.WithString("path", mcp.Description("Path to the note file"), mcp.Required()),
+		mcp.WithNumber("line", mcp.Description("Line number of the task, as returned by list_tasks"), mcp.Required()),
+		mcp.WithString("expected_text", mcp.Description("Current text of the task; used to find it if the line moved")),
+		mcp.WithString("text", mcp.Description("New text of the task")),
+		mcp.WithBoolean("done", mcp.Description("Check or uncheck the task")),
+		mcp.WithString("due", mcp.Description("Due date as YYYY-MM-DD, empty to remove it")),
+		mcp.WithString("assignee", mcp.Description("Assignee, empty to remove it")),
+		mcp.WithString("priority", mcp.Description("highest, high, medium, low or lowest, empty to remove it")),
+		mcp.WithString("expected_hash", mcp.Description("Hash from read_note; the edit is refused if the note changed since")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.UpdateTask))
+}
+
+// ListTasks lists the tasks in the vault that match the filters
+func (ns *NotesServer) ListTasks(ctx context.Context, req mcp.CallToolRequest, params ListTasksRequest) (*mcp.CallToolResult, error) {
+	status := strings.ToLower(params.Status)
+	switch status {
+	case "":
+		status = TaskStatusOpen
+	case TaskStatusOpen, TaskStatusDone, TaskStatusAll:
+	default:
+		return toolErrorResult("invalid status %q, expected open, done or all", params.Status), nil
+	}
+
+	folder := ""
+	if params.Folder != "" {
+		fullPath, err := utils.ValidatePath(ns.vaultDir, params.Folder)
+		if err != nil {
+			return toolErrorResult("Invalid folder: %v", err), nil
+		}
+		folder, _ = filepath.Rel(ns.vaultDir, fullPath)
+		folder = filepath.ToSlash(folder)
+	}
+
+	tasks, err := ns.vaultTasks(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	assignee := strings.TrimPrefix(params.Assignee, "@")
+	tag := strings.TrimPrefix(params.Tag, "#")
+
+	result := TaskListResult{Tasks: []Task{}}
+	for _, task := range tasks {
+		switch {
+		case status == TaskStatusOpen && task.Done,
+			status == TaskStatusDone && !task.Done,
+			params.Overdue && !task.Overdue,
+			assignee != "" && !strings.EqualFold(task.Assignee, assignee),
+			folder != "" && folder != "." && !strings.HasPrefix(task.Path, folder+"/"),
+			tag != "" && !containsFold(task.Tags, tag):
+			continue
+		}
+		result.Tasks = append(result.Tasks, task)
+	}
+
+	if params.Limit > 0 && len(result.Tasks) > params.Limit {
+		result.Tasks = result.Tasks[:params.Limit]
+	}
+	result.Count = len(result.Tasks)
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// ToggleTask flips the checkbox of a task
+func (ns *NotesServer) ToggleTask(ctx context.Context, req mcp.CallToolRequest, params ToggleTaskRequest) (*mcp.CallToolResult, error) {
+	return ns.editTask(params.Path, params.Line, params.ExpectedText, params.ExpectedHash, "toggle_task", func(task parsedTask, line string) (string, error) {
+		return setTaskDone(line, !task.done), nil
+	})
+}
+
+// UpdateTask changes the fields of a task that are given
+func (ns *NotesServer) UpdateTask(ctx context.Context, req mcp.CallToolRequest, params UpdateTaskRequest) (*mcp.CallToolResult, error) {
+	if params.Text == nil && params.Done == nil && params.Due == nil && params.Assignee == nil && params.Priority == nil {
+		return toolErrorResult("nothing to update: give text, done, due, assignee or priority"), nil
+	}
+
+	return ns.editTask(params.Path, params.Line, params.ExpectedText, params.ExpectedHash, "update_task", func(task parsedTask, line string) (string, error) {
+		if params.Text != nil {
+			text := strings.TrimSpace(*params.Text)
+			if text == "" || strings.Contains(text, "\n") {
+				return "", fmt.Errorf("task text must be a single non-empty line")
+			}
+			line = line[:task.textStart] + text + line[task.textStart+len(task.text):]
+		}
+
+		// Each change works on the line as left by the previous one
+		if params.Done != nil {
+			task, _ = parseTaskLine(line, task.line)
+			line = setTaskDone(line, *params.Done)
+		}
+
+		if params.Due != nil {
+			due := strings.TrimSpace(*params.Due)
+			if due != "" {
+				if _, err := time.Parse(templateDateLayout, due); err != nil {
+					return "", fmt.Errorf("invalid due date %q, expected YYYY-MM-DD", due)
+				}
+			}
+			task, _ = parseTaskLine(line, task.line)
+			line = setTaskField(task, line, task.due, due, "📅 "+due)
+		}
+
+		if params.Assignee != nil {
+			assignee := strings.TrimPrefix(strings.TrimSpace(*params.Assignee), "@")
+			task, _ = parseTaskLine(line, task.line)
+
+			// Only the meeting convention can hold names with spaces
+			mention := task.assignee == nil || strings.HasSuffix(task.text[:task.assignee.start], "@")
+			if mention && strings.ContainsAny(assignee, " \t") {
+				return "", fmt.Errorf("assignee %q cannot contain spaces", assignee)
+			}
+			line = setTaskField(task, line, task.assignee, assignee, "@"+assignee)
+		}
+
+		if params.Priority != nil {
+			priority := strings.ToLower(strings.TrimSpace(*params.Priority))
+			emoji := ""
+			for _, p := range taskPriorities {
+				if p.name == priority {
+					emoji = p.emoji
+				}
+			}
+			if priority != "" && emoji == "" {
+				return "", fmt.Errorf("invalid priority %q, expected highest, high, medium, low or lowest", priority)
+			}
+
+			// Keep a priority: field written as a word
+			task, _ = parseTaskLine(line, task.line)
+			value := emoji
+			if task.priority != nil && !isPriorityEmoji(task.text[task.priority.start:task.priority.end]) {
+				value = priority
+			}
+			line = setTaskField(task, line, task.priority, value, emoji)
+		}
+
+		return line, nil
+	})
+}
+
+// editTask finds a task in a note and replaces its line with the one
+// returned by edit. The rest of the note is left untouched.
+func (ns *NotesServer) editTask(path string, lineNumber int, expectedText, expectedHash, tool string, edit func(task parsedTask, line string) (string, error)) (*mcp.CallToolResult, error) {
+	fullPath, err := utils.ValidatePath(ns.vaultDir, path)
+	if err != nil {
+		return toolErrorResult("Invalid path: %v", err), nil
+	}
+
+	var edited parsedTask
+	updated, err := ns.modifyNote(fullPath, expectedHash, tool, func(content string) (string, error) {
+		task, err := locateTask(parseTasks(content), lineNumber, expectedText)
+		if err != nil {
+			return "", err
+		}
+
+		lines := strings.Split(content, "\n")
+		line, cr := strings.CutSuffix(lines[task.line-1], "\r")
+		line, err = edit(task, line)
+		if err != nil {
+			return "", err
+		}
+		if cr {
+			line += "\r"
+		}
+		lines[task.line-1] = line
+
+		edited, _ = parseTaskLine(strings.TrimSuffix(line, "\r"), task.line)
+		return strings.Join(lines, "\n"), nil
+	})
+	if err != nil {
+		return editErrorResult(err), nil
+	}
+
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	task := newTask(filepath.ToSlash(relativePath), edited, lineSections(updated), time.Now())
+
+	result := TaskEditResult{
+		Success: true,
+		Path:    task.Path,
+		Task:    task,
+		Hash:    contentHash([]byte(updated)),
+		Message: fmt.Sprintf("Successfully updated task on line %d", task.Line),
+	}
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Result: hashMeta([]byte(updated)),
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// ListVaultTasks serves a summary of the tasks in the vault and the open tasks
+func (ns *NotesServer) ListVaultTasks(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	tasks, err := ns.vaultTasks(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	summary := TaskSummary{Assignees: map[string]int{}, Tasks: []Task{}}
+	for _, task := range tasks {
+		if task.Done {
+			summary.Done++
+			continue
+		}
+
+		summary.Open++
+		if task.Overdue {
+			summary.Overdue++
+		}
+		if task.Assignee != "" {
+			summary.Assignees[task.Assignee]++
+		}
+		summary.Tasks = append(summary.Tasks, task)
+	}
+
+	summaryJSON, _ := json.MarshalIndent(summary, "", "  ")
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      "notes://tasks/",
+			MIMEType: "application/json",
+			Text:     string(summaryJSON),
+		},
+	}, nil
+}
+
+// vaultTasks returns the tasks of every note in the vault, ordered by due
+// date and then by path and line. Notes are only read again when their
+// modification time or size changes. The templates folder is skipped since
+// its tasks are placeholders.
+func (ns *NotesServer) vaultTasks(now time.Time) ([]Task, error) {
+	templatesDir, _ := ns.templatesDir()
+
+	ns.tasksMu.Lock()
+	defer ns.tasksMu.Unlock()
+
+	var tasks []Task
+	seen := make(map[string]bool)
+	err := filepath.WalkDir(ns.vaultDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != ns.vaultDir && (isHiddenDir(d.Name()) || path == templatesDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isMarkdownFile(d.Name()) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		seen[path] = true
+		cached, ok := ns.taskCache[path]
+		if !ok || !cached.modTime.Equal(info.ModTime()) || cached.size != info.Size() {
+			content, err := utils.ReadFile(path)
+			if err != nil {
+				slog.Warn("Skipping unreadable note", "path", path, "error", err)
+				return nil
+			}
+
+			relativePath, _ := filepath.Rel(ns.vaultDir, path)
+			cached = cachedTasks{modTime: info.ModTime(), size: info.Size()}
+			sections := lineSections(string(content))
+			for _, task := range parseTasks(string(content)) {
+				cached.tasks = append(cached.tasks, newTask(filepath.ToSlash(relativePath), task, sections, time.Time{}))
+			}
+
+			if ns.taskCache == nil {
+				ns.taskCache = make(map[string]cachedTasks)
+			}
+			ns.taskCache[path] = cached
+		}
+
+		tasks = append(tasks, cached.tasks...)
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to scan vault: %w", err)
+	}
+
+	// Forget notes that were deleted
+	for path := range ns.taskCache {
+		if !seen[path] {
+			delete(ns.taskCache, path)
+		}
+	}
+
+	today := now.Format(templateDateLayout)
+	for i := range tasks {
+		tasks[i].Overdue = !tasks[i].Done && tasks[i].Due != "" && tasks[i].Due < today
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if (a.Due == "") != (b.Due == "") {
+			return a.Due != ""
+		}
+		if a.Due != b.Due {
+			return a.Due < b.Due
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+
+	return tasks, nil
+}
+
+// newTask converts a parsed task into its JSON form. Overdue is only set
+// when now is given.
+func newTask(path string, p parsedTask, sections []string, now time.Time) Task {
+	task := Task{
+		Path: path,
+		Line: p.line,
+		Text: p.text,
+		Done: p.done,
+		Tags: p.tags,
+	}
+	if p.due != nil {
+		task.Due = p.due.value
+		task.Overdue = !now.IsZero() && !p.done && task.Due < now.Format(templateDateLayout)
+	}
+	if p.assignee != nil {
+		task.Assignee = p.assignee.value
+	}
+	if p.priority != nil {
+		task.Priority = p.priority.value
+	}
+	if p.line-1 < len(sections) {
+		task.Heading = sections[p.line-1]
+	}
+
+	return task
+}
+
+// parseTasks returns the tasks of a note, skipping frontmatter and fenced code
+func parseTasks(content string) []parsedTask {
+	lines := strings.Split(content, "\n")
+
+	first := 0
+	if fm, err := parseFrontmatter(content); err == nil {
+		first = fm.EndLine
+	}
+
+	var tasks []parsedTask
+	var fences fenceTracker
+	for i := first; i < len(lines); i++ {
+		line := strings.TrimSuffix(lines[i], "\r")
+		if fences.inCode(line) {
+			continue
+		}
+
+		if task, ok := parseTaskLine(line, i+1); ok {
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks
+}
+
+// parseTaskLine parses a checkbox line and the fields written in its text
+func parseTaskLine(line string, lineNumber int) (parsedTask, bool) {
+	match := taskLineRegex.FindStringSubmatchIndex(line)
+	if match == nil {
+		return parsedTask{}, false
+	}
+
+	task := parsedTask{
+		line:      lineNumber,
+		textStart: match[8],
+		text:      line[match[8]:match[9]],
+		done:      line[match[4]:match[5]] != " ",
+	}
+	text := task.text
+
+	if m := taskDueRegex.FindStringSubmatchIndex(text); m != nil {
+		start, end := m[2], m[3]
+		if start < 0 {
+			start, end = m[4], m[5]
+		}
+		task.due = &taskField{value: text[start:end], start: start, end: end, removeStart: m[0], removeEnd: m[1]}
+	}
+
+	for _, m := range taskAssigneeRegex.FindAllStringSubmatchIndex(text, -1) {
+		name := text[m[2]:m[3]]
+		if name == "due" && strings.HasPrefix(text[m[3]:], "(") {
+			continue
+		}
+		task.assignee = &taskField{value: name, start: m[2], end: m[3], removeStart: m[0], removeEnd: m[3]}
+		break
+	}
+
+	if m := taskPriorityRegex.FindStringSubmatchIndex(text); m != nil {
+		field := &taskField{start: m[0], end: m[1], removeStart: m[0], removeEnd: m[1]}
+		if m[2] >= 0 {
+			field.start, field.end = m[2], m[3]
+			field.value = strings.ToLower(text[m[2]:m[3]])
+		} else {
+			for _, p := range taskPriorities {
+				if p.emoji == text[m[0]:m[1]] {
+					field.value = p.name
+				}
+			}
+		}
+		task.priority = field
+	}
+
+	// The meeting template writes "action - assignee - due date"
+	if m := taskConventionRegex.FindStringSubmatchIndex(text); m != nil {
+		if task.due == nil {
+			task.due = &taskField{value: text[m[4]:m[5]], start: m[4], end: m[5], removeStart: m[4] - len(" - "), removeEnd: m[5]}
+		}
+		if task.assignee == nil && m[2] >= 0 {
+			task.assignee = &taskField{value: text[m[2]:m[3]], start: m[2], end: m[3], removeStart: m[2] - len(" - "), removeEnd: m[3]}
+		}
+	}
+
+	for _, tag := range lineTags(text) {
+		name := strings.TrimRight(tag.name, "/")
+		if !containsFold(task.tags, name) {
+			task.tags = append(task.tags, name)
+		}
+	}
+
+	return task, true
+}
+
+// locateTask finds the task on a line. When the line is not the task with
+// the expected text, e.g. because lines were added above it, the task is
+// looked up by its text instead.
+func locateTask(tasks []parsedTask, line int, expectedText string) (parsedTask, error) {
+	expected := normalizeListItem(expectedText)
+
+	for _, task := range tasks {
+		if task.line == line && (expected == "" || normalizeListItem(task.text) == expected) {
+			return task, nil
+		}
+	}
+
+	if expected == "" {
+		return parsedTask{}, fmt.Errorf("line %d is not a task", line)
+	}
+
+	var matches []parsedTask
+	for _, task := range tasks {
+		if normalizeListItem(task.text) == expected {
+			matches = append(matches, task)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return parsedTask{}, fmt.Errorf("no task with text %q found", expectedText)
+	case 1:
+		return matches[0], nil
+	default:
+		var lines []string
+		for _, task := range matches {
+			lines = append(lines, fmt.Sprint(task.line))
+		}
+		return parsedTask{}, fmt.Errorf("task %q is ambiguous, it is on lines %s", expectedText, strings.Join(lines, ", "))
+	}
+}
+
+// setTaskDone sets the checkbox state of a task line
+func setTaskDone(line string, done bool) string {
+	state := " "
+	if done {
+		state = "x"
+	}
+
+	match := taskLineRegex.FindStringSubmatchIndex(line)
+	return line[:match[4]] + state + line[match[5]:]
+}
+
+// setTaskField replaces the value of a field in a task line, removes the
+// field when value is empty, or appends added when the task has no such field
+func setTaskField(task parsedTask, line string, field *taskField, value, added string) string {
+	text := task.text
+
+	switch {
+	case field != nil && value == "":
+		text = strings.TrimRight(text[:field.removeStart], " \t") + text[field.removeEnd:]
+	case field != nil:
+		text = text[:field.start] + value + text[field.end:]
+	case value != "":
+		text += " " + added
+	default:
+		return line
+	}
+
+	return line[:task.textStart] + strings.TrimSpace(text) + line[task.textStart+len(task.text):]
+}
+
+// isPriorityEmoji reports whether text is one of the priority emoji
+func isPriorityEmoji(text string) bool {
+	for _, p := range taskPriorities {
+		if p.emoji == text {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether list contains value, ignoring case
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var taskVaultNotes = map[string]string{
+	"Projects/alpha.md": `---
+title: Alpha
+---
+# Alpha
+
+## Tasks
+- [ ] Write spec 📅 2020-01-10 ⏫ #work
+- [x] Kickoff meeting @alice
+- [ ] Review budget @bob due: 2999-12-31
+
+` + "```" + `
+- [ ] Not a task
+` + "```" + `
+`,
+	"Meetings/sync.md":     "# Sync\n\n## Action Items\n- [ ] Send notes - Alice - 2020-02-01\n- [ ] \n* [ ] Book room #admin\n",
+	"Templates/meeting.md": "- [ ] {{.ACTION_ITEM}}\n",
+}
+
+func listTasksForTest(t *testing.T, ns *NotesServer, params ListTasksRequest) []Task {
+	t.Helper()
+
+	var result TaskListResult
+	response, err := ns.ListTasks(context.Background(), mcp.CallToolRequest{}, params)
+	decodeToolResult(t, response, err, &result)
+	if result.Count != len(result.Tasks) {
+		t.Errorf("Count %d does not match %d tasks", result.Count, len(result.Tasks))
+	}
+	return result.Tasks
+}
+
+func taskTexts(tasks []Task) string {
+	var texts []string
+	for _, task := range tasks {
+		texts = append(texts, task.Text)
+	}
+	return strings.Join(texts, "|")
+}
+
+func TestParseTaskLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected Task
+	}{
+		{"- [ ] Plain", Task{Text: "Plain"}},
+		{"  1. [X] Numbered", Task{Text: "Numbered", Done: true}},
+		{"- [ ] Ship 📅 2025-01-15 🔼 @alice #work #q1", Task{Text: "Ship 📅 2025-01-15 🔼 @alice #work #q1", Due: "2025-01-15", Priority: "medium", Assignee: "alice", Tags: []string{"work", "q1"}}},
+		{"- [ ] Call @due(2025-03-01) priority: High", Task{Text: "Call @due(2025-03-01) priority: High", Due: "2025-03-01", Priority: "high"}},
+		{"- [ ] Send notes - Alice Smith - 2025-01-20", Task{Text: "Send notes - Alice Smith - 2025-01-20", Due: "2025-01-20", Assignee: "Alice Smith"}},
+		{"- [ ] Pay rent - 2025-02-01", Task{Text: "Pay rent - 2025-02-01", Due: "2025-02-01"}},
+		{"- [ ] Mail bob@example.com about #123", Task{Text: "Mail bob@example.com about #123"}},
+		{"- [ ] Fix `#code` see [[Note#Heading]] and https://x.io/#frag #real/", Task{Text: "Fix `#code` see [[Note#Heading]] and https://x.io/#frag #real/", Tags: []string{"real"}}},
+	}
+
+	for _, tt := range tests {
+		parsed, ok := parseTaskLine(tt.line, 1)
+		if !ok {
+			t.Errorf("%q was not parsed as a task", tt.line)
+			continue
+		}
+
+		got := newTask("", parsed, nil, time.Time{})
+		got.Line = 0
+		if got.Text != tt.expected.Text || got.Done != tt.expected.Done || got.Due != tt.expected.Due ||
+			got.Assignee != tt.expected.Assignee || got.Priority != tt.expected.Priority ||
+			strings.Join(got.Tags, ",") != strings.Join(tt.expected.Tags, ",") {
+			t.Errorf("%q: expected %+v, got %+v", tt.line, tt.expected, got)
+		}
+	}
+
+	for _, line := range []string{"- [ ] ", "- [] nope", "[ ] nope", "- [y] nope"} {
+		if _, ok := parseTaskLine(line, 1); ok {
+			t.Errorf("%q should not be a task", line)
+		}
+	}
+}
+
+func TestParseTasks_SkipsFencedCode(t *testing.T) {
+	tasks := parseTasks("- [ ] Before\n````md\n```go\n- [ ] Inside\n```\n- [ ] Still inside\n````\n~~~\n~~~js\n- [ ] Not a task\n~~~\n- [x] After\n")
+
+	if len(tasks) != 2 || tasks[0].text != "Before" || tasks[1].text != "After" || tasks[1].line != 12 {
+		t.Errorf("Unexpected tasks: %+v", tasks)
+	}
+}
+
+func TestListTasks_Filters(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, taskVaultNotes)}
+
+	// Open tasks come first by due date; code blocks, placeholders and
+	// templates are skipped
+	open := listTasksForTest(t, ns, ListTasksRequest{})
+	if got := taskTexts(open); got != "Write spec 📅 2020-01-10 ⏫ #work|Send notes - Alice - 2020-02-01|Review budget @bob due: 2999-12-31|Book room #admin" {
+		t.Errorf("Unexpected open tasks: %s", got)
+	}
+	if open[0].Path != "Projects/alpha.md" || open[0].Line != 7 || open[0].Heading != "Alpha > Tasks" || !open[0].Overdue {
+		t.Errorf("Unexpected task: %+v", open[0])
+	}
+
+	tests := []struct {
+		name     string
+		params   ListTasksRequest
+		expected string
+	}{
+		{"Done", ListTasksRequest{Status: "done"}, "Kickoff meeting @alice"},
+		{"Overdue", ListTasksRequest{Overdue: true}, "Write spec 📅 2020-01-10 ⏫ #work|Send notes - Alice - 2020-02-01"},
+		{"Assignee", ListTasksRequest{Status: "all", Assignee: "@Alice"}, "Send notes - Alice - 2020-02-01|Kickoff meeting @alice"},
+		{"Folder", ListTasksRequest{Folder: "Meetings"}, "Send notes - Alice - 2020-02-01|Book room #admin"},
+		{"Tag", ListTasksRequest{Tag: "#admin"}, "Book room #admin"},
+		{"Limit", ListTasksRequest{Status: "all", Limit: 1}, "Write spec 📅 2020-01-10 ⏫ #work"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := taskTexts(listTasksForTest(t, ns, tt.params)); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	result, err := ns.ListTasks(context.Background(), mcp.CallToolRequest{}, ListTasksRequest{Status: "later"})
+	if err != nil || !result.IsError {
+		t.Errorf("Expected an invalid status error, got %v %v", result, err)
+	}
+}
+
+func TestListTasks_PicksUpChanges(t *testing.T) {
+	tempDir := createVaultForTest(t, taskVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	if tasks := listTasksForTest(t, ns, ListTasksRequest{Folder: "Meetings"}); len(tasks) != 2 {
+		t.Fatalf("Expected 2 tasks, got %+v", tasks)
+	}
+
+	writeNoteForTest(t, tempDir, "Meetings/sync.md", "- [ ] Only one\n")
+	writeNoteForTest(t, tempDir, "Meetings/new.md", "- [ ] Another one\n")
+	if got := taskTexts(listTasksForTest(t, ns, ListTasksRequest{Folder: "Meetings"})); got != "Another one|Only one" {
+		t.Errorf("Expected the updated tasks, got %s", got)
+	}
+}
+
+func TestToggleTask(t *testing.T) {
+	tempDir := createVaultForTest(t, taskVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+	original := readForTest(t, tempDir, "Meetings/sync.md")
+
+	var edit TaskEditResult
+	result, err := ns.ToggleTask(context.Background(), mcp.CallToolRequest{}, ToggleTaskRequest{Path: "Meetings/sync.md", Line: 4})
+	decodeToolResult(t, result, err, &edit)
+	if !edit.Task.Done || edit.Task.Line != 4 || edit.Task.Heading != "Sync > Action Items" {
+		t.Errorf("Unexpected result: %+v", edit)
+	}
+
+	expected := strings.Replace(original, "- [ ] Send notes", "- [x] Send notes", 1)
+	if got := readForTest(t, tempDir, "Meetings/sync.md"); got != expected || edit.Hash != contentHash([]byte(expected)) {
+		t.Errorf("Expected only the task line to change, got %q", got)
+	}
+
+	// The task moved down a line; it is found by its text
+	writeNoteForTest(t, tempDir, "Meetings/sync.md", "Moved\n"+expected)
+	result, err = ns.ToggleTask(context.Background(), mcp.CallToolRequest{}, ToggleTaskRequest{Path: "Meetings/sync.md", Line: 4, ExpectedText: "Send notes - Alice - 2020-02-01"})
+	decodeToolResult(t, result, err, &edit)
+	if edit.Task.Done || edit.Task.Line != 5 {
+		t.Errorf("Expected the moved task to be unchecked, got %+v", edit.Task)
+	}
+
+	tests := []ToggleTaskRequest{
+		{Path: "Meetings/sync.md", Line: 1},
+		{Path: "Meetings/sync.md", Line: 5, ExpectedText: "Something else"},
+		{Path: "missing.md", Line: 1},
+	}
+	for _, params := range tests {
+		result, err := ns.ToggleTask(context.Background(), mcp.CallToolRequest{}, params)
+		if err != nil || !result.IsError {
+			t.Errorf("Expected an error for %+v, got %v %v", params, result, err)
+		}
+	}
+
+	result, err = ns.ToggleTask(context.Background(), mcp.CallToolRequest{}, ToggleTaskRequest{Path: "Meetings/sync.md", Line: 5, ExpectedHash: "stale"})
+	assertConflict(t, result, err)
+}
+
+func TestUpdateTask(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+
+	strPtr := func(s string) *string { return &s }
+	done := true
+
+	tests := []struct {
+		name     string
+		line     string
+		params   UpdateTaskRequest
+		expected string
+	}{
+		{"Add fields", "- [ ] Ship #work", UpdateTaskRequest{Due: strPtr("2025-01-15"), Assignee: strPtr("@alice"), Priority: strPtr("high")}, "- [ ] Ship #work 📅 2025-01-15 @alice ⏫"},
+		{"Replace fields", "- [ ] Ship 📅 2025-01-15 @alice 🔽", UpdateTaskRequest{Due: strPtr("2025-02-01"), Assignee: strPtr("bob"), Priority: strPtr("highest")}, "- [ ] Ship 📅 2025-02-01 @bob 🔺"},
+		{"Remove fields", "- [ ] Ship @due(2025-01-15) @alice priority: low #work", UpdateTaskRequest{Due: strPtr(""), Assignee: strPtr(""), Priority: strPtr("")}, "- [ ] Ship #work"},
+		{"Keep word priority", "- [ ] Ship priority: low", UpdateTaskRequest{Priority: strPtr("high")}, "- [ ] Ship priority: high"},
+		{"Meeting convention", "  - [ ] Send notes - Alice - 2025-01-20", UpdateTaskRequest{Assignee: strPtr("Bob Jones"), Due: strPtr("2025-01-22"), Done: &done}, "  - [x] Send notes - Bob Jones - 2025-01-22"},
+		{"Remove convention due", "- [ ] Send notes - Alice - 2025-01-20", UpdateTaskRequest{Due: strPtr("")}, "- [ ] Send notes - Alice"},
+		{"New text", "- [x] Old", UpdateTaskRequest{Text: strPtr("New 📅 2025-03-01")}, "- [x] New 📅 2025-03-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeNoteForTest(t, tempDir, "note.md", "# Note\n"+tt.line+"\nafter\n")
+
+			tt.params.Path = "note.md"
+			tt.params.Line = 2
+
+			var edit TaskEditResult
+			result, err := ns.UpdateTask(context.Background(), mcp.CallToolRequest{}, tt.params)
+			decodeToolResult(t, result, err, &edit)
+
+			if got := readForTest(t, tempDir, "note.md"); got != "# Note\n"+tt.expected+"\nafter\n" {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	writeNoteForTest(t, tempDir, "note.md", "- [ ] Ship\n")
+	for _, params := range []UpdateTaskRequest{
+		{},
+		{Due: strPtr("next week")},
+		{Priority: strPtr("urgent")},
+		{Assignee: strPtr("Bob Jones")},
+		{Text: strPtr(" ")},
+	} {
+		params.Path = "note.md"
+		params.Line = 1
+		result, err := ns.UpdateTask(context.Background(), mcp.CallToolRequest{}, params)
+		if err != nil || !result.IsError {
+			t.Errorf("Expected an error for %+v, got %v %v", params, result, err)
+		}
+	}
+}
+
+func TestListVaultTasks(t *testing.T) {
+	ns := &NotesServer{vaultDir: createVaultForTest(t, taskVaultNotes)}
+
+	contents, err := ns.ListVaultTasks(context.Background(), mcp.ReadResourceRequest{})
+	if err != nil {
+		t.Fatalf("ListVaultTasks failed: %v", err)
+	}
+
+	var summary TaskSummary
+	if err := json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &summary); err != nil {
+		t.Fatalf("Invalid JSON returned: %v", err)
+	}
+
+	if summary.Open != 4 || summary.Done != 1 || summary.Overdue != 2 || len(summary.Tasks) != 4 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if summary.Assignees["Alice"] != 1 || summary.Assignees["bob"] != 1 {
+		t.Errorf("Unexpected assignee counts: %v", summary.Assignees)
+	}
+}
+
+func TestListTasks_SkipsTemplatesFolder(t *testing.T) {
+	ns := &NotesServer{vaultDir: t.TempDir(), templatesFolder: "Custom"}
+	writeNoteForTest(t, ns.vaultDir, filepath.Join("Custom", "t.md"), "- [ ] {{.X}}\n")
+	writeNoteForTest(t, ns.vaultDir, filepath.Join("Templates", "t.md"), "- [ ] Real task\n")
+
+	if got := taskTexts(listTasksForTest(t, ns, ListTasksRequest{})); got != "Real task" {
+		t.Errorf("Expected only tasks outside the templates folder, got %s", got)
+	}
+}
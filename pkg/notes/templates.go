@@ -3,6 +3,7 @@ package notes
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,29 +13,34 @@ import (
 
 // NoteTemplate represents a note template
 type NoteTemplate struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Content     string `json:"content"`
-	UseCase     string `json:"use_case"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Content     string             `json:"content"`
+	UseCase     string             `json:"use_case"`
+	Variables   []TemplateVariable `json:"variables,omitempty"`
+
+	// Source is "builtin" or the vault path of a user template
+	Source string `json:"source,omitempty"`
 }
 
 // GetTemplatesRequest represents a request for note templates
 type GetTemplatesRequest struct {
-	TemplateType string `json:"template_type,omitempty" mcp:"Type of template: daily, meeting, research, or all"`
+	TemplateType string `json:"template_type,omitempty" mcp:"Type of template: daily, weekly, monthly, quarterly, meeting, research, project, a user template name, or all"`
 }
 
 // CreateFromTemplateRequest represents a request to create a note from a template
 type CreateFromTemplateRequest struct {
-	Path         string            `json:"path" mcp:"Path for the new note"`
-	TemplateType string            `json:"template_type" mcp:"Template type to use"`
-	Variables    map[string]string `json:"variables,omitempty" mcp:"Variables to substitute in template"`
+	Path         string                 `json:"path" mcp:"Path for the new note"`
+	TemplateType string                 `json:"template_type" mcp:"Template type to use"`
+	Variables    map[string]interface{} `json:"variables,omitempty" mcp:"Variables to substitute in template; lists can be looped over"`
+	DryRun       bool                   `json:"dry_run,omitempty" mcp:"Return the diff of the change without writing it"`
 }
 
 func (ns *NotesServer) NewGetTemplatesTools() {
 	tool := mcp.NewTool(
 		"get_note_templates",
 		mcp.WithDescription("Get available note templates"),
-		mcp.WithString("template_type", mcp.Description("Template type: daily, meeting, research, or all")),
+		mcp.WithString("template_type", mcp.Description("Template type: daily, weekly, monthly, quarterly, meeting, research, project, a user template name, or all")),
 	)
 
 	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.GetNoteTemplates))
@@ -46,14 +52,15 @@ func (ns *NotesServer) NewCreateFromTemplateTool() {
 		mcp.WithDescription("Create a new note from a template"),
 		mcp.WithString("path", mcp.Description("Path for the new note"), mcp.Required()),
 		mcp.WithString("template_type", mcp.Description("Template type to use"), mcp.Required()),
-		mcp.WithObject("variables", mcp.Description("Variables to substitute in template")),
+		mcp.WithObject("variables", mcp.Description("Variables to substitute in template; lists can be looped over")),
+		mcp.WithBoolean("dry_run", mcp.Description("Return the diff of the change without writing it")),
 	)
 
 	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.CreateNoteFromTemplate))
 }
 
 func (ns *NotesServer) GetNoteTemplates(ctx context.Context, req mcp.CallToolRequest, params GetTemplatesRequest) (*mcp.CallToolResult, error) {
-	templates := ns.getBuiltinTemplates()
+	templates := ns.getTemplates()
 
 	if params.TemplateType != "" && params.TemplateType != "all" {
 		// Filter to specific template type
@@ -88,7 +95,7 @@ func (ns *NotesServer) GetNoteTemplates(ctx context.Context, req mcp.CallToolReq
 }
 
 func (ns *NotesServer) CreateNoteFromTemplate(ctx context.Context, req mcp.CallToolRequest, params CreateFromTemplateRequest) (*mcp.CallToolResult, error) {
-	templates := ns.getBuiltinTemplates()
+	templates := ns.getTemplates()
 
 	template, exists := templates[params.TemplateType]
 	if !exists {
@@ -100,27 +107,44 @@ func (ns *NotesServer) CreateNoteFromTemplate(ctx context.Context, req mcp.CallT
 		}, nil
 	}
 
-	// Substitute variables in template
-	content := ns.substituteVariables(template.Content, params.Variables)
+	// Render the template with the given variables
+	rendered, err := renderTemplate(params.TemplateType, template, withNoteTitle(params.Variables, params.Path), time.Now())
+	if err != nil {
+		var missing *MissingVariablesError
+		if errors.As(err, &missing) {
+			return missingVariablesResult(missing), nil
+		}
+		return toolErrorResult("%v", err), nil
+	}
 
 	// Create the note using existing write functionality
 	writeParams := WriteNoteRequest{
 		Path:    params.Path,
-		Content: content,
+		Content: rendered.Content,
+		DryRun:  params.DryRun,
+	}
+
+	result, err := ns.writeNote(writeParams, "create_note_from_template")
+	if err == nil && !result.IsError && len(rendered.Unfilled) > 0 {
+		result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf("Warning: no value given for template variables: %s", strings.Join(rendered.Unfilled, ", "))))
 	}
 
-	return ns.WriteNote(ctx, req, writeParams)
+	return result, err
 }
 
+// getBuiltinTemplates returns the templates that ship with the server. They
+// are rendered with renderTemplate, so dates are filled in when a note is
+// created rather than when the templates are loaded.
 func (ns *NotesServer) getBuiltinTemplates() map[string]NoteTemplate {
-	now := time.Now()
-
 	return map[string]NoteTemplate{
 		"daily": {
 			Name:        "Daily Note",
 			Description: "Template for daily notes with common sections",
 			UseCase:     "Daily journaling, task tracking, quick notes",
-			Content: fmt.Sprintf(`# Daily Note - %s
+			Variables: []TemplateVariable{
+				{Name: "DATE", Description: "Date of the note (YYYY-MM-DD)", Default: "{{today}}"},
+			},
+			Content: `# Daily Note - {{.DATE}}
 
 ## Today's Focus
 - 
@@ -142,24 +166,35 @@ func (ns *NotesServer) getBuiltinTemplates() map[string]NoteTemplate {
 - 
 
 ---
-*Created: %s*`, now.Format("2006-01-02"), now.Format("2006-01-02 15:04")),
+*Created: {{now | date "2006-01-02 15:04"}}*`,
 		},
 
 		"meeting": {
 			Name:        "Meeting Notes",
 			Description: "Template for meeting notes with agenda and action items",
 			UseCase:     "Meeting documentation, action item tracking",
-			Content: fmt.Sprintf(`# Meeting Notes - {{TITLE}}
+			Variables: []TemplateVariable{
+				{Name: "TITLE", Description: "Meeting title"},
+				{Name: "DATE", Description: "Meeting date (YYYY-MM-DD)", Default: "{{today}}"},
+				{Name: "ATTENDEES", Description: "Attendees, as text or a list"},
+				{Name: "DURATION", Description: "Meeting length"},
+				{Name: "AGENDA", Description: "List of agenda items"},
+				{Name: "ACTION_ITEM", Description: "First action item"},
+				{Name: "ASSIGNEE", Description: "Owner of the first action item"},
+				{Name: "DUE_DATE", Description: "Due date of the first action item"},
+			},
+			Content: `# Meeting Notes - {{.TITLE}}
 
-**Date:** %s  
-**Attendees:** {{ATTENDEES}}  
-**Duration:** {{DURATION}}
+**Date:** {{.DATE}}  
+**Attendees:** {{join .ATTENDEES ", "}}  
+**Duration:** {{.DURATION}}
 
 ## Agenda
-1. 
+{{range $i, $item := list .AGENDA}}{{add $i 1}}. {{$item}}
+{{else}}1. 
 2. 
 3. 
-
+{{end}}
 ## Discussion
 
 
@@ -167,26 +202,32 @@ func (ns *NotesServer) getBuiltinTemplates() map[string]NoteTemplate {
 - 
 
 ## Action Items
-- [ ] {{ACTION_ITEM}} - {{ASSIGNEE}} - {{DUE_DATE}}
-- [ ] 
+{{if .ACTION_ITEM}}- [ ] {{.ACTION_ITEM}}{{with .ASSIGNEE}} - {{.}}{{end}}{{with .DUE_DATE}} - {{.}}{{end}}
+{{end}}- [ ] 
 - [ ] 
 
 ## Next Steps
 
 
 ---
-*Meeting notes created: %s*`, now.Format("2006-01-02"), now.Format("2006-01-02 15:04")),
+*Meeting notes created: {{now | date "2006-01-02 15:04"}}*`,
 		},
 
 		"research": {
 			Name:        "Research Notes",
 			Description: "Template for research and study notes",
 			UseCase:     "Academic research, learning notes, literature review",
-			Content: fmt.Sprintf(`# Research Notes - {{TOPIC}}
+			Variables: []TemplateVariable{
+				{Name: "TOPIC", Description: "Research topic"},
+				{Name: "SOURCE", Description: "Source being studied"},
+				{Name: "DATE", Description: "Date of the note (YYYY-MM-DD)", Default: "{{today}}"},
+				{Name: "TAGS", Description: "Tags, as text or a list"},
+			},
+			Content: `# Research Notes - {{.TOPIC}}
 
-**Source:** {{SOURCE}}  
-**Date:** %s  
-**Tags:** {{TAGS}}
+**Source:** {{.SOURCE}}  
+**Date:** {{.DATE}}  
+**Tags:** {{join .TAGS ", "}}
 
 ## Summary
 
@@ -211,19 +252,29 @@ func (ns *NotesServer) getBuiltinTemplates() map[string]NoteTemplate {
 - 
 
 ---
-*Research notes created: %s*`, now.Format("2006-01-02"), now.Format("2006-01-02 15:04")),
+*Research notes created: {{now | date "2006-01-02 15:04"}}*`,
 		},
 
 		"project": {
 			Name:        "Project Notes",
 			Description: "Template for project planning and tracking",
 			UseCase:     "Project management, planning, progress tracking",
-			Content: fmt.Sprintf(`# Project: {{PROJECT_NAME}}
+			Variables: []TemplateVariable{
+				{Name: "PROJECT_NAME", Description: "Project name"},
+				{Name: "STATUS", Description: "Project status", Default: "Planning"},
+				{Name: "START_DATE", Description: "Start date (YYYY-MM-DD)", Default: "{{today}}"},
+				{Name: "DUE_DATE", Description: "Due date (YYYY-MM-DD)"},
+				{Name: "OWNER", Description: "Project owner"},
+				{Name: "MILESTONES", Description: "List of milestones"},
+				{Name: "MILESTONE", Description: "First milestone"},
+				{Name: "DATE", Description: "Date of the first milestone"},
+			},
+			Content: `# Project: {{.PROJECT_NAME}}
 
-**Status:** {{STATUS}}  
-**Start Date:** {{START_DATE}}  
-**Due Date:** {{DUE_DATE}}  
-**Owner:** {{OWNER}}
+**Status:** {{.STATUS}}  
+**Start Date:** {{.START_DATE}}  
+**Due Date:** {{.DUE_DATE}}  
+**Owner:** {{.OWNER}}
 
 ## Objective
 
@@ -236,8 +287,9 @@ func (ns *NotesServer) getBuiltinTemplates() map[string]NoteTemplate {
 - 
 
 ## Milestones
-- [ ] {{MILESTONE}} - {{DATE}}
-- [ ] 
+{{range list .MILESTONES}}- [ ] {{.}}
+{{end}}{{if .MILESTONE}}- [ ] {{.MILESTONE}}{{with .DATE}} - {{.}}{{end}}
+{{end}}- [ ] 
 - [ ] 
 
 ## Tasks
@@ -252,25 +304,90 @@ func (ns *NotesServer) getBuiltinTemplates() map[string]NoteTemplate {
 
 
 ## Progress Log
-### %s
+### {{today}}
 
 
 ---
-*Project notes created: %s*`, now.Format("2006-01-02"), now.Format("2006-01-02 15:04")),
+*Project notes created: {{now | date "2006-01-02 15:04"}}*`,
 		},
-	}
-}
 
-func (ns *NotesServer) substituteVariables(content string, variables map[string]string) string {
-	if variables == nil {
-		return content
-	}
+		"weekly": {
+			Name:        "Weekly Note",
+			Description: "Template for weekly notes with goals and a review",
+			UseCase:     "Weekly planning and review",
+			Variables: []TemplateVariable{
+				{Name: "DATE", Description: "A date in the week (YYYY-MM-DD)", Default: "{{today}}"},
+			},
+			Content: `# Week of {{weekStart .DATE}}
 
-	result := content
-	for key, value := range variables {
-		placeholder := fmt.Sprintf("{{%s}}", key)
-		result = strings.ReplaceAll(result, placeholder, value)
-	}
+## Goals
+- 
+
+## Tasks
+- [ ] 
+
+## Notes
 
-	return result
+
+## Review
+### What went well
+- 
+
+### What to improve
+- 
+
+---
+*Created: {{now | date "2006-01-02 15:04"}}*`,
+		},
+
+		"monthly": {
+			Name:        "Monthly Note",
+			Description: "Template for monthly notes with goals and a review",
+			UseCase:     "Monthly planning and review",
+			Variables: []TemplateVariable{
+				{Name: "DATE", Description: "A date in the month (YYYY-MM-DD)", Default: "{{today}}"},
+			},
+			Content: `# {{date "January 2006" .DATE}}
+
+## Goals
+- 
+
+## Tasks
+- [ ] 
+
+## Highlights
+- 
+
+## Review
+
+
+---
+*Created: {{now | date "2006-01-02 15:04"}}*`,
+		},
+
+		"quarterly": {
+			Name:        "Quarterly Note",
+			Description: "Template for quarterly notes with objectives and a review",
+			UseCase:     "Quarterly objectives and review",
+			Variables: []TemplateVariable{
+				{Name: "DATE", Description: "A date in the quarter (YYYY-MM-DD)", Default: "{{today}}"},
+			},
+			Content: `# {{date "2006" .DATE}} Q{{quarter .DATE}}
+
+## Objectives
+- 
+
+## Key Results
+- 
+
+## Tasks
+- [ ] 
+
+## Review
+
+
+---
+*Created: {{now | date "2006-01-02 15:04"}}*`,
+		},
+	}
 }
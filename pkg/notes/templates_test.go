@@ -220,7 +220,7 @@ func TestCreateNoteFromTemplate_Success(t *testing.T) {
 	params := CreateFromTemplateRequest{
 		Path:         "test-note.md",
 		TemplateType: "daily",
-		Variables: map[string]string{
+		Variables: map[string]interface{}{
 			"CUSTOM_VAR": "test value",
 		},
 	}
@@ -374,7 +374,7 @@ func TestCreateFromTemplateRequest_Validation(t *testing.T) {
 			request: CreateFromTemplateRequest{
 				Path:         "notes/meeting.md",
 				TemplateType: "meeting",
-				Variables: map[string]string{
+				Variables: map[string]interface{}{
 					"TITLE": "Team Meeting",
 					"DATE":  "2025-01-15",
 				},
@@ -0,0 +1,576 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// trashDirName is the vault folder deleted notes are moved into
+	trashDirName = ".trash"
+
+	// defaultTrashRetentionDays is used when no retention has been configured
+	defaultTrashRetentionDays = 30
+)
+
+// TrashItem is a deleted note or folder waiting in the trash. Each item is
+// stored as .trash/<id>/<name> with its manifest in .trash/<id>.json.
+type TrashItem struct {
+	ID           string     `json:"id"`
+	OriginalPath string     `json:"original_path"`
+	DeletedAt    time.Time  `json:"deleted_at"`
+	IsFolder     bool       `json:"is_folder"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// trashManifest is the manifest written next to a trash item. It keeps the
+// version logs of the trashed notes so a restore brings their history back.
+type trashManifest struct {
+	TrashItem
+	History []noteHistory `json:"history,omitempty"`
+}
+
+// DeleteNoteRequest represents a request to move a note or folder to the trash
+type DeleteNoteRequest struct {
+	Path string `json:"path" mcp:"Path of the note or folder to delete"`
+}
+
+// ListTrashRequest represents a request to list the trash
+type ListTrashRequest struct{}
+
+// RestoreNoteRequest represents a request to restore an item from the trash
+type RestoreNoteRequest struct {
+	ID           string `json:"id,omitempty" mcp:"Trash item ID from list_trash"`
+	OriginalPath string `json:"original_path,omitempty" mcp:"Restore the most recent deletion of this path"`
+	Destination  string `json:"destination,omitempty" mcp:"Path to restore to (defaults to the original path)"`
+}
+
+// EmptyTrashRequest represents a request to permanently remove trash items
+type EmptyTrashRequest struct {
+	ID            string `json:"id,omitempty" mcp:"Remove only this trash item"`
+	OlderThanDays int    `json:"older_than_days,omitempty" mcp:"Remove items deleted more than this many days ago (defaults to the retention policy)"`
+	All           bool   `json:"all,omitempty" mcp:"Remove every item regardless of age"`
+}
+
+// ListTrashResult is the response of list_trash
+type ListTrashResult struct {
+	RetentionDays int         `json:"retention_days"`
+	Items         []TrashItem `json:"items"`
+}
+
+// RestoreResult is the response of restore_note
+type RestoreResult struct {
+	ID           string `json:"id"`
+	RestoredPath string `json:"restored_path"`
+	IsFolder     bool   `json:"is_folder"`
+}
+
+// EmptyTrashResult is the response of empty_trash
+type EmptyTrashResult struct {
+	Removed   []TrashItem `json:"removed"`
+	Remaining int         `json:"remaining"`
+}
+
+func (ns *NotesServer) NewDeleteNoteTool() {
+	tool := mcp.NewTool(
+		"delete_note",
+		mcp.WithDescription("Delete a note by moving it to the vault trash, where it can be restored with restore_note"),
+		mcp.WithString("path", mcp.Description("Path of the note to delete"), mcp.Required()),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.DeleteNote))
+}
+
+func (ns *NotesServer) NewDeleteFolderTool() {
+	tool := mcp.NewTool(
+		"delete_folder",
+		mcp.WithDescription("Delete a folder and its contents by moving it to the vault trash, where it can be restored with restore_note"),
+		mcp.WithString("path", mcp.Description("Path of the folder to delete"), mcp.Required()),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.DeleteFolder))
+}
+
+func (ns *NotesServer) NewListTrashTool() {
+	tool := mcp.NewTool(
+		"list_trash",
+		mcp.WithDescription("List deleted notes and folders in the vault trash, newest first"),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.ListTrash))
+}
+
+func (ns *NotesServer) NewRestoreNoteTool() {
+	tool := mcp.NewTool(
+		"restore_note",
+		mcp.WithDescription("Restore a deleted note or folder from the trash by ID or original path"),
+		mcp.WithString("id", mcp.Description("Trash item ID from list_trash")),
+		mcp.WithString("original_path", mcp.Description("Restore the most recent deletion of this path")),
+		mcp.WithString("destination", mcp.Description("Path to restore to (defaults to the original path)")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.RestoreNote))
+}
+
+func (ns *NotesServer) NewEmptyTrashTool() {
+	tool := mcp.NewTool(
+		"empty_trash",
+		mcp.WithDescription("Permanently remove items from the trash. By default only items older than the retention policy are removed"),
+		mcp.WithString("id", mcp.Description("Remove only this trash item")),
+		mcp.WithNumber("older_than_days", mcp.Description("Remove items deleted more than this many days ago (defaults to the retention policy)")),
+		mcp.WithBoolean("all", mcp.Description("Remove every item regardless of age")),
+	)
+
+	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.EmptyTrash))
+}
+
+// DeleteNote moves a note to the trash
+func (ns *NotesServer) DeleteNote(ctx context.Context, req mcp.CallToolRequest, params DeleteNoteRequest) (*mcp.CallToolResult, error) {
+	return ns.deleteToTrash(params.Path, false)
+}
+
+// DeleteFolder moves a folder and everything in it to the trash
+func (ns *NotesServer) DeleteFolder(ctx context.Context, req mcp.CallToolRequest, params DeleteNoteRequest) (*mcp.CallToolResult, error) {
+	return ns.deleteToTrash(params.Path, true)
+}
+
+func (ns *NotesServer) deleteToTrash(path string, folder bool) (*mcp.CallToolResult, error) {
+	fullPath, err := utils.ValidatePath(ns.vaultDir, path)
+	if err != nil {
+		return toolErrorResult("Invalid path: %v", err), nil
+	}
+
+	unlock := ns.lockPaths(fullPath)
+	defer unlock()
+
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	if relativePath == "." {
+		return toolErrorResult("Cannot delete the vault root"), nil
+	}
+
+	if isHiddenPath(relativePath) {
+		return toolErrorResult("Cannot delete hidden path: %s", relativePath), nil
+	}
+
+	info, err := utils.Stat(fullPath)
+	if err != nil {
+		return toolErrorResult("Not found: %s", relativePath), nil
+	}
+
+	if folder && !info.IsDir() {
+		return toolErrorResult("Path is a file, use delete_note: %s", relativePath), nil
+	}
+	if !folder && info.IsDir() {
+		return toolErrorResult("Path is a folder, use delete_folder: %s", relativePath), nil
+	}
+
+	item, err := ns.moveToTrash(relativePath, info.IsDir(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := ns.purgeExpiredTrash(); err != nil {
+		slog.Warn("Failed to purge expired trash items", "error", err)
+	}
+
+	itemJSON, _ := json.MarshalIndent(item, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(itemJSON)),
+		},
+	}, nil
+}
+
+// ListTrash lists the items in the trash
+func (ns *NotesServer) ListTrash(ctx context.Context, req mcp.CallToolRequest, params ListTrashRequest) (*mcp.CallToolResult, error) {
+	items, err := ns.purgeExpiredTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	retention := ns.trashRetention()
+	for i := range items {
+		expires := items[i].DeletedAt.Add(retention)
+		items[i].ExpiresAt = &expires
+	}
+
+	result := ListTrashResult{RetentionDays: int(retention / (24 * time.Hour)), Items: items}
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// RestoreNote moves an item out of the trash back into the vault
+func (ns *NotesServer) RestoreNote(ctx context.Context, req mcp.CallToolRequest, params RestoreNoteRequest) (*mcp.CallToolResult, error) {
+	if params.ID == "" && params.OriginalPath == "" {
+		return toolErrorResult("Either id or original_path is required"), nil
+	}
+
+	items, err := ns.trashItems()
+	if err != nil {
+		return nil, err
+	}
+
+	// Items are sorted newest first, so the first match on the original path
+	// is the most recent deletion
+	var item *TrashItem
+	for i := range items {
+		if params.ID != "" && items[i].ID == params.ID {
+			item = &items[i]
+			break
+		}
+		if params.ID == "" && filepath.Clean(params.OriginalPath) == filepath.Clean(items[i].OriginalPath) {
+			item = &items[i]
+			break
+		}
+	}
+	if item == nil {
+		return toolErrorResult("No trash item found for %s", strings.TrimSpace(params.ID+" "+params.OriginalPath)), nil
+	}
+	if !isValidTrashID(item.ID) {
+		return toolErrorResult("Invalid trash item id: %s", item.ID), nil
+	}
+
+	destination := params.Destination
+	if destination == "" {
+		destination = item.OriginalPath
+	}
+
+	destinationPath, err := utils.ValidatePath(ns.vaultDir, destination)
+	if err != nil {
+		return toolErrorResult("Invalid destination path: %v", err), nil
+	}
+
+	unlock := ns.lockPaths(destinationPath)
+	defer unlock()
+
+	relativePath, _ := filepath.Rel(ns.vaultDir, destinationPath)
+	if relativePath == "." || isHiddenPath(relativePath) {
+		return toolErrorResult("Cannot restore to %s", relativePath), nil
+	}
+
+	if _, err := utils.Stat(destinationPath); err == nil {
+		return toolErrorResult("Destination already exists: %s, choose another destination", relativePath), nil
+	}
+
+	if err := utils.MkdirAll(filepath.Dir(destinationPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	manifest, err := ns.readTrashManifest(item.ID)
+	if err != nil {
+		return nil, err
+	}
+	historyLogs, err := ns.restoreHistoryLogs(manifest.History, item.OriginalPath, relativePath)
+	undoHistory := func() {
+		for _, log := range historyLogs {
+			utils.RemoveAll(log)
+		}
+	}
+	if err != nil {
+		undoHistory()
+		return nil, err
+	}
+
+	trashed := filepath.Join(ns.trashDir(), item.ID, filepath.Base(item.OriginalPath))
+	if err := utils.Rename(trashed, destinationPath); err != nil {
+		undoHistory()
+		return nil, fmt.Errorf("failed to restore %s: %w", item.OriginalPath, err)
+	}
+
+	if err := ns.removeTrashItem(item.ID); err != nil {
+		return nil, err
+	}
+
+	result := RestoreResult{ID: item.ID, RestoredPath: relativePath, IsFolder: item.IsFolder}
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+// EmptyTrash permanently removes items from the trash
+func (ns *NotesServer) EmptyTrash(ctx context.Context, req mcp.CallToolRequest, params EmptyTrashRequest) (*mcp.CallToolResult, error) {
+	if params.OlderThanDays < 0 {
+		return toolErrorResult("older_than_days must not be negative"), nil
+	}
+
+	items, err := ns.trashItems()
+	if err != nil {
+		return nil, err
+	}
+
+	retention := ns.trashRetention()
+	if params.OlderThanDays > 0 {
+		retention = time.Duration(params.OlderThanDays) * 24 * time.Hour
+	}
+	cutoff := time.Now().Add(-retention)
+
+	result := EmptyTrashResult{Removed: []TrashItem{}}
+	found := false
+	for _, item := range items {
+		var remove bool
+		switch {
+		case params.ID != "":
+			remove = item.ID == params.ID
+			found = found || remove
+		case params.All:
+			remove = true
+		default:
+			remove = item.DeletedAt.Before(cutoff)
+		}
+
+		if !remove {
+			result.Remaining++
+			continue
+		}
+
+		if err := ns.removeTrashItem(item.ID); err != nil {
+			return nil, err
+		}
+		result.Removed = append(result.Removed, item)
+	}
+
+	if params.ID != "" && !found {
+		return toolErrorResult("No trash item found for %s", params.ID), nil
+	}
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(string(resultJSON)),
+		},
+	}, nil
+}
+
+func (ns *NotesServer) trashDir() string {
+	return filepath.Join(ns.vaultDir, trashDirName)
+}
+
+// trashRetention returns how long items are kept in the trash
+func (ns *NotesServer) trashRetention() time.Duration {
+	days := ns.trashRetentionDays
+	if days <= 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// moveToTrash moves a vault relative path into the trash and writes its manifest
+func (ns *NotesServer) moveToTrash(relativePath string, folder bool, deletedAt time.Time) (*TrashItem, error) {
+	trashDir := ns.trashDir()
+
+	// IDs sort by deletion time and stay readable; a counter keeps them
+	// unique when the same name is deleted twice within a millisecond
+	base := deletedAt.UTC().Format("20060102T150405.000Z") + "-" + filepath.Base(relativePath)
+	id := base
+	for i := 2; ; i++ {
+		if _, err := utils.Stat(filepath.Join(trashDir, id)); err != nil {
+			break
+		}
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	itemDir := filepath.Join(trashDir, id)
+	if err := utils.MkdirAll(itemDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create trash folder: %w", err)
+	}
+
+	histories, err := ns.notesHistory(relativePath, folder)
+	if err != nil {
+		utils.RemoveAll(itemDir)
+		return nil, err
+	}
+
+	item := &TrashItem{
+		ID:           id,
+		OriginalPath: relativePath,
+		DeletedAt:    deletedAt.UTC(),
+		IsFolder:     folder,
+	}
+
+	manifest, _ := json.MarshalIndent(trashManifest{TrashItem: *item, History: histories}, "", "  ")
+	if err := utils.WriteFile(filepath.Join(trashDir, id+".json"), manifest, 0644); err != nil {
+		utils.RemoveAll(itemDir)
+		return nil, fmt.Errorf("failed to write trash manifest: %w", err)
+	}
+
+	if err := utils.Rename(filepath.Join(ns.vaultDir, relativePath), filepath.Join(itemDir, filepath.Base(relativePath))); err != nil {
+		utils.RemoveAll(itemDir)
+		utils.RemoveAll(filepath.Join(trashDir, id+".json"))
+		return nil, fmt.Errorf("failed to move %s to trash: %w", relativePath, err)
+	}
+
+	// The history now lives in the manifest. A log left behind is only a
+	// stale copy, so failing to remove it does not fail the delete.
+	if err := ns.removeHistoryLogs(histories); err != nil {
+		slog.Warn("Failed to remove the history of a trashed note", "path", relativePath, "error", err)
+	}
+
+	return item, nil
+}
+
+// readTrashManifest reads the manifest of a trash item
+func (ns *NotesServer) readTrashManifest(id string) (*trashManifest, error) {
+	content, err := utils.ReadFile(filepath.Join(ns.trashDir(), id+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest trashManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to read trash manifest %s: %w", id, err)
+	}
+	return &manifest, nil
+}
+
+// trashedHistories returns the version logs kept in the trash manifests
+func (ns *NotesServer) trashedHistories() ([]noteHistory, error) {
+	items, err := ns.trashItems()
+	if err != nil {
+		return nil, err
+	}
+
+	var histories []noteHistory
+	for _, item := range items {
+		if !isValidTrashID(item.ID) {
+			continue
+		}
+		manifest, err := ns.readTrashManifest(item.ID)
+		if err != nil {
+			return nil, err
+		}
+		histories = append(histories, manifest.History...)
+	}
+	return histories, nil
+}
+
+// trashItems reads the trash manifests, newest first. Manifests that cannot
+// be read are skipped.
+func (ns *NotesServer) trashItems() ([]TrashItem, error) {
+	items := []TrashItem{}
+
+	entries, err := utils.ReadDir(ns.trashDir())
+	if err != nil {
+		if _, statErr := utils.Stat(ns.trashDir()); statErr != nil {
+			return items, nil
+		}
+		return nil, fmt.Errorf("failed to read trash: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		content, err := utils.ReadFile(filepath.Join(ns.trashDir(), entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		var item TrashItem
+		if err := json.Unmarshal(content, &item); err != nil || item.ID == "" {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].DeletedAt.Equal(items[j].DeletedAt) {
+			return items[i].DeletedAt.After(items[j].DeletedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+
+	return items, nil
+}
+
+// purgeExpiredTrash permanently removes the items deleted longer ago than
+// the retention policy and returns the rest. An item that cannot be removed
+// is kept and logged, so a purge never fails the tool that triggered it.
+func (ns *NotesServer) purgeExpiredTrash() ([]TrashItem, error) {
+	items, err := ns.trashItems()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-ns.trashRetention())
+	kept := items[:0]
+	for _, item := range items {
+		if item.DeletedAt.Before(cutoff) {
+			err := ns.removeTrashItem(item.ID)
+			if err == nil {
+				continue
+			}
+			slog.Warn("Failed to remove expired trash item", "id", item.ID, "error", err)
+		}
+		kept = append(kept, item)
+	}
+
+	return kept, nil
+}
+
+// isValidTrashID reports whether a trash item ID read from a manifest names
+// an entry directly inside the trash folder
+func isValidTrashID(id string) bool {
+	return id != "" && id != "." && id != ".." && filepath.Base(id) == id
+}
+
+// removeTrashItem permanently deletes a trash item and its manifest, and
+// the snapshots only its history referred to
+func (ns *NotesServer) removeTrashItem(id string) error {
+	if !isValidTrashID(id) {
+		return fmt.Errorf("invalid trash item id: %s", id)
+	}
+
+	var hashes []string
+	if manifest, err := ns.readTrashManifest(id); err == nil {
+		for _, history := range manifest.History {
+			for _, v := range history.Versions {
+				hashes = append(hashes, v.Hash)
+			}
+		}
+	}
+
+	if err := utils.RemoveAll(filepath.Join(ns.trashDir(), id)); err != nil {
+		return fmt.Errorf("failed to remove trash item %s: %w", id, err)
+	}
+
+	if err := utils.RemoveAll(filepath.Join(ns.trashDir(), id+".json")); err != nil {
+		return fmt.Errorf("failed to remove trash manifest %s: %w", id, err)
+	}
+
+	ns.historyMu.Lock()
+	defer ns.historyMu.Unlock()
+	ns.removeUnreferencedObjects(hashes)
+
+	return nil
+}
+
+// isHiddenPath reports whether any folder in a vault relative path is hidden
+func isHiddenPath(relativePath string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(relativePath), "/") {
+		if isHiddenDir(part) {
+			return true
+		}
+	}
+	return false
+}
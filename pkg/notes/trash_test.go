@@ -0,0 +1,240 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KyleBrandon/sibyl/pkg/dto"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var trashVaultNotes = map[string]string{
+	"note.md":           "# Note",
+	"projects/a.md":     "# A",
+	"projects/sub/b.md": "# B",
+}
+
+func TestDeleteAndRestoreNote(t *testing.T) {
+	tempDir := createVaultForTest(t, trashVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	var item TrashItem
+	result, err := ns.DeleteNote(ctx, mcp.CallToolRequest{}, DeleteNoteRequest{Path: "note.md"})
+	decodeToolResult(t, result, err, &item)
+
+	if item.OriginalPath != "note.md" || item.DeletedAt.IsZero() || item.IsFolder {
+		t.Errorf("Unexpected trash item: %+v", item)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "note.md")); !os.IsNotExist(err) {
+		t.Error("Deleted note should be gone from the vault")
+	}
+
+	// Trashed notes no longer show up in listings
+	listResult, err := ns.ListNotes(ctx, mcp.CallToolRequest{}, ListNotesRequest{Recursive: true})
+	if err != nil {
+		t.Fatalf("ListNotes failed: %v", err)
+	}
+	var notes []dto.NoteMetadata
+	if err := json.Unmarshal([]byte(listResult.Content[0].(mcp.TextContent).Text), &notes); err != nil {
+		t.Fatalf("Invalid JSON returned: %v", err)
+	}
+	for _, note := range notes {
+		if note.Name == "note.md" {
+			t.Error("Trashed note should not be listed")
+		}
+	}
+
+	var trash ListTrashResult
+	result, err = ns.ListTrash(ctx, mcp.CallToolRequest{}, ListTrashRequest{})
+	decodeToolResult(t, result, err, &trash)
+
+	if len(trash.Items) != 1 || trash.Items[0].ID != item.ID {
+		t.Fatalf("Expected the deleted note in the trash, got %+v", trash.Items)
+	}
+	if trash.RetentionDays != defaultTrashRetentionDays || trash.Items[0].ExpiresAt == nil {
+		t.Errorf("Expected the default retention policy, got %+v", trash)
+	}
+
+	var restored RestoreResult
+	result, err = ns.RestoreNote(ctx, mcp.CallToolRequest{}, RestoreNoteRequest{OriginalPath: "note.md"})
+	decodeToolResult(t, result, err, &restored)
+
+	if restored.RestoredPath != "note.md" {
+		t.Errorf("Expected note.md to be restored, got %+v", restored)
+	}
+	if got := readForTest(t, tempDir, "note.md"); got != "# Note" {
+		t.Errorf("Restored content changed: %q", got)
+	}
+
+	result, err = ns.ListTrash(ctx, mcp.CallToolRequest{}, ListTrashRequest{})
+	decodeToolResult(t, result, err, &trash)
+	if len(trash.Items) != 0 {
+		t.Errorf("Trash should be empty after restore, got %+v", trash.Items)
+	}
+}
+
+func TestDeleteFolder_RestoreToNewDestination(t *testing.T) {
+	tempDir := createVaultForTest(t, trashVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	var item TrashItem
+	result, err := ns.DeleteFolder(ctx, mcp.CallToolRequest{}, DeleteNoteRequest{Path: "projects"})
+	decodeToolResult(t, result, err, &item)
+
+	if !item.IsFolder {
+		t.Errorf("Expected a folder item, got %+v", item)
+	}
+
+	// Something new now occupies the original path
+	os.MkdirAll(filepath.Join(tempDir, "projects"), 0755)
+
+	result, err = ns.RestoreNote(ctx, mcp.CallToolRequest{}, RestoreNoteRequest{ID: item.ID})
+	if err != nil || !result.IsError {
+		t.Fatal("Restore should not overwrite an existing path")
+	}
+
+	var restored RestoreResult
+	result, err = ns.RestoreNote(ctx, mcp.CallToolRequest{}, RestoreNoteRequest{ID: item.ID, Destination: "old/projects"})
+	decodeToolResult(t, result, err, &restored)
+
+	if got := readForTest(t, tempDir, "old/projects/sub/b.md"); got != "# B" {
+		t.Errorf("Folder contents not restored: %q", got)
+	}
+}
+
+func TestDeleteNote_Errors(t *testing.T) {
+	tempDir := createVaultForTest(t, trashVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		folder bool
+		path   string
+	}{
+		{"Missing note", false, "nope.md"},
+		{"Folder via delete_note", false, "projects"},
+		{"File via delete_folder", true, "note.md"},
+		{"Vault root", true, ""},
+		{"Outside vault", false, "../other.md"},
+		{"Trash itself", true, ".trash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result *mcp.CallToolResult
+			var err error
+			if tt.folder {
+				result, err = ns.DeleteFolder(ctx, mcp.CallToolRequest{}, DeleteNoteRequest{Path: tt.path})
+			} else {
+				result, err = ns.DeleteNote(ctx, mcp.CallToolRequest{}, DeleteNoteRequest{Path: tt.path})
+			}
+			if err != nil {
+				t.Fatalf("Expected MCP error, not Go error: %v", err)
+			}
+			if !result.IsError {
+				t.Errorf("Expected error deleting %q", tt.path)
+			}
+		})
+	}
+}
+
+func TestEmptyTrash_RetentionPolicy(t *testing.T) {
+	tempDir := createVaultForTest(t, trashVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir, trashRetentionDays: 7}
+	ctx := context.Background()
+
+	old, err := ns.moveToTrash("note.md", false, time.Now().AddDate(0, 0, -10))
+	if err != nil {
+		t.Fatalf("moveToTrash failed: %v", err)
+	}
+	if _, err := ns.moveToTrash(filepath.Join("projects", "a.md"), false, time.Now().AddDate(0, 0, -2)); err != nil {
+		t.Fatalf("moveToTrash failed: %v", err)
+	}
+
+	var emptied EmptyTrashResult
+	result, err := ns.EmptyTrash(ctx, mcp.CallToolRequest{}, EmptyTrashRequest{})
+	decodeToolResult(t, result, err, &emptied)
+
+	if len(emptied.Removed) != 1 || emptied.Removed[0].ID != old.ID || emptied.Remaining != 1 {
+		t.Errorf("Expected only the expired item to be removed, got %+v", emptied)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, trashDirName, old.ID)); !os.IsNotExist(err) {
+		t.Error("Expired item should be removed from disk")
+	}
+
+	result, err = ns.EmptyTrash(ctx, mcp.CallToolRequest{}, EmptyTrashRequest{OlderThanDays: 1})
+	decodeToolResult(t, result, err, &emptied)
+	if len(emptied.Removed) != 1 || emptied.Remaining != 0 {
+		t.Errorf("Expected older_than_days to override the policy, got %+v", emptied)
+	}
+
+	result, err = ns.EmptyTrash(ctx, mcp.CallToolRequest{}, EmptyTrashRequest{ID: "missing"})
+	if err != nil || !result.IsError {
+		t.Error("Expected error for unknown trash item")
+	}
+}
+
+func TestListTrash_PurgesExpiredItems(t *testing.T) {
+	tempDir := createVaultForTest(t, trashVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir, trashRetentionDays: 7}
+	ctx := context.Background()
+
+	old, err := ns.moveToTrash("note.md", false, time.Now().AddDate(0, 0, -10))
+	if err != nil {
+		t.Fatalf("moveToTrash failed: %v", err)
+	}
+	recent, err := ns.moveToTrash(filepath.Join("projects", "a.md"), false, time.Now().AddDate(0, 0, -2))
+	if err != nil {
+		t.Fatalf("moveToTrash failed: %v", err)
+	}
+
+	var listed ListTrashResult
+	result, err := ns.ListTrash(ctx, mcp.CallToolRequest{}, ListTrashRequest{})
+	decodeToolResult(t, result, err, &listed)
+
+	if len(listed.Items) != 1 || listed.Items[0].ID != recent.ID {
+		t.Errorf("Expected only the unexpired item, got %+v", listed.Items)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, trashDirName, old.ID)); !os.IsNotExist(err) {
+		t.Error("Expired item should be removed from disk")
+	}
+
+	// Deleting a note purges expired items too
+	if _, err := ns.moveToTrash(filepath.Join("projects", "sub", "b.md"), false, time.Now().AddDate(0, 0, -30)); err != nil {
+		t.Fatalf("moveToTrash failed: %v", err)
+	}
+	result, err = ns.DeleteFolder(ctx, mcp.CallToolRequest{}, DeleteNoteRequest{Path: "projects"})
+	if err != nil || result.IsError {
+		t.Fatalf("DeleteFolder failed: %v %v", err, result)
+	}
+	if items, _ := ns.trashItems(); len(items) != 2 {
+		t.Errorf("Expected the expired item to be purged, got %+v", items)
+	}
+}
+
+func TestRestoreNote_InvalidManifestID(t *testing.T) {
+	tempDir := createVaultForTest(t, trashVaultNotes)
+	ns := &NotesServer{vaultDir: tempDir}
+
+	for _, id := range []string{"..", "../note.md", "projects/a.md"} {
+		manifest, _ := json.Marshal(TrashItem{ID: id, OriginalPath: "note.md", DeletedAt: time.Now()})
+		writeNoteForTest(t, tempDir, filepath.Join(trashDirName, "bad.json"), string(manifest))
+
+		result, err := ns.RestoreNote(context.Background(), mcp.CallToolRequest{}, RestoreNoteRequest{OriginalPath: "note.md", Destination: "restored.md"})
+		if err != nil || !result.IsError {
+			t.Errorf("Expected an error for trash item id %q, got %v %v", id, result, err)
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, "restored.md")); err == nil {
+			t.Fatalf("Trash item id %q restored a file from outside the trash", id)
+		}
+	}
+}
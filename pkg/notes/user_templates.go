@@ -0,0 +1,264 @@
+package notes
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultTemplatesFolder is the vault folder user templates are loaded from
+const defaultTemplatesFolder = "Templates"
+
+// templateSourceBuiltin is the source reported for the built-in templates
+const templateSourceBuiltin = "builtin"
+
+// TemplateVariable describes a variable a template expects
+type TemplateVariable struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description"`
+	Default     string `json:"default,omitempty" yaml:"default"`
+	Required    bool   `json:"required,omitempty" yaml:"required"`
+}
+
+// cachedTemplate is a parsed template file and the file state it was parsed from
+type cachedTemplate struct {
+	modTime  time.Time
+	size     int64
+	key      string
+	template NoteTemplate
+	err      error
+}
+
+// templatesDir returns the vault folder holding user templates
+func (ns *NotesServer) templatesDir() (string, error) {
+	folder := ns.templatesFolder
+	if folder == "" {
+		folder = defaultTemplatesFolder
+	}
+	return utils.ValidatePath(ns.vaultDir, folder)
+}
+
+// getTemplates returns the built-in templates together with the user
+// templates found in the templates folder. User templates replace built-ins
+// of the same name.
+func (ns *NotesServer) getTemplates() map[string]NoteTemplate {
+	templates := ns.getBuiltinTemplates()
+	for key, template := range templates {
+		template.Source = templateSourceBuiltin
+		templates[key] = template
+	}
+
+	for key, template := range ns.getUserTemplates() {
+		templates[key] = template
+	}
+
+	return templates
+}
+
+// getUserTemplates loads the templates in the templates folder. Files are
+// only parsed again when their modification time or size changes, so edits
+// are picked up on the next call without rereading unchanged templates.
+func (ns *NotesServer) getUserTemplates() map[string]NoteTemplate {
+	templates := make(map[string]NoteTemplate)
+	if ns.vaultDir == "" {
+		return templates
+	}
+
+	dir, err := ns.templatesDir()
+	if err != nil {
+		slog.Warn("Invalid templates folder", "folder", ns.templatesFolder, "error", err)
+		return templates
+	}
+
+	ns.templatesMu.Lock()
+	defer ns.templatesMu.Unlock()
+
+	seen := make(map[string]bool)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && isHiddenDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isMarkdownFile(d.Name()) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		seen[path] = true
+		cached, ok := ns.templateCache[path]
+		if !ok || !cached.modTime.Equal(info.ModTime()) || cached.size != info.Size() {
+			cached = loadTemplateFile(ns.vaultDir, dir, path, info)
+			if cached.err != nil {
+				slog.Warn("Skipping invalid template", "path", path, "error", cached.err)
+			}
+			if ns.templateCache == nil {
+				ns.templateCache = make(map[string]cachedTemplate)
+			}
+			ns.templateCache[path] = cached
+		}
+
+		if cached.err == nil {
+			templates[cached.key] = cached.template
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		slog.Warn("Failed to load templates", "folder", dir, "error", err)
+	}
+
+	// Forget templates that were deleted
+	for path := range ns.templateCache {
+		if !seen[path] {
+			delete(ns.templateCache, path)
+		}
+	}
+
+	return templates
+}
+
+// loadTemplateFile reads and parses a template file. The template is keyed
+// by its path in the templates folder without the extension, so
+// work/meeting.md and personal/meeting.md are distinct templates.
+func loadTemplateFile(vaultDir, templatesDir, path string, info fs.FileInfo) cachedTemplate {
+	cached := cachedTemplate{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		key:     strings.TrimSuffix(info.Name(), filepath.Ext(info.Name())),
+	}
+	if rel, err := filepath.Rel(templatesDir, path); err == nil {
+		cached.key = filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+	}
+
+	content, err := utils.ReadFile(path)
+	if err != nil {
+		cached.err = err
+		return cached
+	}
+
+	cached.template, cached.err = parseTemplate(cached.key, string(content))
+	if rel, err := filepath.Rel(vaultDir, path); err == nil {
+		cached.template.Source = filepath.ToSlash(rel)
+	}
+
+	return cached
+}
+
+// parseTemplate builds a template from a template file. The name,
+// description, use_case and variables frontmatter fields describe the
+// template; any other fields stay in the content as frontmatter of the
+// notes created from it.
+func parseTemplate(key, content string) (NoteTemplate, error) {
+	template := NoteTemplate{Name: key, Content: content}
+
+	fm, err := parseFrontmatter(content)
+	if err != nil {
+		return template, err
+	}
+	if fm.EndLine == 0 {
+		return template, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(fm.Raw), &doc); err != nil {
+		return template, err
+	}
+	template.Content = fm.Body
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return template, nil
+	}
+
+	mapping := doc.Content[0]
+	var kept []*yaml.Node
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+
+		var err error
+		switch key.Value {
+		case "name":
+			err = value.Decode(&template.Name)
+		case "description":
+			err = value.Decode(&template.Description)
+		case "use_case":
+			err = value.Decode(&template.UseCase)
+		case "variables":
+			template.Variables, err = parseTemplateVariables(value)
+		default:
+			kept = append(kept, key, value)
+		}
+		if err != nil {
+			return template, fmt.Errorf("invalid template field %s: %w", key.Value, err)
+		}
+	}
+
+	if len(kept) > 0 {
+		mapping.Content = kept
+
+		var b bytes.Buffer
+		encoder := yaml.NewEncoder(&b)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(mapping); err != nil {
+			return template, err
+		}
+		template.Content = frontmatterDelimiter + "\n" + b.String() + frontmatterDelimiter + "\n" + fm.Body
+	}
+
+	return template, nil
+}
+
+// parseTemplateVariables reads the variables declared by a template, either
+// as a list of names or variable definitions, or as a mapping from each name
+// to its description or definition
+func parseTemplateVariables(node *yaml.Node) ([]TemplateVariable, error) {
+	var variables []TemplateVariable
+
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			var variable TemplateVariable
+			if item.Kind == yaml.ScalarNode {
+				variable.Name = item.Value
+			} else if err := item.Decode(&variable); err != nil {
+				return nil, err
+			}
+			if variable.Name == "" {
+				return nil, fmt.Errorf("variable at line %d has no name", item.Line)
+			}
+			variables = append(variables, variable)
+		}
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			variable := TemplateVariable{Name: node.Content[i].Value}
+			value := node.Content[i+1]
+			if value.Kind == yaml.ScalarNode {
+				variable.Description = value.Value
+			} else if err := value.Decode(&variable); err != nil {
+				return nil, err
+			}
+			variable.Name = node.Content[i].Value
+			variables = append(variables, variable)
+		}
+
+	default:
+		return nil, fmt.Errorf("expected a list or mapping")
+	}
+
+	return variables, nil
+}
@@ -0,0 +1,187 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const standupTemplate = `---
+name: Standup
+description: Daily standup notes
+use_case: Team standups
+variables:
+  - name: team
+    description: Team name
+    required: true
+  - name: mood
+    default: fine
+tags: [standup]
+---
+# Standup - {{team}}
+`
+
+func TestParseTemplate(t *testing.T) {
+	template, err := parseTemplate("standup", standupTemplate)
+	if err != nil {
+		t.Fatalf("parseTemplate failed: %v", err)
+	}
+
+	if template.Name != "Standup" || template.Description != "Daily standup notes" || template.UseCase != "Team standups" {
+		t.Errorf("Unexpected template metadata: %+v", template)
+	}
+
+	expectedVariables := []TemplateVariable{
+		{Name: "team", Description: "Team name", Required: true},
+		{Name: "mood", Default: "fine"},
+	}
+	if len(template.Variables) != len(expectedVariables) {
+		t.Fatalf("Expected variables %+v, got %+v", expectedVariables, template.Variables)
+	}
+	for i := range expectedVariables {
+		if template.Variables[i] != expectedVariables[i] {
+			t.Errorf("Expected variable %+v, got %+v", expectedVariables[i], template.Variables[i])
+		}
+	}
+
+	// Fields that do not describe the template stay in the note's frontmatter
+	if template.Content != "---\ntags: [standup]\n---\n# Standup - {{team}}\n" {
+		t.Errorf("Unexpected template content: %q", template.Content)
+	}
+}
+
+func TestParseTemplate_VariablesMappingAndPlainFiles(t *testing.T) {
+	template, err := parseTemplate("review", "---\nvariables:\n  project: Project name\n  owner:\n    required: true\n---\nBody\n")
+	if err != nil {
+		t.Fatalf("parseTemplate failed: %v", err)
+	}
+	if len(template.Variables) != 2 || template.Variables[0] != (TemplateVariable{Name: "project", Description: "Project name"}) ||
+		template.Variables[1] != (TemplateVariable{Name: "owner", Required: true}) {
+		t.Errorf("Unexpected variables: %+v", template.Variables)
+	}
+	if template.Name != "review" || template.Content != "Body\n" {
+		t.Errorf("Unexpected template: %+v", template)
+	}
+
+	// A template without frontmatter is used as is
+	template, err = parseTemplate("plain", "# Plain\n")
+	if err != nil || template.Name != "plain" || template.Content != "# Plain\n" {
+		t.Errorf("Unexpected plain template: %+v %v", template, err)
+	}
+
+	if _, err := parseTemplate("bad", "---\nvariables: 3\n---\n"); err == nil {
+		t.Error("Expected an error for invalid variables")
+	}
+}
+
+func TestGetTemplates_LoadsUserTemplates(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	templatesDir := filepath.Join(tempDir, defaultTemplatesFolder)
+
+	writeNoteForTest(t, templatesDir, "standup.md", standupTemplate)
+	writeNoteForTest(t, templatesDir, "daily.md", "---\nname: My Daily\n---\n# My day\n")
+	writeNoteForTest(t, templatesDir, "work/meeting.md", "---\nname: Work Meeting\n---\n# Work\n")
+	writeNoteForTest(t, templatesDir, "personal/meeting.md", "---\nname: Personal Meeting\n---\n# Personal\n")
+	writeNoteForTest(t, templatesDir, "broken.md", "---\nname: [unclosed\n---\n")
+
+	templates := ns.getTemplates()
+
+	if templates["standup"].Name != "Standup" || templates["standup"].Source != "Templates/standup.md" {
+		t.Errorf("Expected the standup user template, got %+v", templates["standup"])
+	}
+	if templates["daily"].Name != "My Daily" {
+		t.Errorf("User templates should replace built-ins, got %+v", templates["daily"])
+	}
+	if templates["meeting"].Source != templateSourceBuiltin {
+		t.Errorf("Expected the built-in meeting template, got %+v", templates["meeting"])
+	}
+
+	// Templates in subfolders are named by their path, so they do not collide
+	if templates["work/meeting"].Name != "Work Meeting" || templates["personal/meeting"].Name != "Personal Meeting" {
+		t.Errorf("Expected both meeting templates, got %+v and %+v", templates["work/meeting"], templates["personal/meeting"])
+	}
+	if _, exists := templates["broken"]; exists {
+		t.Error("Invalid templates should be skipped")
+	}
+}
+
+func TestGetTemplates_HotReload(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir, templatesFolder: "Meta/Templates"}
+	templatesDir := filepath.Join(tempDir, "Meta", "Templates")
+	path := filepath.Join(templatesDir, "review.md")
+
+	if _, exists := ns.getTemplates()["review"]; exists {
+		t.Fatal("Template should not exist yet")
+	}
+
+	writeNoteForTest(t, templatesDir, "review.md", "---\nname: Review\n---\nv1\n")
+	if got := ns.getTemplates()["review"]; got.Content != "v1\n" || got.Source != "Meta/Templates/review.md" {
+		t.Errorf("Expected the new template, got %+v", got)
+	}
+
+	writeNoteForTest(t, templatesDir, "review.md", "---\nname: Review\n---\nversion 2\n")
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("Failed to touch template: %v", err)
+	}
+	if got := ns.getTemplates()["review"]; got.Content != "version 2\n" {
+		t.Errorf("Expected the edited template, got %+v", got)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove template: %v", err)
+	}
+	if _, exists := ns.getTemplates()["review"]; exists || len(ns.templateCache) != 0 {
+		t.Error("Removed templates should disappear")
+	}
+}
+
+func TestUserTemplates_ToolsAndResource(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	ctx := context.Background()
+
+	writeNoteForTest(t, filepath.Join(tempDir, defaultTemplatesFolder), "standup.md", standupTemplate)
+
+	result, err := ns.GetNoteTemplates(ctx, mcp.CallToolRequest{}, GetTemplatesRequest{TemplateType: "standup"})
+	var templates map[string]NoteTemplate
+	decodeToolResult(t, result, err, &templates)
+	if templates["standup"].Name != "Standup" || len(templates["standup"].Variables) != 2 {
+		t.Errorf("Unexpected get_note_templates result: %+v", templates)
+	}
+
+	contents, err := ns.ListNoteTemplates(ctx, mcp.ReadResourceRequest{})
+	if err != nil {
+		t.Fatalf("ListNoteTemplates failed: %v", err)
+	}
+	var resource map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &resource); err != nil {
+		t.Fatalf("Invalid templates resource: %v", err)
+	}
+	if resource["standup"]["uri"] != "notes://templates/standup" || resource["standup"]["source"] != "Templates/standup.md" {
+		t.Errorf("Unexpected resource entry: %v", resource["standup"])
+	}
+	if _, exists := resource["daily"]; !exists {
+		t.Error("Built-in templates should still be listed")
+	}
+
+	result, err = ns.CreateNoteFromTemplate(ctx, mcp.CallToolRequest{}, CreateFromTemplateRequest{
+		Path:         "standup.md",
+		TemplateType: "standup",
+		Variables:    map[string]interface{}{"team": "Core"},
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("CreateNoteFromTemplate failed: %v %v", err, result)
+	}
+	if got := readForTest(t, tempDir, "standup.md"); !strings.Contains(got, "# Standup - Core") {
+		t.Errorf("Unexpected note content: %q", got)
+	}
+}
@@ -0,0 +1,213 @@
+package notes
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KyleBrandon/sibyl/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// filesResourcePrefix is the URI prefix of notes served under notes://files/
+const filesResourcePrefix = "notes://files/"
+
+// templatesResourcePrefix is the URI prefix of the templates resources
+const templatesResourcePrefix = "notes://templates/"
+
+// fileState is what the watcher remembers about a file to notice changes
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// VaultChanges lists the vault paths created, changed and removed between
+// two scans of the vault
+type VaultChanges struct {
+	Created []string
+	Changed []string
+	Removed []string
+}
+
+// empty reports whether nothing changed
+func (c VaultChanges) empty() bool {
+	return len(c.Created) == 0 && len(c.Changed) == 0 && len(c.Removed) == 0
+}
+
+// paths returns every path that changed in any way
+func (c VaultChanges) paths() []string {
+	paths := append(append(append([]string{}, c.Created...), c.Changed...), c.Removed...)
+	sort.Strings(paths)
+	return paths
+}
+
+// watchVault polls the vault for changes until ctx is done. Changes made by
+// other programs, such as an editor or a sync client, update the caches and
+// search index and are sent to clients as resource notifications.
+func (ns *NotesServer) watchVault(ctx context.Context, interval time.Duration) {
+	previous, err := ns.scanVaultState()
+	if err != nil {
+		slog.Warn("Failed to scan vault for changes", "error", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := ns.scanVaultState()
+		if err != nil {
+			slog.Warn("Failed to scan vault for changes", "error", err)
+			continue
+		}
+
+		if changes := diffVaultState(previous, current); !changes.empty() {
+			slog.Debug("Vault changed", "created", changes.Created, "changed", changes.Changed, "removed", changes.Removed)
+			ns.applyVaultChanges(changes)
+		}
+		previous = current
+	}
+}
+
+// scanVaultState records the modification time and size of every file in
+// the vault, keyed by its slash separated vault path. Hidden folders are
+// skipped.
+func (ns *NotesServer) scanVaultState() (map[string]fileState, error) {
+	state := make(map[string]fileState)
+	err := utils.WalkDir(ns.vaultDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != ns.vaultDir && isHiddenDir(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relativePath, _ := filepath.Rel(ns.vaultDir, path)
+		state[filepath.ToSlash(relativePath)] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+
+	return state, err
+}
+
+// diffVaultState compares two scans of the vault
+func diffVaultState(previous, current map[string]fileState) VaultChanges {
+	var changes VaultChanges
+	for path, state := range current {
+		old, ok := previous[path]
+		switch {
+		case !ok:
+			changes.Created = append(changes.Created, path)
+		case !old.modTime.Equal(state.modTime) || old.size != state.size:
+			changes.Changed = append(changes.Changed, path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			changes.Removed = append(changes.Removed, path)
+		}
+	}
+
+	sort.Strings(changes.Created)
+	sort.Strings(changes.Changed)
+	sort.Strings(changes.Removed)
+	return changes
+}
+
+// applyVaultChanges brings the caches and the search index up to date with
+// changed files and notifies clients
+func (ns *NotesServer) applyVaultChanges(changes VaultChanges) {
+	// The caches and the index check modification times themselves, but a
+	// file rewritten within the timestamp granularity at the same size would
+	// be missed, so drop the entries of every changed file
+	paths := changes.paths()
+
+	ns.tasksMu.Lock()
+	ns.templatesMu.Lock()
+	for _, path := range paths {
+		fullPath := filepath.Join(ns.vaultDir, filepath.FromSlash(path))
+		delete(ns.taskCache, fullPath)
+		delete(ns.templateCache, fullPath)
+	}
+	ns.templatesMu.Unlock()
+	ns.tasksMu.Unlock()
+
+	// Only refresh an index that was loaded; otherwise the next search does
+	ns.indexMu.Lock()
+	if ns.index != nil {
+		for _, path := range paths {
+			ns.index.removeDoc(filepath.FromSlash(path))
+		}
+		if _, err := ns.refreshIndex(); err != nil {
+			slog.Warn("Failed to update search index", "error", err)
+		}
+	}
+	ns.indexMu.Unlock()
+
+	if ns.McpServer == nil {
+		return
+	}
+
+	for _, uri := range ns.affectedResources(changes) {
+		ns.McpServer.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+	}
+	if len(changes.Created) > 0 || len(changes.Removed) > 0 {
+		ns.McpServer.SendNotificationToAllClients(mcp.MethodNotificationResourcesListChanged, nil)
+	}
+}
+
+// affectedResources returns the subscribed resources that changes affect.
+// notes://files/ covers the whole vault, notes://files/<folder>/ the notes
+// under a folder and notes://files/<path> a single note. The templates
+// resources change with the templates folder, and the other resources are
+// built from the whole vault.
+func (ns *NotesServer) affectedResources(changes VaultChanges) []string {
+	paths := changes.paths()
+
+	templatesFolder := ""
+	if dir, err := ns.templatesDir(); err == nil {
+		if rel, err := filepath.Rel(ns.vaultDir, dir); err == nil {
+			templatesFolder = filepath.ToSlash(rel) + "/"
+		}
+	}
+
+	affects := func(uri string) bool {
+		for _, path := range paths {
+			switch {
+			case strings.HasPrefix(uri, filesResourcePrefix):
+				target := strings.TrimPrefix(uri, filesResourcePrefix)
+				if target == "" || target == path || (strings.HasSuffix(target, "/") && strings.HasPrefix(path, target)) {
+					return true
+				}
+			case strings.HasPrefix(uri, templatesResourcePrefix):
+				if templatesFolder != "" && strings.HasPrefix(path, templatesFolder) {
+					return true
+				}
+			default:
+				return true
+			}
+		}
+		return false
+	}
+
+	var affected []string
+	for _, uri := range ns.subscribedResources() {
+		if affects(uri) {
+			affected = append(affected, uri)
+		}
+	}
+	return affected
+}
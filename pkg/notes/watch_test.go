@@ -0,0 +1,133 @@
+package notes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffVaultState(t *testing.T) {
+	now := time.Now()
+	previous := map[string]fileState{
+		"same.md":    {modTime: now, size: 10},
+		"touched.md": {modTime: now, size: 10},
+		"grown.md":   {modTime: now, size: 10},
+		"gone.md":    {modTime: now, size: 10},
+	}
+	current := map[string]fileState{
+		"same.md":    {modTime: now, size: 10},
+		"touched.md": {modTime: now.Add(time.Second), size: 10},
+		"grown.md":   {modTime: now, size: 20},
+		"new.md":     {modTime: now, size: 5},
+	}
+
+	changes := diffVaultState(previous, current)
+	if strings.Join(changes.Created, ",") != "new.md" ||
+		strings.Join(changes.Changed, ",") != "grown.md,touched.md" ||
+		strings.Join(changes.Removed, ",") != "gone.md" {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+
+	if !diffVaultState(current, current).empty() {
+		t.Error("Expected no changes between identical scans")
+	}
+}
+
+func TestScanVaultState(t *testing.T) {
+	tempDir := t.TempDir()
+	writeNoteForTest(t, tempDir, "a.md", "# A\n")
+	writeNoteForTest(t, tempDir, "Folder/image.png", "png")
+	writeNoteForTest(t, tempDir, ".sibyl/index.json", "{}")
+
+	ns := &NotesServer{vaultDir: tempDir}
+	state, err := ns.scanVaultState()
+	if err != nil {
+		t.Fatalf("scanVaultState failed: %v", err)
+	}
+	if len(state) != 2 || state["a.md"].size != 4 || state["Folder/image.png"].size != 3 {
+		t.Errorf("Unexpected vault state: %+v", state)
+	}
+}
+
+func TestApplyVaultChanges_RefreshesCachesAndIndex(t *testing.T) {
+	tempDir := t.TempDir()
+	ns := &NotesServer{vaultDir: tempDir}
+	writeNoteForTest(t, tempDir, "note.md", "- [ ] apple\n")
+
+	if got := taskTexts(listTasksForTest(t, ns, ListTasksRequest{})); got != "apple" {
+		t.Fatalf("Expected the apple task, got %s", got)
+	}
+	if err := ns.withIndex(func(idx *searchIndex) error { return nil }); err != nil {
+		t.Fatalf("Failed to build the index: %v", err)
+	}
+
+	// Rewrite the note at the same size and modification time, which the
+	// caches cannot notice by themselves
+	path := filepath.Join(tempDir, "note.md")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat note: %v", err)
+	}
+	writeNoteForTest(t, tempDir, "note.md", "- [ ] lemon\n")
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("Failed to reset modification time: %v", err)
+	}
+
+	ns.applyVaultChanges(VaultChanges{Changed: []string{"note.md"}})
+
+	if got := taskTexts(listTasksForTest(t, ns, ListTasksRequest{})); got != "lemon" {
+		t.Errorf("Expected the task cache to be refreshed, got %s", got)
+	}
+	terms := ns.index.Docs["note.md"].Terms
+	if terms["lemon"] == 0 || terms["apple"] != 0 {
+		t.Errorf("Expected the index to be refreshed, got %v", terms)
+	}
+}
+
+func TestAffectedResources(t *testing.T) {
+	ns := &NotesServer{vaultDir: t.TempDir()}
+	for _, uri := range []string{
+		"notes://files/",
+		"notes://files/Projects/",
+		"notes://files/Projects/alpha.md",
+		"notes://files/Projects/beta.md",
+		"notes://files/Other/",
+		"notes://templates/",
+		"notes://tasks/",
+	} {
+		ns.subscribe(uri)
+	}
+
+	tests := []struct {
+		changes  VaultChanges
+		expected string
+	}{
+		{
+			VaultChanges{Changed: []string{"Projects/alpha.md"}},
+			"notes://files/,notes://files/Projects/,notes://files/Projects/alpha.md,notes://tasks/",
+		},
+		{
+			VaultChanges{Created: []string{"Templates/meeting.md"}},
+			"notes://files/,notes://tasks/,notes://templates/",
+		},
+		{
+			VaultChanges{Removed: []string{"Other/x.md", "Projects/beta.md"}},
+			"notes://files/,notes://files/Other/,notes://files/Projects/,notes://files/Projects/beta.md,notes://tasks/",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := strings.Join(ns.affectedResources(tt.changes), ","); got != tt.expected {
+			t.Errorf("%+v: expected %s, got %s", tt.changes, tt.expected, got)
+		}
+	}
+
+	ns.unsubscribe("notes://files/")
+	ns.unsubscribe("notes://tasks/")
+	got := ns.affectedResources(VaultChanges{Changed: []string{"Projects/gamma.md"}})
+	if strings.Join(got, ",") != "notes://files/Projects/" {
+		t.Errorf("Expected only the folder subscription, got %v", got)
+	}
+}
@@ -10,13 +10,17 @@ import (
 )
 
 type WriteNoteRequest struct {
-	Path    string `json:"path,omitempty" mcp:"Path to the note file to write the contents to"`
-	Content string `json:"content,omitempty" mcp:"Text content to write to the note file"`
+	Path         string `json:"path,omitempty" mcp:"Path to the note file to write the contents to"`
+	Content      string `json:"content,omitempty" mcp:"Text content to write to the note file"`
+	ExpectedHash string `json:"expected_hash,omitempty" mcp:"Hash from read_note; the write is refused if the note changed since"`
+	DryRun       bool   `json:"dry_run,omitempty" mcp:"Return the diff of the change without writing it"`
 }
 
 type AppendNoteRequest struct {
-	Path    string `json:"path,omitempty" mcp:"Path to the note file to append the contents to"`
-	Content string `json:"content,omitempty" mcp:"Text content to append to the end of the note file"`
+	Path         string `json:"path,omitempty" mcp:"Path to the note file to append the contents to"`
+	Content      string `json:"content,omitempty" mcp:"Text content to append to the end of the note file"`
+	ExpectedHash string `json:"expected_hash,omitempty" mcp:"Hash from read_note; the append is refused if the note changed since"`
+	DryRun       bool   `json:"dry_run,omitempty" mcp:"Return the diff of the change without writing it"`
 }
 
 type CreateFolderRequest struct {
@@ -35,6 +39,12 @@ func (ns *NotesServer) NewWriteNoteTool() {
 			mcp.Required(),
 			mcp.Description("Text content to write to the note file"),
 		),
+		mcp.WithString("expected_hash",
+			mcp.Description("Hash from read_note; the write is refused if the note changed since"),
+		),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("Return the diff of the change without writing it"),
+		),
 	)
 
 	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.WriteNote))
@@ -42,13 +52,26 @@ func (ns *NotesServer) NewWriteNoteTool() {
 
 // WriteNote writes content to a note
 func (ns *NotesServer) WriteNote(ctx context.Context, req mcp.CallToolRequest, params WriteNoteRequest) (*mcp.CallToolResult, error) {
+	return ns.writeNote(params, "write_note")
+}
+
+// writeNote writes content to a note, recording the tool responsible in the
+// note's version history
+func (ns *NotesServer) writeNote(params WriteNoteRequest, tool string) (*mcp.CallToolResult, error) {
 	path := params.Path
-	content := params.Content
 
 	fullPath, err := utils.ValidatePath(ns.vaultDir, path)
 	if err != nil {
 		return nil, err
 	}
+	content := ns.formatOnWriteContent(fullPath, params.Content)
+
+	if params.DryRun {
+		return ns.previewWrite(fullPath, params.ExpectedHash, func(string) string { return content }), nil
+	}
+
+	unlock := ns.lockPaths(fullPath)
+	defer unlock()
 
 	// Ensure directory exists
 	dir := filepath.Dir(fullPath)
@@ -60,6 +83,16 @@ func (ns *NotesServer) WriteNote(ctx context.Context, req mcp.CallToolRequest, p
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	if conflict := checkExpectedHash(fullPath, relativePath, params.ExpectedHash); conflict != nil {
+		return conflictResult(conflict), nil
+	}
+
+	// Save the previous content before overwriting it
+	if err := ns.snapshotNote(fullPath, tool); err != nil {
+		return nil, err
+	}
+
 	// Write file
 	// Permissions:
 	// 	Owner=rw
@@ -69,10 +102,10 @@ func (ns *NotesServer) WriteNote(ctx context.Context, req mcp.CallToolRequest, p
 		return nil, fmt.Errorf("failed to write file: %w", err)
 	}
 
-	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
 	return &mcp.CallToolResult{
+		Result: hashMeta([]byte(content)),
 		Content: []mcp.Content{
-			mcp.NewTextContent(fmt.Sprintf("Successfully wrote to note: %s", relativePath)),
+			mcp.NewTextContent(fmt.Sprintf("Successfully wrote to note: %s (hash: %s)", relativePath, contentHash([]byte(content)))),
 		},
 	}, nil
 }
@@ -89,6 +122,12 @@ func (ns *NotesServer) NewAppendNoteTool() {
 			mcp.Required(),
 			mcp.Description("Text content to write to the note file"),
 		),
+		mcp.WithString("expected_hash",
+			mcp.Description("Hash from read_note; the append is refused if the note changed since"),
+		),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("Return the diff of the change without writing it"),
+		),
 	)
 
 	ns.McpServer.AddTool(tool, mcp.NewTypedToolHandler(ns.AppendNote))
@@ -105,6 +144,13 @@ func (ns *NotesServer) AppendNote(ctx context.Context, req mcp.CallToolRequest,
 		return nil, err
 	}
 
+	if params.DryRun {
+		return ns.previewWrite(fullPath, params.ExpectedHash, func(existing string) string { return existing + content }), nil
+	}
+
+	unlock := ns.lockPaths(fullPath)
+	defer unlock()
+
 	// Ensure directory exists
 	dir := filepath.Dir(fullPath)
 	// Permissions:
@@ -115,6 +161,16 @@ func (ns *NotesServer) AppendNote(ctx context.Context, req mcp.CallToolRequest,
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	if conflict := checkExpectedHash(fullPath, relativePath, params.ExpectedHash); conflict != nil {
+		return conflictResult(conflict), nil
+	}
+
+	// Save the previous content before appending to it
+	if err := ns.snapshotNote(fullPath, "append_note"); err != nil {
+		return nil, err
+	}
+
 	// Write file
 	// Permissions:
 	// 	Owner=rw
@@ -124,10 +180,15 @@ func (ns *NotesServer) AppendNote(ctx context.Context, req mcp.CallToolRequest,
 		return nil, fmt.Errorf("failed to write file: %w", err)
 	}
 
-	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	updated, err := utils.ReadFile(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
 	return &mcp.CallToolResult{
+		Result: hashMeta(updated),
 		Content: []mcp.Content{
-			mcp.NewTextContent(fmt.Sprintf("Successfully wrote to note: %s", relativePath)),
+			mcp.NewTextContent(fmt.Sprintf("Successfully wrote to note: %s (hash: %s)", relativePath, contentHash(updated))),
 		},
 	}, nil
 }
@@ -165,3 +226,56 @@ func (ns *NotesServer) CreateFolder(ctx context.Context, req mcp.CallToolRequest
 		},
 	}, nil
 }
+
+// modifyNote applies edit to the current content of an existing note while
+// holding its path lock. The expected hash is checked and the previous
+// content snapshotted before the edited content is written. It returns the
+// new content; a stale expected hash is reported as a *ConflictError.
+func (ns *NotesServer) modifyNote(fullPath, expectedHash, tool string, edit func(content string) (string, error)) (string, error) {
+	unlock := ns.lockPaths(fullPath)
+	defer unlock()
+
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	content, err := utils.ReadFile(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("note not found: %s", relativePath)
+	}
+
+	if expectedHash != "" && contentHash(content) != expectedHash {
+		return "", &ConflictError{Path: relativePath, ExpectedHash: expectedHash, CurrentHash: contentHash(content)}
+	}
+
+	updated, err := edit(string(content))
+	if err != nil {
+		return "", err
+	}
+
+	if updated == string(content) {
+		return updated, nil
+	}
+
+	if err := ns.snapshotNote(fullPath, tool); err != nil {
+		return "", err
+	}
+
+	if err := utils.WriteFile(fullPath, []byte(updated), 0644); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return updated, nil
+}
+
+// previewWrite reports the change update would make to a note without
+// writing it. A stale expected hash is reported as a conflict, as it would
+// be by the write itself.
+func (ns *NotesServer) previewWrite(fullPath, expectedHash string, update func(existing string) string) *mcp.CallToolResult {
+	relativePath, _ := filepath.Rel(ns.vaultDir, fullPath)
+	if conflict := checkExpectedHash(fullPath, relativePath, expectedHash); conflict != nil {
+		return conflictResult(conflict)
+	}
+
+	current, err := utils.ReadFile(fullPath)
+	exists := err == nil
+
+	return changePreviewResult(newChangePreview(filepath.ToSlash(relativePath), current, exists, update(string(current))))
+}
@@ -33,8 +33,10 @@ func ValidatePath(vaultDir, inputPath string) (string, error) {
 	// Clean the path
 	fullPath = filepath.Clean(fullPath)
 
-	// Ensure the path is within the vault directory
-	if !strings.HasPrefix(fullPath, vaultDir) {
+	// Ensure the path is within the vault directory. A prefix match is not
+	// enough: /vault-private starts with /vault but is outside it.
+	rel, err := filepath.Rel(filepath.Clean(vaultDir), fullPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
 		slog.Error("Path is outside the vault directory", "vaultDir", vaultDir, "inputPath", inputPath, "fullPath", fullPath)
 		return "", fmt.Errorf("path is outside vault directory")
 	}
@@ -50,18 +52,82 @@ func ReadFile(path string) ([]byte, error) {
 	return os.ReadFile(path)
 }
 
+// WriteFile atomically replaces the contents of path. The data is written to a
+// temporary file in the same directory, flushed to disk and renamed over the
+// original, so a crash or a full disk never leaves a truncated file behind.
+// An existing file keeps its permissions; perm is only used for new files.
 func WriteFile(path string, data []byte, perm fs.FileMode) error {
-	return os.WriteFile(path, data, perm)
+	// Write through symlinks instead of replacing the link itself
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	// Remove the temporary file unless it was renamed into place
+	renamed := false
+	defer func() {
+		if !renamed {
+			os.Remove(tmpName)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+	renamed = true
+
+	// Persist the rename itself; not every platform supports syncing a directory
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
 }
 
+// AppendFile appends data to the file at path, creating it if needed. The
+// whole file is rewritten atomically with WriteFile.
 func AppendFile(path string, data []byte, perm fs.FileMode) error {
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
-	if err != nil {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
-	defer f.Close()
-	_, err = f.Write(data)
-	return err
+
+	return WriteFile(path, append(existing, data...), perm)
+}
+
+func Rename(oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+func RemoveAll(path string) error {
+	return os.RemoveAll(path)
 }
 
 func MkdirAll(path string, perm fs.FileMode) error {
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		existingMode os.FileMode
+		perm         os.FileMode
+		expectedMode os.FileMode
+	}{
+		{"New file uses perm", 0, 0640, 0640},
+		{"Existing file keeps its mode", 0600, 0644, 0600},
+		{"Existing executable stays executable", 0755, 0644, 0755},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "note.md")
+			if tt.existingMode != 0 {
+				if err := os.WriteFile(path, []byte("old"), tt.existingMode); err != nil {
+					t.Fatalf("Failed to create file: %v", err)
+				}
+				if err := os.Chmod(path, tt.existingMode); err != nil {
+					t.Fatalf("Failed to set mode: %v", err)
+				}
+			}
+
+			if err := WriteFile(path, []byte("new"), tt.perm); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil || string(content) != "new" {
+				t.Errorf("Expected the new content, got %q %v", content, err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Failed to stat file: %v", err)
+			}
+			if info.Mode().Perm() != tt.expectedMode {
+				t.Errorf("Expected mode %v, got %v", tt.expectedMode, info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestWriteFile_ThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.md")
+	link := filepath.Join(dir, "link.md")
+	if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.Symlink("target.md", link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	if err := WriteFile(link, []byte("new"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	info, err := os.Lstat(link)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected the symlink to be kept, got %v %v", info, err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "new" {
+		t.Errorf("Expected the link target to be written, got %q", content)
+	}
+}
+
+func TestWriteFile_NoTempFileLeftOnError(t *testing.T) {
+	dir := t.TempDir()
+
+	// A non-empty folder cannot be replaced by a file, so the rename fails
+	path := filepath.Join(dir, "folder")
+	if err := os.MkdirAll(filepath.Join(path, "child"), 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+
+	if err := WriteFile(path, []byte("data"), 0644); err == nil {
+		t.Fatal("Expected an error writing over a folder")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read folder: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("Temporary file left behind: %s", entry.Name())
+		}
+	}
+}
+
+func TestAppendFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.md")
+
+	if err := AppendFile(path, []byte("one\n"), 0600); err != nil {
+		t.Fatalf("AppendFile failed: %v", err)
+	}
+	if err := AppendFile(path, []byte("two\n"), 0644); err != nil {
+		t.Fatalf("AppendFile failed: %v", err)
+	}
+
+	if content, _ := os.ReadFile(path); string(content) != "one\ntwo\n" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the mode of the new file to be kept, got %v %v", info, err)
+	}
+
+	// Appending through a symlink extends the target
+	link := filepath.Join(dir, "link.md")
+	if err := os.Symlink("log.md", link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := AppendFile(link, []byte("three\n"), 0644); err != nil {
+		t.Fatalf("AppendFile failed: %v", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "one\ntwo\nthree\n" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+}
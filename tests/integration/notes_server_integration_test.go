@@ -153,7 +153,7 @@ Sibyl provides MCP servers for PDF processing and note management.
 		templateResult, err := ns.CreateNoteFromTemplate(ctx, mcp.CallToolRequest{}, notes.CreateFromTemplateRequest{
 			Path:         "new-meeting.md",
 			TemplateType: "meeting",
-			Variables: map[string]string{
+			Variables: map[string]interface{}{
 				"TITLE":     "Integration Test Meeting",
 				"ATTENDEES": "Test Team",
 				"DURATION":  "15 minutes",